@@ -40,7 +40,9 @@
 // 	environment environment variables
 // 	filetype    file types
 // 	gopath      GOPATH environment variable
+// 	goproxy     module proxy protocol
 // 	importpath  import path syntax
+// 	modules     modules, module versions, and more
 // 	packages    package lists
 // 	testflag    testing flags
 // 	testfunc    testing functions
@@ -579,7 +581,7 @@
 //
 // Usage:
 //
-// 	go list [-e] [-f format] [-json] [build flags] [packages]
+// 	go list [-e] [-f format] [-json] [-m] [build flags] [packages]
 //
 // List lists the packages named by the import paths, one per line.
 //
@@ -609,6 +611,7 @@
 //         Root          string // Go root or Go path dir containing this package
 //         ConflictDir   string // this directory shadows Dir in $GOPATH
 //         BinaryOnly    bool   // binary-only package: cannot be recompiled from sources
+//         Module        *Module // module containing package (module mode only)
 //
 //         // Source files
 //         GoFiles        []string // .go source files (excluding CgoFiles, TestGoFiles, XTestGoFiles)
@@ -693,6 +696,19 @@
 // a non-nil Error field; other information may or may not be missing
 // (zeroed).
 //
+// The -m flag causes list to list modules instead of packages.
+// It is only valid in module mode (see 'go help modules') and takes
+// no package arguments. It prints the build list: the main module
+// followed by the selected version of every module it requires,
+// directly or indirectly. The default output for -m is the module
+// path followed by its version; the struct passed to the -f template,
+// and printed by -json, is:
+//
+//     type Module struct {
+//         Path    string // module path
+//         Version string // module version (empty for the main module)
+//     }
+//
 // For more about build flags, see 'go help build'.
 //
 // For more about specifying packages, see 'go help packages'.
@@ -742,8 +758,8 @@
 //
 // 'Go test' recompiles each package along with any files with names matching
 // the file pattern "*_test.go".
-// These additional files can contain test functions, benchmark functions, and
-// example functions. See 'go help testfunc' for more.
+// These additional files can contain test functions, benchmark functions,
+// fuzz targets, and example functions. See 'go help testfunc' for more.
 // Each listed package causes the execution of a separate test binary.
 // Files whose names begin with "_" (including "_test.go") or "." are ignored.
 //
@@ -1004,6 +1020,10 @@
 //
 // 	GCCGO
 // 		The gccgo command to run for 'go build -compiler=gccgo'.
+// 	GO111MODULE
+// 		Controls whether the go command runs in module mode.
+// 		Either off, on, or auto (the default).
+// 		See 'go help modules'.
 // 	GOARCH
 // 		The architecture, or processor, for which to compile code.
 // 		Examples are amd64, 386, arm, ppc64.
@@ -1014,6 +1034,9 @@
 // 		Examples are linux, darwin, windows, netbsd.
 // 	GOPATH
 // 		For more details see: 'go help gopath'.
+// 	GOPROXY
+// 		URL of the module proxy used in module mode.
+// 		See 'go help goproxy'.
 // 	GORACE
 // 		Options for the race detector.
 // 		See https://golang.org/doc/articles/race_detector.html.
@@ -1283,6 +1306,42 @@
 // See https://golang.org/s/go15vendor for details.
 //
 //
+// Module proxy protocol
+//
+// The go command fetches modules from a module proxy named by the
+// GOPROXY environment variable. A module proxy is any web server
+// that responds to GET requests for URLs of a specified form.
+// The requests have no query parameters, so a site serving from a fixed
+// file system, including a local directory named using a file:/// URL,
+// can be a module proxy.
+//
+// The GET requests sent to a Go module proxy are:
+//
+// GET $GOPROXY/<module>/@v/list returns a list of all known versions of the
+// given module, one per line.
+//
+// GET $GOPROXY/<module>/@v/<version>.mod returns the go.mod file
+// for that version of the given module.
+//
+// GET $GOPROXY/<module>/@v/<version>.zip returns the zip archive
+// for that version of the given module.
+//
+// To avoid problems when serving from case-insensitive file systems,
+// the <module> and <version> elements are case-encoded, replacing every
+// uppercase letter with an exclamation mark followed by the corresponding
+// lower-case letter: github.com/Azure encodes as github.com/!azure.
+//
+// Every file in the zip archive for module M at version V must be
+// stored under the directory "M@V/", so that the archive for
+// example.com/hello at v1.2.0 contains example.com/hello@v1.2.0/go.mod,
+// example.com/hello@v1.2.0/hello.go, and so on.
+//
+// Fetching modules directly from version control repositories is not
+// supported. When GOPROXY is unset, only modules already present in the
+// module cache can be used. Setting GOPROXY=off disallows downloading
+// modules altogether.
+//
+//
 // Import path syntax
 //
 // An import path (see 'go help packages') denotes a package stored in the local
@@ -1456,6 +1515,86 @@
 // See https://golang.org/s/go14customimport for details.
 //
 //
+// Modules, module versions, and more
+//
+// A module is a collection of related Go packages that are versioned
+// together as a single unit. Modules record precise dependency
+// requirements and create reproducible builds.
+//
+// A module is defined by a tree of Go source files with a go.mod file
+// in the tree's root directory. The directory containing the go.mod file
+// is called the module root. The module root and its subdirectories,
+// excluding subdirectories with their own go.mod files, make up the
+// module. The go.mod file declares the module path, which is the import
+// path prefix for all packages in the module, and lists the modules
+// the main module requires, by path and semantic version:
+//
+// 	module example.com/hello
+//
+// 	require (
+// 		example.com/greeting v1.2.0
+// 		example.com/world v0.3.1
+// 	)
+//
+// The go.mod file may also exclude specific module versions,
+// which are then skipped in favor of the next higher version,
+// and replace a module version with another module or with a
+// directory on the local file system:
+//
+// 	exclude example.com/world v0.3.0
+// 	replace example.com/greeting v1.2.0 => ../greeting
+//
+// Exclude and replace directives apply only in the main module's
+// go.mod file; they are ignored in the go.mod files of dependencies.
+//
+// Module mode
+//
+// The go command runs in module mode, ignoring GOPATH/src and vendor
+// directories, when the current directory or one of its parents contains
+// a go.mod file and the current directory is outside $GOPATH/src. The
+// GO111MODULE environment variable overrides this choice: GO111MODULE=on
+// requires module mode, and GO111MODULE=off disables it.
+//
+// In module mode, the module containing the current directory is the
+// main module. Packages inside the main module may be named by their
+// import paths or by relative paths such as ./..., and the main module
+// may live anywhere in the file system.
+//
+// Version selection
+//
+// The set of modules providing packages to a build is called the build
+// list. The go command computes it by minimal version selection:
+// starting from the main module's requirements, it follows the
+// requirements listed in each required module's own go.mod file and
+// selects, for each module path, the maximum version required anywhere
+// in the resulting graph. Because the selection depends only on the
+// go.mod files involved, and never on which versions are newest, builds
+// are reproducible. 'go list -m' prints the build list.
+//
+// 'go get path@version' adds or updates a requirement in go.mod, where
+// version is a semantic version such as v1.2.0 or "latest"; the
+// special version "none" removes the requirement. 'go get -u' upgrades
+// the dependencies of the named modules (or, with no arguments, all
+// modules in the build list) to their latest versions. Other commands
+// never change go.mod.
+//
+// Downloading and verifying modules
+//
+// Modules are downloaded from the module proxy named by GOPROXY (see
+// 'go help goproxy') into the module cache in $GOPATH/pkg/mod, where
+// they are shared by all main modules. Commands can run without network
+// access once the required modules are in the cache, or when GOPROXY
+// names a local directory using a file:/// URL.
+//
+// The go command records the expected cryptographic checksum of each
+// downloaded module, and of each go.mod file it consults, in a go.sum
+// file next to go.mod. Each line of go.sum gives a module path, a version
+// (suffixed with /go.mod for go.mod checksums), and a hash. On later
+// downloads the go command checks the content against go.sum and refuses
+// to use a module whose checksum does not match. Both go.mod and go.sum
+// should be checked into version control.
+//
+//
 // Package lists
 //
 // Many commands apply to a set of packages:
@@ -1606,6 +1745,26 @@
 // 	-failfast
 // 	    Do not start new tests after the first test failure.
 //
+// 	-fuzz regexp
+// 	    Run the fuzz target matching the regular expression. When specified,
+// 	    the command line argument must match exactly one package, and regexp
+// 	    must match exactly one fuzz target within that package. After the
+// 	    tests, benchmarks, and the seed corpora of all fuzz targets have run,
+// 	    the matching target is fuzzed: its inputs are mutated at random,
+// 	    guided by the coverage of the package being tested, until an input
+// 	    makes the fuzz function fail. The failing input is minimized and
+// 	    written to the package's testdata/fuzz directory. Interesting inputs
+// 	    found along the way are kept in the build cache and reused by later
+// 	    runs. Unless -timeout is given, fuzzing disables the test timeout.
+//
+// 	-fuzzminimizetime t
+// 	    Spend at most t, specified as a time.Duration, minimizing a
+// 	    failing input found while fuzzing. The default is 1 minute (1m).
+//
+// 	-fuzztime t
+// 	    Stop fuzzing after t, specified as a time.Duration, if no failing
+// 	    input has been found. The default is to fuzz until a failure.
+//
 // 	-list regexp
 // 	    List tests, benchmarks, or examples matching the regular expression.
 // 	    No tests, benchmarks or examples will be run. This will only
@@ -1767,8 +1926,8 @@
 //
 // Testing functions
 //
-// The 'go test' command expects to find test, benchmark, and example functions
-// in the "*_test.go" files corresponding to the package under test.
+// The 'go test' command expects to find test, benchmark, fuzz, and example
+// functions in the "*_test.go" files corresponding to the package under test.
 //
 // A test function is one named TestXxx (where Xxx does not start with a
 // lower case letter) and should have the signature,
@@ -1779,6 +1938,14 @@
 //
 // 	func BenchmarkXxx(b *testing.B) { ... }
 //
+// A fuzz target is one named FuzzXxx and should have the signature,
+//
+// 	func FuzzXxx(f *testing.F) { ... }
+//
+// By default, go test runs the fuzz function passed to f.Fuzz on each input
+// in the target's seed corpus and testdata/fuzz/FuzzXxx directory. See the
+// -fuzz flag in 'go help testflag' for fuzzing with generated inputs.
+//
 // An example function is similar to a test function but, instead of using
 // *testing.T to report success or failure, prints output to os.Stdout.
 // If the last comment in the function starts with "Output:" then the output
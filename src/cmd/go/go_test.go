@@ -5921,3 +5921,63 @@ echo $* >>`+tg.path("pkg-config.out"))
 		t.Errorf("got %q want %q", out, want)
 	}
 }
+
+func TestGoTestFuzz(t *testing.T) {
+	tooSlow(t)
+
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.parallel()
+	tg.makeTempdir()
+	tg.setenv("GOCACHE", tg.path("cache"))
+	tg.setenv("GOPATH", tg.path("."))
+	tg.tempFile("src/fuzzer/fuzzer.go", `package fuzzer
+
+		func Check(b []byte) {
+			if len(b) > 2 {
+				if b[0] == 'B' {
+					if b[1] == 'U' {
+						if b[2] == 'G' {
+							panic("found the bug")
+						}
+					}
+				}
+			}
+		}
+	`)
+	tg.tempFile("src/fuzzer/fuzzer_test.go", `package fuzzer
+
+		import "testing"
+
+		func FuzzCheck(f *testing.F) {
+			f.Add([]byte("seed"))
+			f.Fuzz(func(t *testing.T, b []byte) {
+				Check(b)
+			})
+		}
+	`)
+
+	// Without -fuzz, only the seed corpus runs.
+	tg.run("test", "-v", "fuzzer")
+	tg.grepStdout(`--- PASS: FuzzCheck/seed#0`, "seed corpus did not run")
+
+	// With -fuzz, the failing input is found, minimized, and saved.
+	tg.runFail("test", "-fuzz=FuzzCheck", "-fuzztime=5m", "fuzzer")
+	tg.grepStdout(`--- FAIL: FuzzCheck/[0-9a-f]{16}`, "fuzzing did not report the failing input")
+	tg.grepStdout(`panic: found the bug`, "fuzzing did not report the panic")
+	tg.grepStdout(`Failing input written to testdata.fuzz.FuzzCheck.[0-9a-f]{16}`, "fuzzing did not save the failing input")
+	files, err := ioutil.ReadDir(tg.path("src/fuzzer/testdata/fuzz/FuzzCheck"))
+	tg.must(err)
+	if len(files) != 1 {
+		t.Fatalf("found %d saved inputs, want 1", len(files))
+	}
+	data, err := ioutil.ReadFile(tg.path("src/fuzzer/testdata/fuzz/FuzzCheck/" + files[0].Name()))
+	tg.must(err)
+	if want := "go test fuzz v1\n[]byte(\"BUG\")\n"; string(data) != want {
+		t.Errorf("saved input is %q, want minimized input %q", data, want)
+	}
+
+	// Plain go test now replays the saved input.
+	tg.runFail("test", "fuzzer")
+	tg.grepStdout(`panic: found the bug`, "saved input was not replayed")
+}
@@ -16,6 +16,7 @@ import (
 	"cmd/go/internal/base"
 	"cmd/go/internal/cfg"
 	"cmd/go/internal/load"
+	"cmd/go/internal/modload"
 	"cmd/go/internal/str"
 	"cmd/go/internal/web"
 	"cmd/go/internal/work"
@@ -92,6 +93,11 @@ func init() {
 func runGet(cmd *base.Command, args []string) {
 	work.BuildInit()
 
+	if modload.Enabled() {
+		runModGet(args)
+		return
+	}
+
 	if *getF && !*getU {
 		base.Fatalf("go get: cannot use -f flag without -u")
 	}
@@ -0,0 +1,200 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package get
+
+import (
+	"fmt"
+	"go/build"
+	"path/filepath"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/modfetch"
+	"cmd/go/internal/modload"
+	"cmd/go/internal/module"
+	"cmd/go/internal/semver"
+	"cmd/go/internal/work"
+)
+
+// runModGet implements 'go get' in module mode.
+// Each argument is a package or module path, optionally followed by
+// @version, where version is a semantic version, "latest", or "none"
+// (to drop the requirement).
+// The requirements in go.mod are updated to the given versions,
+// the build list is recomputed, and the named packages are installed.
+func runModGet(args []string) {
+	if *getF || *getFix || *getInsecure {
+		base.Fatalf("go get: -f, -fix, and -insecure flags are not supported in module mode")
+	}
+	if len(args) == 0 && !*getU {
+		args = []string{"."}
+	}
+
+	modFile := modload.ModFile()
+	var (
+		pkgs      []string // packages to install
+		requested []string // module paths named on the command line
+	)
+	for _, arg := range args {
+		path, vers := arg, ""
+		if i := strings.Index(arg, "@"); i >= 0 {
+			path, vers = arg[:i], arg[i+1:]
+		}
+		if build.IsLocalImport(path) {
+			p, ok := modload.ImportPathForDir(filepath.Join(base.Cwd, path))
+			if !ok {
+				base.Errorf("go get %s: directory is outside main module", arg)
+				continue
+			}
+			path = p
+		}
+		if path == modload.Target.Path || strings.HasPrefix(path, modload.Target.Path+"/") || modload.IsStandardImportPath(path) {
+			if vers != "" {
+				base.Errorf("go get %s: cannot request version of %s", arg, path)
+				continue
+			}
+			pkgs = append(pkgs, path)
+			continue
+		}
+		if vers == "none" {
+			// Drop the requirement on the module providing path.
+			modPath := ""
+			for _, r := range modFile.Require {
+				if (path == r.Mod.Path || strings.HasPrefix(path, r.Mod.Path+"/")) && len(r.Mod.Path) > len(modPath) {
+					modPath = r.Mod.Path
+				}
+			}
+			if modPath == "" {
+				base.Errorf("go get %s: %s is not required by go.mod", arg, path)
+				continue
+			}
+			modFile.DropRequire(modPath)
+			continue
+		}
+		modPath, v, err := queryModule(path, vers)
+		if err != nil {
+			base.Errorf("go get %s: %v", arg, err)
+			continue
+		}
+		modFile.AddRequire(modPath, v)
+		requested = append(requested, modPath)
+		pkgs = append(pkgs, path)
+	}
+	base.ExitIfErrors()
+
+	if *getU {
+		// Upgrade the dependencies of the requested modules, or, if
+		// none were requested, every module in the build list.
+		modload.LoadBuildList()
+		upgrade := make(map[string]bool)
+		if len(requested) == 0 {
+			for _, m := range modload.BuildList()[1:] {
+				upgrade[m.Path] = true
+			}
+		} else {
+			reqs := modload.Reqs()
+			seen := make(map[module.Version]bool)
+			var walk func(m module.Version)
+			walk = func(m module.Version) {
+				if seen[m] {
+					return
+				}
+				seen[m] = true
+				list, err := reqs.Required(m)
+				if err != nil {
+					base.Errorf("go get: %v", err)
+					return
+				}
+				for _, r := range list {
+					upgrade[r.Path] = true
+					walk(r)
+				}
+			}
+			for _, path := range requested {
+				walk(module.Version{Path: path, Version: modload.Selected(path)})
+			}
+		}
+		for path := range upgrade {
+			latest, err := modfetch.Latest(path)
+			if err != nil {
+				base.Errorf("go get: %v", err)
+				continue
+			}
+			if semver.Compare(latest, modload.Selected(path)) > 0 {
+				modFile.AddRequire(path, latest)
+			}
+		}
+		base.ExitIfErrors()
+	}
+
+	modload.LoadBuildList()
+	modload.WriteGoMod()
+
+	// Download the source of every requested module,
+	// and drop requested paths that name modules with
+	// no package in their root directory.
+	var install []string
+	for _, path := range pkgs {
+		if modload.IsStandardImportPath(path) || path == modload.Target.Path || strings.HasPrefix(path, modload.Target.Path+"/") {
+			install = append(install, path)
+			continue
+		}
+		if _, _, err := modload.Lookup(path); err != nil {
+			if _, ok := err.(*modload.ImportMissingError); ok && modload.Selected(path) != "" {
+				// A module root without a package is fine.
+				continue
+			}
+			base.Errorf("go get %s: %v", path, err)
+			continue
+		}
+		install = append(install, path)
+	}
+	base.ExitIfErrors()
+
+	if *getD || len(install) == 0 {
+		return
+	}
+	work.InstallPackages(install, true)
+}
+
+// queryModule finds the module providing path, which may be a module
+// path or a package path inside a module, and resolves the version
+// query vers against the versions available from the module proxy.
+// An empty query or "latest" selects the latest version.
+func queryModule(path, vers string) (modPath, version string, err error) {
+	if vers != "" && vers != "latest" && !semver.IsValid(vers) {
+		return "", "", fmt.Errorf("invalid version %q: must be a semantic version, latest, or none", vers)
+	}
+	var firstErr error
+	for p := path; ; {
+		list, err := modfetch.Versions(p)
+		if err == nil && len(list) > 0 {
+			switch vers {
+			case "", "latest":
+				v, err := modfetch.Latest(p)
+				return p, v, err
+			}
+			cv := semver.Canonical(vers)
+			for _, v := range list {
+				if v == cv {
+					return p, v, nil
+				}
+			}
+			return "", "", fmt.Errorf("module %s has no version %s", p, vers)
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		i := strings.LastIndex(p, "/")
+		if i < 0 {
+			break
+		}
+		p = p[:i]
+	}
+	if firstErr == nil {
+		firstErr = fmt.Errorf("no versions found")
+	}
+	return "", "", fmt.Errorf("cannot find module providing %s: %v", path, firstErr)
+}
@@ -456,6 +456,10 @@ General-purpose environment variables:
 
 	GCCGO
 		The gccgo command to run for 'go build -compiler=gccgo'.
+	GO111MODULE
+		Controls whether the go command runs in module mode.
+		Either off, on, or auto (the default).
+		See 'go help modules'.
 	GOARCH
 		The architecture, or processor, for which to compile code.
 		Examples are amd64, 386, arm, ppc64.
@@ -466,6 +470,9 @@ General-purpose environment variables:
 		Examples are linux, darwin, windows, netbsd.
 	GOPATH
 		For more details see: 'go help gopath'.
+	GOPROXY
+		URL of the module proxy used in module mode.
+		See 'go help goproxy'.
 	GORACE
 		Options for the race detector.
 		See https://golang.org/doc/articles/race_detector.html.
@@ -16,11 +16,12 @@ import (
 	"cmd/go/internal/base"
 	"cmd/go/internal/cfg"
 	"cmd/go/internal/load"
+	"cmd/go/internal/modload"
 	"cmd/go/internal/work"
 )
 
 var CmdList = &base.Command{
-	UsageLine: "list [-e] [-f format] [-json] [build flags] [packages]",
+	UsageLine: "list [-e] [-f format] [-json] [-m] [build flags] [packages]",
 	Short:     "list packages",
 	Long: `
 List lists the packages named by the import paths, one per line.
@@ -51,6 +52,7 @@ syntax of package template. The default output is equivalent to -f
         Root          string // Go root or Go path dir containing this package
         ConflictDir   string // this directory shadows Dir in $GOPATH
         BinaryOnly    bool   // binary-only package: cannot be recompiled from sources
+        Module        *Module // module containing package (module mode only)
 
         // Source files
         GoFiles        []string // .go source files (excluding CgoFiles, TestGoFiles, XTestGoFiles)
@@ -135,6 +137,19 @@ printing. Erroneous packages will have a non-empty ImportPath and
 a non-nil Error field; other information may or may not be missing
 (zeroed).
 
+The -m flag causes list to list modules instead of packages.
+It is only valid in module mode (see 'go help modules') and takes
+no package arguments. It prints the build list: the main module
+followed by the selected version of every module it requires,
+directly or indirectly. The default output for -m is the module
+path followed by its version; the struct passed to the -f template,
+and printed by -json, is:
+
+    type Module struct {
+        Path    string // module path
+        Version string // module version (empty for the main module)
+    }
+
 For more about build flags, see 'go help build'.
 
 For more about specifying packages, see 'go help packages'.
@@ -149,6 +164,7 @@ func init() {
 var listE = CmdList.Flag.Bool("e", false, "")
 var listFmt = CmdList.Flag.String("f", "{{.ImportPath}}", "")
 var listJson = CmdList.Flag.Bool("json", false, "")
+var listM = CmdList.Flag.Bool("m", false, "")
 var nl = []byte{'\n'}
 
 func runList(cmd *base.Command, args []string) {
@@ -156,6 +172,11 @@ func runList(cmd *base.Command, args []string) {
 	out := newTrackingWriter(os.Stdout)
 	defer out.w.Flush()
 
+	if *listM {
+		listModules(out, args)
+		return
+	}
+
 	var do func(*load.PackagePublic)
 	if *listJson {
 		do = func(p *load.PackagePublic) {
@@ -255,3 +276,40 @@ func (t *TrackingWriter) Flush() {
 func (t *TrackingWriter) NeedNL() bool {
 	return t.last != '\n'
 }
+
+// listModules implements 'go list -m'.
+func listModules(out *TrackingWriter, args []string) {
+	if !modload.Enabled() {
+		base.Fatalf("go list -m: not using modules")
+	}
+	if len(args) > 0 {
+		base.Fatalf("go list -m: package arguments not supported")
+	}
+	format := *listFmt
+	if format == "{{.ImportPath}}" {
+		format = "{{.Path}}{{with .Version}} {{.}}{{end}}"
+	}
+	tmpl, err := template.New("main").Parse(format)
+	if err != nil {
+		base.Fatalf("%s", err)
+	}
+	for _, m := range modload.BuildList() {
+		if *listJson {
+			b, err := json.MarshalIndent(m, "", "\t")
+			if err != nil {
+				out.Flush()
+				base.Fatalf("%s", err)
+			}
+			out.Write(b)
+			out.Write(nl)
+			continue
+		}
+		if err := tmpl.Execute(out, m); err != nil {
+			out.Flush()
+			base.Fatalf("%s", err)
+		}
+		if out.NeedNL() {
+			out.Write(nl)
+		}
+	}
+}
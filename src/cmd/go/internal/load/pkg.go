@@ -20,6 +20,8 @@ import (
 
 	"cmd/go/internal/base"
 	"cmd/go/internal/cfg"
+	"cmd/go/internal/modload"
+	"cmd/go/internal/module"
 	"cmd/go/internal/str"
 )
 
@@ -48,6 +50,9 @@ type PackagePublic struct {
 	ConflictDir   string `json:",omitempty"` // Dir is hidden by this other directory
 	BinaryOnly    bool   `json:",omitempty"` // package cannot be recompiled
 
+	// Module is the module providing the package (module mode only).
+	Module *module.Version `json:",omitempty"`
+
 	// Stale and StaleReason remain here *only* for the list command.
 	// They are only initialized in preparation for list execution.
 	// The regular build determines staleness on the fly during action execution.
@@ -412,9 +417,13 @@ func LoadImport(path, srcDir string, parent *Package, stk *ImportStack, importPo
 	importPath := path
 	origPath := path
 	isLocal := build.IsLocalImport(path)
+	useModules := !isLocal && modload.Enabled() && !modload.IsStandardImportPath(path)
 	var debugDeprecatedImportcfgDir string
 	if isLocal {
 		importPath = dirToImportPath(filepath.Join(srcDir, path))
+	} else if useModules {
+		// Module mode: the import path is the canonical identifier.
+		// There is no vendor expansion; modload resolves the path below.
 	} else if DebugDeprecatedImportcfg.enabled {
 		if d, i := DebugDeprecatedImportcfg.lookup(parent, path); d != "" {
 			debugDeprecatedImportcfgDir = d
@@ -443,7 +452,17 @@ func LoadImport(path, srcDir string, parent *Package, stk *ImportStack, importPo
 		// in order to return partial information.
 		var bp *build.Package
 		var err error
-		if debugDeprecatedImportcfgDir != "" {
+		var mod module.Version
+		if useModules {
+			var dir string
+			dir, mod, err = modload.Lookup(path)
+			if err != nil {
+				bp = new(build.Package)
+			} else {
+				bp, err = cfg.BuildContext.ImportDir(dir, 0)
+				bp.BinDir = modload.BinDir()
+			}
+		} else if debugDeprecatedImportcfgDir != "" {
 			bp, err = cfg.BuildContext.ImportDir(debugDeprecatedImportcfgDir, 0)
 		} else if DebugDeprecatedImportcfg.enabled {
 			bp = new(build.Package)
@@ -460,10 +479,13 @@ func LoadImport(path, srcDir string, parent *Package, stk *ImportStack, importPo
 		if cfg.GOBIN != "" {
 			bp.BinDir = cfg.GOBIN
 		}
-		if debugDeprecatedImportcfgDir == "" && err == nil && !isLocal && bp.ImportComment != "" && bp.ImportComment != path &&
+		if !useModules && debugDeprecatedImportcfgDir == "" && err == nil && !isLocal && bp.ImportComment != "" && bp.ImportComment != path &&
 			!strings.Contains(path, "/vendor/") && !strings.HasPrefix(path, "vendor/") {
 			err = fmt.Errorf("code in directory %s expects import %q", bp.Dir, bp.ImportComment)
 		}
+		if mod.Path != "" {
+			p.Module = &mod
+		}
 		p.load(stk, bp, err)
 		if p.Error != nil && p.Error.Pos == "" {
 			p = setErrorPos(p, importPos)
@@ -479,7 +501,7 @@ func LoadImport(path, srcDir string, parent *Package, stk *ImportStack, importPo
 	}
 
 	// Checked on every import because the rules depend on the code doing the importing.
-	if perr := disallowInternal(srcDir, p, stk); perr != p {
+	if perr := disallowInternal(srcDir, parent, p, stk); perr != p {
 		return setErrorPos(perr, importPos)
 	}
 	if mode&UseVendor != 0 {
@@ -662,10 +684,10 @@ func reusePackage(p *Package, stk *ImportStack) *Package {
 	return p
 }
 
-// disallowInternal checks that srcDir is allowed to import p.
+// disallowInternal checks that srcDir (containing package importer) is allowed to import p.
 // If the import is allowed, disallowInternal returns the original package p.
 // If not, it returns a new package containing just an appropriate error.
-func disallowInternal(srcDir string, p *Package, stk *ImportStack) *Package {
+func disallowInternal(srcDir string, importer *Package, p *Package, stk *ImportStack) *Package {
 	// golang.org/s/go14internal:
 	// An import of a path containing the element “internal”
 	// is disallowed if the importing code is outside the tree
@@ -704,22 +726,31 @@ func disallowInternal(srcDir string, p *Package, stk *ImportStack) *Package {
 	}
 
 	// Internal is present.
-	// Map import path back to directory corresponding to parent of internal.
 	if i > 0 {
 		i-- // rewind over slash in ".../internal"
 	}
-	parent := p.Dir[:i+len(p.Dir)-len(p.ImportPath)]
-	if str.HasFilePathPrefix(filepath.Clean(srcDir), filepath.Clean(parent)) {
-		return p
-	}
+	if p.Module != nil {
+		// In module mode the directory layout need not match the
+		// import path (the module cache adds @version to directory
+		// names), so compare import paths instead.
+		parent := p.ImportPath[:i]
+		if importer != nil && str.HasPathPrefix(importer.ImportPath, parent) {
+			return p
+		}
+	} else {
+		// Map import path back to directory corresponding to parent of internal.
+		parent := p.Dir[:i+len(p.Dir)-len(p.ImportPath)]
+		if str.HasFilePathPrefix(filepath.Clean(srcDir), filepath.Clean(parent)) {
+			return p
+		}
 
-	// Look for symlinks before reporting error.
-	srcDir = expandPath(srcDir)
-	parent = expandPath(parent)
-	if str.HasFilePathPrefix(filepath.Clean(srcDir), filepath.Clean(parent)) {
-		return p
+		// Look for symlinks before reporting error.
+		srcDir = expandPath(srcDir)
+		parent = expandPath(parent)
+		if str.HasFilePathPrefix(filepath.Clean(srcDir), filepath.Clean(parent)) {
+			return p
+		}
 	}
-
 	// Internal is present, and srcDir is outside parent's tree. Not allowed.
 	perr := *p
 	perr.Error = &PackageError{
@@ -947,6 +978,11 @@ func (p *Package) load(stk *ImportStack, bp *build.Package, err error) {
 			return
 		}
 		_, elem := filepath.Split(p.Dir)
+		if p.Module != nil {
+			// Module cache directories carry an @version suffix;
+			// name the command after its import path instead.
+			elem = pathpkg.Base(p.ImportPath)
+		}
 		full := cfg.BuildContext.GOOS + "_" + cfg.BuildContext.GOARCH + "/" + elem
 		if cfg.BuildContext.GOOS != base.ToolGOOS || cfg.BuildContext.GOARCH != base.ToolGOARCH {
 			// Install cross-compiled binaries to subdirectories of bin.
@@ -1396,6 +1432,14 @@ func LoadPackage(arg string, stk *ImportStack) *Package {
 	}
 
 	// Wasn't a command; must be a package.
+	// In module mode, a local path naming a directory in the
+	// main module refers to the package by its module import path.
+	if build.IsLocalImport(arg) && modload.Enabled() {
+		if path, ok := modload.ImportPathForDir(filepath.Join(base.Cwd, arg)); ok {
+			arg = path
+		}
+	}
+
 	// If it is a local import path but names a standard package,
 	// we treat it as if the user specified the standard package.
 	// This lets you run go test ./ioutil in package io and be
@@ -6,6 +6,7 @@ package load
 
 import (
 	"cmd/go/internal/cfg"
+	"cmd/go/internal/modload"
 	"fmt"
 	"go/build"
 	"log"
@@ -49,6 +50,21 @@ func MatchPackages(pattern string) []string {
 		treeCanMatch = treeCanMatchPattern(pattern)
 	}
 
+	modules := modload.Enabled()
+	if modules && pattern == "all" {
+		// In module mode, "all" is the packages in the main module
+		// together with all their dependencies.
+		roots := modload.MatchPackages(match, func(string) bool { return false })
+		var pkgs []string
+		for _, p := range PackageList(PackagesAndErrors(roots)) {
+			pkgs = append(pkgs, p.ImportPath)
+		}
+		return pkgs
+	}
+	if modules && pattern != "std" && pattern != "cmd" && !modload.IsStandardImportPath(pattern) {
+		return modload.MatchPackages(match, treeCanMatch)
+	}
+
 	have := map[string]bool{
 		"builtin": true, // ignore pseudo-package that exists only for documentation
 	}
@@ -58,7 +74,7 @@ func MatchPackages(pattern string) []string {
 	var pkgs []string
 
 	for _, src := range cfg.BuildContext.SrcDirs() {
-		if (pattern == "std" || pattern == "cmd") && src != cfg.GOROOTsrc {
+		if (pattern == "std" || pattern == "cmd" || modules) && src != cfg.GOROOTsrc {
 			continue
 		}
 		src = filepath.Clean(src) + string(filepath.Separator)
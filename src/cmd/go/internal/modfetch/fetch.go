@@ -0,0 +1,258 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package modfetch downloads modules from a module proxy
+// into the module cache and verifies them against go.sum.
+package modfetch
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cmd/go/internal/module"
+)
+
+// SrcMod is the root of the module cache, usually $GOPATH/pkg/mod.
+// It is set by the modload package before any module is fetched.
+var SrcMod string
+
+// CacheDir returns the download cache directory for the module path.
+func CacheDir(path string) (string, error) {
+	if SrcMod == "" {
+		return "", fmt.Errorf("internal error: modfetch.SrcMod not set")
+	}
+	enc, err := module.EncodePath(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(SrcMod, "cache/download", enc, "@v"), nil
+}
+
+// CachePath returns the file in the download cache for the given
+// module version with the given suffix, such as "mod" or "zip".
+func CachePath(m module.Version, suffix string) (string, error) {
+	dir, err := CacheDir(m.Path)
+	if err != nil {
+		return "", err
+	}
+	v, err := module.EncodeVersion(m.Version)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, v+"."+suffix), nil
+}
+
+// DownloadDir returns the directory to which m is extracted
+// in the module cache.
+func DownloadDir(m module.Version) (string, error) {
+	if SrcMod == "" {
+		return "", fmt.Errorf("internal error: modfetch.SrcMod not set")
+	}
+	enc, err := module.EncodePath(m.Path)
+	if err != nil {
+		return "", err
+	}
+	v, err := module.EncodeVersion(m.Version)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(SrcMod, enc+"@"+v), nil
+}
+
+// GoMod returns the go.mod file for the given module version,
+// consulting the download cache first and then the proxy.
+// The content is checked against go.sum.
+func GoMod(path, version string) ([]byte, error) {
+	m := module.Version{Path: path, Version: version}
+	file, err := CachePath(m, "mod")
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		name, err := proxyName(path, version+".mod")
+		if err != nil {
+			return nil, err
+		}
+		data, err = proxyGet(name)
+		if err != nil {
+			return nil, fmt.Errorf("fetching go.mod for %v: %v", m, err)
+		}
+		if err := checkGoMod(m, data); err != nil {
+			return nil, err
+		}
+		if err := writeFile(file, data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+	if err := checkGoMod(m, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Download downloads the specified module version
+// (or finds it already in the module cache)
+// and returns the directory holding its extracted source tree.
+func Download(m module.Version) (dir string, err error) {
+	dir, err = DownloadDir(m)
+	if err != nil {
+		return "", err
+	}
+	hashFile, err := CachePath(m, "ziphash")
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(dir); err == nil {
+		// The directory was extracted earlier: check that the
+		// recorded hash of its zip file still matches go.sum.
+		data, err := ioutil.ReadFile(hashFile)
+		if err != nil {
+			return "", fmt.Errorf("module %v: missing hash of cached copy; remove %s and try again", m, dir)
+		}
+		if err := checkSum(m, strings.TrimSpace(string(data))); err != nil {
+			return "", err
+		}
+		return dir, nil
+	}
+
+	zipFile, err := CachePath(m, "zip")
+	if err != nil {
+		return "", err
+	}
+	data, err := ioutil.ReadFile(zipFile)
+	if err != nil {
+		name, err := proxyName(m.Path, m.Version+".zip")
+		if err != nil {
+			return "", err
+		}
+		data, err = proxyGet(name)
+		if err != nil {
+			return "", fmt.Errorf("downloading %v: %v", m, err)
+		}
+	}
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("module %v: %v", m, err)
+	}
+	h, err := HashZip(z)
+	if err != nil {
+		return "", fmt.Errorf("module %v: %v", m, err)
+	}
+	if err := checkSum(m, h); err != nil {
+		return "", err
+	}
+	if err := writeFile(zipFile, data); err != nil {
+		return "", err
+	}
+	if err := writeFile(hashFile, []byte(h)); err != nil {
+		return "", err
+	}
+	if err := unzip(dir, z, m); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// unzip extracts z, the zip file for module m, into dir.
+// It extracts into a temporary directory first, so that a failed
+// or interrupted extraction never leaves a partial tree at dir.
+func unzip(dir string, z *zip.Reader, m module.Version) error {
+	prefix := m.Path + "@" + m.Version + "/"
+	if err := os.MkdirAll(filepath.Dir(dir), 0777); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir(filepath.Dir(dir), filepath.Base(dir)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	seen := make(map[string]bool)
+	for _, f := range z.File {
+		if !strings.HasPrefix(f.Name, prefix) {
+			return fmt.Errorf("module %v: zip file contains %s, not under %s", m, f.Name, prefix)
+		}
+		name := f.Name[len(prefix):]
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		if err := checkFileName(name); err != nil {
+			return fmt.Errorf("module %v: zip file contains %s: %v", m, f.Name, err)
+		}
+		lower := strings.ToLower(name)
+		if seen[lower] {
+			return fmt.Errorf("module %v: zip file contains %s more than once (ignoring case)", m, f.Name)
+		}
+		seen[lower] = true
+
+		dst := filepath.Join(tmp, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0777); err != nil {
+			return err
+		}
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+		w, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0444)
+		if err != nil {
+			r.Close()
+			return err
+		}
+		_, err = io.Copy(w, r)
+		r.Close()
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("module %v: extracting %s: %v", m, f.Name, err)
+		}
+	}
+	return os.Rename(tmp, dir)
+}
+
+// checkFileName reports whether the slash-separated name
+// is a safe relative file name for extraction.
+func checkFileName(name string) error {
+	if strings.HasPrefix(name, "/") || strings.Contains(name, "\\") || strings.Contains(name, ":") {
+		return fmt.Errorf("invalid file name")
+	}
+	for _, elem := range strings.Split(name, "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			return fmt.Errorf("invalid file name")
+		}
+	}
+	return nil
+}
+
+// writeFile writes data to file, creating the parent directory
+// as needed. The data is written to a temporary file first
+// so that concurrent readers never observe a partial file.
+func writeFile(file string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), file)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
@@ -0,0 +1,118 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modfetch
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"cmd/go/internal/module"
+	"cmd/go/internal/semver"
+	"cmd/go/internal/web"
+)
+
+var errNoProxy = errors.New("module lookup disabled by GOPROXY=off")
+
+// proxyURL returns the configured proxy base URL.
+func proxyURL() (string, error) {
+	u := os.Getenv("GOPROXY")
+	switch {
+	case u == "":
+		return "", errors.New("module not in cache and GOPROXY is not set (see 'go help goproxy')")
+	case u == "off":
+		return "", errNoProxy
+	case strings.HasPrefix(u, "file://"), strings.HasPrefix(u, "https://"), strings.HasPrefix(u, "http://"):
+		return strings.TrimSuffix(u, "/"), nil
+	}
+	return "", fmt.Errorf("invalid $GOPROXY setting %q: must be an http, https, or file URL", u)
+}
+
+// proxyGet fetches the file name (relative to the proxy root) from the proxy.
+func proxyGet(name string) ([]byte, error) {
+	base, err := proxyURL()
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(base, "file://") {
+		dir := strings.TrimPrefix(base, "file://")
+		if runtime.GOOS == "windows" && strings.HasPrefix(dir, "/") && len(dir) > 2 && dir[2] == ':' {
+			// file:///C:/dir names C:/dir.
+			dir = dir[1:]
+		}
+		data, err := ioutil.ReadFile(filepath.Join(filepath.FromSlash(dir), filepath.FromSlash(name)))
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s/%s: not found", base, name)
+		}
+		return data, err
+	}
+	return web.Get(base + "/" + name)
+}
+
+// proxyName returns the proxy file name for the given module path
+// and version file suffix, such as "list" or "v1.2.0.zip".
+func proxyName(path, suffix string) (string, error) {
+	enc, err := module.EncodePath(path)
+	if err != nil {
+		return "", err
+	}
+	if suffix != "list" {
+		i := strings.LastIndex(suffix, ".")
+		v, err := module.EncodeVersion(suffix[:i])
+		if err != nil {
+			return "", err
+		}
+		suffix = v + suffix[i:]
+	}
+	return enc + "/@v/" + suffix, nil
+}
+
+// Versions returns the known versions of the module path,
+// sorted in increasing semantic version order.
+// Entries in the proxy's list that are not canonical
+// semantic versions are ignored.
+func Versions(path string) ([]string, error) {
+	name, err := proxyName(path, "list")
+	if err != nil {
+		return nil, err
+	}
+	data, err := proxyGet(name)
+	if err != nil {
+		return nil, fmt.Errorf("listing versions of %s: %v", path, err)
+	}
+	var list []string
+	for _, line := range strings.Split(string(data), "\n") {
+		f := strings.Fields(line)
+		if len(f) > 0 && semver.Canonical(f[0]) == f[0] && module.Check(path, f[0]) == nil {
+			list = append(list, f[0])
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return semver.Compare(list[i], list[j]) < 0 })
+	return list, nil
+}
+
+// Latest returns the latest known version of the module path:
+// the highest release version or, if there are no releases,
+// the highest pre-release version.
+func Latest(path string) (string, error) {
+	list, err := Versions(path)
+	if err != nil {
+		return "", err
+	}
+	if len(list) == 0 {
+		return "", fmt.Errorf("no versions of %s available", path)
+	}
+	for i := len(list) - 1; i >= 0; i-- {
+		if semver.Prerelease(list[i]) == "" {
+			return list[i], nil
+		}
+	}
+	return list[len(list)-1], nil
+}
@@ -0,0 +1,184 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modfetch
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"cmd/go/internal/module"
+)
+
+// GoSumFile is the path to the go.sum file for the main module.
+// It is set by the modload package. If it is empty, checksums
+// are neither verified nor recorded.
+var GoSumFile string
+
+// goSum holds the checksums read from GoSumFile
+// plus any added during this run of the go command.
+var goSum struct {
+	mu      sync.Mutex
+	m       map[module.Version][]string // content of go.sum file
+	enabled bool                        // whether to use go.sum at all
+	dirty   bool                        // whether we added any new hashes
+}
+
+// initGoSum initializes the go.sum data.
+// It reports whether use of go.sum is now enabled.
+// The goSum lock must be held.
+func initGoSum() bool {
+	if GoSumFile == "" {
+		return false
+	}
+	if goSum.m != nil {
+		return true
+	}
+	goSum.m = make(map[module.Version][]string)
+	data, err := ioutil.ReadFile(GoSumFile)
+	if err != nil && !os.IsNotExist(err) {
+		goSum.m = nil
+		return false
+	}
+	goSum.enabled = true
+	for lineno, line := range strings.Split(string(data), "\n") {
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		if len(f) != 3 {
+			// Malformed lines are reported when the file is
+			// next rewritten; they are otherwise ignored.
+			fmt.Fprintf(os.Stderr, "go: malformed go.sum:\n%s:%d: wrong number of fields %v\n", GoSumFile, lineno+1, len(f))
+			continue
+		}
+		mod := module.Version{Path: f[0], Version: f[1]}
+		goSum.m[mod] = append(goSum.m[mod], f[2])
+	}
+	return true
+}
+
+// checkGoMod checks the given module's go.mod checksum;
+// data is the go.mod content.
+func checkGoMod(m module.Version, data []byte) error {
+	h, err := HashGoMod(data)
+	if err != nil {
+		return fmt.Errorf("verifying %s/go.mod: %v", m, err)
+	}
+	return checkSum(module.Version{Path: m.Path, Version: m.Version + "/go.mod"}, h)
+}
+
+// checkSum checks that the recorded checksum for mod is h,
+// recording h if there is no checksum for mod yet.
+func checkSum(mod module.Version, h string) error {
+	goSum.mu.Lock()
+	defer goSum.mu.Unlock()
+	if !initGoSum() {
+		return nil
+	}
+	for _, vh := range goSum.m[mod] {
+		if h == vh {
+			return nil
+		}
+		if strings.HasPrefix(vh, "h1:") {
+			return fmt.Errorf("verifying %s: checksum mismatch\n\tdownloaded: %v\n\tgo.sum:     %v", mod, h, vh)
+		}
+	}
+	goSum.m[mod] = append(goSum.m[mod], h)
+	goSum.dirty = true
+	return nil
+}
+
+// WriteGoSum writes the go.sum file if it needs to be updated.
+func WriteGoSum() error {
+	goSum.mu.Lock()
+	defer goSum.mu.Unlock()
+	if !goSum.enabled || !goSum.dirty {
+		return nil
+	}
+
+	var mods []module.Version
+	for m := range goSum.m {
+		mods = append(mods, m)
+	}
+	module.Sort(mods)
+	var buf bytes.Buffer
+	for _, m := range mods {
+		list := goSum.m[m]
+		sort.Strings(list)
+		for _, h := range list {
+			fmt.Fprintf(&buf, "%s %s %s\n", m.Path, m.Version, h)
+		}
+	}
+	if err := ioutil.WriteFile(GoSumFile, buf.Bytes(), 0666); err != nil {
+		return err
+	}
+	goSum.dirty = false
+	return nil
+}
+
+// Hash1 is the "h1:" directory hash function, using SHA-256.
+//
+// Hash1 is "h1:" followed by the base64-encoded SHA-256 hash of a summary
+// prepared as if by the Unix command:
+//
+//	find . -type f | sort | sha256sum
+//
+// More precisely, the hashed summary contains a single line for each file in the list,
+// ordered by sort.Strings applied to the file names, where each line consists of
+// the hexadecimal SHA-256 hash of the file content,
+// two spaces (U+0020), the file name, and a newline (U+000A).
+//
+// File names with newlines (U+000A) are disallowed.
+func Hash1(files []string, open func(string) (io.ReadCloser, error)) (string, error) {
+	h := sha256.New()
+	files = append([]string(nil), files...)
+	sort.Strings(files)
+	for _, file := range files {
+		if strings.Contains(file, "\n") {
+			return "", fmt.Errorf("filenames with newlines are not supported")
+		}
+		r, err := open(file)
+		if err != nil {
+			return "", err
+		}
+		hf := sha256.New()
+		_, err = io.Copy(hf, r)
+		r.Close()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%x  %s\n", hf.Sum(nil), file)
+	}
+	return "h1:" + base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// HashZip returns the hash of the file content in the module zip file z.
+func HashZip(z *zip.Reader) (string, error) {
+	var files []string
+	zfiles := make(map[string]*zip.File)
+	for _, file := range z.File {
+		files = append(files, file.Name)
+		zfiles[file.Name] = file
+	}
+	return Hash1(files, func(name string) (io.ReadCloser, error) {
+		return zfiles[name].Open()
+	})
+}
+
+// HashGoMod returns the hash of the go.mod file content data.
+func HashGoMod(data []byte) (string, error) {
+	return Hash1([]string{"go.mod"}, func(string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	})
+}
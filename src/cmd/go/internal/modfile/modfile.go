@@ -0,0 +1,347 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package modfile parses and formats go.mod files.
+//
+// A go.mod file is a sequence of directives, one per line.
+// Each directive is a verb followed by its arguments.
+// A verb may also be followed by a parenthesized block of
+// argument lines, which is equivalent to repeating the verb
+// on each line of the block:
+//
+//	module example.com/hello
+//
+//	require (
+//		example.com/greeting v1.2.0
+//		example.com/world v0.3.1
+//	)
+//
+//	exclude example.com/world v0.3.0
+//	replace example.com/greeting v1.2.0 => ../greeting
+//
+// Comments begin with // and run to the end of the line.
+// Arguments may be written as Go double-quoted strings.
+package modfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"cmd/go/internal/module"
+	"cmd/go/internal/semver"
+)
+
+// A File is the parsed, interpreted form of a go.mod file.
+type File struct {
+	Module  *Module
+	Require []*Require
+	Exclude []*Exclude
+	Replace []*Replace
+}
+
+// A Module is the module statement.
+type Module struct {
+	Mod  module.Version
+	Line int
+}
+
+// A Require is a single require statement.
+type Require struct {
+	Mod  module.Version
+	Line int
+}
+
+// An Exclude is a single exclude statement.
+type Exclude struct {
+	Mod  module.Version
+	Line int
+}
+
+// A Replace is a single replace statement.
+// If Old.Version is empty, the replacement applies to all versions
+// of Old.Path. If New.Version is empty, New.Path is a directory
+// holding the replacement module's source tree.
+type Replace struct {
+	Old  module.Version
+	New  module.Version
+	Line int
+}
+
+// An Error describes a problem found in a go.mod file.
+type Error struct {
+	File string
+	Line int
+	Err  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Err)
+}
+
+// Parse parses the data, reported in errors as being from file,
+// into a File struct.
+func Parse(file string, data []byte) (*File, error) {
+	f := new(File)
+	var errs []string
+	errorf := func(line int, format string, args ...interface{}) {
+		errs = append(errs, (&Error{file, line, fmt.Sprintf(format, args...)}).Error())
+	}
+
+	var block string // verb of enclosing ( ) block, if any
+	for i, text := range strings.Split(string(data), "\n") {
+		line := i + 1
+		args, err := splitLine(text)
+		if err != nil {
+			errorf(line, "%v", err)
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+		if block != "" {
+			if len(args) == 1 && args[0] == ")" {
+				block = ""
+				continue
+			}
+			f.add(errorf, line, block, args)
+			continue
+		}
+		verb := args[0]
+		args = args[1:]
+		if len(args) == 1 && args[0] == "(" {
+			if verb == "module" {
+				errorf(line, "module directive cannot use a block")
+				continue
+			}
+			block = verb
+			continue
+		}
+		f.add(errorf, line, verb, args)
+	}
+	if block != "" {
+		errorf(len(strings.Split(string(data), "\n")), "unterminated %s block", block)
+	}
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "\n"))
+	}
+	return f, nil
+}
+
+func (f *File) add(errorf func(int, string, ...interface{}), line int, verb string, args []string) {
+	switch verb {
+	default:
+		errorf(line, "unknown directive: %s", verb)
+
+	case "module":
+		if f.Module != nil {
+			errorf(line, "repeated module statement")
+			return
+		}
+		if len(args) != 1 {
+			errorf(line, "usage: module module/path")
+			return
+		}
+		f.Module = &Module{Mod: module.Version{Path: args[0]}, Line: line}
+
+	case "require", "exclude":
+		if len(args) != 2 {
+			errorf(line, "usage: %s module/path v1.2.3", verb)
+			return
+		}
+		v, err := checkVersion(args[0], args[1])
+		if err != nil {
+			errorf(line, "%v", err)
+			return
+		}
+		mod := module.Version{Path: args[0], Version: v}
+		if verb == "require" {
+			f.Require = append(f.Require, &Require{Mod: mod, Line: line})
+		} else {
+			f.Exclude = append(f.Exclude, &Exclude{Mod: mod, Line: line})
+		}
+
+	case "replace":
+		arrow := 2
+		if len(args) >= 2 && args[1] == "=>" {
+			arrow = 1
+		}
+		if len(args) < arrow+2 || len(args) > arrow+3 || args[arrow] != "=>" {
+			errorf(line, "usage: replace module/path [v1.2.3] => other/module v1.4\n\t or replace module/path [v1.2.3] => ../local/directory")
+			return
+		}
+		old := module.Version{Path: args[0]}
+		if arrow == 2 {
+			v, err := checkVersion(args[0], args[1])
+			if err != nil {
+				errorf(line, "%v", err)
+				return
+			}
+			old.Version = v
+		}
+		ns := args[arrow+1:]
+		repl := module.Version{Path: ns[0]}
+		if len(ns) == 1 {
+			if !IsDirectoryPath(ns[0]) {
+				errorf(line, "replacement module without version must be directory path (rooted or starting with ./ or ../)")
+				return
+			}
+		} else {
+			if IsDirectoryPath(ns[0]) {
+				errorf(line, "replacement directory %s cannot have a version", ns[0])
+				return
+			}
+			v, err := checkVersion(ns[0], ns[1])
+			if err != nil {
+				errorf(line, "%v", err)
+				return
+			}
+			repl.Version = v
+		}
+		f.Replace = append(f.Replace, &Replace{Old: old, New: repl, Line: line})
+	}
+}
+
+// checkVersion checks that vers is a valid semantic version for the
+// module path and returns its canonical form.
+func checkVersion(path, vers string) (string, error) {
+	if !semver.IsValid(vers) {
+		return "", fmt.Errorf("invalid version %q for %s: must be of the form v1.2.3", vers, path)
+	}
+	cv := semver.Canonical(vers)
+	if err := module.Check(path, cv); err != nil {
+		return "", err
+	}
+	return cv, nil
+}
+
+// IsDirectoryPath reports whether the given path should be interpreted
+// as a directory path. Just like on the go command line, relative paths
+// and rooted paths are directory paths; the rest are module paths.
+func IsDirectoryPath(ns string) bool {
+	return strings.HasPrefix(ns, "./") || strings.HasPrefix(ns, "../") ||
+		ns == "." || ns == ".." || filepath.IsAbs(ns)
+}
+
+// splitLine splits a line into its space-separated arguments,
+// discarding any trailing comment and unquoting quoted arguments.
+func splitLine(text string) ([]string, error) {
+	var args []string
+	for {
+		text = strings.TrimLeftFunc(text, unicode.IsSpace)
+		if text == "" || strings.HasPrefix(text, "//") {
+			return args, nil
+		}
+		if text[0] == '"' {
+			i := 1
+			for ; i < len(text) && text[i] != '"'; i++ {
+				if text[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(text) {
+				return nil, fmt.Errorf("unterminated quoted string")
+			}
+			s, err := strconv.Unquote(text[:i+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted string %s", text[:i+1])
+			}
+			args = append(args, s)
+			text = text[i+1:]
+			continue
+		}
+		i := strings.IndexFunc(text, unicode.IsSpace)
+		if i < 0 {
+			i = len(text)
+		}
+		if j := strings.Index(text[:i], "//"); j >= 0 {
+			i = j
+		}
+		args = append(args, text[:i])
+		text = text[i:]
+	}
+}
+
+// AddRequire sets the required version of path to vers,
+// adding a new requirement if path is not yet required.
+func (f *File) AddRequire(path, vers string) {
+	for _, r := range f.Require {
+		if r.Mod.Path == path {
+			r.Mod.Version = vers
+			return
+		}
+	}
+	f.Require = append(f.Require, &Require{Mod: module.Version{Path: path, Version: vers}})
+}
+
+// DropRequire removes any requirement of path.
+func (f *File) DropRequire(path string) {
+	w := 0
+	for _, r := range f.Require {
+		if r.Mod.Path != path {
+			f.Require[w] = r
+			w++
+		}
+	}
+	f.Require = f.Require[:w]
+}
+
+// Format returns the canonical formatting of f.
+// Comments and blank lines in the original file are not preserved.
+func (f *File) Format() []byte {
+	var buf bytes.Buffer
+	if f.Module != nil {
+		fmt.Fprintf(&buf, "module %s\n", quote(f.Module.Mod.Path))
+	}
+	block := func(verb string, lines []string) {
+		switch len(lines) {
+		case 0:
+			return
+		case 1:
+			fmt.Fprintf(&buf, "\n%s %s\n", verb, lines[0])
+		default:
+			fmt.Fprintf(&buf, "\n%s (\n", verb)
+			for _, l := range lines {
+				fmt.Fprintf(&buf, "\t%s\n", l)
+			}
+			fmt.Fprintf(&buf, ")\n")
+		}
+	}
+	var lines []string
+	for _, r := range f.Require {
+		lines = append(lines, quote(r.Mod.Path)+" "+r.Mod.Version)
+	}
+	block("require", lines)
+	lines = nil
+	for _, x := range f.Exclude {
+		lines = append(lines, quote(x.Mod.Path)+" "+x.Mod.Version)
+	}
+	block("exclude", lines)
+	lines = nil
+	for _, r := range f.Replace {
+		l := quote(r.Old.Path)
+		if r.Old.Version != "" {
+			l += " " + r.Old.Version
+		}
+		l += " => " + quote(r.New.Path)
+		if r.New.Version != "" {
+			l += " " + r.New.Version
+		}
+		lines = append(lines, l)
+	}
+	block("replace", lines)
+	return buf.Bytes()
+}
+
+// quote returns s, quoted if it cannot be written as a bare argument.
+func quote(s string) string {
+	if s == "" || strings.ContainsAny(s, "\"()") || strings.Contains(s, "//") || strings.IndexFunc(s, unicode.IsSpace) >= 0 {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,113 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modfile
+
+import (
+	"strings"
+	"testing"
+)
+
+const testFile = `// A comment before the module statement.
+module example.com/hello // trailing comment
+
+require (
+	example.com/greeting v1.2 // canonicalized
+	"example.com/world" v0.3.1
+)
+
+require example.com/other/v2 v2.0.0
+exclude example.com/world v0.3.0
+replace example.com/greeting v1.2.0 => ../greeting
+replace example.com/world => example.com/fork v0.3.2
+`
+
+const testFormatted = `module example.com/hello
+
+require (
+	example.com/greeting v1.2.0
+	example.com/world v0.3.1
+	example.com/other/v2 v2.0.0
+)
+
+exclude example.com/world v0.3.0
+
+replace (
+	example.com/greeting v1.2.0 => ../greeting
+	example.com/world => example.com/fork v0.3.2
+)
+`
+
+func TestParse(t *testing.T) {
+	f, err := Parse("go.mod", []byte(testFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Module == nil || f.Module.Mod.Path != "example.com/hello" {
+		t.Fatalf("module = %+v, want example.com/hello", f.Module)
+	}
+	if len(f.Require) != 3 || f.Require[0].Mod.Version != "v1.2.0" || f.Require[1].Mod.Path != "example.com/world" {
+		t.Fatalf("bad require list: %v", f.Require)
+	}
+	if len(f.Exclude) != 1 || f.Exclude[0].Line != 10 {
+		t.Fatalf("bad exclude list: %v", f.Exclude)
+	}
+	if len(f.Replace) != 2 || f.Replace[0].New.Path != "../greeting" || f.Replace[1].Old.Version != "" || f.Replace[1].New.Version != "v0.3.2" {
+		t.Fatalf("bad replace list: %v", f.Replace)
+	}
+	if out := string(f.Format()); out != testFormatted {
+		t.Fatalf("Format:\n%s\nwant:\n%s", out, testFormatted)
+	}
+
+	// Formatted output must parse back to the same file.
+	g, err := Parse("go.mod", f.Format())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := string(g.Format()); out != testFormatted {
+		t.Fatalf("reformat:\n%s\nwant:\n%s", out, testFormatted)
+	}
+}
+
+var parseErrorTests = []struct {
+	in  string
+	err string
+}{
+	{"module a\nmodule b\n", "go.mod:2: repeated module statement"},
+	{"frobnicate x\n", "go.mod:1: unknown directive: frobnicate"},
+	{"require x.y/z 1.0\n", `go.mod:1: invalid version "1.0" for x.y/z`},
+	{"require x.y/z/v2 v1.0.0\n", "go.mod:1: mismatched module path"},
+	{"require (\n\tx.y/z v1.0.0\n", "go.mod:3: unterminated require block"},
+	{"replace x.y/z => x.y/w\n", "go.mod:1: replacement module without version must be directory path"},
+	{"replace x.y/z => ../w v1.0.0\n", "go.mod:1: replacement directory ../w cannot have a version"},
+	{"module \"abc\n", "go.mod:1: unterminated quoted string"},
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tt := range parseErrorTests {
+		_, err := Parse("go.mod", []byte(tt.in))
+		if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+			t.Errorf("Parse(%q) = %v, want error beginning %q", tt.in, err, tt.err)
+		}
+	}
+}
+
+func TestAddRequire(t *testing.T) {
+	f, err := Parse("go.mod", []byte("module m.x\nrequire a.x/b v1.0.0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.AddRequire("a.x/b", "v1.1.0")
+	f.AddRequire("c.x/d", "v0.1.0")
+	f.DropRequire("e.x/f")
+	want := "module m.x\n\nrequire (\n\ta.x/b v1.1.0\n\tc.x/d v0.1.0\n)\n"
+	if out := string(f.Format()); out != want {
+		t.Fatalf("Format:\n%s\nwant:\n%s", out, want)
+	}
+	f.DropRequire("a.x/b")
+	want = "module m.x\n\nrequire c.x/d v0.1.0\n"
+	if out := string(f.Format()); out != want {
+		t.Fatalf("Format:\n%s\nwant:\n%s", out, want)
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modload
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/modfetch"
+	"cmd/go/internal/modfile"
+	"cmd/go/internal/module"
+	"cmd/go/internal/mvs"
+	"cmd/go/internal/semver"
+)
+
+// buildList is the list of modules used in the build,
+// computed by minimal version selection from the main module's
+// requirements. The first element is always Target.
+var buildList []module.Version
+
+// BuildList returns the module build list, computing it on first use.
+// It exits the go command if the build list cannot be loaded.
+func BuildList() []module.Version {
+	if buildList == nil {
+		LoadBuildList()
+	}
+	return buildList
+}
+
+// LoadBuildList recomputes the build list from the main
+// module's go.mod file.
+func LoadBuildList() {
+	list, err := mvs.BuildList(Target, Reqs())
+	if err != nil {
+		base.Fatalf("go: %v", err)
+	}
+	buildList = list
+}
+
+// Selected returns the selected version of the module path in the
+// build list, or "" if the path is not in the build list.
+func Selected(path string) string {
+	for _, m := range BuildList() {
+		if m.Path == path {
+			return m.Version
+		}
+	}
+	return ""
+}
+
+// Reqs returns the module requirement graph described by
+// the main module's go.mod file and those of its dependencies.
+func Reqs() mvs.Reqs {
+	excluded := make(map[module.Version]bool)
+	for _, x := range modFile.Exclude {
+		excluded[x.Mod] = true
+	}
+	return &mvsReqs{
+		excluded: excluded,
+		cache:    make(map[module.Version][]module.Version),
+	}
+}
+
+type mvsReqs struct {
+	excluded map[module.Version]bool
+	cache    map[module.Version][]module.Version
+}
+
+func (r *mvsReqs) Required(mod module.Version) ([]module.Version, error) {
+	if list, ok := r.cache[mod]; ok {
+		return list, nil
+	}
+	var list []module.Version
+	if mod == Target {
+		for _, req := range modFile.Require {
+			list = append(list, req.Mod)
+		}
+	} else {
+		f, err := r.modFile(mod)
+		if err != nil {
+			return nil, err
+		}
+		for _, req := range f.Require {
+			list = append(list, req.Mod)
+		}
+	}
+
+	// Excluded versions are replaced by the next higher available version.
+	for i, m := range list {
+		if !r.excluded[m] {
+			continue
+		}
+		next, err := r.next(m)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = next
+	}
+	r.cache[mod] = list
+	return list, nil
+}
+
+// next returns the first available version of m.Path that is
+// newer than m.Version and not excluded.
+func (r *mvsReqs) next(m module.Version) (module.Version, error) {
+	versions, err := modfetch.Versions(m.Path)
+	if err != nil {
+		return module.Version{}, err
+	}
+	for _, v := range versions {
+		next := module.Version{Path: m.Path, Version: v}
+		if semver.Compare(v, m.Version) > 0 && !r.excluded[next] {
+			return next, nil
+		}
+	}
+	return module.Version{}, fmt.Errorf("%v is excluded and no newer version is available", m)
+}
+
+// modFile returns the parsed go.mod file of the given dependency,
+// taking replacements into account.
+func (r *mvsReqs) modFile(mod module.Version) (*modfile.File, error) {
+	var (
+		data []byte
+		err  error
+		name = mod.String() + "/go.mod"
+	)
+	repl := Replacement(mod)
+	switch {
+	case repl.Path == "":
+		data, err = modfetch.GoMod(mod.Path, mod.Version)
+	case repl.Version == "":
+		name = filepath.Join(replaceDir(repl.Path), "go.mod")
+		data, err = ioutil.ReadFile(name)
+	default:
+		data, err = modfetch.GoMod(repl.Path, repl.Version)
+	}
+	if err != nil {
+		return nil, err
+	}
+	f, err := modfile.Parse(name, data)
+	if err != nil {
+		return nil, err
+	}
+	if f.Module != nil && repl.Path == "" && f.Module.Mod.Path != mod.Path {
+		return nil, fmt.Errorf("go.mod declares module path %s, but it was required as %s", f.Module.Mod.Path, mod.Path)
+	}
+	return f, nil
+}
+
+// Max returns the maximum of v1 and v2 according to semver.Compare.
+//
+// As a special case, the version "" is considered higher than all other versions.
+// The main module (also known as the target) has no version and must be chosen
+// over other versions of the same module in the module dependency graph.
+func (*mvsReqs) Max(v1, v2 string) string {
+	if v1 != "" && semver.Compare(v1, v2) == -1 {
+		return v2
+	}
+	return v1
+}
+
+// Replacement returns the replacement for mod, if any, from go.mod.
+// A replacement naming mod's exact version takes precedence over
+// one that applies to all versions of mod.Path.
+// If there is no replacement for mod, Replacement returns
+// a module.Version with Path == "".
+func Replacement(mod module.Version) module.Version {
+	var found *modfile.Replace
+	for _, r := range modFile.Replace {
+		if r.Old.Path != mod.Path {
+			continue
+		}
+		if r.Old.Version == mod.Version {
+			return r.New
+		}
+		if r.Old.Version == "" {
+			found = r
+		}
+	}
+	if found == nil {
+		return module.Version{}
+	}
+	return found.New
+}
+
+// replaceDir returns the directory named by a directory replacement,
+// which is interpreted relative to the main module's root.
+func replaceDir(dir string) string {
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(ModRoot, dir)
+}
+
+// ModuleDir returns the directory holding the source tree of the
+// module m from the build list, downloading it if necessary.
+func ModuleDir(m module.Version) (string, error) {
+	if m == Target {
+		return ModRoot, nil
+	}
+	repl := Replacement(m)
+	if repl.Path != "" {
+		if repl.Version == "" {
+			return replaceDir(repl.Path), nil
+		}
+		m = repl
+	}
+	return modfetch.Download(m)
+}
@@ -0,0 +1,129 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modload
+
+import "cmd/go/internal/base"
+
+var HelpModules = &base.Command{
+	UsageLine: "modules",
+	Short:     "modules, module versions, and more",
+	Long: `
+A module is a collection of related Go packages that are versioned
+together as a single unit. Modules record precise dependency
+requirements and create reproducible builds.
+
+A module is defined by a tree of Go source files with a go.mod file
+in the tree's root directory. The directory containing the go.mod file
+is called the module root. The module root and its subdirectories,
+excluding subdirectories with their own go.mod files, make up the
+module. The go.mod file declares the module path, which is the import
+path prefix for all packages in the module, and lists the modules
+the main module requires, by path and semantic version:
+
+	module example.com/hello
+
+	require (
+		example.com/greeting v1.2.0
+		example.com/world v0.3.1
+	)
+
+The go.mod file may also exclude specific module versions,
+which are then skipped in favor of the next higher version,
+and replace a module version with another module or with a
+directory on the local file system:
+
+	exclude example.com/world v0.3.0
+	replace example.com/greeting v1.2.0 => ../greeting
+
+Exclude and replace directives apply only in the main module's
+go.mod file; they are ignored in the go.mod files of dependencies.
+
+Module mode
+
+The go command runs in module mode, ignoring GOPATH/src and vendor
+directories, when the current directory or one of its parents contains
+a go.mod file and the current directory is outside $GOPATH/src. The
+GO111MODULE environment variable overrides this choice: GO111MODULE=on
+requires module mode, and GO111MODULE=off disables it.
+
+In module mode, the module containing the current directory is the
+main module. Packages inside the main module may be named by their
+import paths or by relative paths such as ./..., and the main module
+may live anywhere in the file system.
+
+Version selection
+
+The set of modules providing packages to a build is called the build
+list. The go command computes it by minimal version selection:
+starting from the main module's requirements, it follows the
+requirements listed in each required module's own go.mod file and
+selects, for each module path, the maximum version required anywhere
+in the resulting graph. Because the selection depends only on the
+go.mod files involved, and never on which versions are newest, builds
+are reproducible. 'go list -m' prints the build list.
+
+'go get path@version' adds or updates a requirement in go.mod, where
+version is a semantic version such as v1.2.0 or "latest"; the
+special version "none" removes the requirement. 'go get -u' upgrades
+the dependencies of the named modules (or, with no arguments, all
+modules in the build list) to their latest versions. Other commands
+never change go.mod.
+
+Downloading and verifying modules
+
+Modules are downloaded from the module proxy named by GOPROXY (see
+'go help goproxy') into the module cache in $GOPATH/pkg/mod, where
+they are shared by all main modules. Commands can run without network
+access once the required modules are in the cache, or when GOPROXY
+names a local directory using a file:/// URL.
+
+The go command records the expected cryptographic checksum of each
+downloaded module, and of each go.mod file it consults, in a go.sum
+file next to go.mod. Each line of go.sum gives a module path, a version
+(suffixed with /go.mod for go.mod checksums), and a hash. On later
+downloads the go command checks the content against go.sum and refuses
+to use a module whose checksum does not match. Both go.mod and go.sum
+should be checked into version control.
+	`,
+}
+
+var HelpGoproxy = &base.Command{
+	UsageLine: "goproxy",
+	Short:     "module proxy protocol",
+	Long: `
+The go command fetches modules from a module proxy named by the
+GOPROXY environment variable. A module proxy is any web server
+that responds to GET requests for URLs of a specified form.
+The requests have no query parameters, so a site serving from a fixed
+file system, including a local directory named using a file:/// URL,
+can be a module proxy.
+
+The GET requests sent to a Go module proxy are:
+
+GET $GOPROXY/<module>/@v/list returns a list of all known versions of the
+given module, one per line.
+
+GET $GOPROXY/<module>/@v/<version>.mod returns the go.mod file
+for that version of the given module.
+
+GET $GOPROXY/<module>/@v/<version>.zip returns the zip archive
+for that version of the given module.
+
+To avoid problems when serving from case-insensitive file systems,
+the <module> and <version> elements are case-encoded, replacing every
+uppercase letter with an exclamation mark followed by the corresponding
+lower-case letter: github.com/Azure encodes as github.com/!azure.
+
+Every file in the zip archive for module M at version V must be
+stored under the directory "M@V/", so that the archive for
+example.com/hello at v1.2.0 contains example.com/hello@v1.2.0/go.mod,
+example.com/hello@v1.2.0/hello.go, and so on.
+
+Fetching modules directly from version control repositories is not
+supported. When GOPROXY is unset, only modules already present in the
+module cache can be used. Setting GOPROXY=off disallows downloading
+modules altogether.
+	`,
+}
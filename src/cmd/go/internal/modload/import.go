@@ -0,0 +1,90 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modload
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cmd/go/internal/module"
+)
+
+// An ImportMissingError reports that no module in the build list
+// provides the imported package.
+type ImportMissingError struct {
+	ImportPath string
+}
+
+func (e *ImportMissingError) Error() string {
+	return fmt.Sprintf("cannot find module providing package %s (add a requirement to go.mod with 'go get')", e.ImportPath)
+}
+
+// Lookup returns the directory holding the package with the given
+// import path, along with the module that provides it.
+// The package must be provided by exactly one module in the build list.
+func Lookup(path string) (dir string, mod module.Version, err error) {
+	var (
+		dirs []string
+		mods []module.Version
+	)
+	for _, m := range BuildList() {
+		if m.Path != path && !strings.HasPrefix(path, m.Path+"/") {
+			continue
+		}
+		root, err := ModuleDir(m)
+		if err != nil {
+			return "", module.Version{}, err
+		}
+		d := filepath.Join(root, filepath.FromSlash(strings.TrimPrefix(path[len(m.Path):], "/")))
+		if !inModule(root, d) {
+			// The directory belongs to a nested module,
+			// not to the module rooted at root.
+			continue
+		}
+		if hasGoFiles(d) {
+			dirs = append(dirs, d)
+			mods = append(mods, m)
+		}
+	}
+	switch len(mods) {
+	case 0:
+		return "", module.Version{}, &ImportMissingError{ImportPath: path}
+	case 1:
+		return dirs[0], mods[0], nil
+	}
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "ambiguous import: found %s in multiple modules:", path)
+	for i, m := range mods {
+		fmt.Fprintf(&buf, "\n\t%s (%s)", m, dirs[i])
+	}
+	return "", module.Version{}, fmt.Errorf("%s", buf.String())
+}
+
+// inModule reports whether dir, a directory inside the module tree
+// rooted at root, belongs to that module rather than to a module
+// nested within it (a subdirectory with its own go.mod file).
+func inModule(root, dir string) bool {
+	for dir != root && len(dir) > len(root) {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return false
+		}
+		dir = filepath.Dir(dir)
+	}
+	return true
+}
+
+// hasGoFiles reports whether dir contains any .go files.
+func hasGoFiles(dir string) bool {
+	fis, _ := ioutil.ReadDir(dir)
+	for _, fi := range fis {
+		if !fi.IsDir() && strings.HasSuffix(fi.Name(), ".go") {
+			return true
+		}
+	}
+	return false
+}
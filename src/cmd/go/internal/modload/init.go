@@ -0,0 +1,215 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package modload resolves packages in module mode:
+// it finds the main module's go.mod file, computes the build list
+// of required module versions, and maps import paths to directories.
+package modload
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/cfg"
+	"cmd/go/internal/modfetch"
+	"cmd/go/internal/modfile"
+	"cmd/go/internal/module"
+	"cmd/go/internal/str"
+)
+
+var (
+	initialized bool
+	enabled     bool
+
+	// ModRoot is the directory containing the main module's go.mod file.
+	ModRoot string
+
+	// Target is the main module.
+	Target module.Version
+
+	modFile     *modfile.File
+	modFileData []byte // original content of go.mod, to detect changes
+)
+
+// Enabled reports whether the go command is running in module mode.
+// The decision is made on first use from the GO111MODULE environment
+// variable and the location of the current directory; see Init.
+func Enabled() bool {
+	Init()
+	return enabled
+}
+
+// Init determines whether module mode is enabled and,
+// if so, reads the main module's go.mod file.
+//
+// GO111MODULE=off disables module mode and GO111MODULE=on
+// requires it. When GO111MODULE is unset or set to auto,
+// module mode is enabled if the current directory or one of its
+// parents contains a go.mod file, unless the current directory
+// is inside $GOPATH/src or $GOROOT/src.
+func Init() {
+	if initialized {
+		return
+	}
+	initialized = true
+
+	env := os.Getenv("GO111MODULE")
+	switch env {
+	default:
+		base.Fatalf("go: unknown environment setting GO111MODULE=%s", env)
+	case "off":
+		return
+	case "", "auto", "on":
+	}
+
+	root := findModuleRoot(base.Cwd)
+	if env != "on" {
+		if root == "" || inGopathOrGoroot(base.Cwd) {
+			return
+		}
+	}
+	if root == "" {
+		base.Fatalf("go: cannot find main module; see 'go help modules'")
+	}
+
+	if len(cfg.Gopath) == 0 || cfg.Gopath[0] == "" {
+		base.Fatalf("go: module mode requires GOPATH to locate the module cache; see 'go help gopath'")
+	}
+	enabled = true
+	ModRoot = root
+	modfetch.SrcMod = filepath.Join(cfg.Gopath[0], "pkg", "mod")
+	modfetch.GoSumFile = filepath.Join(ModRoot, "go.sum")
+	base.AtExit(func() {
+		if err := modfetch.WriteGoSum(); err != nil {
+			base.Errorf("go: updating go.sum: %v", err)
+		}
+	})
+
+	gomod := filepath.Join(ModRoot, "go.mod")
+	data, err := ioutil.ReadFile(gomod)
+	if err != nil {
+		base.Fatalf("go: %v", err)
+	}
+	f, err := modfile.Parse(gomod, data)
+	if err != nil {
+		base.Fatalf("go: errors parsing go.mod:\n%v", err)
+	}
+	if f.Module == nil {
+		base.Fatalf("go: %s: no module declaration (add 'module path/to/module')", base.ShortPath(gomod))
+	}
+	modFile = f
+	modFileData = data
+	Target = f.Module.Mod
+}
+
+// findModuleRoot returns the nearest directory at or above dir
+// containing a go.mod file, or "" if there is none.
+func findModuleRoot(dir string) string {
+	dir = filepath.Clean(dir)
+	for {
+		if fi, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil && !fi.IsDir() {
+			return dir
+		}
+		d := filepath.Dir(dir)
+		if d == dir {
+			return ""
+		}
+		dir = d
+	}
+}
+
+// inGopathOrGoroot reports whether dir is inside $GOROOT/src
+// or the src directory of a GOPATH entry.
+func inGopathOrGoroot(dir string) bool {
+	if str.HasFilePathPrefix(dir, cfg.GOROOTsrc) {
+		return true
+	}
+	for _, p := range cfg.Gopath {
+		if p != "" && str.HasFilePathPrefix(dir, filepath.Join(p, "src")) {
+			return true
+		}
+	}
+	return false
+}
+
+// BinDir returns the directory in which commands are installed
+// in module mode: $GOBIN, or else the bin directory of the
+// first GOPATH entry.
+func BinDir() string {
+	if cfg.GOBIN != "" {
+		return cfg.GOBIN
+	}
+	return filepath.Join(cfg.Gopath[0], "bin")
+}
+
+// ModFile returns the parsed go.mod file of the main module.
+func ModFile() *modfile.File {
+	Init()
+	if !enabled {
+		base.Fatalf("go: modules disabled (see 'go help modules')")
+	}
+	return modFile
+}
+
+// WriteGoMod writes the current go.mod file, if it has changed,
+// along with any new go.sum entries.
+func WriteGoMod() {
+	if !enabled {
+		return
+	}
+	data := modFile.Format()
+	if !bytes.Equal(data, modFileData) {
+		if err := ioutil.WriteFile(filepath.Join(ModRoot, "go.mod"), data, 0666); err != nil {
+			base.Fatalf("go: %v", err)
+		}
+		modFileData = data
+	}
+	if err := modfetch.WriteGoSum(); err != nil {
+		base.Fatalf("go: updating go.sum: %v", err)
+	}
+}
+
+// ImportPathForDir returns the import path of the package in dir,
+// which must be an absolute path, if dir is inside the main module.
+func ImportPathForDir(dir string) (string, bool) {
+	if !Enabled() {
+		return "", false
+	}
+	dir = filepath.Clean(dir)
+	if dir == ModRoot {
+		return Target.Path, true
+	}
+	if !str.HasFilePathPrefix(dir, ModRoot) {
+		return "", false
+	}
+	rel := filepath.ToSlash(dir[len(ModRoot)+1:])
+	if strings.HasPrefix(rel, "vendor/") || strings.Contains(rel, "/vendor/") {
+		return "", false
+	}
+	return Target.Path + "/" + rel, true
+}
+
+// isMainModuleImport reports whether path names a package
+// in the main module.
+func isMainModuleImport(path string) bool {
+	return path == Target.Path || strings.HasPrefix(path, Target.Path+"/")
+}
+
+// IsStandardImportPath reports whether path should be resolved
+// in $GOROOT/src rather than in a module: its first element
+// has no dot, and it is not part of the main module.
+func IsStandardImportPath(path string) bool {
+	if isMainModuleImport(path) {
+		return false
+	}
+	i := strings.Index(path, "/")
+	if i < 0 {
+		i = len(path)
+	}
+	return !strings.Contains(path[:i], ".")
+}
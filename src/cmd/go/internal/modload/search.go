@@ -0,0 +1,73 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modload
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/cfg"
+	"cmd/go/internal/module"
+)
+
+// MatchPackages returns the import paths of the packages in the build
+// list for which match reports true. Modules other than the main module
+// are only searched (and downloaded) if treeCanMatch reports true
+// for their module path.
+func MatchPackages(match, treeCanMatch func(string) bool) []string {
+	var pkgs []string
+	for _, m := range BuildList() {
+		if m != Target && !treeCanMatch(m.Path) {
+			continue
+		}
+		root, err := ModuleDir(m)
+		if err != nil {
+			base.Errorf("go: %v", err)
+			continue
+		}
+		pkgs = append(pkgs, walkModule(m, root, match)...)
+	}
+	return pkgs
+}
+
+// walkModule returns the packages in the module m, rooted at dir,
+// whose import paths satisfy match.
+func walkModule(m module.Version, root string, match func(string) bool) []string {
+	var pkgs []string
+	filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.IsDir() {
+			return nil
+		}
+		if path != root {
+			// Avoid .foo, _foo, testdata and vendor directory trees,
+			// and do not descend into nested modules.
+			elem := fi.Name()
+			if strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_") || elem == "testdata" || elem == "vendor" {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+		name := m.Path
+		if path != root {
+			name += "/" + filepath.ToSlash(path[len(root)+1:])
+		}
+		if !match(name) || !hasGoFiles(path) {
+			return nil
+		}
+		if _, err := cfg.BuildContext.ImportDir(path, 0); err != nil {
+			if _, noGo := err.(*build.NoGoError); noGo {
+				return nil
+			}
+		}
+		pkgs = append(pkgs, name)
+		return nil
+	})
+	return pkgs
+}
@@ -0,0 +1,276 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package module defines the module.Version type
+// along with support code.
+package module
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"cmd/go/internal/semver"
+)
+
+// A Version is defined by a module path and version pair.
+type Version struct {
+	Path string
+
+	// Version is usually a semantic version in canonical form.
+	// There are two exceptions to this general rule.
+	// First, the top-level target of a build has no specific version
+	// and uses Version = "".
+	// Second, during MVS calculations the version "none" is used
+	// to represent the decision to take no version of a given module.
+	Version string `json:",omitempty"`
+}
+
+func (m Version) String() string {
+	if m.Version == "" {
+		return m.Path
+	}
+	return m.Path + "@" + m.Version
+}
+
+// Check checks that a given module path, version pair is valid.
+// In addition to the path being a valid module path
+// and the version being a valid semantic version,
+// the two must correspond.
+// For example, the path "yaml/v2" only corresponds to
+// semantic versions beginning with "v2.".
+func Check(path, version string) error {
+	if err := CheckPath(path); err != nil {
+		return err
+	}
+	if !semver.IsValid(version) {
+		return fmt.Errorf("malformed semantic version %q", version)
+	}
+	if semver.Canonical(version) != version {
+		return fmt.Errorf("version %q is not in canonical form", version)
+	}
+	_, pathMajor, _ := SplitPathVersion(path)
+	if !MatchPathMajor(version, pathMajor) {
+		if pathMajor == "" {
+			pathMajor = "v0 or v1"
+		} else {
+			pathMajor = pathMajor[1:]
+		}
+		return fmt.Errorf("mismatched module path %v and version %v (want %v)", path, version, pathMajor)
+	}
+	return nil
+}
+
+// firstPathOK reports whether r can appear in the first element of a module path.
+// The first element of the path must be an LDH domain name, at least for now.
+// To avoid case ambiguity, the domain name must be entirely lower case.
+func firstPathOK(r rune) bool {
+	return r == '-' || r == '.' ||
+		'0' <= r && r <= '9' ||
+		'a' <= r && r <= 'z'
+}
+
+// pathOK reports whether r can appear in a module path.
+// The module path elements are restricted to ASCII letters, digits,
+// and a few punctuation characters, so that they can be used safely
+// as directory names on all systems.
+func pathOK(r rune) bool {
+	if r < utf8.RuneSelf {
+		return r == '+' || r == '-' || r == '.' || r == '_' || r == '~' ||
+			'0' <= r && r <= '9' ||
+			'A' <= r && r <= 'Z' ||
+			'a' <= r && r <= 'z'
+	}
+	return false
+}
+
+// CheckPath checks that a module path is valid.
+func CheckPath(path string) error {
+	if path == "" {
+		return fmt.Errorf("malformed module path %q: empty path", path)
+	}
+	i := strings.Index(path, "/")
+	if i < 0 {
+		i = len(path)
+	}
+	if i == 0 {
+		return fmt.Errorf("malformed module path %q: leading slash", path)
+	}
+	if !strings.Contains(path[:i], ".") {
+		return fmt.Errorf("malformed module path %q: missing dot in first path element", path)
+	}
+	if path[0] == '-' {
+		return fmt.Errorf("malformed module path %q: leading dash in first path element", path)
+	}
+	for _, r := range path[:i] {
+		if !firstPathOK(r) {
+			return fmt.Errorf("malformed module path %q: invalid char %q in first path element", path, r)
+		}
+	}
+	for _, elem := range strings.Split(path, "/") {
+		if elem == "" {
+			return fmt.Errorf("malformed module path %q: empty path element", path)
+		}
+		if elem == "." || elem == ".." || elem[0] == '.' || elem[len(elem)-1] == '.' {
+			return fmt.Errorf("malformed module path %q: invalid path element %q", path, elem)
+		}
+		for _, r := range elem {
+			if !pathOK(r) {
+				return fmt.Errorf("malformed module path %q: invalid char %q", path, r)
+			}
+		}
+	}
+	if _, _, ok := SplitPathVersion(path); !ok {
+		return fmt.Errorf("malformed module path %q: invalid version suffix", path)
+	}
+	return nil
+}
+
+// SplitPathVersion returns prefix and major version such that prefix+pathMajor == path
+// and version is either empty or "/vN" for N >= 2.
+func SplitPathVersion(path string) (prefix, pathMajor string, ok bool) {
+	i := len(path)
+	for i > 0 && '0' <= path[i-1] && path[i-1] <= '9' {
+		i--
+	}
+	if i <= 1 || i == len(path) || path[i-1] != 'v' || path[i-2] != '/' {
+		return path, "", true
+	}
+	prefix, pathMajor = path[:i-2], path[i-2:]
+	if pathMajor[2] == '0' || pathMajor == "/v1" {
+		return path, "", false
+	}
+	return prefix, pathMajor, true
+}
+
+// MatchPathMajor reports whether the semantic version v
+// matches the path major version pathMajor.
+func MatchPathMajor(v, pathMajor string) bool {
+	m := semver.Major(v)
+	if pathMajor == "" {
+		return m == "v0" || m == "v1"
+	}
+	return pathMajor[0] == '/' && m == pathMajor[1:]
+}
+
+// Sort sorts the list by Path, breaking ties by comparing Versions.
+// A version may carry a file suffix, as in "v1.2.0/go.mod"; such
+// versions sort immediately after the bare version.
+func Sort(list []Version) {
+	sort.Slice(list, func(i, j int) bool {
+		mi := list[i]
+		mj := list[j]
+		if mi.Path != mj.Path {
+			return mi.Path < mj.Path
+		}
+		vi, fi := splitVersionFile(mi.Version)
+		vj, fj := splitVersionFile(mj.Version)
+		if vi != vj {
+			return semver.Compare(vi, vj) < 0
+		}
+		return fi < fj
+	})
+}
+
+// splitVersionFile splits a version like "v1.2.0/go.mod"
+// into the version and the file suffix.
+func splitVersionFile(v string) (vers, file string) {
+	if i := strings.Index(v, "/"); i >= 0 {
+		return v[:i], v[i:]
+	}
+	return v, ""
+}
+
+// EncodePath returns the safe encoding of the given module path.
+// It fails if the module path is invalid.
+//
+// Module paths appear as substrings of file system paths
+// (in the download cache) and of web server URLs in the proxy protocol.
+// Some file systems are case-insensitive, so upper case letters
+// are encoded as an exclamation mark followed by the letter's
+// lower case equivalent: "github.com/Sirupsen/logrus" is
+// encoded as "github.com/!sirupsen/logrus".
+func EncodePath(path string) (encoding string, err error) {
+	if err := CheckPath(path); err != nil {
+		return "", err
+	}
+	return encodeString(path), nil
+}
+
+// EncodeVersion returns the safe encoding of the given module version.
+// Versions are allowed to be in non-semver form but must be valid file names
+// and not contain exclamation marks.
+func EncodeVersion(v string) (encoding string, err error) {
+	if v == "" || strings.ContainsAny(v, "!/\\") {
+		return "", fmt.Errorf("disallowed version string %q", v)
+	}
+	return encodeString(v), nil
+}
+
+func encodeString(s string) string {
+	haveUpper := false
+	for _, r := range s {
+		if 'A' <= r && r <= 'Z' {
+			haveUpper = true
+			break
+		}
+	}
+	if !haveUpper {
+		return s
+	}
+	var buf []byte
+	for _, r := range s {
+		if 'A' <= r && r <= 'Z' {
+			buf = append(buf, '!', byte(unicode.ToLower(r)))
+		} else {
+			buf = append(buf, string(r)...)
+		}
+	}
+	return string(buf)
+}
+
+// DecodePath returns the module path of the given safe encoding.
+// It fails if the encoding is invalid.
+func DecodePath(encoding string) (path string, err error) {
+	path, ok := decodeString(encoding)
+	if !ok {
+		return "", fmt.Errorf("invalid module path encoding %q", encoding)
+	}
+	if err := CheckPath(path); err != nil {
+		return "", fmt.Errorf("invalid module path encoding %q: %v", encoding, err)
+	}
+	return path, nil
+}
+
+func decodeString(encoding string) (string, bool) {
+	var buf []byte
+	bang := false
+	for _, r := range encoding {
+		if r >= utf8.RuneSelf {
+			return "", false
+		}
+		if bang {
+			bang = false
+			if r < 'a' || 'z' < r {
+				return "", false
+			}
+			buf = append(buf, byte(unicode.ToUpper(r)))
+			continue
+		}
+		if r == '!' {
+			bang = true
+			continue
+		}
+		if 'A' <= r && r <= 'Z' {
+			return "", false
+		}
+		buf = append(buf, byte(r))
+	}
+	if bang {
+		return "", false
+	}
+	return string(buf), true
+}
@@ -0,0 +1,129 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package module
+
+import "testing"
+
+var checkTests = []struct {
+	path    string
+	version string
+	ok      bool
+}{
+	{"rsc.io/quote", "0.1.0", false},
+	{"rsc io/quote", "v1.0.0", false},
+
+	{"github.com/go-yaml/yaml", "v0.8.0", true},
+	{"github.com/go-yaml/yaml", "v1.0.0", true},
+	{"github.com/go-yaml/yaml", "v2.0.0", false},
+	{"github.com/go-yaml/yaml", "v2.1.5", false},
+	{"github.com/go-yaml/yaml", "v3.0.0", false},
+
+	{"github.com/go-yaml/yaml/v2", "v1.0.0", false},
+	{"github.com/go-yaml/yaml/v2", "v2.0.0", true},
+	{"github.com/go-yaml/yaml/v2", "v2.1.5", true},
+	{"github.com/go-yaml/yaml/v2", "v3.0.0", false},
+
+	{"rsc.io/quote", "v17.0.0", false},
+	{"rsc.io/quote", "v1.2", false},
+}
+
+func TestCheck(t *testing.T) {
+	for _, tt := range checkTests {
+		err := Check(tt.path, tt.version)
+		if tt.ok && err != nil {
+			t.Errorf("Check(%q, %q) = %v, wanted nil error", tt.path, tt.version, err)
+		} else if !tt.ok && err == nil {
+			t.Errorf("Check(%q, %q) succeeded, wanted error", tt.path, tt.version)
+		}
+	}
+}
+
+var checkPathTests = []struct {
+	path string
+	ok   bool
+}{
+	{`x.y/z`, true},
+	{`x.y`, true},
+
+	{``, false},
+	{`x.y/\ffz`, false},
+	{`/x.y/z`, false},
+	{`x./z`, false},
+	{`.x/z`, false},
+	{`-x/z`, false},
+	{`x..y/z`, true},
+	{`x.y/z/../../w`, false},
+	{`x.y//z`, false},
+	{`x.y/z//w`, false},
+	{`x.y/z/`, false},
+
+	{`x.y/z/v0`, false},
+	{`x.y/z/v1`, false},
+	{`x.y/z/v2`, true},
+	{`x.y/z/v2.0`, true},
+	{`X.y/z`, false},
+	{`x.Y/z`, false},
+	{`x.y/Z`, true},
+	{`x/y`, false},
+	{`x.y/z/.w`, false},
+	{`x.y/z/w.`, false},
+}
+
+func TestCheckPath(t *testing.T) {
+	for _, tt := range checkPathTests {
+		err := CheckPath(tt.path)
+		if tt.ok && err != nil {
+			t.Errorf("CheckPath(%q) = %v, wanted nil error", tt.path, err)
+		} else if !tt.ok && err == nil {
+			t.Errorf("CheckPath(%q) succeeded, wanted error", tt.path)
+		}
+	}
+}
+
+var encodeTests = []struct {
+	path string
+	enc  string
+}{
+	{"ascii.com/abcdefghijklmnopqrstuvwxyz.-+/~_0123456789", "ascii.com/abcdefghijklmnopqrstuvwxyz.-+/~_0123456789"},
+	{"github.com/GoogleCloudPlatform/omega", "github.com/!google!cloud!platform/omega"},
+}
+
+func TestEncodePath(t *testing.T) {
+	// Check invalid paths.
+	for _, tt := range checkPathTests {
+		if !tt.ok {
+			_, err := EncodePath(tt.path)
+			if err == nil {
+				t.Errorf("EncodePath(%q): succeeded, want error (invalid path)", tt.path)
+			}
+		}
+	}
+
+	// Check encodings.
+	for _, tt := range encodeTests {
+		enc, err := EncodePath(tt.path)
+		if err != nil {
+			t.Errorf("EncodePath(%q): unexpected error: %v", tt.path, err)
+			continue
+		}
+		if enc != tt.enc {
+			t.Errorf("EncodePath(%q) = %q, want %q", tt.path, enc, tt.enc)
+		}
+		dec, err := DecodePath(enc)
+		if err != nil {
+			t.Errorf("DecodePath(%q): unexpected error: %v", enc, err)
+			continue
+		}
+		if dec != tt.path {
+			t.Errorf("DecodePath(%q) = %q, want %q", enc, dec, tt.path)
+		}
+	}
+
+	for _, bad := range []string{"github.com/!Google", "github.com/!", "github.com/Google"} {
+		if _, err := DecodePath(bad); err == nil {
+			t.Errorf("DecodePath(%q): succeeded, want error", bad)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package mvs implements Minimal Version Selection.
+//
+// Given a target module and the requirement graph reachable from it,
+// minimal version selection picks, for every module path in the graph,
+// the maximum of the versions mentioned by any requirement. The result
+// depends only on the requirement graph, never on what versions happen
+// to be newest at the time of the build, which makes builds reproducible.
+package mvs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"cmd/go/internal/module"
+)
+
+// Reqs is the requirement graph on which Minimal Version Selection (MVS) operates.
+//
+// The version strings are opaque except for the special version "none"
+// (see the documentation for module.Version). In particular, MVS does not
+// assume that the version strings are semantic versions; instead, the Max method
+// gives access to the comparison operation.
+type Reqs interface {
+	// Required returns the module versions explicitly required by m itself.
+	// The caller must not modify the returned list.
+	Required(m module.Version) ([]module.Version, error)
+
+	// Max returns the maximum of v1 and v2 (it returns either v1 or v2).
+	//
+	// For all versions v, Max(v, "none") must be v,
+	// and for the target passed as the first argument to MVS functions,
+	// Max(target, v) must be target.
+	//
+	// Note that v1 < v2 can be written Max(v1, v2) != v1
+	// and similarly v1 <= v2 can be written Max(v1, v2) == v2.
+	Max(v1, v2 string) string
+}
+
+// A MissingModuleError reports that a requirement of some module
+// in the graph could not be loaded.
+type MissingModuleError struct {
+	Module module.Version
+	Stack  []module.Version // path from the target to Module
+	Err    error
+}
+
+func (e *MissingModuleError) Error() string {
+	var buf strings.Builder
+	for _, m := range e.Stack {
+		buf.WriteString(m.String())
+		buf.WriteString(" requires\n\t")
+	}
+	fmt.Fprintf(&buf, "%s: %v", e.Module, e.Err)
+	return buf.String()
+}
+
+// BuildList returns the build list for the target module.
+// The first element is the target itself, with the remainder
+// of the list sorted by path.
+func BuildList(target module.Version, reqs Reqs) ([]module.Version, error) {
+	// Explore the requirement graph breadth-first,
+	// visiting every module version mentioned by any requirement,
+	// and record the maximum version seen for each path.
+	type node struct {
+		m      module.Version
+		parent *node
+	}
+	stack := func(n *node) []module.Version {
+		var list []module.Version
+		for n = n.parent; n != nil; n = n.parent {
+			list = append(list, n.m)
+		}
+		for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+			list[i], list[j] = list[j], list[i]
+		}
+		return list
+	}
+
+	max := map[string]string{target.Path: target.Version}
+	required := map[module.Version][]module.Version{}
+	seen := map[module.Version]bool{target: true}
+	queue := []*node{{m: target}}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		list, err := reqs.Required(n.m)
+		if err != nil {
+			return nil, &MissingModuleError{Module: n.m, Stack: stack(n), Err: err}
+		}
+		required[n.m] = list
+		for _, r := range list {
+			if r.Path == target.Path {
+				// The target is always selected at its own version.
+				continue
+			}
+			if v, ok := max[r.Path]; !ok || reqs.Max(v, r.Version) != v {
+				max[r.Path] = r.Version
+			}
+			if !seen[r] && r.Version != "none" {
+				seen[r] = true
+				queue = append(queue, &node{m: r, parent: n})
+			}
+		}
+	}
+
+	// The build list is the set of selected versions reachable from the
+	// target through selected versions only. Versions that are mentioned
+	// solely by requirements of unselected versions are dropped.
+	selected := func(path string) module.Version {
+		return module.Version{Path: path, Version: max[path]}
+	}
+	reached := map[string]bool{target.Path: true}
+	var list []module.Version
+	work := []module.Version{target}
+	for len(work) > 0 {
+		m := work[0]
+		work = work[1:]
+		for _, r := range required[m] {
+			if reached[r.Path] {
+				continue
+			}
+			s := selected(r.Path)
+			if s.Version == "none" {
+				continue
+			}
+			reached[r.Path] = true
+			list = append(list, s)
+			work = append(work, s)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+	return append([]module.Version{target}, list...), nil
+}
+
+// Req returns the minimal requirement list for the target module
+// that results in the given build list, with the constraint that all
+// module paths listed in base must appear in the returned list.
+func Req(target module.Version, list []module.Version, base []string, reqs Reqs) ([]module.Version, error) {
+	// Note: Not running in parallel because we assume
+	// that list came from a previous operation that paged
+	// in all the requirements, so there's no I/O to overlap now.
+
+	// Compute postorder, cache requirements.
+	var postorder []module.Version
+	reqCache := map[module.Version][]module.Version{}
+	reqCache[target] = nil
+	var walk func(module.Version) error
+	walk = func(m module.Version) error {
+		_, ok := reqCache[m]
+		if ok {
+			return nil
+		}
+		required, err := reqs.Required(m)
+		if err != nil {
+			return err
+		}
+		reqCache[m] = required
+		for _, m1 := range required {
+			if err := walk(m1); err != nil {
+				return err
+			}
+		}
+		postorder = append(postorder, m)
+		return nil
+	}
+	for _, m := range list {
+		if err := walk(m); err != nil {
+			return nil, err
+		}
+	}
+
+	// Walk modules in reverse post-order, only adding those not implied already.
+	have := map[string]string{}
+	walk = func(m module.Version) error {
+		if v, ok := have[m.Path]; ok && reqs.Max(m.Version, v) == v {
+			return nil
+		}
+		have[m.Path] = m.Version
+		for _, m1 := range reqCache[m] {
+			walk(m1)
+		}
+		return nil
+	}
+	max := map[string]string{}
+	for _, m := range list {
+		if v, ok := max[m.Path]; ok {
+			max[m.Path] = reqs.Max(m.Version, v)
+		} else {
+			max[m.Path] = m.Version
+		}
+	}
+	// First walk the base modules that must be listed.
+	var min []module.Version
+	for _, path := range base {
+		m := module.Version{Path: path, Version: max[path]}
+		min = append(min, m)
+		walk(m)
+	}
+	// Now the reverse postorder to bring in anything else.
+	for i := len(postorder) - 1; i >= 0; i-- {
+		m := postorder[i]
+		if max[m.Path] != m.Version {
+			// Older version.
+			continue
+		}
+		if have[m.Path] != m.Version {
+			min = append(min, m)
+			walk(m)
+		}
+	}
+	sort.Slice(min, func(i, j int) bool {
+		return min[i].Path < min[j].Path
+	})
+	return min, nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mvs
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cmd/go/internal/module"
+)
+
+// reqsMap is a Reqs built from a textual description of the graph.
+// Versions are single letters or digits compared as strings.
+type reqsMap map[module.Version][]module.Version
+
+func (r reqsMap) Max(v1, v2 string) string {
+	if v1 == "none" || v2 == "" {
+		return v2
+	}
+	if v2 == "none" || v1 == "" {
+		return v1
+	}
+	if v1 < v2 {
+		return v2
+	}
+	return v1
+}
+
+func (r reqsMap) Required(m module.Version) ([]module.Version, error) {
+	rr, ok := r[m]
+	if !ok {
+		return nil, fmt.Errorf("missing module: %v", m)
+	}
+	return rr, nil
+}
+
+// parseGraph parses lines of the form "A: B1 C2" meaning that the
+// target A requires B version 1 and C version 2, or "B1: D3" meaning
+// that B version 1 requires D version 3.
+func parseGraph(desc string) reqsMap {
+	reqs := reqsMap{}
+	for _, line := range strings.Split(strings.TrimSpace(desc), "\n") {
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		key := parseMod(strings.TrimSuffix(f[0], ":"))
+		reqs[key] = []module.Version{}
+		for _, s := range f[1:] {
+			reqs[key] = append(reqs[key], parseMod(s))
+		}
+	}
+	return reqs
+}
+
+func parseMod(s string) module.Version {
+	if len(s) == 1 {
+		return module.Version{Path: s}
+	}
+	return module.Version{Path: s[:1], Version: s[1:]}
+}
+
+func modList(s string) []module.Version {
+	var list []module.Version
+	for _, f := range strings.Fields(s) {
+		list = append(list, parseMod(f))
+	}
+	return list
+}
+
+var buildListTests = []struct {
+	name  string
+	graph string
+	want  string
+}{
+	{
+		name: "blog",
+		graph: `
+			A: B1 C2
+			B1: D3
+			C1: D2
+			C2: D4
+			C3: D5
+			C4: G1
+			D2: E1
+			D3: E2
+			D4: E2 F1
+			D5: E2
+			G1: C4
+			E1:
+			E2:
+			E3:
+			F1:
+			F2:
+		`,
+		want: "A B1 C2 D4 E2 F1",
+	},
+	{
+		// B2 requires C1, but B2 is not selected (nothing requires it),
+		// so C must not appear in the build list.
+		name: "unreachable",
+		graph: `
+			A: B1
+			B1:
+			B2: C1
+			C1:
+		`,
+		want: "A B1",
+	},
+	{
+		// D2 is required only by C1, which loses to C2,
+		// but D2 is still the maximum version of D in the graph.
+		name: "prune",
+		graph: `
+			A: B1 C2
+			B1: C1
+			C1: D2
+			C2: D1
+			D1:
+			D2:
+		`,
+		want: "A B1 C2 D2",
+	},
+	{
+		name: "cycle",
+		graph: `
+			A: B1
+			B1: C1
+			C1: B2
+			B2: C1
+		`,
+		want: "A B2 C1",
+	},
+	{
+		name: "none",
+		graph: `
+			A: B1 Cnone
+			B1: C1
+			C1:
+		`,
+		want: "A B1 C1",
+	},
+}
+
+func TestBuildList(t *testing.T) {
+	for _, tt := range buildListTests {
+		reqs := parseGraph(tt.graph)
+		list, err := BuildList(module.Version{Path: "A"}, reqs)
+		if err != nil {
+			t.Errorf("%s: BuildList: %v", tt.name, err)
+			continue
+		}
+		if want := modList(tt.want); !reflect.DeepEqual(list, want) {
+			t.Errorf("%s: BuildList = %v, want %v", tt.name, list, want)
+		}
+	}
+}
+
+func TestBuildListMissing(t *testing.T) {
+	reqs := parseGraph(`
+		A: B1
+		B1: C1
+	`)
+	_, err := BuildList(module.Version{Path: "A"}, reqs)
+	if err == nil {
+		t.Fatal("BuildList succeeded with missing module")
+	}
+	me, ok := err.(*MissingModuleError)
+	if !ok || me.Module != parseMod("C1") || !reflect.DeepEqual(me.Stack, modList("A B1")) {
+		t.Fatalf("BuildList error = %v, want MissingModuleError for C1 via A, B1", err)
+	}
+}
+
+func TestReq(t *testing.T) {
+	reqs := parseGraph(`
+		A: B1 C1 D1
+		B1: D1
+		C1: D1
+		D1:
+	`)
+	list, err := BuildList(module.Version{Path: "A"}, reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	min, err := Req(module.Version{Path: "A"}, list, nil, reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := modList("B1 C1"); !reflect.DeepEqual(min, want) {
+		t.Fatalf("Req = %v, want %v", min, want)
+	}
+	min, err = Req(module.Version{Path: "A"}, list, []string{"D"}, reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := modList("B1 C1 D1"); !reflect.DeepEqual(min, want) {
+		t.Fatalf("Req with base D = %v, want %v", min, want)
+	}
+}
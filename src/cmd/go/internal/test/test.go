@@ -63,8 +63,8 @@ followed by detailed output for each failed package.
 
 'Go test' recompiles each package along with any files with names matching
 the file pattern "*_test.go".
-These additional files can contain test functions, benchmark functions, and
-example functions. See 'go help testfunc' for more.
+These additional files can contain test functions, benchmark functions,
+fuzz targets, and example functions. See 'go help testfunc' for more.
 Each listed package causes the execution of a separate test binary.
 Files whose names begin with "_" (including "_test.go") or "." are ignored.
 
@@ -253,6 +253,26 @@ const testFlag2 = `
 	-failfast
 	    Do not start new tests after the first test failure.
 
+	-fuzz regexp
+	    Run the fuzz target matching the regular expression. When specified,
+	    the command line argument must match exactly one package, and regexp
+	    must match exactly one fuzz target within that package. After the
+	    tests, benchmarks, and the seed corpora of all fuzz targets have run,
+	    the matching target is fuzzed: its inputs are mutated at random,
+	    guided by the coverage of the package being tested, until an input
+	    makes the fuzz function fail. The failing input is minimized and
+	    written to the package's testdata/fuzz directory. Interesting inputs
+	    found along the way are kept in the build cache and reused by later
+	    runs. Unless -timeout is given, fuzzing disables the test timeout.
+
+	-fuzzminimizetime t
+	    Spend at most t, specified as a time.Duration, minimizing a
+	    failing input found while fuzzing. The default is 1 minute (1m).
+
+	-fuzztime t
+	    Stop fuzzing after t, specified as a time.Duration, if no failing
+	    input has been found. The default is to fuzz until a failure.
+
 	-list regexp
 	    List tests, benchmarks, or examples matching the regular expression.
 	    No tests, benchmarks or examples will be run. This will only
@@ -416,8 +436,8 @@ var HelpTestfunc = &base.Command{
 	UsageLine: "testfunc",
 	Short:     "testing functions",
 	Long: `
-The 'go test' command expects to find test, benchmark, and example functions
-in the "*_test.go" files corresponding to the package under test.
+The 'go test' command expects to find test, benchmark, fuzz, and example
+functions in the "*_test.go" files corresponding to the package under test.
 
 A test function is one named TestXxx (where Xxx does not start with a
 lower case letter) and should have the signature,
@@ -428,6 +448,14 @@ A benchmark function is one named BenchmarkXxx and should have the signature,
 
 	func BenchmarkXxx(b *testing.B) { ... }
 
+A fuzz target is one named FuzzXxx and should have the signature,
+
+	func FuzzXxx(f *testing.F) { ... }
+
+By default, go test runs the fuzz function passed to f.Fuzz on each input
+in the target's seed corpus and testdata/fuzz/FuzzXxx directory. See the
+-fuzz flag in 'go help testflag' for fuzzing with generated inputs.
+
 An example function is similar to a test function but, instead of using
 *testing.T to report success or failure, prints output to os.Stdout.
 If the last comment in the function starts with "Output:" then the output
@@ -480,6 +508,7 @@ var (
 	testCoverPaths   []string        // -coverpkg flag
 	testCoverPkgs    []*load.Package // -coverpkg flag
 	testCoverProfile string          // -coverprofile flag
+	testFuzz         string          // -fuzz flag
 	testOutputDir    string          // -outputdir flag
 	testO            string          // -o flag
 	testProfile      string          // profiling flag that limits test to one package
@@ -554,6 +583,16 @@ func runTest(cmd *base.Command, args []string) {
 	if testProfile != "" && len(pkgs) != 1 {
 		base.Fatalf("cannot use %s flag with multiple packages", testProfile)
 	}
+	if testFuzz != "" && len(pkgs) != 1 {
+		base.Fatalf("cannot use -fuzz flag with multiple packages")
+	}
+	if dir := cache.DefaultDir(); testFuzz != "" && dir != "off" {
+		// Keep the interesting inputs found while fuzzing,
+		// so that the next run can pick up where this one left off.
+		// The flag goes ahead of any arguments given after -args.
+		cacheDir := filepath.Join(dir, "fuzz", filepath.FromSlash(pkgs[0].ImportPath))
+		testArgs = append([]string{"-test.fuzzcachedir=" + cacheDir}, testArgs...)
+	}
 	initCoverProfile()
 	defer closeCoverProfile()
 
@@ -563,8 +602,10 @@ func runTest(cmd *base.Command, args []string) {
 	// timer does not get a chance to fire.
 	if dt, err := time.ParseDuration(testTimeout); err == nil && dt > 0 {
 		testKillTimeout = dt + 1*time.Minute
-	} else if err == nil && dt == 0 {
+	} else if err == nil && dt == 0 || testTimeout == "" && testFuzz != "" {
 		// An explicit zero disables the test timeout.
+		// So does fuzzing, which runs until it finds a failure,
+		// unless a timeout was given explicitly.
 		// Let it have one century (almost) before we kill it.
 		testKillTimeout = 100 * 365 * 24 * time.Hour
 	}
@@ -792,7 +833,11 @@ func builderTest(b *work.Builder, p *load.Package) (buildAction, runAction, prin
 
 	localCover := testCover && testCoverPaths == nil
 
-	ptest, pxtest, err = load.TestPackagesFor(p, localCover || p.Name == "main")
+	// Fuzzing is guided by the coverage counters of the package being tested,
+	// so instrument it even without -cover.
+	fuzzCover := testFuzz != "" && testCoverPaths == nil
+
+	ptest, pxtest, err = load.TestPackagesFor(p, localCover || fuzzCover || p.Name == "main")
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -813,8 +858,12 @@ func builderTest(b *work.Builder, p *load.Package) (buildAction, runAction, prin
 	// only for this package and only for this test?
 	// Yes, if -cover is on but -coverpkg has not specified
 	// a list of packages for global coverage.
-	if localCover {
+	if localCover || fuzzCover {
 		ptest.Internal.CoverMode = testCoverMode
+		if !testCover {
+			// Fuzzing needs hit counts, not just whether a block ran.
+			ptest.Internal.CoverMode = "count"
+		}
 		var coverFiles []string
 		coverFiles = append(coverFiles, ptest.GoFiles...)
 		coverFiles = append(coverFiles, ptest.CgoFiles...)
@@ -1159,10 +1208,10 @@ func (c *runCache) builderRunTest(b *work.Builder, a *work.Action) error {
 	}
 
 	var buf bytes.Buffer
-	if len(pkgArgs) == 0 || testBench {
+	if len(pkgArgs) == 0 || testBench || testFuzz != "" {
 		// Stream test output (no buffering) when no package has
-		// been given on the command line (implicit current directory)
-		// or when benchmarking.
+		// been given on the command line (implicit current directory),
+		// when benchmarking, or when fuzzing.
 		// No change to stdout.
 	} else {
 		// If we're only running a single package under test or if parallelism is
@@ -1735,7 +1784,7 @@ func builderNoTest(b *work.Builder, a *work.Action) error {
 }
 
 // isTestFunc tells whether fn has the type of a testing function. arg
-// specifies the parameter type we look for: B, M or T.
+// specifies the parameter type we look for: B, F, M or T.
 func isTestFunc(fn *ast.FuncDecl, arg string) bool {
 	if fn.Type.Results != nil && len(fn.Type.Results.List) > 0 ||
 		fn.Type.Params.List == nil ||
@@ -1750,7 +1799,7 @@ func isTestFunc(fn *ast.FuncDecl, arg string) bool {
 	// We can't easily check that the type is *testing.M
 	// because we don't know how testing has been imported,
 	// but at least check that it's *M or *something.M.
-	// Same applies for B and T.
+	// Same applies for B, F and T.
 	if name, ok := ptr.X.(*ast.Ident); ok && name.Name == arg {
 		return true
 	}
@@ -1811,6 +1860,7 @@ func writeTestmain(out string, t *testFuncs) error {
 type testFuncs struct {
 	Tests       []testFunc
 	Benchmarks  []testFunc
+	FuzzTargets []testFunc
 	Examples    []testFunc
 	TestMain    *testFunc
 	Package     *load.Package
@@ -1821,12 +1871,19 @@ type testFuncs struct {
 	Cover       []coverInfo
 }
 
+// CoverMode returns the coverage mode reported by the test binary.
+// It is empty when the coverage counters are used only for fuzzing.
 func (t *testFuncs) CoverMode() string {
+	if !testCover {
+		return ""
+	}
 	return testCoverMode
 }
 
+// CoverEnabled reports whether the test binary registers coverage counters,
+// either to report coverage or to guide fuzzing.
 func (t *testFuncs) CoverEnabled() bool {
-	return testCover
+	return testCover || testFuzz != ""
 }
 
 // ImportPath returns the import path of the package being tested, if it is within GOPATH.
@@ -1911,6 +1968,13 @@ func (t *testFuncs) load(filename, pkg string, doImport, seen *bool) error {
 			}
 			t.Benchmarks = append(t.Benchmarks, testFunc{pkg, name, "", false})
 			*doImport, *seen = true, true
+		case isTest(name, "Fuzz"):
+			err := checkTestFunc(n, "F")
+			if err != nil {
+				return err
+			}
+			t.FuzzTargets = append(t.FuzzTargets, testFunc{pkg, name, "", false})
+			*doImport, *seen = true, true
 		}
 	}
 	ex := doc.Examples(f)
@@ -1969,6 +2033,12 @@ var benchmarks = []testing.InternalBenchmark{
 {{end}}
 }
 
+var fuzzTargets = []testing.InternalFuzzTarget{
+{{range .FuzzTargets}}
+	{"{{.Name}}", {{.Package}}.{{.Name}}},
+{{end}}
+}
+
 var examples = []testing.InternalExample{
 {{range .Examples}}
 	{"{{.Name}}", {{.Package}}.{{.Name}}, {{.Output | printf "%q"}}, {{.Unordered}}},
@@ -2027,7 +2097,7 @@ func main() {
 		CoveredPackages: {{printf "%q" .Covered}},
 	})
 {{end}}
-	m := testing.MainStart(testdeps.TestDeps{}, tests, benchmarks, examples)
+	m := testing.MainStart(testdeps.TestDeps{}, tests, benchmarks, fuzzTargets, examples)
 {{with .TestMain}}
 	{{.Package}}.{{.Name}}(m)
 {{else}}
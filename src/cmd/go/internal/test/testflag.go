@@ -47,6 +47,9 @@ var testFlagDefn = []*cmdflag.Defn{
 	{Name: "cpu", PassToTest: true},
 	{Name: "cpuprofile", PassToTest: true},
 	{Name: "failfast", BoolVar: new(bool), PassToTest: true},
+	{Name: "fuzz", PassToTest: true},
+	{Name: "fuzzminimizetime", PassToTest: true},
+	{Name: "fuzztime", PassToTest: true},
 	{Name: "list", PassToTest: true},
 	{Name: "memprofile", PassToTest: true},
 	{Name: "memprofilerate", PassToTest: true},
@@ -153,6 +156,8 @@ func testFlags(args []string) (packageNames, passToTest []string) {
 				testBench = true
 			case "list":
 				testList = true
+			case "fuzz":
+				testFuzz = value
 			case "timeout":
 				testTimeout = value
 			case "blockprofile", "cpuprofile", "memprofile", "mutexprofile":
@@ -422,6 +422,11 @@ func InstallPackages(args []string, forGet bool) {
 
 	for _, p := range pkgs {
 		if p.Target == "" && (!p.Standard || p.ImportPath != "unsafe") {
+			if p.Module != nil && p.Name != "main" {
+				// Module mode does not install packages into a
+				// pkg directory; they live only in the build cache.
+				continue
+			}
 			switch {
 			case p.Internal.GobinSubdir:
 				base.Errorf("go %s: cannot install cross-compiled binaries when GOBIN is set", cfg.CmdName)
@@ -9,6 +9,7 @@ package work
 import (
 	"cmd/go/internal/base"
 	"cmd/go/internal/cfg"
+	"cmd/go/internal/modload"
 	"flag"
 	"fmt"
 	"os"
@@ -16,6 +17,7 @@ import (
 )
 
 func BuildInit() {
+	modload.Init()
 	instrumentInit()
 	buildModeInit()
 
@@ -27,6 +27,7 @@ import (
 	"cmd/go/internal/get"
 	"cmd/go/internal/help"
 	"cmd/go/internal/list"
+	"cmd/go/internal/modload"
 	"cmd/go/internal/run"
 	"cmd/go/internal/test"
 	"cmd/go/internal/tool"
@@ -60,7 +61,9 @@ func init() {
 		help.HelpEnvironment,
 		help.HelpFileType,
 		help.HelpGopath,
+		modload.HelpGoproxy,
 		help.HelpImportPath,
+		modload.HelpModules,
 		help.HelpPackages,
 		test.HelpTestflag,
 		test.HelpTestfunc,
@@ -0,0 +1,172 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main_test
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testModule describes a module version served by the test proxy.
+type testModule struct {
+	path, version string
+	files         map[string]string // file name -> content; must include go.mod
+}
+
+var testModules = []testModule{
+	{"example.com/greet", "v1.0.0", map[string]string{
+		"go.mod":   "module example.com/greet\n",
+		"greet.go": "package greet\n\nfunc Hello() string { return \"hello v1.0.0\" }\n",
+	}},
+	{"example.com/greet", "v1.1.0", map[string]string{
+		"go.mod":                    "module example.com/greet\n\nrequire example.com/world v0.1.0\n",
+		"greet.go":                  "package greet\n\nimport \"example.com/world\"\n\nfunc Hello() string { return \"hello \" + world.Name() }\n",
+		"internal/secret/secret.go": "package secret\n",
+	}},
+	{"example.com/world", "v0.1.0", map[string]string{
+		"go.mod":   "module example.com/world\n",
+		"world.go": "package world\n\nfunc Name() string { return \"world v0.1.0\" }\n",
+	}},
+	{"example.com/world", "v0.2.0", map[string]string{
+		"go.mod":   "module example.com/world\n",
+		"world.go": "package world\n\nfunc Name() string { return \"world v0.2.0\" }\n",
+	}},
+}
+
+// modTestgo returns a testgoData set up to run in module mode
+// in a main module example.com/hello, with a file:/// module proxy
+// serving testModules and a fresh module cache.
+func modTestgo(t *testing.T) *testgoData {
+	tg := testgo(t)
+	tg.makeTempdir()
+	for _, m := range testModules {
+		dir := tg.path("proxy/" + m.path + "/@v")
+		tg.must(os.MkdirAll(dir, 0777))
+		list, _ := ioutil.ReadFile(filepath.Join(dir, "list"))
+		tg.must(ioutil.WriteFile(filepath.Join(dir, "list"), append(list, m.version+"\n"...), 0666))
+		tg.must(ioutil.WriteFile(filepath.Join(dir, m.version+".mod"), []byte(m.files["go.mod"]), 0666))
+		f, err := os.Create(filepath.Join(dir, m.version+".zip"))
+		tg.must(err)
+		z := zip.NewWriter(f)
+		for name, content := range m.files {
+			w, err := z.Create(m.path + "@" + m.version + "/" + name)
+			tg.must(err)
+			_, err = w.Write([]byte(content))
+			tg.must(err)
+		}
+		tg.must(z.Close())
+		tg.must(f.Close())
+	}
+	tg.setenv("GOPROXY", "file://"+filepath.ToSlash(tg.path("proxy")))
+	tg.setenv("GOPATH", tg.path("gopath"))
+	tg.setenv("GO111MODULE", "auto")
+	tg.tempFile("hello/go.mod", "module example.com/hello\n\nrequire example.com/greet v1.0.0\n")
+	tg.tempFile("hello/main.go", `package main
+
+		import (
+			"fmt"
+
+			"example.com/greet"
+		)
+
+		func main() { fmt.Println(greet.Hello()) }
+	`)
+	tg.cd(tg.path("hello"))
+	return tg
+}
+
+func TestModBuild(t *testing.T) {
+	tg := modTestgo(t)
+	defer tg.cleanup()
+
+	tg.run("build", "-o", "hello"+exeSuffix, ".")
+	tg.run("list", "-m")
+	tg.grepStdout(`^example.com/hello$`, "main module not listed first")
+	tg.grepStdout(`^example.com/greet v1.0.0$`, "greet v1.0.0 not in build list")
+
+	sum, err := ioutil.ReadFile(tg.path("hello/go.sum"))
+	tg.must(err)
+	for _, want := range []string{"example.com/greet v1.0.0 h1:", "example.com/greet v1.0.0/go.mod h1:"} {
+		if !strings.Contains(string(sum), want) {
+			t.Errorf("go.sum does not contain %q:\n%s", want, sum)
+		}
+	}
+
+	tg.run("list", "-f", "{{.Module.Path}} {{.Module.Version}} {{.Dir}}", "example.com/greet")
+	tg.grepStdout(`^example.com/greet v1.0.0 .*example.com.greet@v1.0.0$`, "greet not loaded from module cache")
+}
+
+func TestModGetMVS(t *testing.T) {
+	tg := modTestgo(t)
+	defer tg.cleanup()
+
+	// Upgrading greet pulls in world v0.1.0.
+	tg.run("get", "-d", "example.com/greet@v1.1.0")
+	tg.run("list", "-m")
+	tg.grepStdout(`^example.com/greet v1.1.0$`, "greet not upgraded")
+	tg.grepStdout(`^example.com/world v0.1.0$`, "world v0.1.0 not selected")
+
+	// Requiring a newer world directly selects the maximum.
+	tg.run("get", "-d", "example.com/world@latest")
+	tg.run("list", "-m")
+	tg.grepStdout(`^example.com/world v0.2.0$`, "world v0.2.0 not selected")
+	gomod, err := ioutil.ReadFile(tg.path("hello/go.mod"))
+	tg.must(err)
+	if !strings.Contains(string(gomod), "example.com/world v0.2.0") {
+		t.Errorf("go.mod does not require world v0.2.0:\n%s", gomod)
+	}
+
+	// Requesting a version the proxy does not have fails.
+	tg.runFail("get", "-d", "example.com/world@v0.3.0")
+	tg.grepStderr("has no version v0.3.0", "missing version not reported")
+
+	// Dropping the direct requirement falls back to greet's.
+	tg.run("get", "-d", "example.com/world@none")
+	tg.run("list", "-m")
+	tg.grepStdout(`^example.com/world v0.1.0$`, "world v0.1.0 not selected after drop")
+}
+
+func TestModChecksumMismatch(t *testing.T) {
+	tg := modTestgo(t)
+	defer tg.cleanup()
+
+	tg.tempFile("hello/go.sum", "example.com/greet v1.0.0 h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=\n")
+	tg.runFail("build", "-o", "hello"+exeSuffix, ".")
+	tg.grepStderr("verifying example.com/greet@v1.0.0: checksum mismatch", "checksum mismatch not detected")
+}
+
+func TestModReplaceDir(t *testing.T) {
+	tg := modTestgo(t)
+	defer tg.cleanup()
+
+	tg.tempFile("greet/go.mod", "module example.com/greet\n")
+	tg.tempFile("greet/greet.go", "package greet\n\nfunc Hello() string { return \"local greet\" }\n")
+	tg.tempFile("hello/go.mod", "module example.com/hello\n\nrequire example.com/greet v1.0.0\n\nreplace example.com/greet => ../greet\n")
+	tg.run("run", "main.go")
+	tg.grepStdout("local greet", "replacement directory not used")
+}
+
+func TestModInternal(t *testing.T) {
+	tg := modTestgo(t)
+	defer tg.cleanup()
+
+	tg.tempFile("hello/go.mod", "module example.com/hello\n\nrequire example.com/greet v1.1.0\n")
+	tg.tempFile("hello/x/x.go", "package x\n\nimport _ \"example.com/greet/internal/secret\"\n")
+	tg.runFail("build", "./x")
+	tg.grepStderr("use of internal package not allowed", "internal package import allowed across modules")
+}
+
+func TestModOff(t *testing.T) {
+	tg := modTestgo(t)
+	defer tg.cleanup()
+
+	tg.setenv("GO111MODULE", "off")
+	tg.runFail("list", "-m")
+	tg.grepStderr("not using modules", "list -m succeeded outside module mode")
+}
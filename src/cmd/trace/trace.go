@@ -554,6 +554,8 @@ func generateTrace(params *traceParams) (ViewerData, error) {
 		case trace.EvGCStart:
 			ctx.emitSlice(ev, "GC")
 		case trace.EvGCDone:
+		case trace.EvMemoryLimitGC:
+			ctx.emitInstant(ev, "memory limit GC", "")
 		case trace.EvGCSTWStart:
 			if ctx.mode != defaultTraceview {
 				continue
@@ -507,6 +507,7 @@ const (
 	argFloat
 	argComplex
 	argPointer
+	argError
 	anyType printfArgType = ^0
 )
 
@@ -549,6 +550,7 @@ var printVerbs = []printVerb{
 	{'T', "-", anyType},
 	{'U', "-#", argRune | argInt},
 	{'v', allFlags, anyType},
+	{'w', allFlags, argError},
 	{'x', sharpNumFlag, argRune | argInt | argString},
 	{'X', sharpNumFlag, argRune | argInt | argString},
 }
@@ -567,6 +569,12 @@ func (f *File) okPrintfArg(call *ast.CallExpr, state *formatState) (ok bool) {
 		}
 	}
 
+	// Only Errorf knows how to wrap an error.
+	if state.verb == 'w' && state.name != "Errorf" {
+		f.Badf(call.Pos(), "%s call has error-wrapping directive %%w", state.name)
+		return false
+	}
+
 	// Does current arg implement fmt.Formatter?
 	formatter := false
 	if state.argNum < len(call.Args) {
@@ -610,7 +618,7 @@ func (f *File) okPrintfArg(call *ast.CallExpr, state *formatState) (ok bool) {
 			return false
 		}
 	}
-	if state.verb == '%' || formatter {
+	if state.verb == '%' || formatter && state.verb != 'w' {
 		return true
 	}
 	argNum := state.argNums[len(state.argNums)-1]
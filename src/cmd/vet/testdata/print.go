@@ -108,6 +108,11 @@ func PrintfTests() {
 
 	fmt.Printf("%g", 1+2i)
 	fmt.Printf("%#e %#E %#f %#F %#g %#G", 1.2, 1.2, 1.2, 1.2, 1.2, 1.2) // OK since Go 1.9
+	_ = fmt.Errorf("%w", os.ErrNotExist)
+	_ = fmt.Errorf("%v: %w", "context", os.ErrNotExist)
+	_ = fmt.Errorf("%w", "hi")            // ERROR "Errorf format %w has arg \x22hi\x22 of wrong type string"
+	fmt.Printf("%w", os.ErrNotExist)      // ERROR "Printf call has error-wrapping directive %w"
+	_ = fmt.Sprintf("%w", os.ErrNotExist) // ERROR "Sprintf call has error-wrapping directive %w"
 	// Some bad format/argTypes
 	fmt.Printf("%b", "hi")                      // ERROR "Printf format %b has arg \x22hi\x22 of wrong type string"
 	fmt.Printf("%t", c)                         // ERROR "Printf format %t has arg c of wrong type complex64"
@@ -138,6 +138,10 @@ func (f *File) matchArgTypeInternal(t printfArgType, typ types.Type, arg ast.Exp
 			return true // probably a type check problem
 		}
 	}
+	// %w accepts only errors, even those that implement fmt.Formatter.
+	if t == argError {
+		return types.ConvertibleTo(typ, errorType)
+	}
 	// If the type implements fmt.Formatter, we have nothing to check.
 	if f.isFormatter(typ) {
 		return true
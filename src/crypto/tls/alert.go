@@ -38,6 +38,9 @@ const (
 	alertInappropriateFallback  alert = 86
 	alertUserCanceled           alert = 90
 	alertNoRenegotiation        alert = 100
+	alertMissingExtension       alert = 109
+	alertUnsupportedExtension   alert = 110
+	alertCertificateRequired    alert = 116
 	alertNoApplicationProtocol  alert = 120
 )
 
@@ -65,6 +68,9 @@ var alertText = map[alert]string{
 	alertInappropriateFallback:  "inappropriate fallback",
 	alertUserCanceled:           "user canceled",
 	alertNoRenegotiation:        "no renegotiation",
+	alertMissingExtension:       "missing extension",
+	alertUnsupportedExtension:   "unsupported extension",
+	alertCertificateRequired:    "certificate required",
 	alertNoApplicationProtocol:  "no application protocol",
 }
 
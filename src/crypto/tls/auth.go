@@ -0,0 +1,193 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tls
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// verifyHandshakeSignature verifies a signature against pre-hashed handshake
+// contents, or against the unhashed contents for Ed25519.
+func verifyHandshakeSignature(sigType uint8, pubkey crypto.PublicKey, hashFunc crypto.Hash, digest, sig []byte) error {
+	switch sigType {
+	case signatureECDSA:
+		pubKey, ok := pubkey.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("tls: ECDSA signing requires a ECDSA public key")
+		}
+		ecdsaSig := new(ecdsaSignature)
+		if _, err := asn1.Unmarshal(sig, ecdsaSig); err != nil {
+			return err
+		}
+		if ecdsaSig.R.Sign() <= 0 || ecdsaSig.S.Sign() <= 0 {
+			return errors.New("tls: ECDSA signature contained zero or negative values")
+		}
+		if !ecdsa.Verify(pubKey, digest, ecdsaSig.R, ecdsaSig.S) {
+			return errors.New("tls: ECDSA verification failure")
+		}
+	case signatureRSA:
+		pubKey, ok := pubkey.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("tls: RSA signing requires a RSA public key")
+		}
+		if err := rsa.VerifyPKCS1v15(pubKey, hashFunc, digest, sig); err != nil {
+			return err
+		}
+	case signatureRSAPSS:
+		pubKey, ok := pubkey.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("tls: RSA signing requires a RSA public key")
+		}
+		signOpts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+		if err := rsa.VerifyPSS(pubKey, hashFunc, digest, sig, signOpts); err != nil {
+			return err
+		}
+	case signatureEd25519:
+		pubKey, ok := pubkey.(ed25519.PublicKey)
+		if !ok {
+			return errors.New("tls: Ed25519 signing requires an Ed25519 public key")
+		}
+		if !ed25519.Verify(pubKey, digest, sig) {
+			return errors.New("tls: Ed25519 verification failure")
+		}
+	default:
+		return errors.New("tls: unknown signature algorithm")
+	}
+	return nil
+}
+
+const (
+	serverSignatureContext = "TLS 1.3, server CertificateVerify\x00"
+	clientSignatureContext = "TLS 1.3, client CertificateVerify\x00"
+)
+
+var signaturePadding = []byte{
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+}
+
+// signedMessage returns the pre-hashed (if necessary) message to be signed by
+// certificate keys in TLS 1.3. See RFC 8446, Section 4.4.3.
+func signedMessage(sigHash crypto.Hash, context string, transcript hash.Hash) []byte {
+	if sigHash == 0 {
+		// Ed25519 signs the message itself.
+		signed := make([]byte, 0, len(signaturePadding)+len(context)+transcript.Size())
+		signed = append(signed, signaturePadding...)
+		signed = append(signed, context...)
+		return transcript.Sum(signed)
+	}
+	h := sigHash.New()
+	h.Write(signaturePadding)
+	h.Write([]byte(context))
+	h.Write(transcript.Sum(nil))
+	return h.Sum(nil)
+}
+
+// signatureSchemesForCertificate returns the list of supported SignatureSchemes
+// for a given certificate, based on the public key, in the order they should
+// be tried. It returns nil if the key type is not supported in TLS 1.3.
+func signatureSchemesForCertificate(cert *Certificate) []SignatureScheme {
+	priv, ok := cert.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil
+	}
+
+	switch pub := priv.Public().(type) {
+	case *ecdsa.PublicKey:
+		// In TLS 1.3 the curve is bound to the hash, so only one scheme
+		// matches a given ECDSA key.
+		switch pub.Curve {
+		case elliptic.P256():
+			return []SignatureScheme{ECDSAWithP256AndSHA256}
+		case elliptic.P384():
+			return []SignatureScheme{ECDSAWithP384AndSHA384}
+		case elliptic.P521():
+			return []SignatureScheme{ECDSAWithP521AndSHA512}
+		default:
+			return nil
+		}
+	case *rsa.PublicKey:
+		// RSA keys can only sign with RSASSA-PSS in TLS 1.3.
+		return []SignatureScheme{PSSWithSHA256, PSSWithSHA384, PSSWithSHA512}
+	case ed25519.PublicKey:
+		return []SignatureScheme{Ed25519}
+	default:
+		return nil
+	}
+}
+
+// pickSignatureSchemeTLS13 selects, in peer preference order, a signature
+// scheme that cert can sign with and that is allowed in TLS 1.3.
+func pickSignatureSchemeTLS13(cert *Certificate, peerAlgs []SignatureScheme) (SignatureScheme, error) {
+	certAlgs := signatureSchemesForCertificate(cert)
+	if certAlgs == nil {
+		return 0, fmt.Errorf("tls: unsupported certificate key (%T)", cert.PrivateKey)
+	}
+	for _, sigAlg := range peerAlgs {
+		if !isSupportedSignatureAlgorithm(sigAlg, certAlgs) {
+			continue
+		}
+		// RSASSA-PSS with SHA-512 needs at least a 1040-bit key, so
+		// skip it if the key is too small.
+		if sigAlg == PSSWithSHA512 {
+			if pub, ok := cert.PrivateKey.(crypto.Signer).Public().(*rsa.PublicKey); ok && pub.N.BitLen() < 1040 {
+				continue
+			}
+		}
+		return sigAlg, nil
+	}
+	return 0, errors.New("tls: peer doesn't support any of the certificate's signature algorithms")
+}
+
+// signTLS13 signs the transcript with the given private key and scheme,
+// for a CertificateVerify message with the given context.
+func signTLS13(config *Config, cert *Certificate, sigAlg SignatureScheme, context string, transcript hash.Hash) ([]byte, error) {
+	sigHash, err := lookupTLSHash(sigAlg)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := cert.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("tls: certificate private key does not implement crypto.Signer")
+	}
+	digest := signedMessage(sigHash, context, transcript)
+	var signOpts crypto.SignerOpts = sigHash
+	if signatureFromSignatureScheme(sigAlg) == signatureRSAPSS {
+		signOpts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: sigHash}
+	}
+	return priv.Sign(config.rand(), digest, signOpts)
+}
+
+// verifyTLS13 checks a TLS 1.3 CertificateVerify signature made by the peer
+// with the given public key. sigAlg must be one that was offered locally.
+func verifyTLS13(pubkey crypto.PublicKey, sigAlg SignatureScheme, context string, transcript hash.Hash, sig []byte) error {
+	sigType := signatureFromSignatureScheme(sigAlg)
+	// PKCS #1 v1.5 and SHA-1 are forbidden in TLS 1.3 CertificateVerify
+	// messages. See RFC 8446, Section 4.4.3.
+	if sigType == signatureRSA || sigAlg == ECDSAWithSHA1 ||
+		!isSupportedSignatureAlgorithm(sigAlg, supportedSignatureAlgorithmsTLS13) {
+		return errors.New("tls: peer used an illegal signature algorithm")
+	}
+	sigHash, err := lookupTLSHash(sigAlg)
+	if err != nil {
+		return err
+	}
+	digest := signedMessage(sigHash, context, transcript)
+	return verifyHandshakeSignature(sigType, pubkey, sigHash, digest, sig)
+}
@@ -26,6 +26,7 @@ const (
 	VersionTLS10 = 0x0301
 	VersionTLS11 = 0x0302
 	VersionTLS12 = 0x0303
+	VersionTLS13 = 0x0304
 )
 
 const (
@@ -36,7 +37,7 @@ const (
 	maxWarnAlertCount = 5            // maximum number of consecutive warning alerts
 
 	minVersion = VersionTLS10
-	maxVersion = VersionTLS12
+	maxVersion = VersionTLS13
 )
 
 // TLS record types.
@@ -51,19 +52,22 @@ const (
 
 // TLS handshake message types.
 const (
-	typeHelloRequest       uint8 = 0
-	typeClientHello        uint8 = 1
-	typeServerHello        uint8 = 2
-	typeNewSessionTicket   uint8 = 4
-	typeCertificate        uint8 = 11
-	typeServerKeyExchange  uint8 = 12
-	typeCertificateRequest uint8 = 13
-	typeServerHelloDone    uint8 = 14
-	typeCertificateVerify  uint8 = 15
-	typeClientKeyExchange  uint8 = 16
-	typeFinished           uint8 = 20
-	typeCertificateStatus  uint8 = 22
-	typeNextProtocol       uint8 = 67 // Not IANA assigned
+	typeHelloRequest        uint8 = 0
+	typeClientHello         uint8 = 1
+	typeServerHello         uint8 = 2
+	typeNewSessionTicket    uint8 = 4
+	typeEncryptedExtensions uint8 = 8
+	typeCertificate         uint8 = 11
+	typeServerKeyExchange   uint8 = 12
+	typeCertificateRequest  uint8 = 13
+	typeServerHelloDone     uint8 = 14
+	typeCertificateVerify   uint8 = 15
+	typeClientKeyExchange   uint8 = 16
+	typeFinished            uint8 = 20
+	typeCertificateStatus   uint8 = 22
+	typeKeyUpdate           uint8 = 24
+	typeNextProtocol        uint8 = 67  // Not IANA assigned
+	typeMessageHash         uint8 = 254 // synthetic message
 )
 
 // TLS compression types.
@@ -73,16 +77,22 @@ const (
 
 // TLS extension numbers
 const (
-	extensionServerName          uint16 = 0
-	extensionStatusRequest       uint16 = 5
-	extensionSupportedCurves     uint16 = 10
-	extensionSupportedPoints     uint16 = 11
-	extensionSignatureAlgorithms uint16 = 13
-	extensionALPN                uint16 = 16
-	extensionSCT                 uint16 = 18 // https://tools.ietf.org/html/rfc6962#section-6
-	extensionSessionTicket       uint16 = 35
-	extensionNextProtoNeg        uint16 = 13172 // not IANA assigned
-	extensionRenegotiationInfo   uint16 = 0xff01
+	extensionServerName             uint16 = 0
+	extensionStatusRequest          uint16 = 5
+	extensionSupportedCurves        uint16 = 10
+	extensionSupportedPoints        uint16 = 11
+	extensionSignatureAlgorithms    uint16 = 13
+	extensionALPN                   uint16 = 16
+	extensionSCT                    uint16 = 18 // https://tools.ietf.org/html/rfc6962#section-6
+	extensionSessionTicket          uint16 = 35
+	extensionPreSharedKey           uint16 = 41
+	extensionSupportedVersions      uint16 = 43
+	extensionCookie                 uint16 = 44
+	extensionPSKModes               uint16 = 45
+	extensionCertificateAuthorities uint16 = 47
+	extensionKeyShare               uint16 = 51
+	extensionNextProtoNeg           uint16 = 13172 // not IANA assigned
+	extensionRenegotiationInfo      uint16 = 0xff01
 )
 
 // TLS signaling cipher suite values
@@ -101,6 +111,27 @@ const (
 	X25519    CurveID = 29
 )
 
+// TLS 1.3 Key Share
+// See https://tools.ietf.org/html/rfc8446#section-4.2.8
+type keyShare struct {
+	group CurveID
+	data  []byte
+}
+
+// TLS 1.3 PSK Key Exchange Modes
+// See https://tools.ietf.org/html/rfc8446#section-4.2.9
+const (
+	pskModePlain uint8 = 0
+	pskModeDHE   uint8 = 1
+)
+
+// TLS 1.3 PSK Identity. Can be a Session Ticket, or a reference to a saved
+// session. See https://tools.ietf.org/html/rfc8446#section-4.2.11
+type pskIdentity struct {
+	label               []byte
+	obfuscatedTicketAge uint32
+}
+
 // TLS Elliptic Curve Point Formats
 // https://www.iana.org/assignments/tls-parameters/tls-parameters.xml#tls-parameters-9
 const (
@@ -131,6 +162,12 @@ const (
 const (
 	signatureRSA   uint8 = 1
 	signatureECDSA uint8 = 3
+
+	// signatureRSAPSS and signatureEd25519 have no legacy TLS 1.2 code
+	// point; they are only used internally to tell the signature schemes
+	// apart by signature method.
+	signatureRSAPSS  uint8 = 225
+	signatureEd25519 uint8 = 226
 )
 
 // supportedSignatureAlgorithms contains the signature and hash algorithms that
@@ -148,6 +185,43 @@ var supportedSignatureAlgorithms = []SignatureScheme{
 	ECDSAWithSHA1,
 }
 
+// supportedSignatureAlgorithmsTLS13 contains the signature and hash algorithms
+// advertised in a ClientHello that offers TLS 1.3, and accepted in a TLS 1.3
+// CertificateVerify message. It is a superset of supportedSignatureAlgorithms,
+// since the peer may still negotiate TLS 1.2, but the PKCS #1 v1.5 and SHA-1
+// schemes are rejected in TLS 1.3 handshake signatures.
+var supportedSignatureAlgorithmsTLS13 = []SignatureScheme{
+	PSSWithSHA256,
+	ECDSAWithP256AndSHA256,
+	PSSWithSHA384,
+	ECDSAWithP384AndSHA384,
+	PSSWithSHA512,
+	ECDSAWithP521AndSHA512,
+	Ed25519,
+	PKCS1WithSHA256,
+	PKCS1WithSHA384,
+	PKCS1WithSHA512,
+	PKCS1WithSHA1,
+	ECDSAWithSHA1,
+}
+
+// helloRetryRequestRandom is set as the Random value of a ServerHello
+// to signal that the message is actually a HelloRetryRequest.
+var helloRetryRequestRandom = []byte{ // See RFC 8446, Section 4.1.3.
+	0xCF, 0x21, 0xAD, 0x74, 0xE5, 0x9A, 0x61, 0x11,
+	0xBE, 0x1D, 0x8C, 0x02, 0x1E, 0x65, 0xB8, 0x91,
+	0xC2, 0xA2, 0x11, 0x16, 0x7A, 0xBB, 0x8C, 0x5E,
+	0x07, 0x9E, 0x09, 0xE2, 0xC8, 0xA8, 0x33, 0x9C,
+}
+
+const (
+	// downgradeCanaryTLS12 or downgradeCanaryTLS11 is embedded in the server
+	// random as a downgrade protection if the server would be capable of
+	// negotiating a higher version. See RFC 8446, Section 4.1.3.
+	downgradeCanaryTLS12 = "DOWNGRD\x01"
+	downgradeCanaryTLS11 = "DOWNGRD\x00"
+)
+
 // ConnectionState records basic TLS details about the connection.
 type ConnectionState struct {
 	Version                     uint16                // TLS version used by the connection (e.g. VersionTLS12)
@@ -167,7 +241,8 @@ type ConnectionState struct {
 	// because resumption does not include enough context (see
 	// https://mitls.org/pages/attacks/3SHAKE#channelbindings). This will
 	// change in future versions of Go once the TLS master-secret fix has
-	// been standardized and implemented.
+	// been standardized and implemented. It is also nil for TLS 1.3
+	// connections, which do not define the tls-unique binding.
 	TLSUnique []byte
 }
 
@@ -189,9 +264,14 @@ type ClientSessionState struct {
 	sessionTicket      []uint8               // Encrypted ticket used for session resumption with server
 	vers               uint16                // SSL/TLS version negotiated for the session
 	cipherSuite        uint16                // Ciphersuite negotiated for the session
-	masterSecret       []byte                // MasterSecret generated by client on a full handshake
+	masterSecret       []byte                // MasterSecret generated by client on a full handshake, or the PSK in TLS 1.3
 	serverCertificates []*x509.Certificate   // Certificate chain presented by the server
 	verifiedChains     [][]*x509.Certificate // Certificate chains we built for verification
+	receivedAt         time.Time             // When the session ticket was received from the server
+
+	// TLS 1.3 fields.
+	ageAdd uint32    // Random obfuscation factor for sending the ticket age
+	useBy  time.Time // Expiration of the ticket lifetime as set by the server
 }
 
 // ClientSessionCache is a cache of ClientSessionState objects that can be used
@@ -226,6 +306,9 @@ const (
 	ECDSAWithP384AndSHA384 SignatureScheme = 0x0503
 	ECDSAWithP521AndSHA512 SignatureScheme = 0x0603
 
+	// EdDSA algorithms.
+	Ed25519 SignatureScheme = 0x0807
+
 	// Legacy signature and hash algorithms for TLS 1.2.
 	ECDSAWithSHA1 SignatureScheme = 0x0203
 )
@@ -442,8 +525,10 @@ type Config struct {
 	// This should be used only for testing.
 	InsecureSkipVerify bool
 
-	// CipherSuites is a list of supported cipher suites. If CipherSuites
-	// is nil, TLS uses a list of suites supported by the implementation.
+	// CipherSuites is a list of supported cipher suites for TLS versions
+	// up to TLS 1.2. If CipherSuites is nil, TLS uses a list of suites
+	// supported by the implementation. The TLS 1.3 cipher suites are not
+	// configurable.
 	CipherSuites []uint16
 
 	// PreferServerCipherSuites controls whether the server selects the
@@ -475,8 +560,7 @@ type Config struct {
 	MinVersion uint16
 
 	// MaxVersion contains the maximum SSL/TLS version that is acceptable.
-	// If zero, then the maximum version supported by this package is used,
-	// which is currently TLS 1.2.
+	// If zero, then TLS 1.3 is taken as the maximum.
 	MaxVersion uint16
 
 	// CurvePreferences contains the elliptic curves that will be used in
@@ -689,19 +773,33 @@ func (c *Config) curvePreferences() []CurveID {
 	return c.CurvePreferences
 }
 
-// mutualVersion returns the protocol version to use given the advertised
-// version of the peer.
-func (c *Config) mutualVersion(vers uint16) (uint16, bool) {
+// supportedVersions returns the protocol versions enabled by the Config,
+// highest first. Versions above maxVersion are ignored.
+func (c *Config) supportedVersions() []uint16 {
 	minVersion := c.minVersion()
 	maxVersion := c.maxVersion()
+	if maxVersion > VersionTLS13 {
+		maxVersion = VersionTLS13
+	}
 
-	if vers < minVersion {
-		return 0, false
+	var versions []uint16
+	for v := maxVersion; v >= minVersion && v >= VersionSSL30; v-- {
+		versions = append(versions, v)
 	}
-	if vers > maxVersion {
-		vers = maxVersion
+	return versions
+}
+
+// mutualVersion returns the protocol version to use given the versions
+// advertised by the peer.
+func (c *Config) mutualVersion(peerVersions []uint16) (uint16, bool) {
+	for _, v := range c.supportedVersions() {
+		for _, pv := range peerVersions {
+			if v == pv {
+				return v, true
+			}
+		}
 	}
-	return vers, true
+	return 0, false
 }
 
 // getCertificate returns the best certificate for the given ClientHelloInfo,
@@ -768,14 +866,23 @@ func (c *Config) BuildNameToCertificate() {
 	}
 }
 
-// writeKeyLog logs client random and master secret if logging was enabled by
-// setting c.KeyLogWriter.
-func (c *Config) writeKeyLog(clientRandom, masterSecret []byte) error {
+const (
+	keyLogLabelTLS12           = "CLIENT_RANDOM"
+	keyLogLabelClientHandshake = "CLIENT_HANDSHAKE_TRAFFIC_SECRET"
+	keyLogLabelServerHandshake = "SERVER_HANDSHAKE_TRAFFIC_SECRET"
+	keyLogLabelClientTraffic   = "CLIENT_TRAFFIC_SECRET_0"
+	keyLogLabelServerTraffic   = "SERVER_TRAFFIC_SECRET_0"
+)
+
+// writeKeyLog logs a secret under the given NSS key log label, keyed by the
+// client random, if logging was enabled by setting c.KeyLogWriter. TLS 1.2
+// logs the master secret, while TLS 1.3 logs each traffic secret.
+func (c *Config) writeKeyLog(label string, clientRandom, secret []byte) error {
 	if c.KeyLogWriter == nil {
 		return nil
 	}
 
-	logLine := []byte(fmt.Sprintf("CLIENT_RANDOM %x %x\n", clientRandom, masterSecret))
+	logLine := []byte(fmt.Sprintf("%s %x %x\n", label, clientRandom, secret))
 
 	writerMutex.Lock()
 	_, err := c.KeyLogWriter.Write(logLine)
@@ -793,9 +900,9 @@ type Certificate struct {
 	Certificate [][]byte
 	// PrivateKey contains the private key corresponding to the public key
 	// in Leaf. For a server, this must implement crypto.Signer and/or
-	// crypto.Decrypter, with an RSA or ECDSA PublicKey. For a client
-	// (performing client authentication), this must be a crypto.Signer
-	// with an RSA or ECDSA PublicKey.
+	// crypto.Decrypter, with an RSA, ECDSA or Ed25519 PublicKey. For a
+	// client (performing client authentication), this must be a
+	// crypto.Signer with an RSA, ECDSA or Ed25519 PublicKey.
 	PrivateKey crypto.PrivateKey
 	// OCSPStaple contains an optional OCSP response which will be served
 	// to clients that request it.
@@ -900,8 +1007,9 @@ func defaultConfig() *Config {
 }
 
 var (
-	once                   sync.Once
-	varDefaultCipherSuites []uint16
+	once                        sync.Once
+	varDefaultCipherSuites      []uint16
+	varDefaultCipherSuitesTLS13 []uint16
 )
 
 func defaultCipherSuites() []uint16 {
@@ -909,9 +1017,22 @@ func defaultCipherSuites() []uint16 {
 	return varDefaultCipherSuites
 }
 
+// defaultCipherSuitesTLS13 returns the TLS 1.3 cipher suites, in preference
+// order. They are not configurable through Config.CipherSuites.
+func defaultCipherSuitesTLS13() []uint16 {
+	once.Do(initDefaultCipherSuites)
+	return varDefaultCipherSuitesTLS13
+}
+
 func initDefaultCipherSuites() {
 	var topCipherSuites []uint16
 	if cipherhw.AESGCMSupport() {
+		varDefaultCipherSuitesTLS13 = []uint16{
+			TLS_AES_128_GCM_SHA256,
+			TLS_CHACHA20_POLY1305_SHA256,
+			TLS_AES_256_GCM_SHA384,
+		}
+
 		// If AES-GCM hardware is provided then prioritise AES-GCM
 		// cipher suites.
 		topCipherSuites = []uint16{
@@ -925,6 +1046,11 @@ func initDefaultCipherSuites() {
 	} else {
 		// Without AES-GCM hardware, we put the ChaCha20-Poly1305
 		// cipher suites first.
+		varDefaultCipherSuitesTLS13 = []uint16{
+			TLS_CHACHA20_POLY1305_SHA256,
+			TLS_AES_128_GCM_SHA256,
+			TLS_AES_256_GCM_SHA384,
+		}
 		topCipherSuites = []uint16{
 			TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
 			TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
@@ -973,6 +1099,10 @@ func signatureFromSignatureScheme(signatureAlgorithm SignatureScheme) uint8 {
 		return signatureRSA
 	case ECDSAWithSHA1, ECDSAWithP256AndSHA256, ECDSAWithP384AndSHA384, ECDSAWithP521AndSHA512:
 		return signatureECDSA
+	case PSSWithSHA256, PSSWithSHA384, PSSWithSHA512:
+		return signatureRSAPSS
+	case Ed25519:
+		return signatureEd25519
 	default:
 		return 0
 	}
@@ -81,6 +81,10 @@ type Conn struct {
 	clientProtocol         string
 	clientProtocolFallback bool
 
+	// resumptionSecret is the TLS 1.3 resumption_master_secret, which the
+	// client keeps to turn NewSessionTicket messages into sessions.
+	resumptionSecret []byte
+
 	// input/output
 	in, out   halfConn     // in.Mutex < out.Mutex
 	rawInput  *block       // raw input, right off the wire
@@ -156,6 +160,8 @@ type halfConn struct {
 	nextCipher interface{} // next encryption state
 	nextMac    macFunction // next MAC algorithm
 
+	trafficSecret []byte // current TLS 1.3 traffic secret
+
 	// used to save allocating a new buffer for each MAC.
 	inDigestBuf, outDigestBuf []byte
 }
@@ -189,6 +195,20 @@ func (hc *halfConn) changeCipherSpec() error {
 	return nil
 }
 
+// setTrafficSecret sets the current TLS 1.3 traffic secret and switches to
+// the record protection keys derived from it. Unlike changeCipherSpec, the new
+// keys take effect immediately.
+func (hc *halfConn) setTrafficSecret(suite *cipherSuiteTLS13, secret []byte) {
+	hc.version = VersionTLS13
+	hc.trafficSecret = secret
+	key, iv := suite.trafficKey(secret)
+	hc.cipher = suite.aead(key, iv)
+	hc.mac = nil
+	for i := range hc.seq {
+		hc.seq[i] = 0
+	}
+}
+
 // incSeq increments the sequence number.
 func (hc *halfConn) incSeq() {
 	for i := 7; i >= 0; i-- {
@@ -302,16 +322,41 @@ func (hc *halfConn) decrypt(b *block) (ok bool, prefixLen int, alertValue alert)
 				nonce = hc.seq[:]
 			}
 
-			copy(hc.additionalData[:], hc.seq[:])
-			copy(hc.additionalData[8:], b.data[:3])
-			n := len(payload) - c.Overhead()
-			hc.additionalData[11] = byte(n >> 8)
-			hc.additionalData[12] = byte(n)
+			var additionalData []byte
+			if hc.version == VersionTLS13 {
+				// In TLS 1.3 the record header is authenticated as is,
+				// and the outer type is always application_data.
+				if recordType(b.data[0]) != recordTypeApplicationData {
+					return false, 0, alertUnexpectedMessage
+				}
+				additionalData = b.data[:recordHeaderLen]
+			} else {
+				copy(hc.additionalData[:], hc.seq[:])
+				copy(hc.additionalData[8:], b.data[:3])
+				n := len(payload) - c.Overhead()
+				hc.additionalData[11] = byte(n >> 8)
+				hc.additionalData[12] = byte(n)
+				additionalData = hc.additionalData[:]
+			}
 			var err error
-			payload, err = c.Open(payload[:0], nonce, payload, hc.additionalData[:])
+			payload, err = c.Open(payload[:0], nonce, payload, additionalData)
 			if err != nil {
 				return false, 0, alertBadRecordMAC
 			}
+			if hc.version == VersionTLS13 {
+				// Strip the zero padding and recover the real content
+				// type, which replaces the outer one in the header.
+				// See RFC 8446, Section 5.4.
+				i := len(payload) - 1
+				for i >= 0 && payload[i] == 0 {
+					i--
+				}
+				if i < 0 {
+					return false, 0, alertUnexpectedMessage
+				}
+				b.data[0] = payload[i]
+				payload = payload[:i]
+			}
 			b.resize(recordHeaderLen + explicitIVLen + len(payload))
 		case cbcMode:
 			blockSize := c.BlockSize()
@@ -409,6 +454,14 @@ func (hc *halfConn) encrypt(b *block, explicitIVLen int) (bool, alert) {
 			c.XORKeyStream(payload, payload)
 		case aead:
 			payloadLen := len(b.data) - recordHeaderLen - explicitIVLen
+			if hc.version == VersionTLS13 {
+				// Move the real content type inside the encrypted
+				// payload. See RFC 8446, Section 5.2.
+				b.resize(len(b.data) + 1)
+				b.data[len(b.data)-1] = b.data[0]
+				b.data[0] = byte(recordTypeApplicationData)
+				payloadLen++
+			}
 			b.resize(len(b.data) + c.Overhead())
 			nonce := b.data[recordHeaderLen : recordHeaderLen+explicitIVLen]
 			if len(nonce) == 0 {
@@ -417,12 +470,22 @@ func (hc *halfConn) encrypt(b *block, explicitIVLen int) (bool, alert) {
 			payload := b.data[recordHeaderLen+explicitIVLen:]
 			payload = payload[:payloadLen]
 
-			copy(hc.additionalData[:], hc.seq[:])
-			copy(hc.additionalData[8:], b.data[:3])
-			hc.additionalData[11] = byte(payloadLen >> 8)
-			hc.additionalData[12] = byte(payloadLen)
+			var additionalData []byte
+			if hc.version == VersionTLS13 {
+				n := len(b.data) - recordHeaderLen
+				copy(hc.additionalData[:], b.data[:3])
+				hc.additionalData[3] = byte(n >> 8)
+				hc.additionalData[4] = byte(n)
+				additionalData = hc.additionalData[:recordHeaderLen]
+			} else {
+				copy(hc.additionalData[:], hc.seq[:])
+				copy(hc.additionalData[8:], b.data[:3])
+				hc.additionalData[11] = byte(payloadLen >> 8)
+				hc.additionalData[12] = byte(payloadLen)
+				additionalData = hc.additionalData[:]
+			}
 
-			c.Seal(payload[:0], nonce, payload, hc.additionalData[:])
+			c.Seal(payload[:0], nonce, payload, additionalData)
 		case cbcMode:
 			blockSize := c.BlockSize()
 			if explicitIVLen > 0 {
@@ -574,7 +637,9 @@ func (c *Conn) readRecord(want recordType) error {
 		c.sendAlert(alertInternalError)
 		return c.in.setErrorLocked(errors.New("tls: unknown record type requested"))
 	case recordTypeHandshake, recordTypeChangeCipherSpec:
-		if c.handshakeComplete {
+		// TLS 1.3 handshake messages may follow the handshake, and a
+		// partial one is completed by reading more handshake records.
+		if c.handshakeComplete && !(want == recordTypeHandshake && c.vers == VersionTLS13) {
 			c.sendAlert(alertInternalError)
 			return c.in.setErrorLocked(errors.New("tls: handshake or ChangeCipherSpec requested while not in handshake"))
 		}
@@ -617,7 +682,9 @@ Again:
 
 	vers := uint16(b.data[1])<<8 | uint16(b.data[2])
 	n := int(b.data[3])<<8 | int(b.data[4])
-	if c.haveVers && vers != c.vers {
+	// The TLS 1.3 record version is frozen at TLS 1.2 (or TLS 1.0 for an
+	// initial ClientHello) and must be ignored. See RFC 8446, Section 5.1.
+	if c.haveVers && c.vers != VersionTLS13 && vers != c.vers {
 		c.sendAlert(alertProtocolVersion)
 		msg := fmt.Sprintf("received record with version %x when expecting version %x", vers, c.vers)
 		return c.in.setErrorLocked(c.newRecordHeaderError(msg))
@@ -649,11 +716,34 @@ Again:
 
 	// Process message.
 	b, c.rawInput = c.in.splitBlock(b, recordHeaderLen+n)
+
+	// TLS 1.3 peers may send unencrypted change_cipher_spec records during
+	// the handshake for middlebox compatibility, which must be dropped.
+	// See RFC 8446, Section 5.
+	if c.vers == VersionTLS13 && typ == recordTypeChangeCipherSpec && !c.handshakeComplete {
+		data := b.data[recordHeaderLen:]
+		isValid := len(data) == 1 && data[0] == 1
+		c.in.freeBlock(b)
+		if !isValid {
+			return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+		}
+
+		c.warnCount++
+		if c.warnCount > maxWarnAlertCount {
+			c.sendAlert(alertUnexpectedMessage)
+			return c.in.setErrorLocked(errors.New("tls: too many ignored records"))
+		}
+
+		goto Again
+	}
+
 	ok, off, alertValue := c.in.decrypt(b)
 	if !ok {
 		c.in.freeBlock(b)
 		return c.in.setErrorLocked(c.sendAlert(alertValue))
 	}
+	// Decryption reveals the real content type of TLS 1.3 records.
+	typ = recordType(b.data[0])
 	b.off = off
 	data := b.data[b.off:]
 	if len(data) > maxPlaintext {
@@ -680,6 +770,12 @@ Again:
 			c.in.setErrorLocked(io.EOF)
 			break
 		}
+		if c.vers == VersionTLS13 {
+			// TLS 1.3 alerts other than close_notify are always
+			// fatal. See RFC 8446, Section 6.
+			c.in.setErrorLocked(&net.OpError{Op: "remote error", Err: alert(data[1])})
+			break
+		}
 		switch data[0] {
 		case alertLevelWarning:
 			// drop on the floor
@@ -723,7 +819,7 @@ Again:
 
 	case recordTypeHandshake:
 		// TODO(rsc): Should at least pick off connection close.
-		if typ != want && !(c.isClient && c.config.Renegotiation != RenegotiateNever) {
+		if typ != want && c.vers != VersionTLS13 && !(c.isClient && c.config.Renegotiation != RenegotiateNever) {
 			return c.in.setErrorLocked(c.sendAlert(alertNoRenegotiation))
 		}
 		c.hand.Write(data)
@@ -817,6 +913,9 @@ func (c *Conn) maxPayloadSizeForWrite(typ recordType, explicitIVLen int) int {
 			payloadBytes -= macSize
 		case cipher.AEAD:
 			payloadBytes -= ciph.Overhead()
+			if c.out.version == VersionTLS13 {
+				payloadBytes-- // encrypted content type
+			}
 		case cbcMode:
 			blockSize := ciph.BlockSize()
 			// The payload must fit in a multiple of blockSize, with
@@ -911,6 +1010,10 @@ func (c *Conn) writeRecordLocked(typ recordType, data []byte) (int, error) {
 			// Some TLS servers fail if the record version is
 			// greater than TLS 1.0 for the initial ClientHello.
 			vers = VersionTLS10
+		} else if vers == VersionTLS13 {
+			// TLS 1.3 froze the record layer version to TLS 1.2.
+			// See RFC 8446, Section 5.1.
+			vers = VersionTLS12
 		}
 		b.data[1] = byte(vers >> 8)
 		b.data[2] = byte(vers)
@@ -935,7 +1038,9 @@ func (c *Conn) writeRecordLocked(typ recordType, data []byte) (int, error) {
 		data = data[m:]
 	}
 
-	if typ == recordTypeChangeCipherSpec {
+	// The change_cipher_spec records of TLS 1.3 are only sent for
+	// compatibility and don't change the record protection.
+	if typ == recordTypeChangeCipherSpec && c.vers != VersionTLS13 {
 		if err := c.out.changeCipherSpec(); err != nil {
 			return n, c.sendAlertLocked(err.(alert))
 		}
@@ -991,12 +1096,26 @@ func (c *Conn) readHandshake() (interface{}, error) {
 	case typeServerHello:
 		m = new(serverHelloMsg)
 	case typeNewSessionTicket:
-		m = new(newSessionTicketMsg)
+		if c.vers == VersionTLS13 {
+			m = new(newSessionTicketMsgTLS13)
+		} else {
+			m = new(newSessionTicketMsg)
+		}
+	case typeEncryptedExtensions:
+		m = new(encryptedExtensionsMsg)
 	case typeCertificate:
-		m = new(certificateMsg)
+		if c.vers == VersionTLS13 {
+			m = new(certificateMsgTLS13)
+		} else {
+			m = new(certificateMsg)
+		}
 	case typeCertificateRequest:
-		m = &certificateRequestMsg{
-			hasSignatureAndHash: c.vers >= VersionTLS12,
+		if c.vers == VersionTLS13 {
+			m = new(certificateRequestMsgTLS13)
+		} else {
+			m = &certificateRequestMsg{
+				hasSignatureAndHash: c.vers >= VersionTLS12,
+			}
 		}
 	case typeCertificateStatus:
 		m = new(certificateStatusMsg)
@@ -1014,6 +1133,8 @@ func (c *Conn) readHandshake() (interface{}, error) {
 		m = new(nextProtoMsg)
 	case typeFinished:
 		m = new(finishedMsg)
+	case typeKeyUpdate:
+		m = new(keyUpdateMsg)
 	default:
 		return nil, c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
 	}
@@ -1133,6 +1254,62 @@ func (c *Conn) handleRenegotiation() error {
 	return c.handshakeErr
 }
 
+// handlePostHandshakeMessage processes a handshake message arrived after the
+// handshake is complete. Up to TLS 1.2, it indicates the start of a
+// renegotiation.
+// c.in.Mutex <= L
+func (c *Conn) handlePostHandshakeMessage() error {
+	if c.vers != VersionTLS13 {
+		return c.handleRenegotiation()
+	}
+
+	msg, err := c.readHandshake()
+	if err != nil {
+		return err
+	}
+
+	switch msg := msg.(type) {
+	case *newSessionTicketMsgTLS13:
+		return c.handleNewSessionTicket(msg)
+	case *keyUpdateMsg:
+		return c.handleKeyUpdate(msg)
+	default:
+		c.sendAlert(alertUnexpectedMessage)
+		return fmt.Errorf("tls: received unexpected handshake message of type %T", msg)
+	}
+}
+
+// handleKeyUpdate processes a TLS 1.3 KeyUpdate message, moving the read
+// direction to the next traffic secret, and replying with a KeyUpdate of our
+// own if the peer requested it. See RFC 8446, Section 4.6.3.
+// c.in.Mutex <= L
+func (c *Conn) handleKeyUpdate(keyUpdate *keyUpdateMsg) error {
+	cipherSuite := cipherSuiteTLS13ByID(c.cipherSuite)
+	if cipherSuite == nil {
+		return c.in.setErrorLocked(c.sendAlert(alertInternalError))
+	}
+
+	newSecret := cipherSuite.nextTrafficSecret(c.in.trafficSecret)
+	c.in.setTrafficSecret(cipherSuite, newSecret)
+
+	if keyUpdate.updateRequested {
+		c.out.Lock()
+		defer c.out.Unlock()
+
+		msg := &keyUpdateMsg{}
+		if _, err := c.writeRecordLocked(recordTypeHandshake, msg.marshal()); err != nil {
+			// Surface the error at the next write.
+			c.out.setErrorLocked(err)
+			return nil
+		}
+
+		newSecret := cipherSuite.nextTrafficSecret(c.out.trafficSecret)
+		c.out.setTrafficSecret(cipherSuite, newSecret)
+	}
+
+	return nil
+}
+
 // Read can be made to time out and return a net.Error with Timeout() == true
 // after a fixed time limit; see SetDeadline and SetReadDeadline.
 func (c *Conn) Read(b []byte) (n int, err error) {
@@ -1159,8 +1336,9 @@ func (c *Conn) Read(b []byte) (n int, err error) {
 			}
 			if c.hand.Len() > 0 {
 				// We received handshake bytes, indicating the
-				// start of a renegotiation.
-				if err := c.handleRenegotiation(); err != nil {
+				// start of a renegotiation, or a TLS 1.3
+				// post-handshake message.
+				if err := c.handlePostHandshakeMessage(); err != nil {
 					return 0, err
 				}
 			}
@@ -1369,7 +1547,7 @@ func (c *Conn) ConnectionState() ConnectionState {
 		state.VerifiedChains = c.verifiedChains
 		state.SignedCertificateTimestamps = c.scts
 		state.OCSPResponse = c.ocspResponse
-		if !c.didResume {
+		if !c.didResume && c.vers != VersionTLS13 {
 			if c.clientFinishedIsFirst {
 				state.TLSUnique = c.clientFinished[:]
 			} else {
@@ -111,5 +111,8 @@ func ExampleConfig_keyLogWriter() {
 	// preferences.
 
 	// Output:
-	// CLIENT_RANDOM 0000000000000000000000000000000000000000000000000000000000000000 baca0df460a688e44ce018b025183cc2353ae01f89755ef766eedd3ecc302888ee3b3a22962e45f48c20df15a98c0e80
+	// CLIENT_HANDSHAKE_TRAFFIC_SECRET 0000000000000000000000000000000000000000000000000000000000000000 f44babd21d14d2e7cbab03eed1223bed46b93d8e0ca37bee977c66d75601e500
+	// SERVER_HANDSHAKE_TRAFFIC_SECRET 0000000000000000000000000000000000000000000000000000000000000000 dbd4eae3005e3a79f036287937ec1e4cad2b7a3b8c0a0d96094675e6f0183024
+	// CLIENT_TRAFFIC_SECRET_0 0000000000000000000000000000000000000000000000000000000000000000 cf71a4be68e44fa7c32be2f4765a5a046121952778587dceda47eba34bf55329
+	// SERVER_TRAFFIC_SECRET_0 0000000000000000000000000000000000000000000000000000000000000000 55fac64aea0d5c9aef45459676ea5f0f50f0a273c034065798f3ba757a2f4071
 }
@@ -463,6 +463,25 @@ func runClientTestTLS12(t *testing.T, template *clientTest) {
 	runClientTestForVersion(t, template, "TLSv12-", "-tls1_2")
 }
 
+// runClientTestTLS13 is like the other runClientTestTLS functions, but
+// enables TLS 1.3 in the client, which testConfig leaves disabled to
+// match the older recordings.
+func runClientTestTLS13(t *testing.T, template *clientTest) {
+	if *update {
+		checkOpenSSLVersion(t)
+		if !opensslSupportsTLS13 {
+			t.Fatal("updating the TLS 1.3 test data requires OpenSSL 1.1.1 or later")
+		}
+	}
+	test := *template
+	if test.config == nil {
+		test.config = testConfig
+	}
+	test.config = test.config.Clone()
+	test.config.MaxVersion = VersionTLS13
+	runClientTestForVersion(t, &test, "TLSv13-", "-tls1_3")
+}
+
 func TestHandshakeClientRSARC4(t *testing.T) {
 	test := &clientTest{
 		name:    "RSA-RC4",
@@ -660,6 +679,72 @@ func TestHandshakeClientCertECDSA(t *testing.T) {
 	runClientTestTLS12(t, test)
 }
 
+func TestHandshakeClientTLS13(t *testing.T) {
+	tests := []*clientTest{
+		{
+			name:    "AES128-SHA256",
+			command: []string{"openssl", "s_server", "-ciphersuites", "TLS_AES_128_GCM_SHA256"},
+		},
+		{
+			name:    "AES256-SHA384",
+			command: []string{"openssl", "s_server", "-ciphersuites", "TLS_AES_256_GCM_SHA384"},
+		},
+		{
+			name:    "CHACHA20-SHA256",
+			command: []string{"openssl", "s_server", "-ciphersuites", "TLS_CHACHA20_POLY1305_SHA256"},
+		},
+		{
+			name:    "ECDSA",
+			command: []string{"openssl", "s_server"},
+			cert:    testECDSACertificate,
+			key:     testECDSAPrivateKey,
+		},
+		{
+			// The client sends an X25519 key share, and the server
+			// asks for a P-256 one instead.
+			name:    "HelloRetryRequest",
+			command: []string{"openssl", "s_server", "-groups", "P-256"},
+			validate: func(state ConnectionState) error {
+				if state.CipherSuite != TLS_AES_128_GCM_SHA256 {
+					return fmt.Errorf("got cipher suite %x", state.CipherSuite)
+				}
+				return nil
+			},
+		},
+	}
+	for _, test := range tests {
+		runClientTestTLS13(t, test)
+	}
+}
+
+func TestHandshakeClientCertTLS13(t *testing.T) {
+	rsaConfig := testConfig.Clone()
+	cert, _ := X509KeyPair([]byte(clientCertificatePEM), []byte(clientKeyPEM))
+	rsaConfig.Certificates = []Certificate{cert}
+
+	ecdsaConfig := testConfig.Clone()
+	cert, _ = X509KeyPair([]byte(clientECDSACertificatePEM), []byte(clientECDSAKeyPEM))
+	ecdsaConfig.Certificates = []Certificate{cert}
+
+	runClientTestTLS13(t, &clientTest{
+		name:    "ClientCert-RSA-RSAPSS",
+		command: []string{"openssl", "s_server", "-verify", "1"},
+		config:  rsaConfig,
+	})
+	runClientTestTLS13(t, &clientTest{
+		name:    "ClientCert-ECDSA-RSAPSS",
+		command: []string{"openssl", "s_server", "-verify", "1"},
+		config:  ecdsaConfig,
+	})
+	runClientTestTLS13(t, &clientTest{
+		name:    "ClientCert-RSA-ECDSA",
+		command: []string{"openssl", "s_server", "-verify", "1"},
+		config:  rsaConfig,
+		cert:    testECDSACertificate,
+		key:     testECDSAPrivateKey,
+	})
+}
+
 func TestClientResumption(t *testing.T) {
 	serverConfig := &Config{
 		CipherSuites: []uint16{TLS_RSA_WITH_RC4_128_SHA, TLS_ECDHE_RSA_WITH_RC4_128_SHA},
@@ -745,6 +830,58 @@ func TestClientResumption(t *testing.T) {
 	testResumeState("WithoutSessionCache", false)
 }
 
+func TestClientResumptionTLS13(t *testing.T) {
+	serverConfig := &Config{
+		Certificates: testConfig.Certificates,
+		MaxVersion:   VersionTLS13,
+	}
+	clientConfig := &Config{
+		ClientSessionCache: NewLRUClientSessionCache(32),
+		InsecureSkipVerify: true,
+		MaxVersion:         VersionTLS13,
+		// localPipe listens on a new port every time, so key the
+		// session cache by name rather than by address.
+		ServerName: "example.golang",
+	}
+
+	testResumeState := func(test string, didResume bool, wantVersion uint16) {
+		_, hs, err := testHandshake(clientConfig, serverConfig)
+		if err != nil {
+			t.Fatalf("%s: handshake failed: %s", test, err)
+		}
+		if hs.Version != wantVersion {
+			t.Fatalf("%s: got version %x, expected: %x", test, hs.Version, wantVersion)
+		}
+		if hs.DidResume != didResume {
+			t.Fatalf("%s resumed: %v, expected: %v", test, hs.DidResume, didResume)
+		}
+		if didResume && hs.PeerCertificates == nil {
+			t.Fatalf("%s: expected non-nil certificates after resumption", test)
+		}
+	}
+
+	testResumeState("Handshake", false, VersionTLS13)
+	testResumeState("Resume", true, VersionTLS13)
+
+	var key [32]byte
+	if _, err := io.ReadFull(serverConfig.rand(), key[:]); err != nil {
+		t.Fatalf("Failed to read new SessionTicketKey: %s", err)
+	}
+	serverConfig.SetSessionTicketKeys([][32]byte{key})
+	testResumeState("InvalidSessionTicketKey", false, VersionTLS13)
+	testResumeState("ResumeAfterInvalidSessionTicketKey", true, VersionTLS13)
+
+	// A TLS 1.3 session must not be resumed at TLS 1.2, and vice versa.
+	serverConfig.MaxVersion = VersionTLS12
+	testResumeState("DowngradedServer", false, VersionTLS12)
+	serverConfig.MaxVersion = VersionTLS13
+	testResumeState("UpgradedServer", false, VersionTLS13)
+	testResumeState("ResumeAfterUpgrade", true, VersionTLS13)
+
+	serverConfig.SessionTicketsDisabled = true
+	testResumeState("SessionTicketsDisabled", false, VersionTLS13)
+}
+
 func TestLRUClientSessionCache(t *testing.T) {
 	// Initialize cache of capacity 4.
 	cache := NewLRUClientSessionCache(4)
@@ -28,6 +28,14 @@ type clientHelloMsg struct {
 	secureRenegotiation          []byte
 	secureRenegotiationSupported bool
 	alpnProtocols                []string
+
+	// TLS 1.3 extensions. See RFC 8446, Section 4.2.
+	supportedVersions []uint16
+	cookie            []byte
+	keyShares         []keyShare
+	pskModes          []uint8
+	pskIdentities     []pskIdentity
+	pskBinders        [][]byte
 }
 
 func (m *clientHelloMsg) equal(i interface{}) bool {
@@ -53,7 +61,13 @@ func (m *clientHelloMsg) equal(i interface{}) bool {
 		eqSignatureAlgorithms(m.supportedSignatureAlgorithms, m1.supportedSignatureAlgorithms) &&
 		m.secureRenegotiationSupported == m1.secureRenegotiationSupported &&
 		bytes.Equal(m.secureRenegotiation, m1.secureRenegotiation) &&
-		eqStrings(m.alpnProtocols, m1.alpnProtocols)
+		eqStrings(m.alpnProtocols, m1.alpnProtocols) &&
+		eqUint16s(m.supportedVersions, m1.supportedVersions) &&
+		bytes.Equal(m.cookie, m1.cookie) &&
+		eqKeyShares(m.keyShares, m1.keyShares) &&
+		bytes.Equal(m.pskModes, m1.pskModes) &&
+		eqPSKIdentities(m.pskIdentities, m1.pskIdentities) &&
+		eqByteSlices(m.pskBinders, m1.pskBinders)
 }
 
 func (m *clientHelloMsg) marshal() []byte {
@@ -109,6 +123,33 @@ func (m *clientHelloMsg) marshal() []byte {
 	if m.scts {
 		numExtensions++
 	}
+	if len(m.supportedVersions) > 0 {
+		extensionsLength += 1 + 2*len(m.supportedVersions)
+		numExtensions++
+	}
+	if len(m.cookie) > 0 {
+		extensionsLength += 2 + len(m.cookie)
+		numExtensions++
+	}
+	if len(m.keyShares) > 0 {
+		extensionsLength += 2
+		for _, ks := range m.keyShares {
+			extensionsLength += 4 + len(ks.data)
+		}
+		numExtensions++
+	}
+	if len(m.pskModes) > 0 {
+		extensionsLength += 1 + len(m.pskModes)
+		numExtensions++
+	}
+	if len(m.pskIdentities) > 0 {
+		extensionsLength += 2
+		for _, psk := range m.pskIdentities {
+			extensionsLength += 2 + len(psk.label) + 4
+		}
+		extensionsLength += m.bindersLen()
+		numExtensions++
+	}
 	if numExtensions > 0 {
 		extensionsLength += 4 * numExtensions
 		length += 2 + extensionsLength
@@ -291,12 +332,154 @@ func (m *clientHelloMsg) marshal() []byte {
 		// zero uint16 for the zero-length extension_data
 		z = z[4:]
 	}
+	if len(m.supportedVersions) > 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.1
+		z[0] = byte(extensionSupportedVersions >> 8)
+		z[1] = byte(extensionSupportedVersions)
+		l := 1 + 2*len(m.supportedVersions)
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		z[4] = byte(l - 1)
+		z = z[5:]
+		for _, vers := range m.supportedVersions {
+			z[0] = byte(vers >> 8)
+			z[1] = byte(vers)
+			z = z[2:]
+		}
+	}
+	if len(m.cookie) > 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.2
+		z[0] = byte(extensionCookie >> 8)
+		z[1] = byte(extensionCookie)
+		l := 2 + len(m.cookie)
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		z[4] = byte(len(m.cookie) >> 8)
+		z[5] = byte(len(m.cookie))
+		copy(z[6:], m.cookie)
+		z = z[l+4:]
+	}
+	if len(m.keyShares) > 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.8
+		z[0] = byte(extensionKeyShare >> 8)
+		z[1] = byte(extensionKeyShare)
+		lengths := z[2:]
+		z = z[6:]
+
+		sharesLength := 0
+		for _, ks := range m.keyShares {
+			z[0] = byte(ks.group >> 8)
+			z[1] = byte(ks.group)
+			z[2] = byte(len(ks.data) >> 8)
+			z[3] = byte(len(ks.data))
+			copy(z[4:], ks.data)
+			z = z[4+len(ks.data):]
+			sharesLength += 4 + len(ks.data)
+		}
+
+		lengths[2] = byte(sharesLength >> 8)
+		lengths[3] = byte(sharesLength)
+		sharesLength += 2
+		lengths[0] = byte(sharesLength >> 8)
+		lengths[1] = byte(sharesLength)
+	}
+	if len(m.pskModes) > 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.9
+		z[0] = byte(extensionPSKModes >> 8)
+		z[1] = byte(extensionPSKModes)
+		l := 1 + len(m.pskModes)
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		z[4] = byte(len(m.pskModes))
+		copy(z[5:], m.pskModes)
+		z = z[4+l:]
+	}
+	if len(m.pskIdentities) > 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.11
+		// The pre_shared_key extension must be the last one.
+		z[0] = byte(extensionPreSharedKey >> 8)
+		z[1] = byte(extensionPreSharedKey)
+		lengths := z[2:]
+		z = z[6:]
+
+		identitiesLength := 0
+		for _, psk := range m.pskIdentities {
+			z[0] = byte(len(psk.label) >> 8)
+			z[1] = byte(len(psk.label))
+			copy(z[2:], psk.label)
+			z = z[2+len(psk.label):]
+			z[0] = byte(psk.obfuscatedTicketAge >> 24)
+			z[1] = byte(psk.obfuscatedTicketAge >> 16)
+			z[2] = byte(psk.obfuscatedTicketAge >> 8)
+			z[3] = byte(psk.obfuscatedTicketAge)
+			z = z[4:]
+			identitiesLength += 2 + len(psk.label) + 4
+		}
+		m.marshalBinders(z)
+
+		lengths[2] = byte(identitiesLength >> 8)
+		lengths[3] = byte(identitiesLength)
+		l := 2 + identitiesLength + m.bindersLen()
+		lengths[0] = byte(l >> 8)
+		lengths[1] = byte(l)
+	}
 
 	m.raw = x
 
 	return x
 }
 
+// bindersLen returns the length of the PskBinderEntry list, including its
+// length prefix, which closes the pre_shared_key extension.
+func (m *clientHelloMsg) bindersLen() int {
+	l := 2
+	for _, binder := range m.pskBinders {
+		l += 1 + len(binder)
+	}
+	return l
+}
+
+// marshalBinders writes the PskBinderEntry list to z, which must be exactly
+// m.bindersLen() bytes long.
+func (m *clientHelloMsg) marshalBinders(z []byte) {
+	l := m.bindersLen() - 2
+	z[0] = byte(l >> 8)
+	z[1] = byte(l)
+	z = z[2:]
+	for _, binder := range m.pskBinders {
+		z[0] = byte(len(binder))
+		copy(z[1:], binder)
+		z = z[1+len(binder):]
+	}
+}
+
+// marshalWithoutBinders returns the ClientHello through the
+// PreSharedKeyExtension.identities field, which is the part covered by the
+// binders. See RFC 8446, Section 4.2.11.2. m.pskBinders must already hold
+// slices of the correct length.
+func (m *clientHelloMsg) marshalWithoutBinders() []byte {
+	fullMessage := m.marshal()
+	return fullMessage[:len(fullMessage)-m.bindersLen()]
+}
+
+// updateBinders replaces m.pskBinders, updating the cached marshaled
+// representation in place. The new binders must have the same lengths as
+// the current ones.
+func (m *clientHelloMsg) updateBinders(pskBinders [][]byte) {
+	if len(pskBinders) != len(m.pskBinders) {
+		panic("tls: internal error: pskBinders length mismatch")
+	}
+	for i := range m.pskBinders {
+		if len(pskBinders[i]) != len(m.pskBinders[i]) {
+			panic("tls: internal error: pskBinders length mismatch")
+		}
+	}
+	m.pskBinders = pskBinders
+	if m.raw != nil {
+		m.marshalBinders(m.raw[len(m.raw)-m.bindersLen():])
+	}
+}
+
 func (m *clientHelloMsg) unmarshal(data []byte) bool {
 	if len(data) < 42 {
 		return false
@@ -347,6 +530,12 @@ func (m *clientHelloMsg) unmarshal(data []byte) bool {
 	m.supportedSignatureAlgorithms = nil
 	m.alpnProtocols = nil
 	m.scts = false
+	m.supportedVersions = nil
+	m.cookie = nil
+	m.keyShares = nil
+	m.pskModes = nil
+	m.pskIdentities = nil
+	m.pskBinders = nil
 
 	if len(data) == 0 {
 		// ClientHello is optionally followed by extension data
@@ -496,6 +685,112 @@ func (m *clientHelloMsg) unmarshal(data []byte) bool {
 			if length != 0 {
 				return false
 			}
+		case extensionSupportedVersions:
+			// https://tools.ietf.org/html/rfc8446#section-4.2.1
+			if length < 1 {
+				return false
+			}
+			l := int(data[0])
+			if l%2 == 1 || l == 0 || length != l+1 {
+				return false
+			}
+			d := data[1:length]
+			for len(d) > 0 {
+				m.supportedVersions = append(m.supportedVersions, uint16(d[0])<<8|uint16(d[1]))
+				d = d[2:]
+			}
+		case extensionCookie:
+			// https://tools.ietf.org/html/rfc8446#section-4.2.2
+			if length < 2 {
+				return false
+			}
+			l := int(data[0])<<8 | int(data[1])
+			if l == 0 || length != l+2 {
+				return false
+			}
+			m.cookie = data[2:length]
+		case extensionKeyShare:
+			// https://tools.ietf.org/html/rfc8446#section-4.2.8
+			if length < 2 {
+				return false
+			}
+			l := int(data[0])<<8 | int(data[1])
+			if length != l+2 {
+				return false
+			}
+			d := data[2:length]
+			for len(d) > 0 {
+				if len(d) < 4 {
+					return false
+				}
+				var ks keyShare
+				ks.group = CurveID(d[0])<<8 | CurveID(d[1])
+				dataLen := int(d[2])<<8 | int(d[3])
+				d = d[4:]
+				if dataLen == 0 || len(d) < dataLen {
+					return false
+				}
+				ks.data = d[:dataLen]
+				d = d[dataLen:]
+				m.keyShares = append(m.keyShares, ks)
+			}
+		case extensionPSKModes:
+			// https://tools.ietf.org/html/rfc8446#section-4.2.9
+			if length < 1 {
+				return false
+			}
+			l := int(data[0])
+			if length != l+1 {
+				return false
+			}
+			m.pskModes = data[1:length]
+		case extensionPreSharedKey:
+			// https://tools.ietf.org/html/rfc8446#section-4.2.11
+			if len(data) != length {
+				return false // pre_shared_key must be the last extension
+			}
+			if length < 2 {
+				return false
+			}
+			l := int(data[0])<<8 | int(data[1])
+			if l == 0 || length < l+2 {
+				return false
+			}
+			d := data[2 : 2+l]
+			for len(d) > 0 {
+				if len(d) < 2 {
+					return false
+				}
+				labelLen := int(d[0])<<8 | int(d[1])
+				d = d[2:]
+				if labelLen == 0 || len(d) < labelLen+4 {
+					return false
+				}
+				var psk pskIdentity
+				psk.label = d[:labelLen]
+				d = d[labelLen:]
+				psk.obfuscatedTicketAge = uint32(d[0])<<24 | uint32(d[1])<<16 | uint32(d[2])<<8 | uint32(d[3])
+				d = d[4:]
+				m.pskIdentities = append(m.pskIdentities, psk)
+			}
+			d = data[2+l : length]
+			if len(d) < 2 {
+				return false
+			}
+			l = int(d[0])<<8 | int(d[1])
+			d = d[2:]
+			if l == 0 || len(d) != l {
+				return false
+			}
+			for len(d) > 0 {
+				binderLen := int(d[0])
+				d = d[1:]
+				if binderLen == 0 || len(d) < binderLen {
+					return false
+				}
+				m.pskBinders = append(m.pskBinders, d[:binderLen])
+				d = d[binderLen:]
+			}
 		}
 		data = data[length:]
 	}
@@ -518,6 +813,16 @@ type serverHelloMsg struct {
 	secureRenegotiation          []byte
 	secureRenegotiationSupported bool
 	alpnProtocol                 string
+
+	// TLS 1.3 extensions. See RFC 8446, Section 4.2.
+	supportedVersion        uint16
+	serverShare             keyShare
+	selectedIdentityPresent bool
+	selectedIdentity        uint16
+
+	// HelloRetryRequest extensions
+	cookie        []byte
+	selectedGroup CurveID
 }
 
 func (m *serverHelloMsg) equal(i interface{}) bool {
@@ -547,7 +852,14 @@ func (m *serverHelloMsg) equal(i interface{}) bool {
 		m.ticketSupported == m1.ticketSupported &&
 		m.secureRenegotiationSupported == m1.secureRenegotiationSupported &&
 		bytes.Equal(m.secureRenegotiation, m1.secureRenegotiation) &&
-		m.alpnProtocol == m1.alpnProtocol
+		m.alpnProtocol == m1.alpnProtocol &&
+		m.supportedVersion == m1.supportedVersion &&
+		m.serverShare.group == m1.serverShare.group &&
+		bytes.Equal(m.serverShare.data, m1.serverShare.data) &&
+		m.selectedIdentityPresent == m1.selectedIdentityPresent &&
+		m.selectedIdentity == m1.selectedIdentity &&
+		bytes.Equal(m.cookie, m1.cookie) &&
+		m.selectedGroup == m1.selectedGroup
 }
 
 func (m *serverHelloMsg) marshal() []byte {
@@ -593,6 +905,26 @@ func (m *serverHelloMsg) marshal() []byte {
 		extensionsLength += 2 + sctLen
 		numExtensions++
 	}
+	if m.supportedVersion != 0 {
+		extensionsLength += 2
+		numExtensions++
+	}
+	if m.serverShare.group != 0 {
+		extensionsLength += 4 + len(m.serverShare.data)
+		numExtensions++
+	}
+	if m.selectedIdentityPresent {
+		extensionsLength += 2
+		numExtensions++
+	}
+	if len(m.cookie) > 0 {
+		extensionsLength += 2 + len(m.cookie)
+		numExtensions++
+	}
+	if m.selectedGroup != 0 {
+		extensionsLength += 2
+		numExtensions++
+	}
 
 	if numExtensions > 0 {
 		extensionsLength += 4 * numExtensions
@@ -688,6 +1020,60 @@ func (m *serverHelloMsg) marshal() []byte {
 			z = z[len(sct)+2:]
 		}
 	}
+	if m.supportedVersion != 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.1
+		z[0] = byte(extensionSupportedVersions >> 8)
+		z[1] = byte(extensionSupportedVersions)
+		z[3] = 2
+		z[4] = byte(m.supportedVersion >> 8)
+		z[5] = byte(m.supportedVersion)
+		z = z[6:]
+	}
+	if m.serverShare.group != 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.8
+		z[0] = byte(extensionKeyShare >> 8)
+		z[1] = byte(extensionKeyShare)
+		l := 4 + len(m.serverShare.data)
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		z[4] = byte(m.serverShare.group >> 8)
+		z[5] = byte(m.serverShare.group)
+		z[6] = byte(len(m.serverShare.data) >> 8)
+		z[7] = byte(len(m.serverShare.data))
+		copy(z[8:], m.serverShare.data)
+		z = z[4+l:]
+	}
+	if m.selectedIdentityPresent {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.11
+		z[0] = byte(extensionPreSharedKey >> 8)
+		z[1] = byte(extensionPreSharedKey)
+		z[3] = 2
+		z[4] = byte(m.selectedIdentity >> 8)
+		z[5] = byte(m.selectedIdentity)
+		z = z[6:]
+	}
+	if len(m.cookie) > 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.2
+		z[0] = byte(extensionCookie >> 8)
+		z[1] = byte(extensionCookie)
+		l := 2 + len(m.cookie)
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		z[4] = byte(len(m.cookie) >> 8)
+		z[5] = byte(len(m.cookie))
+		copy(z[6:], m.cookie)
+		z = z[4+l:]
+	}
+	if m.selectedGroup != 0 {
+		// In a HelloRetryRequest the key_share extension only carries the
+		// selected group. See RFC 8446, Section 4.2.8.
+		z[0] = byte(extensionKeyShare >> 8)
+		z[1] = byte(extensionKeyShare)
+		z[3] = 2
+		z[4] = byte(m.selectedGroup >> 8)
+		z[5] = byte(m.selectedGroup)
+		z = z[6:]
+	}
 
 	m.raw = x
 
@@ -720,6 +1106,12 @@ func (m *serverHelloMsg) unmarshal(data []byte) bool {
 	m.scts = nil
 	m.ticketSupported = false
 	m.alpnProtocol = ""
+	m.supportedVersion = 0
+	m.serverShare = keyShare{}
+	m.selectedIdentityPresent = false
+	m.selectedIdentity = 0
+	m.cookie = nil
+	m.selectedGroup = 0
 
 	if len(data) == 0 {
 		// ServerHello is optionally followed by extension data
@@ -827,6 +1219,155 @@ func (m *serverHelloMsg) unmarshal(data []byte) bool {
 				m.scts = append(m.scts, d[:sctLen])
 				d = d[sctLen:]
 			}
+		case extensionSupportedVersions:
+			if length != 2 {
+				return false
+			}
+			m.supportedVersion = uint16(data[0])<<8 | uint16(data[1])
+		case extensionKeyShare:
+			// A HelloRetryRequest carries only the selected group, while
+			// a ServerHello carries a full KeyShareEntry.
+			if length == 2 {
+				m.selectedGroup = CurveID(data[0])<<8 | CurveID(data[1])
+				break
+			}
+			if length < 4 {
+				return false
+			}
+			m.serverShare.group = CurveID(data[0])<<8 | CurveID(data[1])
+			l := int(data[2])<<8 | int(data[3])
+			if l == 0 || length != l+4 {
+				return false
+			}
+			m.serverShare.data = data[4:length]
+		case extensionPreSharedKey:
+			if length != 2 {
+				return false
+			}
+			m.selectedIdentityPresent = true
+			m.selectedIdentity = uint16(data[0])<<8 | uint16(data[1])
+		case extensionCookie:
+			if length < 2 {
+				return false
+			}
+			l := int(data[0])<<8 | int(data[1])
+			if l == 0 || length != l+2 {
+				return false
+			}
+			m.cookie = data[2:length]
+		}
+		data = data[length:]
+	}
+
+	return true
+}
+
+type encryptedExtensionsMsg struct {
+	raw          []byte
+	alpnProtocol string
+}
+
+func (m *encryptedExtensionsMsg) equal(i interface{}) bool {
+	m1, ok := i.(*encryptedExtensionsMsg)
+	if !ok {
+		return false
+	}
+
+	return bytes.Equal(m.raw, m1.raw) &&
+		m.alpnProtocol == m1.alpnProtocol
+}
+
+func (m *encryptedExtensionsMsg) marshal() []byte {
+	if m.raw != nil {
+		return m.raw
+	}
+
+	// See https://tools.ietf.org/html/rfc8446#section-4.3.1
+	alpnLen := len(m.alpnProtocol)
+	extensionsLength := 0
+	if alpnLen > 0 {
+		if alpnLen >= 256 {
+			panic("invalid ALPN protocol")
+		}
+		extensionsLength += 4 + 2 + 1 + alpnLen
+	}
+
+	length := 2 + extensionsLength
+	x := make([]byte, 4+length)
+	x[0] = typeEncryptedExtensions
+	x[1] = uint8(length >> 16)
+	x[2] = uint8(length >> 8)
+	x[3] = uint8(length)
+	x[4] = uint8(extensionsLength >> 8)
+	x[5] = uint8(extensionsLength)
+	z := x[6:]
+	if alpnLen > 0 {
+		z[0] = byte(extensionALPN >> 8)
+		z[1] = byte(extensionALPN & 0xff)
+		l := 2 + 1 + alpnLen
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		l -= 2
+		z[4] = byte(l >> 8)
+		z[5] = byte(l)
+		z[6] = byte(alpnLen)
+		copy(z[7:], m.alpnProtocol)
+	}
+
+	m.raw = x
+
+	return x
+}
+
+func (m *encryptedExtensionsMsg) unmarshal(data []byte) bool {
+	if len(data) < 6 {
+		return false
+	}
+	m.raw = data
+	m.alpnProtocol = ""
+
+	length := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	if len(data)-4 != length {
+		return false
+	}
+	extensionsLength := int(data[4])<<8 | int(data[5])
+	data = data[6:]
+	if len(data) != extensionsLength {
+		return false
+	}
+
+	for len(data) != 0 {
+		if len(data) < 4 {
+			return false
+		}
+		extension := uint16(data[0])<<8 | uint16(data[1])
+		length := int(data[2])<<8 | int(data[3])
+		data = data[4:]
+		if len(data) < length {
+			return false
+		}
+
+		switch extension {
+		case extensionALPN:
+			d := data[:length]
+			if len(d) < 3 {
+				return false
+			}
+			l := int(d[0])<<8 | int(d[1])
+			if l != len(d)-2 {
+				return false
+			}
+			d = d[2:]
+			l = int(d[0])
+			if l != len(d)-1 {
+				return false
+			}
+			d = d[1:]
+			if len(d) == 0 {
+				// ALPN protocols must not be empty.
+				return false
+			}
+			m.alpnProtocol = string(d)
 		}
 		data = data[length:]
 	}
@@ -921,6 +1462,175 @@ func (m *certificateMsg) unmarshal(data []byte) bool {
 	return true
 }
 
+// certificateMsgTLS13 is the TLS 1.3 Certificate message, where each
+// certificate carries its own extensions. The OCSP staple and the SCTs are
+// only sent along with the leaf. See RFC 8446, Section 4.4.2.
+type certificateMsgTLS13 struct {
+	raw          []byte
+	certificates [][]byte
+	ocspStaple   []byte
+	scts         [][]byte
+}
+
+func (m *certificateMsgTLS13) equal(i interface{}) bool {
+	m1, ok := i.(*certificateMsgTLS13)
+	if !ok {
+		return false
+	}
+
+	return bytes.Equal(m.raw, m1.raw) &&
+		eqByteSlices(m.certificates, m1.certificates) &&
+		bytes.Equal(m.ocspStaple, m1.ocspStaple) &&
+		eqByteSlices(m.scts, m1.scts)
+}
+
+func (m *certificateMsgTLS13) marshal() (x []byte) {
+	if m.raw != nil {
+		return m.raw
+	}
+
+	var certList []byte
+	for i, cert := range m.certificates {
+		certList = append(certList, byte(len(cert)>>16), byte(len(cert)>>8), byte(len(cert)))
+		certList = append(certList, cert...)
+
+		var extensions []byte
+		if i == 0 && len(m.ocspStaple) > 0 {
+			l := 1 + 3 + len(m.ocspStaple)
+			extensions = append(extensions, byte(extensionStatusRequest>>8), byte(extensionStatusRequest))
+			extensions = append(extensions, byte(l>>8), byte(l))
+			extensions = append(extensions, statusTypeOCSP)
+			extensions = append(extensions, byte(len(m.ocspStaple)>>16), byte(len(m.ocspStaple)>>8), byte(len(m.ocspStaple)))
+			extensions = append(extensions, m.ocspStaple...)
+		}
+		if i == 0 && len(m.scts) > 0 {
+			sctLen := 0
+			for _, sct := range m.scts {
+				sctLen += 2 + len(sct)
+			}
+			l := 2 + sctLen
+			extensions = append(extensions, byte(extensionSCT>>8), byte(extensionSCT))
+			extensions = append(extensions, byte(l>>8), byte(l))
+			extensions = append(extensions, byte(sctLen>>8), byte(sctLen))
+			for _, sct := range m.scts {
+				extensions = append(extensions, byte(len(sct)>>8), byte(len(sct)))
+				extensions = append(extensions, sct...)
+			}
+		}
+		certList = append(certList, byte(len(extensions)>>8), byte(len(extensions)))
+		certList = append(certList, extensions...)
+	}
+
+	// The certificate_request_context is always empty, as post-handshake
+	// authentication is not supported.
+	length := 1 + 3 + len(certList)
+	x = make([]byte, 4, 4+length)
+	x[0] = typeCertificate
+	x[1] = uint8(length >> 16)
+	x[2] = uint8(length >> 8)
+	x[3] = uint8(length)
+	x = append(x, 0)
+	x = append(x, byte(len(certList)>>16), byte(len(certList)>>8), byte(len(certList)))
+	x = append(x, certList...)
+
+	m.raw = x
+	return
+}
+
+func (m *certificateMsgTLS13) unmarshal(data []byte) bool {
+	if len(data) < 8 {
+		return false
+	}
+	m.raw = data
+	m.certificates = nil
+	m.ocspStaple = nil
+	m.scts = nil
+
+	length := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	if len(data)-4 != length || data[4] != 0 {
+		return false
+	}
+	certsLen := int(data[5])<<16 | int(data[6])<<8 | int(data[7])
+	d := data[8:]
+	if len(d) != certsLen {
+		return false
+	}
+
+	for len(d) > 0 {
+		if len(d) < 3 {
+			return false
+		}
+		certLen := int(d[0])<<16 | int(d[1])<<8 | int(d[2])
+		d = d[3:]
+		if certLen == 0 || len(d) < certLen+2 {
+			return false
+		}
+		m.certificates = append(m.certificates, d[:certLen])
+		d = d[certLen:]
+
+		extensionsLength := int(d[0])<<8 | int(d[1])
+		d = d[2:]
+		if len(d) < extensionsLength {
+			return false
+		}
+		extensions := d[:extensionsLength]
+		d = d[extensionsLength:]
+
+		for len(extensions) > 0 {
+			if len(extensions) < 4 {
+				return false
+			}
+			extension := uint16(extensions[0])<<8 | uint16(extensions[1])
+			l := int(extensions[2])<<8 | int(extensions[3])
+			extensions = extensions[4:]
+			if len(extensions) < l {
+				return false
+			}
+			e := extensions[:l]
+			extensions = extensions[l:]
+
+			// Only the extensions of the leaf are of interest.
+			if len(m.certificates) != 1 {
+				continue
+			}
+			switch extension {
+			case extensionStatusRequest:
+				if len(e) < 4 || e[0] != statusTypeOCSP {
+					return false
+				}
+				ocspLen := int(e[1])<<16 | int(e[2])<<8 | int(e[3])
+				if ocspLen == 0 || len(e) != 4+ocspLen {
+					return false
+				}
+				m.ocspStaple = e[4:]
+			case extensionSCT:
+				if len(e) < 2 {
+					return false
+				}
+				sctsLen := int(e[0])<<8 | int(e[1])
+				e = e[2:]
+				if sctsLen == 0 || len(e) != sctsLen {
+					return false
+				}
+				for len(e) > 0 {
+					if len(e) < 2 {
+						return false
+					}
+					sctLen := int(e[0])<<8 | int(e[1])
+					e = e[2:]
+					if sctLen == 0 || len(e) < sctLen {
+						return false
+					}
+					m.scts = append(m.scts, e[:sctLen])
+					e = e[sctLen:]
+				}
+			}
+		}
+	}
+
+	return true
+}
+
 type serverKeyExchangeMsg struct {
 	raw []byte
 	key []byte
@@ -1350,6 +2060,141 @@ func (m *certificateRequestMsg) unmarshal(data []byte) bool {
 	return len(data) == 0
 }
 
+// certificateRequestMsgTLS13 is the TLS 1.3 CertificateRequest message, where
+// the parameters moved into extensions. See RFC 8446, Section 4.3.2.
+type certificateRequestMsgTLS13 struct {
+	raw                          []byte
+	supportedSignatureAlgorithms []SignatureScheme
+	certificateAuthorities       [][]byte
+}
+
+func (m *certificateRequestMsgTLS13) equal(i interface{}) bool {
+	m1, ok := i.(*certificateRequestMsgTLS13)
+	if !ok {
+		return false
+	}
+
+	return bytes.Equal(m.raw, m1.raw) &&
+		eqSignatureAlgorithms(m.supportedSignatureAlgorithms, m1.supportedSignatureAlgorithms) &&
+		eqByteSlices(m.certificateAuthorities, m1.certificateAuthorities)
+}
+
+func (m *certificateRequestMsgTLS13) marshal() (x []byte) {
+	if m.raw != nil {
+		return m.raw
+	}
+
+	var extensions []byte
+	sigAlgsLen := 2 * len(m.supportedSignatureAlgorithms)
+	extensions = append(extensions, byte(extensionSignatureAlgorithms>>8), byte(extensionSignatureAlgorithms))
+	extensions = append(extensions, byte((2+sigAlgsLen)>>8), byte(2+sigAlgsLen))
+	extensions = append(extensions, byte(sigAlgsLen>>8), byte(sigAlgsLen))
+	for _, sigAlg := range m.supportedSignatureAlgorithms {
+		extensions = append(extensions, byte(sigAlg>>8), byte(sigAlg))
+	}
+	if len(m.certificateAuthorities) > 0 {
+		casLen := 0
+		for _, ca := range m.certificateAuthorities {
+			casLen += 2 + len(ca)
+		}
+		extensions = append(extensions, byte(extensionCertificateAuthorities>>8), byte(extensionCertificateAuthorities))
+		extensions = append(extensions, byte((2+casLen)>>8), byte(2+casLen))
+		extensions = append(extensions, byte(casLen>>8), byte(casLen))
+		for _, ca := range m.certificateAuthorities {
+			extensions = append(extensions, byte(len(ca)>>8), byte(len(ca)))
+			extensions = append(extensions, ca...)
+		}
+	}
+
+	// The certificate_request_context is always empty, as post-handshake
+	// authentication is not supported.
+	length := 1 + 2 + len(extensions)
+	x = make([]byte, 4, 4+length)
+	x[0] = typeCertificateRequest
+	x[1] = uint8(length >> 16)
+	x[2] = uint8(length >> 8)
+	x[3] = uint8(length)
+	x = append(x, 0)
+	x = append(x, byte(len(extensions)>>8), byte(len(extensions)))
+	x = append(x, extensions...)
+
+	m.raw = x
+	return
+}
+
+func (m *certificateRequestMsgTLS13) unmarshal(data []byte) bool {
+	if len(data) < 7 {
+		return false
+	}
+	m.raw = data
+	m.supportedSignatureAlgorithms = nil
+	m.certificateAuthorities = nil
+
+	length := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	if len(data)-4 != length || data[4] != 0 {
+		return false
+	}
+	extensionsLength := int(data[5])<<8 | int(data[6])
+	data = data[7:]
+	if len(data) != extensionsLength {
+		return false
+	}
+
+	for len(data) != 0 {
+		if len(data) < 4 {
+			return false
+		}
+		extension := uint16(data[0])<<8 | uint16(data[1])
+		length := int(data[2])<<8 | int(data[3])
+		data = data[4:]
+		if len(data) < length {
+			return false
+		}
+		d := data[:length]
+		data = data[length:]
+
+		switch extension {
+		case extensionSignatureAlgorithms:
+			if len(d) < 2 {
+				return false
+			}
+			l := int(d[0])<<8 | int(d[1])
+			d = d[2:]
+			if l == 0 || l%2 == 1 || len(d) != l {
+				return false
+			}
+			for len(d) > 0 {
+				m.supportedSignatureAlgorithms = append(m.supportedSignatureAlgorithms, SignatureScheme(d[0])<<8|SignatureScheme(d[1]))
+				d = d[2:]
+			}
+		case extensionCertificateAuthorities:
+			if len(d) < 2 {
+				return false
+			}
+			l := int(d[0])<<8 | int(d[1])
+			d = d[2:]
+			if l == 0 || len(d) != l {
+				return false
+			}
+			for len(d) > 0 {
+				if len(d) < 2 {
+					return false
+				}
+				caLen := int(d[0])<<8 | int(d[1])
+				d = d[2:]
+				if caLen == 0 || len(d) < caLen {
+					return false
+				}
+				m.certificateAuthorities = append(m.certificateAuthorities, d[:caLen])
+				d = d[caLen:]
+			}
+		}
+	}
+
+	// The signature_algorithms extension is mandatory.
+	return len(m.supportedSignatureAlgorithms) > 0
+}
+
 type certificateVerifyMsg struct {
 	raw                 []byte
 	hasSignatureAndHash bool
@@ -1491,6 +2336,95 @@ func (m *newSessionTicketMsg) unmarshal(data []byte) bool {
 	return true
 }
 
+type newSessionTicketMsgTLS13 struct {
+	raw      []byte
+	lifetime uint32
+	ageAdd   uint32
+	nonce    []byte
+	label    []byte
+}
+
+func (m *newSessionTicketMsgTLS13) equal(i interface{}) bool {
+	m1, ok := i.(*newSessionTicketMsgTLS13)
+	if !ok {
+		return false
+	}
+
+	return bytes.Equal(m.raw, m1.raw) &&
+		m.lifetime == m1.lifetime &&
+		m.ageAdd == m1.ageAdd &&
+		bytes.Equal(m.nonce, m1.nonce) &&
+		bytes.Equal(m.label, m1.label)
+}
+
+func (m *newSessionTicketMsgTLS13) marshal() (x []byte) {
+	if m.raw != nil {
+		return m.raw
+	}
+
+	// See https://tools.ietf.org/html/rfc8446#section-4.6.1
+	length := 4 + 4 + 1 + len(m.nonce) + 2 + len(m.label) + 2
+	x = make([]byte, 4+length)
+	x[0] = typeNewSessionTicket
+	x[1] = uint8(length >> 16)
+	x[2] = uint8(length >> 8)
+	x[3] = uint8(length)
+	x[4] = uint8(m.lifetime >> 24)
+	x[5] = uint8(m.lifetime >> 16)
+	x[6] = uint8(m.lifetime >> 8)
+	x[7] = uint8(m.lifetime)
+	x[8] = uint8(m.ageAdd >> 24)
+	x[9] = uint8(m.ageAdd >> 16)
+	x[10] = uint8(m.ageAdd >> 8)
+	x[11] = uint8(m.ageAdd)
+	x[12] = uint8(len(m.nonce))
+	copy(x[13:], m.nonce)
+	y := x[13+len(m.nonce):]
+	y[0] = uint8(len(m.label) >> 8)
+	y[1] = uint8(len(m.label))
+	copy(y[2:], m.label)
+	// The extensions list is left empty.
+
+	m.raw = x
+
+	return
+}
+
+func (m *newSessionTicketMsgTLS13) unmarshal(data []byte) bool {
+	m.raw = data
+
+	if len(data) < 13 {
+		return false
+	}
+
+	length := uint32(data[1])<<16 | uint32(data[2])<<8 | uint32(data[3])
+	if uint32(len(data))-4 != length {
+		return false
+	}
+
+	m.lifetime = uint32(data[4])<<24 | uint32(data[5])<<16 | uint32(data[6])<<8 | uint32(data[7])
+	m.ageAdd = uint32(data[8])<<24 | uint32(data[9])<<16 | uint32(data[10])<<8 | uint32(data[11])
+	nonceLen := int(data[12])
+	data = data[13:]
+	if len(data) < nonceLen+2 {
+		return false
+	}
+	m.nonce = data[:nonceLen]
+	data = data[nonceLen:]
+
+	labelLen := int(data[0])<<8 | int(data[1])
+	data = data[2:]
+	if labelLen == 0 || len(data) < labelLen+2 {
+		return false
+	}
+	m.label = data[:labelLen]
+	data = data[labelLen:]
+
+	// Extensions, such as early_data, are ignored.
+	extensionsLength := int(data[0])<<8 | int(data[1])
+	return len(data)-2 == extensionsLength
+}
+
 type helloRequestMsg struct {
 }
 
@@ -1502,6 +2436,53 @@ func (*helloRequestMsg) unmarshal(data []byte) bool {
 	return len(data) == 4
 }
 
+type keyUpdateMsg struct {
+	raw             []byte
+	updateRequested bool
+}
+
+func (m *keyUpdateMsg) equal(i interface{}) bool {
+	m1, ok := i.(*keyUpdateMsg)
+	if !ok {
+		return false
+	}
+
+	return bytes.Equal(m.raw, m1.raw) &&
+		m.updateRequested == m1.updateRequested
+}
+
+func (m *keyUpdateMsg) marshal() []byte {
+	if m.raw != nil {
+		return m.raw
+	}
+
+	// See https://tools.ietf.org/html/rfc8446#section-4.6.3
+	x := []byte{typeKeyUpdate, 0, 0, 1, 0}
+	if m.updateRequested {
+		x[4] = 1
+	}
+
+	m.raw = x
+
+	return x
+}
+
+func (m *keyUpdateMsg) unmarshal(data []byte) bool {
+	m.raw = data
+	if len(data) != 5 || data[1] != 0 || data[2] != 0 || data[3] != 1 {
+		return false
+	}
+	switch data[4] {
+	case 0:
+		m.updateRequested = false
+	case 1:
+		m.updateRequested = true
+	default:
+		return false
+	}
+	return true
+}
+
 func eqUint16s(x, y []uint16) bool {
 	if len(x) != len(y) {
 		return false
@@ -1561,3 +2542,27 @@ func eqSignatureAlgorithms(x, y []SignatureScheme) bool {
 	}
 	return true
 }
+
+func eqKeyShares(x, y []keyShare) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i].group != y[i].group || !bytes.Equal(x[i].data, y[i].data) {
+			return false
+		}
+	}
+	return true
+}
+
+func eqPSKIdentities(x, y []pskIdentity) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i].obfuscatedTicketAge != y[i].obfuscatedTicketAge || !bytes.Equal(x[i].label, y[i].label) {
+			return false
+		}
+	}
+	return true
+}
@@ -7,6 +7,7 @@ package tls
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/subtle"
 	"crypto/x509"
@@ -42,10 +43,24 @@ func (c *Conn) serverHandshake() error {
 	// encrypt the tickets with.
 	c.config.serverInitOnce.Do(func() { c.config.serverInit(nil) })
 
+	clientHello, err := c.readClientHello()
+	if err != nil {
+		return err
+	}
+
+	if c.vers == VersionTLS13 {
+		hs := serverHandshakeStateTLS13{
+			c:           c,
+			clientHello: clientHello,
+		}
+		return hs.handshake()
+	}
+
 	hs := serverHandshakeState{
-		c: c,
+		c:           c,
+		clientHello: clientHello,
 	}
-	isResume, err := hs.readClientHello()
+	isResume, err := hs.processClientHello()
 	if err != nil {
 		return err
 	}
@@ -108,39 +123,44 @@ func (c *Conn) serverHandshake() error {
 	return nil
 }
 
-// readClientHello reads a ClientHello message from the client and decides
-// whether we will perform session resumption.
-func (hs *serverHandshakeState) readClientHello() (isResume bool, err error) {
-	c := hs.c
-
+// readClientHello reads a ClientHello message from the client, applies
+// Config.GetConfigForClient and selects the protocol version.
+func (c *Conn) readClientHello() (*clientHelloMsg, error) {
 	msg, err := c.readHandshake()
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	var ok bool
-	hs.clientHello, ok = msg.(*clientHelloMsg)
+	clientHello, ok := msg.(*clientHelloMsg)
 	if !ok {
 		c.sendAlert(alertUnexpectedMessage)
-		return false, unexpectedMessageError(hs.clientHello, msg)
+		return nil, unexpectedMessageError(clientHello, msg)
 	}
 
 	if c.config.GetConfigForClient != nil {
-		if newConfig, err := c.config.GetConfigForClient(hs.clientHelloInfo()); err != nil {
+		if newConfig, err := c.config.GetConfigForClient(newClientHelloInfo(c, clientHello)); err != nil {
 			c.sendAlert(alertInternalError)
-			return false, err
+			return nil, err
 		} else if newConfig != nil {
 			newConfig.serverInitOnce.Do(func() { newConfig.serverInit(c.config) })
 			c.config = newConfig
 		}
 	}
 
-	c.vers, ok = c.config.mutualVersion(hs.clientHello.vers)
+	c.vers, ok = c.config.mutualVersion(clientSupportedVersions(clientHello))
 	if !ok {
 		c.sendAlert(alertProtocolVersion)
-		return false, fmt.Errorf("tls: client offered an unsupported, maximum protocol version of %x", hs.clientHello.vers)
+		return nil, fmt.Errorf("tls: client offered an unsupported, maximum protocol version of %x", clientHello.vers)
 	}
 	c.haveVers = true
 
+	return clientHello, nil
+}
+
+// processClientHello prepares the TLS 1.2 and earlier ServerHello and decides
+// whether we will perform session resumption.
+func (hs *serverHandshakeState) processClientHello() (isResume bool, err error) {
+	c := hs.c
+
 	hs.hello = new(serverHelloMsg)
 
 	supportedCurve := false
@@ -186,6 +206,16 @@ Curves:
 		return false, err
 	}
 
+	// A server that supports TLS 1.3 signals a downgrade in the last eight
+	// bytes of its random. See RFC 8446, Section 4.1.3.
+	if c.config.maxVersion() >= VersionTLS13 {
+		if c.vers == VersionTLS12 {
+			copy(hs.hello.random[24:], downgradeCanaryTLS12)
+		} else {
+			copy(hs.hello.random[24:], downgradeCanaryTLS11)
+		}
+	}
+
 	if len(hs.clientHello.secureRenegotiation) != 0 {
 		c.sendAlert(alertHandshakeFailure)
 		return false, errors.New("tls: initial handshake had non-empty renegotiation extension")
@@ -226,6 +256,10 @@ Curves:
 		switch priv.Public().(type) {
 		case *ecdsa.PublicKey:
 			hs.ecdsaOk = true
+		case ed25519.PublicKey:
+			// Ed25519 keys sign with the ECDSA cipher suites, as long as
+			// the client supports the Ed25519 signature scheme.
+			hs.ecdsaOk = isSupportedSignatureAlgorithm(Ed25519, hs.clientHello.supportedSignatureAlgorithms)
 		case *rsa.PublicKey:
 			hs.rsaSignOk = true
 		default:
@@ -290,9 +324,13 @@ func (hs *serverHandshakeState) checkForResumption() bool {
 		return false
 	}
 
-	var ok bool
 	var sessionTicket = append([]uint8{}, hs.clientHello.sessionTicket...)
-	if hs.sessionState, ok = c.decryptTicket(sessionTicket); !ok {
+	plaintext, usedOldKey := c.decryptTicket(sessionTicket)
+	if plaintext == nil {
+		return false
+	}
+	hs.sessionState = &sessionState{usedOldKey: usedOldKey}
+	if !hs.sessionState.unmarshal(plaintext) {
 		return false
 	}
 
@@ -347,9 +385,10 @@ func (hs *serverHandshakeState) doResumeHandshake() error {
 	}
 
 	if len(hs.sessionState.certificates) > 0 {
-		if _, err := hs.processCertsFromClient(hs.sessionState.certificates); err != nil {
+		if _, err := c.processCertsFromClient(hs.sessionState.certificates); err != nil {
 			return err
 		}
+		hs.certsFromClient = hs.sessionState.certificates
 	}
 
 	hs.masterSecret = hs.sessionState.masterSecret
@@ -471,10 +510,11 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 			}
 		}
 
-		pub, err = hs.processCertsFromClient(certMsg.certificates)
+		pub, err = c.processCertsFromClient(certMsg.certificates)
 		if err != nil {
 			return err
 		}
+		hs.certsFromClient = certMsg.certificates
 
 		msg, err = c.readHandshake()
 		if err != nil {
@@ -496,7 +536,7 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 		return err
 	}
 	hs.masterSecret = masterFromPreMasterSecret(c.vers, hs.suite, preMasterSecret, hs.clientHello.random, hs.hello.random)
-	if err := c.config.writeKeyLog(hs.clientHello.random, hs.masterSecret); err != nil {
+	if err := c.config.writeKeyLog(keyLogLabelTLS12, hs.clientHello.random, hs.masterSecret); err != nil {
 		c.sendAlert(alertInternalError)
 		return err
 	}
@@ -572,6 +612,20 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 				break
 			}
 			err = rsa.VerifyPKCS1v15(key, hashFunc, digest, certVerify.signature)
+		case ed25519.PublicKey:
+			if sigType != signatureEd25519 {
+				err = errors.New("tls: bad signature type for client's Ed25519 certificate")
+				break
+			}
+			var signed []byte
+			if signed, _, err = hs.finishedHash.hashForClientCertificate(sigType, signatureAlgorithm, hs.masterSecret); err != nil {
+				break
+			}
+			if !ed25519.Verify(key, signed, certVerify.signature) {
+				err = errors.New("tls: Ed25519 verification failure")
+			}
+		default:
+			err = errors.New("tls: unsupported client certificate key type")
 		}
 		if err != nil {
 			c.sendAlert(alertBadCertificate)
@@ -670,7 +724,7 @@ func (hs *serverHandshakeState) sendSessionTicket() error {
 		masterSecret: hs.masterSecret,
 		certificates: hs.certsFromClient,
 	}
-	m.ticket, err = c.encryptTicket(&state)
+	m.ticket, err = c.encryptTicket(state.marshal())
 	if err != nil {
 		return err
 	}
@@ -706,10 +760,7 @@ func (hs *serverHandshakeState) sendFinished(out []byte) error {
 // processCertsFromClient takes a chain of client certificates either from a
 // Certificates message or from a sessionState and verifies them. It returns
 // the public key of the leaf certificate.
-func (hs *serverHandshakeState) processCertsFromClient(certificates [][]byte) (crypto.PublicKey, error) {
-	c := hs.c
-
-	hs.certsFromClient = certificates
+func (c *Conn) processCertsFromClient(certificates [][]byte) (crypto.PublicKey, error) {
 	certs := make([]*x509.Certificate, len(certificates))
 	var err error
 	for i, asn1Data := range certificates {
@@ -753,7 +804,7 @@ func (hs *serverHandshakeState) processCertsFromClient(certificates [][]byte) (c
 
 	var pub crypto.PublicKey
 	switch key := certs[0].PublicKey.(type) {
-	case *ecdsa.PublicKey, *rsa.PublicKey:
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
 		pub = key
 	default:
 		c.sendAlert(alertUnsupportedCertificate)
@@ -807,30 +858,40 @@ func (hs *serverHandshakeState) setCipherSuite(id uint16, supportedCipherSuites
 }
 
 // suppVersArray is the backing array of ClientHelloInfo.SupportedVersions
+// for clients that don't send the supported_versions extension.
 var suppVersArray = [...]uint16{VersionTLS12, VersionTLS11, VersionTLS10, VersionSSL30}
 
-func (hs *serverHandshakeState) clientHelloInfo() *ClientHelloInfo {
-	if hs.cachedClientHelloInfo != nil {
-		return hs.cachedClientHelloInfo
+// clientSupportedVersions returns the versions offered by the client, highest
+// first. Clients that don't send the supported_versions extension support
+// every version up to the legacy one in the ClientHello, capped at TLS 1.2.
+func clientSupportedVersions(clientHello *clientHelloMsg) []uint16 {
+	if len(clientHello.supportedVersions) > 0 {
+		return clientHello.supportedVersions
 	}
-
-	var supportedVersions []uint16
-	if hs.clientHello.vers > VersionTLS12 {
-		supportedVersions = suppVersArray[:]
-	} else if hs.clientHello.vers >= VersionSSL30 {
-		supportedVersions = suppVersArray[VersionTLS12-hs.clientHello.vers:]
+	if clientHello.vers > VersionTLS12 {
+		return suppVersArray[:]
+	} else if clientHello.vers >= VersionSSL30 {
+		return suppVersArray[VersionTLS12-clientHello.vers:]
 	}
+	return nil
+}
 
-	hs.cachedClientHelloInfo = &ClientHelloInfo{
-		CipherSuites:      hs.clientHello.cipherSuites,
-		ServerName:        hs.clientHello.serverName,
-		SupportedCurves:   hs.clientHello.supportedCurves,
-		SupportedPoints:   hs.clientHello.supportedPoints,
-		SignatureSchemes:  hs.clientHello.supportedSignatureAlgorithms,
-		SupportedProtos:   hs.clientHello.alpnProtocols,
-		SupportedVersions: supportedVersions,
-		Conn:              hs.c.conn,
+func newClientHelloInfo(c *Conn, clientHello *clientHelloMsg) *ClientHelloInfo {
+	return &ClientHelloInfo{
+		CipherSuites:      clientHello.cipherSuites,
+		ServerName:        clientHello.serverName,
+		SupportedCurves:   clientHello.supportedCurves,
+		SupportedPoints:   clientHello.supportedPoints,
+		SignatureSchemes:  clientHello.supportedSignatureAlgorithms,
+		SupportedProtos:   clientHello.alpnProtocols,
+		SupportedVersions: clientSupportedVersions(clientHello),
+		Conn:              c.conn,
 	}
+}
 
+func (hs *serverHandshakeState) clientHelloInfo() *ClientHelloInfo {
+	if hs.cachedClientHelloInfo == nil {
+		hs.cachedClientHelloInfo = newClientHelloInfo(hs.c, hs.clientHello)
+	}
 	return hs.cachedClientHelloInfo
 }
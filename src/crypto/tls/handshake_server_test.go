@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rsa"
 	"crypto/x509"
@@ -16,6 +17,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math/big"
 	"net"
 	"os"
@@ -82,10 +84,15 @@ func testClientHelloFailure(t *testing.T, serverConfig *Config, m handshakeMessa
 		cli.writeRecord(recordTypeHandshake, m.marshal())
 		c.Close()
 	}()
+	conn := Server(s, serverConfig)
+	ch, err := conn.readClientHello()
 	hs := serverHandshakeState{
-		c: Server(s, serverConfig),
+		c:           conn,
+		clientHello: ch,
+	}
+	if err == nil {
+		_, err = hs.processClientHello()
 	}
-	_, err := hs.readClientHello()
 	s.Close()
 	if len(expectedSubStr) == 0 {
 		if err != nil && err != io.EOF {
@@ -314,12 +321,15 @@ func TestClose(t *testing.T) {
 }
 
 func testHandshake(clientConfig, serverConfig *Config) (serverState, clientState ConnectionState, err error) {
-	c, s := net.Pipe()
+	c, s := localPipe()
 	done := make(chan bool)
 	go func() {
 		cli := Client(c, clientConfig)
 		cli.Handshake()
 		clientState = cli.ConnectionState()
+		// Read until the server closes the connection, to process any
+		// TLS 1.3 session tickets.
+		ioutil.ReadAll(cli)
 		c.Close()
 		done <- true
 	}()
@@ -350,6 +360,254 @@ func TestVersion(t *testing.T) {
 	}
 }
 
+func TestVersionTLS13(t *testing.T) {
+	tests := []struct {
+		clientMax, serverMax uint16
+		want                 uint16
+	}{
+		{0, 0, VersionTLS13},
+		{VersionTLS13, VersionTLS13, VersionTLS13},
+		{VersionTLS13, 0, VersionTLS13},
+		{0, VersionTLS13, VersionTLS13},
+		{VersionTLS12, 0, VersionTLS12},
+		{0, VersionTLS12, VersionTLS12},
+		{VersionTLS13, VersionTLS11, VersionTLS11},
+		{VersionTLS11, VersionTLS13, VersionTLS11},
+	}
+	for _, test := range tests {
+		serverConfig := &Config{
+			Certificates: testConfig.Certificates,
+			MaxVersion:   test.serverMax,
+		}
+		clientConfig := &Config{
+			InsecureSkipVerify: true,
+			MaxVersion:         test.clientMax,
+		}
+		state, _, err := testHandshake(clientConfig, serverConfig)
+		if err != nil {
+			t.Errorf("client %x, server %x: handshake failed: %s", test.clientMax, test.serverMax, err)
+			continue
+		}
+		if state.Version != test.want {
+			t.Errorf("client %x, server %x: got version %x, want %x", test.clientMax, test.serverMax, state.Version, test.want)
+		}
+		if test.want == VersionTLS13 && cipherSuiteTLS13ByID(state.CipherSuite) == nil {
+			t.Errorf("negotiated non-TLS 1.3 cipher suite %x", state.CipherSuite)
+		}
+	}
+}
+
+func TestHandshakeTLS13(t *testing.T) {
+	tests := []struct {
+		name   string
+		cert   Certificate
+		curves []CurveID
+	}{
+		{"RSA", testConfig.Certificates[0], nil},
+		{"ECDSA", Certificate{Certificate: [][]byte{testECDSACertificate}, PrivateKey: testECDSAPrivateKey}, nil},
+		{"Ed25519", Certificate{Certificate: [][]byte{testEd25519Certificate}, PrivateKey: testEd25519PrivateKey}, nil},
+		// The client only sends an X25519 key share, so this requires a
+		// HelloRetryRequest.
+		{"HelloRetryRequest", testConfig.Certificates[0], []CurveID{CurveP256}},
+	}
+	for _, test := range tests {
+		serverConfig := &Config{
+			Certificates:     []Certificate{test.cert},
+			CurvePreferences: test.curves,
+			MaxVersion:       VersionTLS13,
+			NextProtos:       []string{"foo", "bar"},
+		}
+		clientConfig := &Config{
+			InsecureSkipVerify: true,
+			MaxVersion:         VersionTLS13,
+			NextProtos:         []string{"bar"},
+		}
+		serverState, clientState, err := testHandshake(clientConfig, serverConfig)
+		if err != nil {
+			t.Errorf("%s: handshake failed: %s", test.name, err)
+			continue
+		}
+		if serverState.Version != VersionTLS13 || clientState.Version != VersionTLS13 {
+			t.Errorf("%s: got versions %x and %x, want TLS 1.3", test.name, serverState.Version, clientState.Version)
+		}
+		if serverState.CipherSuite != clientState.CipherSuite {
+			t.Errorf("%s: cipher suite mismatch: %x and %x", test.name, serverState.CipherSuite, clientState.CipherSuite)
+		}
+		if clientState.NegotiatedProtocol != "bar" {
+			t.Errorf("%s: got ALPN protocol %q, want %q", test.name, clientState.NegotiatedProtocol, "bar")
+		}
+		if len(clientState.PeerCertificates) != 1 {
+			t.Errorf("%s: got %d peer certificates, want 1", test.name, len(clientState.PeerCertificates))
+		}
+		if clientState.TLSUnique != nil {
+			t.Errorf("%s: TLSUnique should be nil for TLS 1.3", test.name)
+		}
+	}
+}
+
+func TestKeyUpdateTLS13(t *testing.T) {
+	serverConfig := &Config{
+		Certificates: testConfig.Certificates,
+		MaxVersion:   VersionTLS13,
+	}
+	clientConfig := &Config{
+		InsecureSkipVerify: true,
+		MaxVersion:         VersionTLS13,
+	}
+
+	c, s := localPipe()
+	defer c.Close()
+	defer s.Close()
+
+	errChan := make(chan error, 1)
+	go func() {
+		cli := Client(c, clientConfig)
+		buf := make([]byte, 5)
+		if _, err := io.ReadFull(cli, buf); err != nil {
+			errChan <- err
+			return
+		}
+		// The KeyUpdate preceding the data requested an update, so
+		// this write is protected with the next traffic secret.
+		_, err := cli.Write(buf)
+		errChan <- err
+	}()
+
+	srv := Server(s, serverConfig)
+	if err := srv.Handshake(); err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+
+	srv.out.Lock()
+	keyUpdate := &keyUpdateMsg{updateRequested: true}
+	if _, err := srv.writeRecordLocked(recordTypeHandshake, keyUpdate.marshal()); err != nil {
+		t.Fatal(err)
+	}
+	suite := cipherSuiteTLS13ByID(srv.cipherSuite)
+	srv.out.setTrafficSecret(suite, suite.nextTrafficSecret(srv.out.trafficSecret))
+	srv.out.Unlock()
+
+	if _, err := srv.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(srv, buf); err != nil {
+		t.Fatalf("server read failed: %s", err)
+	}
+	if string(buf) != "hello" {
+		t.Errorf("got %q, want %q", buf, "hello")
+	}
+	if err := <-errChan; err != nil {
+		t.Fatalf("client failed: %s", err)
+	}
+}
+
+// downgradeConn removes the TLS 1.3 extensions from the first ClientHello
+// read through it, like an attacker forcing the use of TLS 1.2 would.
+type downgradeConn struct {
+	net.Conn
+	buf     []byte
+	started bool
+}
+
+func (c *downgradeConn) Read(b []byte) (int, error) {
+	if !c.started {
+		c.started = true
+		header := make([]byte, recordHeaderLen)
+		if _, err := io.ReadFull(c.Conn, header); err != nil {
+			return 0, err
+		}
+		body := make([]byte, int(header[3])<<8|int(header[4]))
+		if _, err := io.ReadFull(c.Conn, body); err != nil {
+			return 0, err
+		}
+		var hello clientHelloMsg
+		if !hello.unmarshal(body) {
+			return 0, errors.New("downgradeConn: failed to parse ClientHello")
+		}
+		hello.raw = nil
+		hello.supportedVersions = nil
+		hello.keyShares = nil
+		hello.pskModes = nil
+		msg := hello.marshal()
+		c.buf = append(header[:3], byte(len(msg)>>8), byte(len(msg)))
+		c.buf = append(c.buf, msg...)
+	}
+	if len(c.buf) > 0 {
+		n := copy(b, c.buf)
+		c.buf = c.buf[n:]
+		return n, nil
+	}
+	return c.Conn.Read(b)
+}
+
+func TestDowngradeCanary(t *testing.T) {
+	serverConfig := &Config{
+		Certificates: testConfig.Certificates,
+		MaxVersion:   VersionTLS13,
+	}
+	clientConfig := &Config{
+		InsecureSkipVerify: true,
+		MaxVersion:         VersionTLS13,
+	}
+
+	c, s := localPipe()
+	errChan := make(chan error, 1)
+	go func() {
+		srv := Server(&downgradeConn{Conn: s}, serverConfig)
+		errChan <- srv.Handshake()
+		s.Close()
+	}()
+
+	cli := Client(c, clientConfig)
+	err := cli.Handshake()
+	c.Close()
+	<-errChan
+	if err == nil || !strings.Contains(err.Error(), "downgrade attempt detected") {
+		t.Errorf("got error %v, want a downgrade attempt error", err)
+	}
+
+	// A client that doesn't offer TLS 1.3 must ignore the canary.
+	clientConfig.MaxVersion = VersionTLS12
+	state, _, err := testHandshake(clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if state.Version != VersionTLS12 {
+		t.Errorf("got version %x, want %x", state.Version, VersionTLS12)
+	}
+}
+
+func TestEd25519TLS12(t *testing.T) {
+	serverConfig := &Config{
+		Certificates: []Certificate{{
+			Certificate: [][]byte{testEd25519Certificate},
+			PrivateKey:  testEd25519PrivateKey,
+		}},
+		MaxVersion: VersionTLS12,
+	}
+	// Clients only offer Ed25519 when they also offer TLS 1.3.
+	clientConfig := &Config{
+		InsecureSkipVerify: true,
+		MaxVersion:         VersionTLS13,
+	}
+	_, state, err := testHandshake(clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if state.Version != VersionTLS12 {
+		t.Errorf("got version %x, want %x", state.Version, VersionTLS12)
+	}
+	if suite := mutualCipherSuite([]uint16{state.CipherSuite}, state.CipherSuite); suite == nil || suite.flags&suiteECDSA == 0 {
+		t.Errorf("got cipher suite %x, want an ECDHE_ECDSA suite", state.CipherSuite)
+	}
+
+	clientConfig.MaxVersion = VersionTLS12
+	if _, _, err := testHandshake(clientConfig, serverConfig); err == nil {
+		t.Error("handshake with a client that doesn't support Ed25519 succeeded")
+	}
+}
+
 func TestCipherSuitePreference(t *testing.T) {
 	serverConfig := &Config{
 		CipherSuites: []uint16{TLS_RSA_WITH_RC4_128_SHA, TLS_RSA_WITH_AES_128_CBC_SHA, TLS_ECDHE_RSA_WITH_RC4_128_SHA},
@@ -694,6 +952,23 @@ func runServerTestTLS12(t *testing.T, template *serverTest) {
 	runServerTestForVersion(t, template, "TLSv12-", "-tls1_2")
 }
 
+// runServerTestTLS13 is like runClientTestTLS13, for the server.
+func runServerTestTLS13(t *testing.T, template *serverTest) {
+	if *update {
+		checkOpenSSLVersion(t)
+		if !opensslSupportsTLS13 {
+			t.Fatal("updating the TLS 1.3 test data requires OpenSSL 1.1.1 or later")
+		}
+	}
+	test := *template
+	if test.config == nil {
+		test.config = testConfig
+	}
+	test.config = test.config.Clone()
+	test.config.MaxVersion = VersionTLS13
+	runServerTestForVersion(t, &test, "TLSv13-", "-tls1_3")
+}
+
 func TestHandshakeServerRSARC4(t *testing.T) {
 	test := &serverTest{
 		name:    "RSA-RC4",
@@ -1184,6 +1459,110 @@ func TestClientAuth(t *testing.T) {
 	runServerTestTLS12(t, test)
 }
 
+func TestHandshakeServerTLS13(t *testing.T) {
+	ecdsaConfig := testConfig.Clone()
+	ecdsaConfig.Certificates = []Certificate{{
+		Certificate: [][]byte{testECDSACertificate},
+		PrivateKey:  testECDSAPrivateKey,
+	}}
+	ecdsaConfig.BuildNameToCertificate()
+
+	p256Config := testConfig.Clone()
+	p256Config.CurvePreferences = []CurveID{CurveP256}
+
+	tests := []*serverTest{
+		{
+			name:    "AES128-SHA256",
+			command: []string{"openssl", "s_client", "-no_ticket", "-ciphersuites", "TLS_AES_128_GCM_SHA256"},
+		},
+		{
+			name:    "AES256-SHA384",
+			command: []string{"openssl", "s_client", "-no_ticket", "-ciphersuites", "TLS_AES_256_GCM_SHA384"},
+		},
+		{
+			name:    "CHACHA20-SHA256",
+			command: []string{"openssl", "s_client", "-no_ticket", "-ciphersuites", "TLS_CHACHA20_POLY1305_SHA256"},
+		},
+		{
+			name:   "ECDSA",
+			config: ecdsaConfig,
+		},
+		{
+			name:    "RSA-RSAPSS",
+			command: []string{"openssl", "s_client", "-no_ticket", "-sigalgs", "rsa_pss_rsae_sha256"},
+		},
+		{
+			// The client only sends an X25519 key share, and the
+			// server asks for a P-256 one instead.
+			name:    "HelloRetryRequest",
+			command: []string{"openssl", "s_client", "-no_ticket", "-groups", "X25519:P-256"},
+			config:  p256Config,
+			validate: func(state ConnectionState) error {
+				if state.Version != VersionTLS13 {
+					return fmt.Errorf("got version %x", state.Version)
+				}
+				return nil
+			},
+		},
+	}
+	for _, test := range tests {
+		runServerTestTLS13(t, test)
+	}
+}
+
+func TestClientAuthTLS13(t *testing.T) {
+	clientCert := Certificate{
+		Certificate: [][]byte{testRSACertificate},
+		PrivateKey:  testRSAPrivateKey,
+	}
+	tests := []struct {
+		name       string
+		clientAuth ClientAuthType
+		certs      []Certificate
+		wantCerts  int
+		wantErr    string
+	}{
+		{"NoCertRequested", NoClientCert, []Certificate{clientCert}, 0, ""},
+		{"RequestNoCert", RequestClientCert, nil, 0, ""},
+		{"RequestCert", RequestClientCert, []Certificate{clientCert}, 1, ""},
+		{"RequireMissing", RequireAnyClientCert, nil, 0, "client didn't provide a certificate"},
+		{"RequireECDSA", RequireAnyClientCert, []Certificate{{
+			Certificate: [][]byte{testECDSACertificate},
+			PrivateKey:  testECDSAPrivateKey,
+		}}, 1, ""},
+		{"RequireEd25519", RequireAnyClientCert, []Certificate{{
+			Certificate: [][]byte{testEd25519Certificate},
+			PrivateKey:  testEd25519PrivateKey,
+		}}, 1, ""},
+	}
+	for _, test := range tests {
+		serverConfig := &Config{
+			Certificates: testConfig.Certificates,
+			ClientAuth:   test.clientAuth,
+			MaxVersion:   VersionTLS13,
+		}
+		clientConfig := &Config{
+			Certificates:       test.certs,
+			InsecureSkipVerify: true,
+			MaxVersion:         VersionTLS13,
+		}
+		state, _, err := testHandshake(clientConfig, serverConfig)
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("%s: got error %v, want %q", test.name, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: handshake failed: %s", test.name, err)
+			continue
+		}
+		if len(state.PeerCertificates) != test.wantCerts {
+			t.Errorf("%s: got %d client certificates, want %d", test.name, len(state.PeerCertificates), test.wantCerts)
+		}
+	}
+}
+
 func TestSNIGivenOnFailure(t *testing.T) {
 	const expectedServerName = "test.testing"
 
@@ -1205,10 +1584,15 @@ func TestSNIGivenOnFailure(t *testing.T) {
 		cli.writeRecord(recordTypeHandshake, clientHello.marshal())
 		c.Close()
 	}()
+	conn := Server(s, serverConfig)
+	ch, err := conn.readClientHello()
 	hs := serverHandshakeState{
-		c: Server(s, serverConfig),
+		c:           conn,
+		clientHello: ch,
+	}
+	if err == nil {
+		_, err = hs.processClientHello()
 	}
-	_, err := hs.readClientHello()
 	defer s.Close()
 
 	if err == nil {
@@ -1381,6 +1765,8 @@ var testSNICertificate = fromHex("0441883421114c81480804c430820237308201a0a00302
 
 var testP256Certificate = fromHex("308201693082010ea00302010202105012dc24e1124ade4f3e153326ff27bf300a06082a8648ce3d04030230123110300e060355040a130741636d6520436f301e170d3137303533313232343934375a170d3138303533313232343934375a30123110300e060355040a130741636d6520436f3059301306072a8648ce3d020106082a8648ce3d03010703420004c02c61c9b16283bbcc14956d886d79b358aa614596975f78cece787146abf74c2d5dc578c0992b4f3c631373479ebf3892efe53d21c4f4f1cc9a11c3536b7f75a3463044300e0603551d0f0101ff0404030205a030130603551d25040c300a06082b06010505070301300c0603551d130101ff04023000300f0603551d1104083006820474657374300a06082a8648ce3d0403020349003046022100963712d6226c7b2bef41512d47e1434131aaca3ba585d666c924df71ac0448b3022100f4d05c725064741aef125f243cdbccaa2a5d485927831f221c43023bd5ae471a")
 
+var testEd25519Certificate = fromHex("308201783082012aa00302010202144a34f72b4500e392fac3ae05331337bbbdb82238300506032b657030233110300e060355040a0c0741636d6520436f310f300d06035504030c06476f6c616e673020170d3236313031363130343132315a180f32313236303932323130343132315a30233110300e060355040a0c0741636d6520436f310f300d06035504030c06476f6c616e67302a300506032b65700321009f8a6bf277ed66702ed7da4d7dcc501977e2d5f1ac56f4feb9e1af2c8ac9ceeba36e306c301d0603551d0e041604144ae2012a34b2f019ba6d67fd7e2eaba0a448e165301f0603551d230418301680144ae2012a34b2f019ba6d67fd7e2eaba0a448e165300f0603551d130101ff040530030101ff30190603551d1104123010820e6578616d706c652e676f6c616e67300506032b6570034100ef3c7042618755dcf4ef0bd48189f0fdcb1cfa7a5ce8ecbefb36da6303d62a491f37a18dfb5f001eb349cc4332ba5e31fc6d186559d675ba058dec9ffa6aa10b")
+
 var testRSAPrivateKey = &rsa.PrivateKey{
 	PublicKey: rsa.PublicKey{
 		N: bigFromString("153980389784927331788354528594524332344709972855165340650588877572729725338415474372475094155672066328274535240275856844648695200875763869073572078279316458648124537905600131008790701752441155668003033945258023841165089852359980273279085783159654751552359397986180318708491098942831252291841441726305535546071"),
@@ -1402,4 +1788,6 @@ var testECDSAPrivateKey = &ecdsa.PrivateKey{
 	D: bigFromString("5477294338614160138026852784385529180817726002953041720191098180813046231640184669647735805135001309477695746518160084669446643325196003346204701381388769751"),
 }
 
+var testEd25519PrivateKey = ed25519.NewKeyFromSeed(fromHex("092fbc8b9bd98b25914cd1dae21f3c2c7c9aa86241368f80d26b4eac84eb6d4c"))
+
 var testP256PrivateKey, _ = x509.ParseECPrivateKey(fromHex("30770201010420012f3b52bc54c36ba3577ad45034e2e8efe1e6999851284cb848725cfe029991a00a06082a8648ce3d030107a14403420004c02c61c9b16283bbcc14956d886d79b358aa614596975f78cece787146abf74c2d5dc578c0992b4f3c631373479ebf3892efe53d21c4f4f1cc9a11c3536b7f75"))
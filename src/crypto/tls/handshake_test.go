@@ -42,6 +42,7 @@ var (
 
 	opensslVersionTestOnce sync.Once
 	opensslVersionTestErr  error
+	opensslSupportsTLS13   bool
 )
 
 func checkOpenSSLVersion(t *testing.T) {
@@ -70,11 +71,19 @@ func testOpenSSLVersion() {
 	if strings.HasPrefix(version, "OpenSSL 1.1.0") {
 		return
 	}
+	if strings.HasPrefix(version, "OpenSSL 1.1.1") || strings.HasPrefix(version, "OpenSSL 3.") {
+		// These implement TLS 1.3, but lack some of the older cipher
+		// suites and protocol versions, so they are only suitable for
+		// the TLS 1.3 test data.
+		opensslSupportsTLS13 = true
+		return
+	}
 
 	println("***********************************************")
 	println("")
 	println("You need to build OpenSSL 1.1.0 from source in order")
-	println("to update the test data.")
+	println("to update the test data, or OpenSSL 1.1.1 or later")
+	println("to update the TLS 1.3 test data.")
 	println("")
 	println("Configure it with:")
 	println("./Configure enable-weak-ssl-ciphers enable-ssl3 enable-ssl3-method -static linux-x86_64")
@@ -213,6 +222,38 @@ func parseTestData(r io.Reader) (flows [][]byte, err error) {
 	return flows, nil
 }
 
+// localPipe returns the two ends of a TCP connection over the loopback
+// interface. Unlike with net.Pipe, writes don't wait for the peer to read,
+// which TLS 1.3 needs as the server may be sending session tickets while the
+// client sends its Finished message.
+func localPipe() (net.Conn, net.Conn) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("failed to open local listener: " + err.Error())
+	}
+	defer l.Close()
+
+	type result struct {
+		c   net.Conn
+		err error
+	}
+	accepted := make(chan result, 1)
+	go func() {
+		c, err := l.Accept()
+		accepted <- result{c, err}
+	}()
+
+	c1, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		panic("failed to dial local listener: " + err.Error())
+	}
+	r := <-accepted
+	if r.err != nil {
+		panic("failed to accept local connection: " + r.err.Error())
+	}
+	return c1, r.c
+}
+
 // tempFile creates a temp file containing contents and returns its path.
 func tempFile(contents string) string {
 	file, err := ioutil.TempFile("", "go-tls-test")
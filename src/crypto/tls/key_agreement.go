@@ -7,12 +7,12 @@ package tls
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/md5"
 	"crypto/rsa"
 	"crypto/sha1"
 	"crypto/x509"
-	"encoding/asn1"
 	"errors"
 	"io"
 	"math/big"
@@ -111,16 +111,27 @@ func md5SHA1Hash(slices [][]byte) []byte {
 
 // hashForServerKeyExchange hashes the given slices and returns their digest
 // and the identifier of the hash function used. The signatureAlgorithm argument
-// is only used for >= TLS 1.2 and identifies the hash function to use.
+// is only used for >= TLS 1.2 and identifies the hash function to use. For
+// Ed25519, the slices are concatenated and returned unhashed, with a zero
+// crypto.Hash.
 func hashForServerKeyExchange(sigType uint8, signatureAlgorithm SignatureScheme, version uint16, slices ...[]byte) ([]byte, crypto.Hash, error) {
 	if version >= VersionTLS12 {
-		if !isSupportedSignatureAlgorithm(signatureAlgorithm, supportedSignatureAlgorithms) {
+		// supportedSignatureAlgorithmsTLS13 is a superset of the schemes
+		// used when TLS 1.3 isn't offered.
+		if !isSupportedSignatureAlgorithm(signatureAlgorithm, supportedSignatureAlgorithmsTLS13) {
 			return nil, crypto.Hash(0), errors.New("tls: unsupported hash function used by peer")
 		}
 		hashFunc, err := lookupTLSHash(signatureAlgorithm)
 		if err != nil {
 			return nil, crypto.Hash(0), err
 		}
+		if hashFunc == 0 {
+			var signed []byte
+			for _, slice := range slices {
+				signed = append(signed, slice...)
+			}
+			return signed, hashFunc, nil
+		}
 		h := hashFunc.New()
 		for _, slice := range slices {
 			h.Write(slice)
@@ -156,7 +167,7 @@ func pickTLS12HashForSignature(sigType uint8, clientList []SignatureScheme) (Sig
 		if signatureFromSignatureScheme(sigAlg) != sigType {
 			continue
 		}
-		if isSupportedSignatureAlgorithm(sigAlg, supportedSignatureAlgorithms) {
+		if isSupportedSignatureAlgorithm(sigAlg, supportedSignatureAlgorithmsTLS13) {
 			return sigAlg, nil
 		}
 	}
@@ -247,31 +258,24 @@ NextCandidate:
 	serverECDHParams[3] = byte(len(ecdhePublic))
 	copy(serverECDHParams[4:], ecdhePublic)
 
-	var signatureAlgorithm SignatureScheme
-
-	if ka.version >= VersionTLS12 {
-		var err error
-		signatureAlgorithm, err = pickTLS12HashForSignature(ka.sigType, clientHello.supportedSignatureAlgorithms)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	digest, hashFunc, err := hashForServerKeyExchange(ka.sigType, signatureAlgorithm, ka.version, clientHello.random, hello.random, serverECDHParams)
-	if err != nil {
-		return nil, err
-	}
-
 	priv, ok := cert.PrivateKey.(crypto.Signer)
 	if !ok {
 		return nil, errors.New("tls: certificate private key does not implement crypto.Signer")
 	}
-	var sig []byte
+	sigType := ka.sigType
 	switch ka.sigType {
 	case signatureECDSA:
-		_, ok := priv.Public().(*ecdsa.PublicKey)
-		if !ok {
-			return nil, errors.New("tls: ECDHE ECDSA requires an ECDSA server key")
+		switch priv.Public().(type) {
+		case *ecdsa.PublicKey:
+		case ed25519.PublicKey:
+			// Ed25519 keys are used with the ECDSA cipher suites.
+			// See RFC 8422, Section 5.1.
+			if ka.version < VersionTLS12 {
+				return nil, errors.New("tls: Ed25519 server keys require TLS 1.2")
+			}
+			sigType = signatureEd25519
+		default:
+			return nil, errors.New("tls: ECDHE ECDSA requires an ECDSA or Ed25519 server key")
 		}
 	case signatureRSA:
 		_, ok := priv.Public().(*rsa.PublicKey)
@@ -281,7 +285,22 @@ NextCandidate:
 	default:
 		return nil, errors.New("tls: unknown ECDHE signature algorithm")
 	}
-	sig, err = priv.Sign(config.rand(), digest, hashFunc)
+
+	var signatureAlgorithm SignatureScheme
+	if ka.version >= VersionTLS12 {
+		var err error
+		signatureAlgorithm, err = pickTLS12HashForSignature(sigType, clientHello.supportedSignatureAlgorithms)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	digest, hashFunc, err := hashForServerKeyExchange(sigType, signatureAlgorithm, ka.version, clientHello.random, hello.random, serverECDHParams)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := priv.Sign(config.rand(), digest, hashFunc)
 	if err != nil {
 		return nil, errors.New("tls: failed to sign ECDHE parameters: " + err.Error())
 	}
@@ -377,10 +396,20 @@ func (ka *ecdheKeyAgreement) processServerKeyExchange(config *Config, clientHell
 	}
 
 	var signatureAlgorithm SignatureScheme
+	sigType := ka.sigType
 	if ka.version >= VersionTLS12 {
 		// handle SignatureAndHashAlgorithm
 		signatureAlgorithm = SignatureScheme(sig[0])<<8 | SignatureScheme(sig[1])
-		if signatureFromSignatureScheme(signatureAlgorithm) != ka.sigType {
+		sigType = signatureFromSignatureScheme(signatureAlgorithm)
+		// RSASSA-PSS may be used with RSA cipher suites if the client
+		// offered it, which it does when offering TLS 1.3.
+		// Likewise, Ed25519 may be used with ECDSA cipher suites.
+		if sigType == signatureRSAPSS && ka.sigType == signatureRSA ||
+			sigType == signatureEd25519 && ka.sigType == signatureECDSA {
+			if !isSupportedSignatureAlgorithm(signatureAlgorithm, clientHello.supportedSignatureAlgorithms) {
+				return errServerKeyExchange
+			}
+		} else if sigType != ka.sigType {
 			return errServerKeyExchange
 		}
 		sig = sig[2:]
@@ -398,35 +427,7 @@ func (ka *ecdheKeyAgreement) processServerKeyExchange(config *Config, clientHell
 	if err != nil {
 		return err
 	}
-	switch ka.sigType {
-	case signatureECDSA:
-		pubKey, ok := cert.PublicKey.(*ecdsa.PublicKey)
-		if !ok {
-			return errors.New("tls: ECDHE ECDSA requires a ECDSA server public key")
-		}
-		ecdsaSig := new(ecdsaSignature)
-		if _, err := asn1.Unmarshal(sig, ecdsaSig); err != nil {
-			return err
-		}
-		if ecdsaSig.R.Sign() <= 0 || ecdsaSig.S.Sign() <= 0 {
-			return errors.New("tls: ECDSA signature contained zero or negative values")
-		}
-		if !ecdsa.Verify(pubKey, digest, ecdsaSig.R, ecdsaSig.S) {
-			return errors.New("tls: ECDSA verification failure")
-		}
-	case signatureRSA:
-		pubKey, ok := cert.PublicKey.(*rsa.PublicKey)
-		if !ok {
-			return errors.New("tls: ECDHE RSA requires a RSA server public key")
-		}
-		if err := rsa.VerifyPKCS1v15(pubKey, hashFunc, digest, sig); err != nil {
-			return err
-		}
-	default:
-		return errors.New("tls: unknown ECDHE signature algorithm")
-	}
-
-	return nil
+	return verifyHandshakeSignature(sigType, cert.PublicKey, hashFunc, digest, sig)
 }
 
 func (ka *ecdheKeyAgreement) generateClientKeyExchange(config *Config, clientHello *clientHelloMsg, cert *x509.Certificate) ([]byte, *clientKeyExchangeMsg, error) {
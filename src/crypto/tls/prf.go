@@ -177,9 +177,12 @@ func keysFromMasterSecret(version uint16, suite *cipherSuite, masterSecret, clie
 }
 
 // lookupTLSHash looks up the corresponding crypto.Hash for a given
-// hash from a TLS SignatureScheme.
+// hash from a TLS SignatureScheme. It returns zero for Ed25519, which
+// signs the message directly.
 func lookupTLSHash(signatureAlgorithm SignatureScheme) (crypto.Hash, error) {
 	switch signatureAlgorithm {
+	case Ed25519:
+		return 0, nil
 	case PKCS1WithSHA1, ECDSAWithSHA1:
 		return crypto.SHA1, nil
 	case PKCS1WithSHA256, PSSWithSHA256, ECDSAWithP256AndSHA256:
@@ -313,7 +316,7 @@ func (h finishedHash) serverSum(masterSecret []byte) []byte {
 // client's CertificateVerify with, or an error if none can be found.
 func (h finishedHash) selectClientCertSignatureAlgorithm(serverList []SignatureScheme, sigType uint8) (SignatureScheme, error) {
 	for _, v := range serverList {
-		if signatureFromSignatureScheme(v) == sigType && isSupportedSignatureAlgorithm(v, supportedSignatureAlgorithms) {
+		if signatureFromSignatureScheme(v) == sigType && isSupportedSignatureAlgorithm(v, supportedSignatureAlgorithmsTLS13) {
 			return v, nil
 		}
 	}
@@ -343,6 +346,9 @@ func (h finishedHash) hashForClientCertificate(sigType uint8, signatureAlgorithm
 		if err != nil {
 			return nil, 0, err
 		}
+		if hashAlg == 0 {
+			return h.buffer, 0, nil
+		}
 		hash := hashAlg.New()
 		hash.Write(h.buffer)
 		return hash.Sum(nil), hashAlg, nil
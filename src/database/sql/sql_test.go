@@ -1788,6 +1788,9 @@ func TestMaxIdleConns(t *testing.T) {
 	if got := len(db.freeConn); got != 0 {
 		t.Errorf("freeConns after set to zero = %d; want 0", got)
 	}
+	if got := db.Stats().MaxIdleClosed; got != 1 {
+		t.Errorf("MaxIdleClosed = %d; want 1", got)
+	}
 
 	tx, err = db.Begin()
 	if err != nil {
@@ -1797,6 +1800,9 @@ func TestMaxIdleConns(t *testing.T) {
 	if got := len(db.freeConn); got != 0 {
 		t.Errorf("freeConns = %d; want 0", got)
 	}
+	if got := db.Stats().MaxIdleClosed; got != 2 {
+		t.Errorf("MaxIdleClosed = %d; want 2", got)
+	}
 }
 
 func TestMaxOpenConns(t *testing.T) {
@@ -2088,6 +2094,14 @@ func TestStats(t *testing.T) {
 	}
 	tx.Commit()
 
+	stats = db.Stats()
+	if got := stats.Idle; got != 1 {
+		t.Errorf("stats.Idle = %d; want 1", got)
+	}
+	if got := stats.InUse; got != 0 {
+		t.Errorf("stats.InUse = %d; want 0", got)
+	}
+
 	closeDB(t, db)
 	stats = db.Stats()
 	if got := stats.OpenConnections; got != 0 {
@@ -2177,6 +2191,95 @@ func TestConnMaxLifetime(t *testing.T) {
 	if closes != 1 {
 		t.Errorf("closes = %d; want 1", closes)
 	}
+	if s := db.Stats(); s.MaxLifetimeClosed != 1 {
+		t.Errorf("MaxLifetimeClosed = %d; want 1", s.MaxLifetimeClosed)
+	}
+}
+
+func TestStatsWait(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetMaxOpenConns(1)
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := db.Conn(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Conn with busy pool: got err %v; want %v", err, context.DeadlineExceeded)
+	}
+	conn.Close()
+
+	stats := db.Stats()
+	if got := stats.MaxOpenConnections; got != 1 {
+		t.Errorf("stats.MaxOpenConnections = %d; want 1", got)
+	}
+	if got := stats.WaitCount; got != 1 {
+		t.Errorf("stats.WaitCount = %d; want 1", got)
+	}
+	if stats.WaitDuration <= 0 {
+		t.Errorf("stats.WaitDuration = %v; want > 0", stats.WaitDuration)
+	}
+}
+
+func TestConnMaxIdleTime(t *testing.T) {
+	t0 := time.Unix(1000000, 0)
+	offset := time.Duration(0)
+
+	nowFunc = func() time.Time { return t0.Add(offset) }
+	defer func() { nowFunc = time.Now }()
+
+	db := newTestDB(t, "magicquery")
+	defer closeDB(t, db)
+
+	db.SetMaxIdleConns(10)
+	db.SetMaxOpenConns(10)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx2, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Commit()
+
+	// Return the second conn later, so that only the first
+	// exceeds the idle time limit.
+	offset = 5 * time.Second
+	tx2.Commit()
+
+	if g, w := db.numFreeConns(), 2; g < w {
+		t.Fatalf("free conns = %d; want at least %d", g, w)
+	}
+	free := db.numFreeConns()
+
+	offset = 11 * time.Second
+	db.SetConnMaxIdleTime(10 * time.Second)
+
+	db.mu.Lock()
+	closing := db.connectionCleanerRunLocked()
+	db.mu.Unlock()
+	for _, c := range closing {
+		c.Close()
+	}
+
+	if g, w := len(closing), free-1; g != w {
+		t.Errorf("closed conns = %d; want %d", g, w)
+	}
+	if g, w := db.numFreeConns(), 1; g != w {
+		t.Errorf("free conns = %d; want %d", g, w)
+	}
+	if g, w := db.Stats().MaxIdleTimeClosed, int64(free-1); g != w {
+		t.Errorf("MaxIdleTimeClosed = %d; want %d", g, w)
+	}
+	if g := db.Stats().MaxLifetimeClosed; g != 0 {
+		t.Errorf("MaxLifetimeClosed = %d; want 0", g)
+	}
 }
 
 // golang.org/issue/5323
@@ -204,6 +204,14 @@
 		fmt.Sprintf("%d %d %#[1]x %#x", 16, 17)
 	will yield "16 17 0x10 0x11".
 
+	Wrapping errors:
+
+	If the format specifier includes a %w verb with an error operand,
+	the error returned by Errorf will implement an Unwrap method returning
+	the operand. It is invalid to include more than one %w verb or to
+	supply it with an operand that does not implement the error
+	interface. The %w verb is otherwise a synonym for %v.
+
 	Format errors:
 
 	If an invalid argument is given for a verb, such as providing
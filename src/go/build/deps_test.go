@@ -34,7 +34,7 @@ import (
 //
 var pkgDeps = map[string][]string{
 	// L0 is the lowest level, core, nearly unavoidable packages.
-	"errors":                  {},
+	"errors":                  {"internal/reflectlite"},
 	"io":                      {"errors", "sync", "sync/atomic"},
 	"runtime":                 {"unsafe", "runtime/internal/atomic", "runtime/internal/sys"},
 	"runtime/internal/sys":    {},
@@ -44,6 +44,8 @@ var pkgDeps = map[string][]string{
 	"sync/atomic":             {"unsafe"},
 	"unsafe":                  {},
 	"internal/cpu":            {"runtime"},
+	"internal/reflectlite":    {"runtime", "unsafe"},
+	"internal/oserror":        {"errors"},
 
 	"L0": {
 		"errors",
@@ -54,6 +56,8 @@ var pkgDeps = map[string][]string{
 		"sync/atomic",
 		"unsafe",
 		"internal/cpu",
+		"internal/reflectlite",
+		"internal/oserror",
 	},
 
 	// L1 adds simple functions and strings processing,
@@ -173,8 +177,9 @@ var pkgDeps = map[string][]string{
 	},
 
 	// Formatted I/O: few dependencies (L1) but we must add reflect.
-	"fmt": {"L1", "os", "reflect"},
-	"log": {"L1", "os", "fmt", "time"},
+	"fmt":          {"L1", "os", "reflect"},
+	"log":          {"L1", "os", "fmt", "time", "log/internal"},
+	"log/internal": {},
 
 	// Packages used by testing must be low-level (L2+fmt).
 	"regexp":         {"L2", "regexp/syntax"},
@@ -184,7 +189,7 @@ var pkgDeps = map[string][]string{
 	"runtime/trace":  {"L0", "context", "fmt"},
 	"text/tabwriter": {"L2"},
 
-	"testing":          {"L2", "flag", "fmt", "internal/race", "os", "runtime/debug", "runtime/pprof", "runtime/trace", "time"},
+	"testing":          {"L2", "flag", "fmt", "internal/race", "io/ioutil", "os", "reflect", "runtime/debug", "runtime/pprof", "runtime/trace", "time"},
 	"testing/iotest":   {"L2", "log"},
 	"testing/quick":    {"L2", "flag", "fmt", "reflect", "time"},
 	"internal/testenv": {"L2", "OS", "flag", "testing", "syscall"},
@@ -303,12 +308,19 @@ var pkgDeps = map[string][]string{
 	// Internal package used only for testing.
 	"os/signal/internal/pty": {"CGO", "fmt", "os", "syscall"},
 
+	// IP address value types; used by net.
+	"net/netip": {"L1"},
+
+	// DNS messages, used by net's resolver.
+	"net/dnsmessage": {"L1", "net/netip"},
+
 	// Basic networking.
 	// Because net must be used by any package that wants to
 	// do networking portably, it must have a small dependency set: just L0+basic os.
 	"net": {
 		"L0", "CGO",
 		"context", "math/rand", "os", "reflect", "sort", "syscall", "time",
+		"net/netip", "net/dnsmessage",
 		"internal/nettrace", "internal/poll",
 		"internal/syscall/windows", "internal/singleflight", "internal/race",
 		"golang_org/x/net/lif", "golang_org/x/net/route",
@@ -322,6 +334,9 @@ var pkgDeps = map[string][]string{
 		"net/url",
 	},
 
+	// Structured logging.
+	"log/slog": {"L4", "context", "encoding", "encoding/json", "log/internal"},
+
 	// Uses of networking.
 	"log/syslog":    {"L4", "OS", "net"},
 	"net/mail":      {"L4", "NET", "OS", "mime"},
@@ -363,10 +378,15 @@ var pkgDeps = map[string][]string{
 	"crypto/elliptic": {"L4", "CRYPTO", "math/big"},
 	"crypto/rsa":      {"L4", "CRYPTO", "crypto/rand", "math/big"},
 
+	// Ed25519 only needs crypto/rand for key generation.
+	"crypto/ed25519":                       {"L3", "CRYPTO", "crypto/rand", "crypto/ed25519/internal/edwards25519"},
+	"crypto/ed25519/internal/edwards25519": {"encoding/binary"},
+
 	"CRYPTO-MATH": {
 		"CRYPTO",
 		"crypto/dsa",
 		"crypto/ecdsa",
+		"crypto/ed25519",
 		"crypto/elliptic",
 		"crypto/rand",
 		"crypto/rsa",
@@ -394,6 +414,7 @@ var pkgDeps = map[string][]string{
 	"net/http": {
 		"L4", "NET", "OS",
 		"compress/gzip",
+		"compress/zlib",
 		"container/list",
 		"context",
 		"crypto/rand",
@@ -416,10 +437,11 @@ var pkgDeps = map[string][]string{
 	// HTTP-using packages.
 	"expvar":             {"L4", "OS", "encoding/json", "net/http"},
 	"net/http/cgi":       {"L4", "NET", "OS", "crypto/tls", "net/http", "regexp"},
-	"net/http/cookiejar": {"L4", "NET", "net/http"},
+	"net/http/cookiejar": {"L4", "NET", "encoding/json", "net/http"},
 	"net/http/fcgi":      {"L4", "NET", "OS", "context", "net/http", "net/http/cgi"},
 	"net/http/httptest":  {"L4", "NET", "OS", "crypto/tls", "flag", "net/http", "net/http/internal", "crypto/x509"},
-	"net/http/httputil":  {"L4", "NET", "OS", "context", "net/http", "net/http/internal"},
+	"net/http/httputil":  {"L4", "NET", "OS", "context", "net/http", "net/http/internal", "golang_org/x/net/lex/httplex"},
+	"net/http/websocket": {"L4", "NET", "OS", "CRYPTO", "compress/flate", "context", "crypto/rand", "encoding/base64", "encoding/binary", "net/http"},
 	"net/http/pprof":     {"L4", "OS", "html/template", "net/http", "runtime/pprof", "runtime/trace"},
 	"net/rpc":            {"L4", "NET", "encoding/gob", "html/template", "net/http"},
 	"net/rpc/jsonrpc":    {"L4", "NET", "encoding/json", "net/rpc"},
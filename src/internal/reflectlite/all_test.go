@@ -0,0 +1,101 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reflectlite_test
+
+import (
+	. "internal/reflectlite"
+	"io"
+	"os"
+	"testing"
+)
+
+type myError struct{}
+
+func (*myError) Error() string { return "my error" }
+
+var (
+	errorType  = TypeOf((*error)(nil)).Elem()
+	readerType = TypeOf((*io.Reader)(nil)).Elem()
+)
+
+func TestTypeOf(t *testing.T) {
+	if TypeOf(nil) != nil {
+		t.Errorf("TypeOf(nil) != nil")
+	}
+	typ := TypeOf(&myError{})
+	if typ.Kind() != Ptr || typ.String() != "*reflectlite_test.myError" {
+		t.Errorf("TypeOf(&myError{}) = %v (kind %v)", typ, typ.Kind())
+	}
+	if e := typ.Elem(); e.Name() != "myError" || e.PkgPath() != "internal/reflectlite_test" || e.Kind() != Struct {
+		t.Errorf("Elem = %v, name %q, pkg %q, kind %v", e, e.Name(), e.PkgPath(), e.Kind())
+	}
+	if errorType.Kind() != Interface || errorType.Name() != "error" {
+		t.Errorf("error type = %v (kind %v)", errorType, errorType.Kind())
+	}
+}
+
+func TestImplements(t *testing.T) {
+	tests := []struct {
+		x    interface{}
+		t    Type
+		want bool
+	}{
+		{&myError{}, errorType, true},
+		{myError{}, errorType, false},
+		{&os.PathError{}, errorType, true},
+		{os.Stdin, readerType, true},
+		{"string", readerType, false},
+	}
+	for _, tt := range tests {
+		if got := TypeOf(tt.x).Implements(tt.t); got != tt.want {
+			t.Errorf("%T implements %v = %v, want %v", tt.x, tt.t, got, tt.want)
+		}
+		if got := TypeOf(tt.x).AssignableTo(tt.t); got != tt.want {
+			t.Errorf("%T assignable to %v = %v, want %v", tt.x, tt.t, got, tt.want)
+		}
+	}
+	if !TypeOf(3).Comparable() || TypeOf([]int(nil)).Comparable() {
+		t.Errorf("Comparable reports wrong results for int and []int")
+	}
+}
+
+func TestSet(t *testing.T) {
+	var err error
+	v := ValueOf(&err).Elem()
+	if !v.CanSet() || v.Kind() != Interface || !v.IsNil() {
+		t.Fatalf("ValueOf(&err).Elem() = settable %v, kind %v, nil %v", v.CanSet(), v.Kind(), v.IsNil())
+	}
+	e := &myError{}
+	v.Set(ValueOf(e))
+	if err != e {
+		t.Errorf("after Set, err = %v, want %v", err, e)
+	}
+
+	var pe *os.PathError
+	p := &os.PathError{Op: "open"}
+	ValueOf(&pe).Elem().Set(ValueOf(p))
+	if pe != p {
+		t.Errorf("after Set, pe = %v, want %v", pe, p)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Set of unaddressable value did not panic")
+		}
+	}()
+	ValueOf(err).Set(ValueOf(e))
+}
+
+func TestLen(t *testing.T) {
+	if n := ValueOf("hello").Len(); n != 5 {
+		t.Errorf("Len of string = %d, want 5", n)
+	}
+	if n := ValueOf([]int{1, 2}).Len(); n != 2 {
+		t.Errorf("Len of slice = %d, want 2", n)
+	}
+	if n := ValueOf([3]byte{}).Len(); n != 3 {
+		t.Errorf("Len of array = %d, want 3", n)
+	}
+}
@@ -1062,7 +1062,8 @@ const (
 	EvUserTaskEnd       = 46 // end of task [timestamp, internal task id, stack]
 	EvUserSpan          = 47 // trace.WithSpan [timestamp, internal task id, mode(0:start, 1:end), stack, name string]
 	EvUserLog           = 48 // trace.Log [timestamp, internal id, key string id, stack, value string]
-	EvCount             = 49
+	EvMemoryLimitGC     = 49 // GC cycle paced by the memory limit [timestamp, memory limit]
+	EvCount             = 50
 )
 
 var EventDescriptions = [EvCount]struct {
@@ -1121,4 +1122,5 @@ var EventDescriptions = [EvCount]struct {
 	EvUserTaskEnd:       {"UserTaskEnd", 1011, true, []string{"taskid"}, nil},
 	EvUserSpan:          {"UserSpan", 1011, true, []string{"taskid", "mode", "typeid"}, []string{"name"}},
 	EvUserLog:           {"UserLog", 1011, true, []string{"id", "keyid"}, []string{"category", "message"}},
+	EvMemoryLimitGC:     {"MemoryLimitGC", 1011, false, []string{"mem"}, nil},
 }
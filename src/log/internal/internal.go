@@ -0,0 +1,14 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package internal contains definitions used by both log and log/slog.
+package internal
+
+// DefaultOutput holds a function which calls the default log.Logger's
+// output function. It allows the default log/slog handler to write
+// through the log package without exporting its internals.
+//
+// The pc argument identifies the logging call site; if it is zero, the
+// caller of the logging function is used.
+var DefaultOutput func(pc uintptr, data []byte) error
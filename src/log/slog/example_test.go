@@ -0,0 +1,71 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slog_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+)
+
+// removeTime removes the time attribute so that example output is
+// deterministic.
+func removeTime(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.TimeKey && len(groups) == 0 {
+		return slog.Attr{}
+	}
+	return a
+}
+
+func ExampleNewTextHandler() {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{ReplaceAttr: removeTime}))
+	logger.Info("hello", "count", 3, slog.Group("request", "method", "GET", "path", "/index.html"))
+	logger.With("id", 42).Warn("disk full", "free", 0.5)
+	// Output:
+	// level=INFO msg=hello count=3 request.method=GET request.path=/index.html
+	// level=WARN msg="disk full" id=42 free=0.5
+}
+
+func ExampleNewJSONHandler() {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{ReplaceAttr: removeTime}))
+	logger.WithGroup("request").Info("hello", "method", "GET", slog.Bool("cached", true))
+	// Output:
+	// {"level":"INFO","msg":"hello","request":{"method":"GET","cached":true}}
+}
+
+func ExampleHandlerOptions_level() {
+	var level slog.LevelVar
+	level.Set(slog.LevelWarn)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level:       &level,
+		ReplaceAttr: removeTime,
+	}))
+	logger.Info("not printed")
+	logger.Warn("printed")
+
+	// Lower the level at run time.
+	level.Set(slog.LevelDebug)
+	logger.Debug("now printed")
+	logger.Log(context.Background(), slog.LevelDebug-2, "still not printed")
+	// Output:
+	// level=WARN msg=printed
+	// level=DEBUG msg="now printed"
+}
+
+type Token string
+
+// LogValue implements slog.LogValuer.
+// It avoids revealing the token.
+func (Token) LogValue() slog.Value {
+	return slog.StringValue("REDACTED_TOKEN")
+}
+
+func ExampleLogValuer_secret() {
+	t := Token("shhhh!")
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{ReplaceAttr: removeTime}))
+	logger.Info("permission granted", "user", "Perry", "token", t)
+	// Output:
+	// level=INFO msg="permission granted" user=Perry token=REDACTED_TOKEN
+}
@@ -0,0 +1,544 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A Handler handles log records produced by a Logger.
+//
+// A typical handler may print log records to standard error,
+// or write them to a file or database, or perhaps augment them
+// with additional attributes and pass them on to another handler.
+//
+// Any of the Handler's methods may be called concurrently with itself
+// or with other methods. It is the responsibility of the Handler to
+// manage this concurrency.
+//
+// Users of the slog package should not invoke Handler methods directly.
+// They should use the methods of Logger instead.
+type Handler interface {
+	// Enabled reports whether the handler handles records at the given level.
+	// The handler ignores records whose level is lower.
+	// It is called early, before any arguments are processed,
+	// to save effort if the log event should be discarded.
+	// If called from a Logger method, the first argument is the context
+	// passed to that method, or context.Background() if nil was passed
+	// or the method does not take a context.
+	// The context is passed so Enabled can use its values
+	// to make a decision.
+	Enabled(context.Context, Level) bool
+
+	// Handle handles the Record.
+	// It will only be called when Enabled returns true.
+	// The Context argument is as for Enabled.
+	// It is present solely to provide Handlers access to the context's values.
+	// Canceling the context should not affect record processing.
+	//
+	// Handle methods that produce output should observe the following rules:
+	//   - If r.Time is the zero time, ignore the time.
+	//   - If r.PC is zero, ignore it.
+	//   - Attr's values should be resolved.
+	//   - If an Attr's key and value are both the zero value, ignore the Attr.
+	//   - If a group's key is empty, inline the group's Attrs.
+	//   - If a group has no Attrs (even if it has a non-empty key),
+	//     ignore it.
+	Handle(context.Context, Record) error
+
+	// WithAttrs returns a new Handler whose attributes consist of
+	// both the receiver's attributes and the arguments.
+	// The Handler owns the slice: it may retain, modify or discard it.
+	WithAttrs(attrs []Attr) Handler
+
+	// WithGroup returns a new Handler with the given group appended to
+	// the receiver's existing groups.
+	// The keys of all subsequent attributes, whether added by With or in a
+	// Record, should be qualified by the sequence of group names.
+	//
+	// If the name is empty, WithGroup returns the receiver.
+	WithGroup(name string) Handler
+}
+
+type defaultHandler struct {
+	ch *commonHandler
+	// internal.DefaultOutput, except for testing
+	output func(pc uintptr, data []byte) error
+}
+
+func newDefaultHandler(output func(uintptr, []byte) error) *defaultHandler {
+	return &defaultHandler{
+		ch:     &commonHandler{json: false},
+		output: output,
+	}
+}
+
+func (*defaultHandler) Enabled(_ context.Context, l Level) bool {
+	return l >= LevelInfo
+}
+
+// Collect the level, attributes and message in a string and
+// write it with the default log.Logger.
+// Let the log.Logger handle time and file/line.
+func (h *defaultHandler) Handle(ctx context.Context, r Record) error {
+	buf := make([]byte, 0, 1024)
+	buf = append(buf, r.Level.String()...)
+	buf = append(buf, ' ')
+	buf = append(buf, r.Message...)
+	s := h.ch.newHandleState(buf, " ")
+	s.appendNonBuiltIns(r)
+	return h.output(r.PC, s.buf)
+}
+
+func (h *defaultHandler) WithAttrs(as []Attr) Handler {
+	return &defaultHandler{h.ch.withAttrs(as), h.output}
+}
+
+func (h *defaultHandler) WithGroup(name string) Handler {
+	return &defaultHandler{h.ch.withGroup(name), h.output}
+}
+
+// HandlerOptions are options for a TextHandler or JSONHandler.
+// A zero HandlerOptions consists entirely of default values.
+type HandlerOptions struct {
+	// AddSource causes the handler to compute the source code position
+	// of the log statement and add a SourceKey attribute to the output.
+	AddSource bool
+
+	// Level reports the minimum record level that will be logged.
+	// The handler discards records with lower levels.
+	// If Level is nil, the handler assumes LevelInfo.
+	// The handler calls Level.Level for each record processed;
+	// to adjust the minimum level dynamically, use a LevelVar.
+	Level Leveler
+
+	// ReplaceAttr is called to rewrite each non-group attribute before it is logged.
+	// The attribute's value has been resolved (see Value.Resolve).
+	// If ReplaceAttr returns a zero Attr, the attribute is discarded.
+	//
+	// The built-in attributes with keys "time", "level", "source", and "msg"
+	// are passed to this function, except that time is omitted
+	// if zero, and source is omitted if AddSource is false.
+	//
+	// The first argument is a list of currently open groups that contain the
+	// Attr. It must not be retained or modified. ReplaceAttr is never called
+	// for Group attributes, only their contents. For example, the attribute
+	// list
+	//
+	//	Int("a", 1), Group("g", Int("b", 2)), Int("c", 3)
+	//
+	// results in consecutive calls to ReplaceAttr with the following arguments:
+	//
+	//	nil, Int("a", 1)
+	//	[]string{"g"}, Int("b", 2)
+	//	nil, Int("c", 3)
+	//
+	// ReplaceAttr can be used to change the default keys of the built-in
+	// attributes, convert types (for example, to replace a time.Time with the
+	// integer seconds since the Unix epoch), sanitize personal information, or
+	// remove attributes from the output.
+	ReplaceAttr func(groups []string, a Attr) Attr
+}
+
+// Keys for "built-in" attributes.
+const (
+	// TimeKey is the key used by the built-in handlers for the time
+	// when the log method is called. The associated Value is a time.Time.
+	TimeKey = "time"
+	// LevelKey is the key used by the built-in handlers for the level
+	// of the log call. The associated value is a Level.
+	LevelKey = "level"
+	// MessageKey is the key used by the built-in handlers for the
+	// message of the log call. The associated value is a string.
+	MessageKey = "msg"
+	// SourceKey is the key used by the built-in handlers for the source file
+	// and line of the log call. The associated value is a *Source.
+	SourceKey = "source"
+)
+
+// commonHandler holds the state shared by TextHandler, JSONHandler and
+// the default handler.
+type commonHandler struct {
+	json              bool // true => output JSON; false => output text
+	opts              HandlerOptions
+	preformattedAttrs []byte
+	// groupPrefix is for the text handler only.
+	// It holds the prefix for groups that were already pre-formatted.
+	// A group will appear here when a call to WithGroup is followed by
+	// a call to WithAttrs.
+	groupPrefix string
+	groups      []string // all groups started from WithGroup
+	nOpenGroups int      // the number of groups opened in preformattedAttrs
+	mu          *sync.Mutex
+	w           io.Writer
+}
+
+func (h *commonHandler) clone() *commonHandler {
+	// We can't use assignment because we can't copy the mutex.
+	return &commonHandler{
+		json:              h.json,
+		opts:              h.opts,
+		preformattedAttrs: h.preformattedAttrs[:len(h.preformattedAttrs):len(h.preformattedAttrs)],
+		groupPrefix:       h.groupPrefix,
+		groups:            h.groups[:len(h.groups):len(h.groups)],
+		nOpenGroups:       h.nOpenGroups,
+		w:                 h.w,
+		mu:                h.mu, // mutex shared among all clones of this handler
+	}
+}
+
+// enabled reports whether l is greater than or equal to the
+// minimum level.
+func (h *commonHandler) enabled(l Level) bool {
+	minLevel := LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return l >= minLevel
+}
+
+func (h *commonHandler) withAttrs(as []Attr) *commonHandler {
+	// We are going to ignore empty groups, so if the entire slice consists of
+	// them, there is nothing to do.
+	if countEmptyGroups(as) == len(as) {
+		return h
+	}
+	h2 := h.clone()
+	// Pre-format the attributes as an optimization.
+	s := h2.newHandleState(h2.preformattedAttrs, "")
+	s.prefix = append(s.prefix, h.groupPrefix...)
+	if pfa := h2.preformattedAttrs; len(pfa) > 0 {
+		s.sep = h.attrSep()
+		if h2.json && pfa[len(pfa)-1] == '{' {
+			s.sep = ""
+		}
+	}
+	// Remember the position in the buffer, in case all attrs are empty.
+	pos := len(s.buf)
+	s.openGroups()
+	if !s.appendAttrs(as) {
+		s.buf = s.buf[:pos]
+	} else {
+		// Remember the new prefix for later keys.
+		h2.groupPrefix = string(s.prefix)
+		// Remember how many opened groups are in preformattedAttrs,
+		// so we don't open them again when we handle a Record.
+		h2.nOpenGroups = len(h2.groups)
+	}
+	h2.preformattedAttrs = s.buf
+	return h2
+}
+
+func (h *commonHandler) withGroup(name string) *commonHandler {
+	h2 := h.clone()
+	h2.groups = append(h2.groups, name)
+	return h2
+}
+
+// handle is the internal implementation of Handler.Handle
+// used by TextHandler and JSONHandler.
+func (h *commonHandler) handle(r Record) error {
+	var buf []byte
+	if h.json {
+		buf = append(buf, '{')
+	}
+	s := h.newHandleState(buf, "")
+	// Built-in attributes. They are not in a group.
+	stateGroups := s.groups
+	s.groups = nil // So ReplaceAttrs sees no groups instead of the pre groups.
+	rep := h.opts.ReplaceAttr
+	// time
+	if !r.Time.IsZero() {
+		key := TimeKey
+		val := r.Time.Round(0) // strip monotonic to match Attr behavior
+		if rep == nil {
+			s.appendKey(key)
+			s.appendTime(val)
+		} else {
+			s.appendAttr(Time(key, val))
+		}
+	}
+	// level
+	key := LevelKey
+	val := r.Level
+	if rep == nil {
+		s.appendKey(key)
+		s.appendString(val.String())
+	} else {
+		s.appendAttr(Any(key, val))
+	}
+	// source
+	if h.opts.AddSource {
+		s.appendAttr(Any(SourceKey, r.source()))
+	}
+	key = MessageKey
+	msg := r.Message
+	if rep == nil {
+		s.appendKey(key)
+		s.appendString(msg)
+	} else {
+		s.appendAttr(String(key, msg))
+	}
+	s.groups = stateGroups // Restore groups passed to ReplaceAttrs.
+	s.appendNonBuiltIns(r)
+	s.buf = append(s.buf, '\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(s.buf)
+	return err
+}
+
+// attrSep returns the separator between attributes.
+func (h *commonHandler) attrSep() string {
+	if h.json {
+		return ","
+	}
+	return " "
+}
+
+// handleState holds state for a single call to commonHandler.handle.
+// The initial value of sep determines whether to emit a separator
+// before the next key, after which it stays true.
+type handleState struct {
+	h      *commonHandler
+	buf    []byte
+	sep    string   // separator to write before next key
+	prefix []byte   // for text: key prefix
+	groups []string // the open groups, for ReplaceAttr
+}
+
+func (h *commonHandler) newHandleState(buf []byte, sep string) *handleState {
+	s := &handleState{
+		h:   h,
+		buf: buf,
+		sep: sep,
+	}
+	if h.opts.ReplaceAttr != nil {
+		s.groups = append([]string(nil), h.groups[:h.nOpenGroups]...)
+	}
+	return s
+}
+
+func (s *handleState) appendNonBuiltIns(r Record) {
+	// preformatted Attrs
+	if pfa := s.h.preformattedAttrs; len(pfa) > 0 {
+		s.buf = append(s.buf, s.sep...)
+		s.buf = append(s.buf, pfa...)
+		s.sep = s.h.attrSep()
+		if s.h.json && pfa[len(pfa)-1] == '{' {
+			s.sep = ""
+		}
+	}
+	// Attrs in Record -- unlike the built-in ones, they are in groups started
+	// from WithGroup.
+	// If the record has no Attrs, don't output any groups.
+	nOpenGroups := s.h.nOpenGroups
+	if r.NumAttrs() > 0 {
+		s.prefix = append(s.prefix[:0], s.h.groupPrefix...)
+		// The group may turn out to be empty even though it has attrs (for
+		// example, ReplaceAttr may delete all the attrs).
+		// So remember where we are in the buffer, to restore the position
+		// later if necessary.
+		pos := len(s.buf)
+		s.openGroups()
+		nOpenGroups = len(s.h.groups)
+		empty := true
+		r.Attrs(func(a Attr) bool {
+			if s.appendAttr(a) {
+				empty = false
+			}
+			return true
+		})
+		if empty {
+			s.buf = s.buf[:pos]
+			nOpenGroups = s.h.nOpenGroups
+		}
+	}
+	if s.h.json {
+		// Close all open groups.
+		for range s.h.groups[:nOpenGroups] {
+			s.buf = append(s.buf, '}')
+		}
+		// Close the top-level object.
+		s.buf = append(s.buf, '}')
+	}
+}
+
+// openGroups opens the groups started by WithGroup that are not yet
+// part of the preformatted attributes.
+func (s *handleState) openGroups() {
+	for _, n := range s.h.groups[s.h.nOpenGroups:] {
+		s.openGroup(n)
+	}
+}
+
+// Separator for group names and keys.
+const keyComponentSep = '.'
+
+// openGroup starts a new group of attributes
+// with the given name.
+func (s *handleState) openGroup(name string) {
+	if s.h.json {
+		s.appendKey(name)
+		s.buf = append(s.buf, '{')
+		s.sep = ""
+	} else {
+		s.prefix = append(s.prefix, name...)
+		s.prefix = append(s.prefix, keyComponentSep)
+	}
+	// Collect group names for ReplaceAttr.
+	if s.h.opts.ReplaceAttr != nil {
+		s.groups = append(s.groups, name)
+	}
+}
+
+// closeGroup ends the group with the given name.
+func (s *handleState) closeGroup(name string) {
+	if s.h.json {
+		s.buf = append(s.buf, '}')
+	} else {
+		s.prefix = s.prefix[:len(s.prefix)-len(name)-1 /* for keyComponentSep */]
+	}
+	s.sep = s.h.attrSep()
+	if s.h.opts.ReplaceAttr != nil {
+		s.groups = s.groups[:len(s.groups)-1]
+	}
+}
+
+// appendAttrs appends the slice of Attrs.
+// It reports whether something was appended.
+func (s *handleState) appendAttrs(as []Attr) bool {
+	nonEmpty := false
+	for _, a := range as {
+		if s.appendAttr(a) {
+			nonEmpty = true
+		}
+	}
+	return nonEmpty
+}
+
+// appendAttr appends the Attr's key and value.
+// It handles replacement and checking for an empty key.
+// It reports whether something was appended.
+func (s *handleState) appendAttr(a Attr) bool {
+	a.Value = a.Value.Resolve()
+	if rep := s.h.opts.ReplaceAttr; rep != nil && a.Value.Kind() != KindGroup {
+		// a.Value is resolved before calling ReplaceAttr, so the user doesn't have to.
+		a = rep(s.groups, a)
+		// The ReplaceAttr function may return an unresolved Attr.
+		a.Value = a.Value.Resolve()
+	}
+	// Elide empty Attrs.
+	if a.isEmpty() {
+		return false
+	}
+	// Special case: Source.
+	if v := a.Value; v.Kind() == KindAny {
+		if src, ok := v.Any().(*Source); ok {
+			if s.h.json {
+				a.Value = src.group()
+			} else {
+				a.Value = StringValue(fmt.Sprintf("%s:%d", src.File, src.Line))
+			}
+		}
+	}
+	if a.Value.Kind() == KindGroup {
+		attrs := a.Value.Group()
+		// Output only non-empty groups.
+		if len(attrs) == 0 {
+			return false
+		}
+		// The group may still turn out to be empty, for example if
+		// ReplaceAttr removes all of its attrs, so remember the state
+		// in order to undo the group's output.
+		pos, sep := len(s.buf), s.sep
+		// Inline a group with an empty key.
+		if a.Key != "" {
+			s.openGroup(a.Key)
+		}
+		nonEmpty := s.appendAttrs(attrs)
+		if a.Key != "" {
+			s.closeGroup(a.Key)
+		}
+		if !nonEmpty {
+			s.buf, s.sep = s.buf[:pos], sep
+			return false
+		}
+	} else {
+		s.appendKey(a.Key)
+		s.appendValue(a.Value)
+	}
+	return true
+}
+
+func (s *handleState) appendError(err error) {
+	s.appendString(fmt.Sprintf("!ERROR:%v", err))
+}
+
+func (s *handleState) appendKey(key string) {
+	s.buf = append(s.buf, s.sep...)
+	if len(s.prefix) > 0 {
+		s.appendString(string(s.prefix) + key)
+	} else {
+		s.appendString(key)
+	}
+	if s.h.json {
+		s.buf = append(s.buf, ':')
+	} else {
+		s.buf = append(s.buf, '=')
+	}
+	s.sep = s.h.attrSep()
+}
+
+func (s *handleState) appendString(str string) {
+	if s.h.json {
+		s.buf = appendEscapedJSONString(s.buf, str)
+	} else {
+		// text
+		if needsQuoting(str) {
+			s.buf = strconv.AppendQuote(s.buf, str)
+		} else {
+			s.buf = append(s.buf, str...)
+		}
+	}
+}
+
+func (s *handleState) appendValue(v Value) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.appendString(fmt.Sprintf("!PANIC: %v", r))
+		}
+	}()
+
+	var err error
+	if s.h.json {
+		err = appendJSONValue(s, v)
+	} else {
+		err = appendTextValue(s, v)
+	}
+	if err != nil {
+		s.appendError(err)
+	}
+}
+
+// rfc3339Millis is the layout used for times by TextHandler.
+const rfc3339Millis = "2006-01-02T15:04:05.000Z07:00"
+
+func (s *handleState) appendTime(t time.Time) {
+	if s.h.json {
+		// Match the format used by time.Time.MarshalJSON.
+		s.buf = append(s.buf, '"')
+		s.buf = t.AppendFormat(s.buf, time.RFC3339Nano)
+		s.buf = append(s.buf, '"')
+	} else {
+		s.buf = t.AppendFormat(s.buf, rfc3339Millis)
+	}
+}
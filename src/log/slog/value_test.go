@@ -0,0 +1,286 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slog
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValueEqual(t *testing.T) {
+	var x, y int
+	vals := []Value{
+		{},
+		Int64Value(1),
+		Int64Value(2),
+		Float64Value(3.5),
+		Float64Value(3.7),
+		BoolValue(true),
+		BoolValue(false),
+		TimeValue(testTime),
+		AnyValue(&x),
+		AnyValue(&y),
+		GroupValue(Bool("b", true), Int("i", 3)),
+	}
+	for i, v1 := range vals {
+		for j, v2 := range vals {
+			got := v1.Equal(v2)
+			want := i == j
+			if got != want {
+				t.Errorf("%v.Equal(%v): got %t, want %t", v1, v2, got, want)
+			}
+		}
+	}
+}
+
+func TestValueString(t *testing.T) {
+	for _, test := range []struct {
+		v    Value
+		want string
+	}{
+		{Int64Value(-3), "-3"},
+		{Uint64Value(1), "1"},
+		{Float64Value(.15), "0.15"},
+		{BoolValue(true), "true"},
+		{StringValue("foo"), "foo"},
+		{TimeValue(testTime), "2000-01-02 03:04:05 +0000 UTC"},
+		{DurationValue(3 * time.Second), "3s"},
+		{AnyValue(time.Duration(0)), "0s"},
+		{GroupValue(Int("a", 1), Bool("b", true)), "[a=1 b=true]"},
+		{Value{}, "<nil>"},
+	} {
+		if got := test.v.String(); got != test.want {
+			t.Errorf("%#v:\ngot  %q\nwant %q", test.v, got, test.want)
+		}
+	}
+}
+
+func TestValueNoAlloc(t *testing.T) {
+	var (
+		i int64
+		u uint64
+		f float64
+		b bool
+		s string
+		x interface{}
+		p = &i
+		d time.Duration
+	)
+	a := int(testing.AllocsPerRun(5, func() {
+		i = Int64Value(1).Int64()
+		u = Uint64Value(1).Uint64()
+		f = Float64Value(1).Float64()
+		b = BoolValue(true).Bool()
+		s = StringValue("foo").String()
+		d = DurationValue(d).Duration()
+		x = AnyValue(p).Any()
+	}))
+	if a != 0 {
+		t.Errorf("got %d allocs, want zero", a)
+	}
+	_ = u
+	_ = f
+	_ = b
+	_ = s
+	_ = x
+}
+
+func TestAnyValue(t *testing.T) {
+	for _, test := range []struct {
+		in   interface{}
+		want Value
+	}{
+		{1, IntValue(1)},
+		{1.5, Float64Value(1.5)},
+		{float32(2.5), Float64Value(2.5)},
+		{"s", StringValue("s")},
+		{true, BoolValue(true)},
+		{testTime, TimeValue(testTime)},
+		{time.Hour, DurationValue(time.Hour)},
+		{[]Attr{Int("i", 3)}, GroupValue(Int("i", 3))},
+		{IntValue(4), IntValue(4)},
+		{uint8(2), Uint64Value(2)},
+		{uint16(2), Uint64Value(2)},
+		{uint32(2), Uint64Value(2)},
+		{uint64(2), Uint64Value(2)},
+		{int8(3), Int64Value(3)},
+		{int16(3), Int64Value(3)},
+		{int32(3), Int64Value(3)},
+		{int64(3), Int64Value(3)},
+	} {
+		got := AnyValue(test.in)
+		if !got.Equal(test.want) {
+			t.Errorf("%v (%[1]T): got %v (kind %s), want %v (kind %s)",
+				test.in, got, got.Kind(), test.want, test.want.Kind())
+		}
+	}
+}
+
+func TestValueAny(t *testing.T) {
+	for _, want := range []interface{}{
+		nil,
+		LevelDebug + 100,
+		time.UTC,
+		KindBool,
+		[]Attr{Int("a", 1)},
+		int64(2),
+		uint64(3),
+		true,
+		time.Minute,
+		testTime,
+		3.0,
+		"foo",
+	} {
+		v := AnyValue(want)
+		got := v.Any()
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestLogValue(t *testing.T) {
+	want := "replaced"
+	r := &replace{StringValue(want)}
+	v := AnyValue(r)
+	if g, w := v.Kind(), KindLogValuer; g != w {
+		t.Errorf("got %s, want %s", g, w)
+	}
+	got := v.LogValuer().LogValue().Any()
+	if got != want {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	// Test Resolve.
+	got = v.Resolve().Any()
+	if got != want {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	// Test Resolve max iteration.
+	r.v = AnyValue(r) // create a cycle
+	v = AnyValue(r)
+	got = v.Resolve().Any()
+	if _, ok := got.(error); !ok {
+		t.Errorf("expected error, got %T", got)
+	}
+
+	// Groups are not recursively resolved.
+	c := Any("c", &replace{StringValue("d")})
+	v = AnyValue(&replace{GroupValue(Int("a", 1), Group("b", c))})
+	got2 := v.Resolve().Any().([]Attr)
+	want2 := []Attr{Int("a", 1), Group("b", c)}
+	if fmt.Sprint(got2) != fmt.Sprint(want2) {
+		t.Errorf("got %v, want %v", got2, want2)
+	}
+
+	// Verify that panics in Resolve are caught and turn into errors.
+	v = AnyValue(panickingLogValue{})
+	got = v.Resolve().Any()
+	gotErr, ok := got.(error)
+	if !ok {
+		t.Errorf("expected error, got %T", got)
+	}
+	if !strings.Contains(gotErr.Error(), "LogValue panicked") {
+		t.Errorf("got %q, want substring %q", gotErr, "LogValue panicked")
+	}
+}
+
+func TestRecordAttrs(t *testing.T) {
+	as := []Attr{Int("k1", 1), String("k2", "foo"), Int("k3", 3),
+		Int64("k4", -1), Float64("f", 3.1), Uint64("u", 999)}
+	r := newRecordWithAttrs(as)
+	if g, w := r.NumAttrs(), len(as); g != w {
+		t.Errorf("NumAttrs: got %d, want %d", g, w)
+	}
+	if got := attrsSlice(r); !attrsEqual(got, as) {
+		t.Errorf("got %v, want %v", got, as)
+	}
+
+	// Early return.
+	var got []Attr
+	r.Attrs(func(a Attr) bool {
+		got = append(got, a)
+		return len(got) < 2
+	})
+	want := as[:2]
+	if !attrsEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRecordAliasing(t *testing.T) {
+	r1 := NewRecord(time.Time{}, 0, "", 0)
+	r1.AddAttrs(Int("p", 0))
+	r2 := r1
+	r1.AddAttrs(Int("a", 1))
+	r2.AddAttrs(Int("b", 2))
+	if got, want := attrsSlice(r1), []Attr{Int("p", 0), Int("a", 1)}; !attrsEqual(got, want) {
+		t.Errorf("r1: got %v, want %v", got, want)
+	}
+	if got, want := attrsSlice(r2), []Attr{Int("p", 0), Int("b", 2)}; !attrsEqual(got, want) {
+		t.Errorf("r2: got %v, want %v", got, want)
+	}
+
+	r3 := r1.Clone()
+	r3.AddAttrs(Int("c", 3))
+	if got, want := r1.NumAttrs(), 2; got != want {
+		t.Errorf("r1 after Clone: got %d attrs, want %d", got, want)
+	}
+}
+
+func TestRecordAdd(t *testing.T) {
+	r := NewRecord(time.Time{}, 0, "", 0)
+	r.Add("a", 1, Int("b", 2), "c", "d", 4, "e")
+	want := []Attr{Int("a", 1), Int("b", 2), String("c", "d"), Int(badKey, 4), String(badKey, "e")}
+	if got := attrsSlice(r); !attrsEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Empty groups are omitted.
+	r = NewRecord(time.Time{}, 0, "", 0)
+	r.Add(Group("g"), "x", 1)
+	if got, want := attrsSlice(r), []Attr{Int("x", 1)}; !attrsEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+type replace struct {
+	v Value
+}
+
+func (r *replace) LogValue() Value { return r.v }
+
+type panickingLogValue struct{}
+
+func (panickingLogValue) LogValue() Value { panic("bad") }
+
+var testTime = time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func newRecordWithAttrs(as []Attr) Record {
+	r := NewRecord(time.Now(), LevelInfo, "", 0)
+	r.AddAttrs(as...)
+	return r
+}
+
+func attrsSlice(r Record) []Attr {
+	var as []Attr
+	r.Attrs(func(a Attr) bool { as = append(as, a); return true })
+	return as
+}
+
+func attrsEqual(as1, as2 []Attr) bool {
+	if len(as1) != len(as2) {
+		return false
+	}
+	for i := range as1 {
+		if !as1[i].Equal(as2[i]) {
+			return false
+		}
+	}
+	return true
+}
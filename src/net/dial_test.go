@@ -143,7 +143,8 @@ const (
 // more quickly than expected. This test hook prevents dialTCP from returning
 // before the deadline.
 func slowDialTCP(ctx context.Context, net string, laddr, raddr *TCPAddr) (*TCPConn, error) {
-	c, err := doDialTCP(ctx, net, laddr, raddr)
+	dp := dialParam{network: net, address: raddr.String()}
+	c, err := dp.doDialTCP(ctx, laddr, raddr)
 	if ParseIP(slowDst4).Equal(raddr.IP) || ParseIP(slowDst6).Equal(raddr.IP) {
 		// Wait for the deadline, or indefinitely if none exists.
 		<-ctx.Done()
@@ -467,7 +468,8 @@ func TestDialParallelSpuriousConnection(t *testing.T) {
 		// Now ignore the provided context (which will be canceled) and use a
 		// different one to make sure this completes with a valid connection,
 		// which we hope to be closed below:
-		return doDialTCP(context.Background(), net, laddr, raddr)
+		dp := dialParam{network: net, address: raddr.String()}
+		return dp.doDialTCP(context.Background(), laddr, raddr)
 	}
 
 	d := Dialer{
@@ -0,0 +1,253 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"net/dnsmessage"
+	"sync"
+	"time"
+)
+
+// defaultDNSCacheEntries is the size of a DNSCache with no MaxEntries.
+const defaultDNSCacheEntries = 1024
+
+// A DNSCache caches the responses received by Go's built-in DNS
+// resolver, so that repeated lookups of the same name do not query
+// the name servers again until the records expire. It is used by a
+// Resolver whose Cache field points to it, and may be shared by
+// several Resolvers.
+//
+// Responses are cached for the least TTL of the records they hold.
+// Negative responses, saying that a name does not exist or has no
+// records of the type asked for, are cached as RFC 2308 describes,
+// for as long as the SOA record in the response allows; responses
+// with no SOA record are not cached, nor are server failures or
+// errors reaching the name servers. The whole cache is flushed when
+// the system DNS configuration (/etc/resolv.conf) changes.
+//
+// A DNSCache must not be copied after first use.
+type DNSCache struct {
+	// MaxEntries is the maximum number of responses held.
+	// When it is reached, the least recently used response is
+	// removed. If zero, a default of 1024 is used.
+	MaxEntries int
+
+	// MaxTTL, if non-zero, limits how long a response is cached,
+	// whatever its TTLs say.
+	MaxTTL time.Duration
+
+	mu       sync.Mutex
+	entries  map[dnsCacheKey]*dnsCacheEntry
+	lru      dnsCacheEntry // sentinel; lru.next is most recently used
+	confTime time.Time     // of the DNS configuration the entries came from
+	stats    DNSCacheStats
+}
+
+// DNSCacheStats holds the statistics of a DNSCache.
+type DNSCacheStats struct {
+	Entries      int    // responses currently cached
+	Hits         uint64 // lookups answered from the cache
+	NegativeHits uint64 // lookups answered with a cached negative response, included in Hits
+	Misses       uint64 // lookups that had to query a name server
+	Evictions    uint64 // responses removed to stay within MaxEntries
+}
+
+// A dnsCacheKey identifies the responses to queries for a name and
+// type. A response saying the name does not exist answers queries
+// for all types, and is cached with qtype 0.
+type dnsCacheKey struct {
+	name  string // lower case
+	qtype dnsmessage.Type
+}
+
+type dnsCacheEntry struct {
+	key        dnsCacheKey
+	msg        []byte // packed response
+	server     string // that sent msg
+	negative   bool
+	stored     time.Time
+	expires    time.Time
+	prev, next *dnsCacheEntry
+}
+
+// Stats returns the statistics of c.
+func (c *DNSCache) Stats() DNSCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	return stats
+}
+
+// Flush removes all responses from c.
+func (c *DNSCache) Flush() {
+	c.mu.Lock()
+	c.flushLocked()
+	c.mu.Unlock()
+}
+
+func (c *DNSCache) flushLocked() {
+	c.entries = nil
+	c.lru.prev, c.lru.next = &c.lru, &c.lru
+}
+
+func newDNSCacheKey(name string, qtype dnsmessage.Type) dnsCacheKey {
+	b := []byte(name)
+	lowerASCIIBytes(b)
+	return dnsCacheKey{string(b), qtype}
+}
+
+// checkConf flushes c if its entries came from a DNS configuration
+// other than the one read from a resolv.conf modified at confTime.
+func (c *DNSCache) checkConf(confTime time.Time) {
+	if c.entries == nil || !c.confTime.Equal(confTime) {
+		c.flushLocked()
+		c.confTime = confTime
+	}
+}
+
+// get returns the cached response to the query for name and qtype,
+// with the TTLs of its answer records reduced by the time it has
+// been cached, and the server that sent it.
+func (c *DNSCache) get(name string, qtype dnsmessage.Type, confTime, now time.Time) (msg *dnsmessage.Message, server string, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checkConf(confTime)
+	key := newDNSCacheKey(name, qtype)
+	e := c.entries[key]
+	if e == nil {
+		key.qtype = 0
+		e = c.entries[key]
+	}
+	if e != nil && !now.Before(e.expires) {
+		c.remove(e)
+		e = nil
+	}
+	if e == nil {
+		c.stats.Misses++
+		return nil, "", false
+	}
+	msg = new(dnsmessage.Message)
+	if msg.Unpack(e.msg) != nil {
+		c.remove(e)
+		c.stats.Misses++
+		return nil, "", false
+	}
+	age := uint32(now.Sub(e.stored) / time.Second)
+	for _, rr := range msg.Answers {
+		if h := rr.Header(); h.TTL > age {
+			h.TTL -= age
+		} else {
+			h.TTL = 0
+		}
+	}
+	c.unlink(e)
+	c.pushFront(e)
+	c.stats.Hits++
+	if e.negative {
+		c.stats.NegativeHits++
+	}
+	return msg, e.server, true
+}
+
+// put caches msg, the response from server to the query for name
+// and qtype. Positive reports whether msg answers the query; if not,
+// msg is a negative response.
+func (c *DNSCache) put(name string, qtype dnsmessage.Type, server string, msg *dnsmessage.Message, positive bool, confTime, now time.Time) {
+	ttl := dnsCacheTTL(msg, positive)
+	if c.MaxTTL > 0 && ttl > c.MaxTTL {
+		ttl = c.MaxTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+	if !positive && msg.RCode == dnsmessage.RCodeNameError {
+		qtype = 0
+	}
+	m := *msg
+	m.Additionals = nil
+	b, err := m.Pack()
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checkConf(confTime)
+	key := newDNSCacheKey(name, qtype)
+	if e := c.entries[key]; e != nil {
+		c.remove(e)
+	}
+	max := c.MaxEntries
+	if max <= 0 {
+		max = defaultDNSCacheEntries
+	}
+	for len(c.entries) >= max {
+		c.remove(c.lru.prev)
+		c.stats.Evictions++
+	}
+	e := &dnsCacheEntry{
+		key:      key,
+		msg:      b,
+		server:   server,
+		negative: !positive,
+		stored:   now,
+		expires:  now.Add(ttl),
+	}
+	if c.entries == nil {
+		c.entries = make(map[dnsCacheKey]*dnsCacheEntry)
+	}
+	c.entries[key] = e
+	c.pushFront(e)
+}
+
+func (c *DNSCache) remove(e *dnsCacheEntry) {
+	c.unlink(e)
+	delete(c.entries, e.key)
+}
+
+func (c *DNSCache) unlink(e *dnsCacheEntry) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	e.prev, e.next = nil, nil
+}
+
+func (c *DNSCache) pushFront(e *dnsCacheEntry) {
+	e.prev = &c.lru
+	e.next = c.lru.next
+	e.prev.next = e
+	e.next.prev = e
+}
+
+// dnsCacheTTL returns how long msg may be cached. For a positive
+// response that is the least TTL of its answer records. For a
+// negative one it is, per RFC 2308, section 5, the lesser of the TTL
+// of the SOA record in its authority section and the SOA's MINIMUM
+// field, or zero if there is no SOA record.
+func dnsCacheTTL(msg *dnsmessage.Message, positive bool) time.Duration {
+	var ttl uint32
+	found := false
+	if positive {
+		for _, rr := range msg.Answers {
+			if t := rr.Header().TTL; !found || t < ttl {
+				ttl, found = t, true
+			}
+		}
+	} else {
+		for _, rr := range msg.Authorities {
+			if soa, ok := rr.(*dnsmessage.SOA); ok {
+				ttl, found = soa.Hdr.TTL, true
+				if soa.MinTTL < ttl {
+					ttl = soa.MinTTL
+				}
+				break
+			}
+		}
+	}
+	if !found {
+		return 0
+	}
+	return time.Duration(ttl) * time.Second
+}
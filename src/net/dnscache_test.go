@@ -0,0 +1,144 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"net/dnsmessage"
+	"testing"
+	"time"
+)
+
+func dnsCacheTestResponse(name string, qtype dnsmessage.Type, rcode dnsmessage.RCode, ttl uint32, soa *dnsmessage.SOA) *dnsmessage.Message {
+	msg := &dnsmessage.Message{
+		Header:    dnsmessage.Header{Response: true, RecursionAvailable: true, RCode: rcode},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	if rcode == dnsmessage.RCodeSuccess && soa == nil {
+		msg.Answers = []dnsmessage.RR{
+			&dnsmessage.A{
+				Hdr: dnsmessage.RRHeader{Name: name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: ttl},
+				A:   [4]byte{192, 0, 2, 1},
+			},
+			&dnsmessage.A{
+				Hdr: dnsmessage.RRHeader{Name: name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: ttl + 100},
+				A:   [4]byte{192, 0, 2, 2},
+			},
+		}
+	}
+	if soa != nil {
+		msg.Authorities = []dnsmessage.RR{soa}
+	}
+	return msg
+}
+
+func TestDNSCache(t *testing.T) {
+	var c DNSCache
+	conf := time.Unix(1e9, 0)
+	now := time.Unix(1.5e9, 0)
+	soa := &dnsmessage.SOA{
+		Hdr:    dnsmessage.RRHeader{Name: "golang.org.", Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET, TTL: 600},
+		NS:     "ns.golang.org.",
+		MBox:   "hostmaster.golang.org.",
+		MinTTL: 60,
+	}
+
+	c.put("golang.org.", dnsmessage.TypeA, "192.0.2.53:53", dnsCacheTestResponse("golang.org.", dnsmessage.TypeA, dnsmessage.RCodeSuccess, 300, nil), true, conf, now)
+	c.put("nx.golang.org.", dnsmessage.TypeA, "192.0.2.53:53", dnsCacheTestResponse("nx.golang.org.", dnsmessage.TypeA, dnsmessage.RCodeNameError, 0, soa), false, conf, now)
+	c.put("golang.org.", dnsmessage.TypeTXT, "192.0.2.53:53", dnsCacheTestResponse("golang.org.", dnsmessage.TypeTXT, dnsmessage.RCodeSuccess, 0, soa), false, conf, now)
+	// Negative responses without SOA are not cached.
+	c.put("golang.org.", dnsmessage.TypeMX, "192.0.2.53:53", dnsCacheTestResponse("golang.org.", dnsmessage.TypeMX, dnsmessage.RCodeNameError, 0, nil), false, conf, now)
+	if n := c.Stats().Entries; n != 3 {
+		t.Fatalf("got %d entries; want 3", n)
+	}
+
+	tests := []struct {
+		name     string
+		qtype    dnsmessage.Type
+		after    time.Duration
+		ok       bool
+		rcode    dnsmessage.RCode
+		firstTTL uint32
+	}{
+		{"golang.org.", dnsmessage.TypeA, 100 * time.Second, true, dnsmessage.RCodeSuccess, 200},
+		{"GoLang.ORG.", dnsmessage.TypeA, 299 * time.Second, true, dnsmessage.RCodeSuccess, 1},
+		{"golang.org.", dnsmessage.TypeAAAA, 0, false, 0, 0},
+		{"golang.org.", dnsmessage.TypeMX, 0, false, 0, 0},
+		{"golang.org.", dnsmessage.TypeTXT, 59 * time.Second, true, dnsmessage.RCodeSuccess, 0},
+		{"nx.golang.org.", dnsmessage.TypeA, 30 * time.Second, true, dnsmessage.RCodeNameError, 0},
+		// NXDOMAIN answers queries of any type.
+		{"nx.golang.org.", dnsmessage.TypeAAAA, 30 * time.Second, true, dnsmessage.RCodeNameError, 0},
+		// The SOA's MINIMUM is less than its TTL.
+		{"golang.org.", dnsmessage.TypeTXT, 60 * time.Second, false, 0, 0},
+		{"golang.org.", dnsmessage.TypeA, 300 * time.Second, false, 0, 0},
+	}
+	for _, tt := range tests {
+		msg, server, ok := c.get(tt.name, tt.qtype, conf, now.Add(tt.after))
+		if ok != tt.ok {
+			t.Errorf("get(%q, %v) after %v: ok = %v; want %v", tt.name, tt.qtype, tt.after, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if server != "192.0.2.53:53" || msg.RCode != tt.rcode {
+			t.Errorf("get(%q, %v) = %v from %q; want RCode %v", tt.name, tt.qtype, msg, server, tt.rcode)
+		}
+		if len(msg.Answers) > 0 && msg.Answers[0].Header().TTL != tt.firstTTL {
+			t.Errorf("get(%q, %v) after %v: TTL = %d; want %d", tt.name, tt.qtype, tt.after, msg.Answers[0].Header().TTL, tt.firstTTL)
+		}
+	}
+
+	stats := c.Stats()
+	want := DNSCacheStats{Entries: 1, Hits: 5, NegativeHits: 3, Misses: 4}
+	if stats != want {
+		t.Errorf("got %+v; want %+v", stats, want)
+	}
+
+	// A new DNS configuration flushes the cache.
+	if _, _, ok := c.get("nx.golang.org.", dnsmessage.TypeA, conf.Add(time.Second), now); ok {
+		t.Error("got response cached with previous DNS configuration")
+	}
+	if n := c.Stats().Entries; n != 0 {
+		t.Errorf("got %d entries after DNS configuration change; want 0", n)
+	}
+}
+
+func TestDNSCacheLimits(t *testing.T) {
+	c := DNSCache{MaxEntries: 2, MaxTTL: time.Minute}
+	var conf time.Time
+	now := time.Unix(1.5e9, 0)
+	for _, name := range []string{"a.golang.org.", "b.golang.org."} {
+		c.put(name, dnsmessage.TypeA, "192.0.2.53:53", dnsCacheTestResponse(name, dnsmessage.TypeA, dnsmessage.RCodeSuccess, 300, nil), true, conf, now)
+	}
+	// Using a makes b the least recently used entry.
+	if _, _, ok := c.get("a.golang.org.", dnsmessage.TypeA, conf, now); !ok {
+		t.Fatal("a.golang.org. not cached")
+	}
+	c.put("c.golang.org.", dnsmessage.TypeA, "192.0.2.53:53", dnsCacheTestResponse("c.golang.org.", dnsmessage.TypeA, dnsmessage.RCodeSuccess, 300, nil), true, conf, now)
+	for _, tt := range []struct {
+		name string
+		ok   bool
+	}{
+		{"a.golang.org.", true},
+		{"b.golang.org.", false},
+		{"c.golang.org.", true},
+	} {
+		if _, _, ok := c.get(tt.name, dnsmessage.TypeA, conf, now); ok != tt.ok {
+			t.Errorf("get(%q) = %v; want %v", tt.name, ok, tt.ok)
+		}
+	}
+	if stats := c.Stats(); stats.Entries != 2 || stats.Evictions != 1 {
+		t.Errorf("got %+v; want 2 entries and 1 eviction", stats)
+	}
+
+	if _, _, ok := c.get("a.golang.org.", dnsmessage.TypeA, conf, now.Add(time.Minute)); ok {
+		t.Error("got response cached for longer than MaxTTL")
+	}
+
+	c.Flush()
+	if n := c.Stats().Entries; n != 0 {
+		t.Errorf("got %d entries after Flush; want 0", n)
+	}
+}
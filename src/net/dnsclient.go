@@ -6,6 +6,7 @@ package net
 
 import (
 	"math/rand"
+	"net/dnsmessage"
 	"sort"
 )
 
@@ -37,20 +38,20 @@ func reverseaddr(addr string) (arpa string, err error) {
 
 // Find answer for name in dns message.
 // On return, if err == nil, addrs != nil.
-func answer(name, server string, dns *dnsMsg, qtype uint16) (cname string, addrs []dnsRR, err error) {
-	addrs = make([]dnsRR, 0, len(dns.answer))
+func answer(name, server string, dns *dnsmessage.Message, qtype dnsmessage.Type) (cname string, addrs []dnsmessage.RR, err error) {
+	addrs = make([]dnsmessage.RR, 0, len(dns.Answers))
 
-	if dns.rcode == dnsRcodeNameError {
+	if dns.RCode == dnsmessage.RCodeNameError {
 		return "", nil, &DNSError{Err: errNoSuchHost.Error(), Name: name, Server: server}
 	}
-	if dns.rcode != dnsRcodeSuccess {
+	if dns.RCode != dnsmessage.RCodeSuccess {
 		// None of the error codes make sense
 		// for the query we sent. If we didn't get
 		// a name error and we didn't get success,
 		// the server is behaving incorrectly or
 		// having temporary trouble.
 		err := &DNSError{Err: "server misbehaving", Name: name, Server: server}
-		if dns.rcode == dnsRcodeServerFailure {
+		if dns.RCode == dnsmessage.RCodeServerFailure {
 			err.IsTemporary = true
 		}
 		return "", nil, err
@@ -64,8 +65,8 @@ func answer(name, server string, dns *dnsMsg, qtype uint16) (cname string, addrs
 Cname:
 	for cnameloop := 0; cnameloop < 10; cnameloop++ {
 		addrs = addrs[0:0]
-		for _, rr := range dns.answer {
-			if _, justHeader := rr.(*dnsRR_Header); justHeader {
+		for _, rr := range dns.Answers {
+			if _, justHeader := rr.(*dnsmessage.RRHeader); justHeader {
 				// Corrupt record: we only have a
 				// header. That header might say it's
 				// of type qtype, but we don't
@@ -73,13 +74,13 @@ Cname:
 				continue
 			}
 			h := rr.Header()
-			if h.Class == dnsClassINET && equalASCIILabel(h.Name, name) {
-				switch h.Rrtype {
+			if h.Class == dnsmessage.ClassINET && equalASCIILabel(h.Name, name) {
+				switch h.Type {
 				case qtype:
 					addrs = append(addrs, rr)
-				case dnsTypeCNAME:
+				case dnsmessage.TypeCNAME:
 					// redirect to cname
-					name = rr.(*dnsRR_CNAME).Cname
+					name = rr.(*dnsmessage.CNAME).CNAME
 					continue Cname
 				}
 			}
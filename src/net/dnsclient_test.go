@@ -6,6 +6,7 @@ package net
 
 import (
 	"math/rand"
+	"net/dnsmessage"
 	"testing"
 )
 
@@ -71,13 +72,13 @@ func TestWeighting(t *testing.T) {
 // Issue 8434: verify that Temporary returns true on an error when rcode
 // is SERVFAIL
 func TestIssue8434(t *testing.T) {
-	msg := &dnsMsg{
-		dnsMsgHdr: dnsMsgHdr{
-			rcode: dnsRcodeServerFailure,
+	msg := &dnsmessage.Message{
+		Header: dnsmessage.Header{
+			RCode: dnsmessage.RCodeServerFailure,
 		},
 	}
 
-	_, _, err := answer("golang.org", "foo:53", msg, uint16(dnsTypeSRV))
+	_, _, err := answer("golang.org", "foo:53", msg, dnsmessage.TypeSRV)
 	if err == nil {
 		t.Fatal("expected an error")
 	}
@@ -96,14 +97,14 @@ func TestIssue8434(t *testing.T) {
 // Issue 12778: verify that NXDOMAIN without RA bit errors as
 // "no such host" and not "server misbehaving"
 func TestIssue12778(t *testing.T) {
-	msg := &dnsMsg{
-		dnsMsgHdr: dnsMsgHdr{
-			rcode:               dnsRcodeNameError,
-			recursion_available: false,
+	msg := &dnsmessage.Message{
+		Header: dnsmessage.Header{
+			RCode:              dnsmessage.RCodeNameError,
+			RecursionAvailable: false,
 		},
 	}
 
-	_, _, err := answer("golang.org", "foo:53", msg, uint16(dnsTypeSRV))
+	_, _, err := answer("golang.org", "foo:53", msg, dnsmessage.TypeSRV)
 	if err == nil {
 		t.Fatal("expected an error")
 	}
@@ -115,3 +116,37 @@ func TestIssue12778(t *testing.T) {
 		t.Fatalf("Err = %#v; wanted %q", de.Err, errNoSuchHost.Error())
 	}
 }
+
+func TestAnswer(t *testing.T) {
+	srv := func(name string, port uint16) dnsmessage.RR {
+		return &dnsmessage.SRV{
+			Hdr:    dnsmessage.RRHeader{Name: name, Type: dnsmessage.TypeSRV, Class: dnsmessage.ClassINET},
+			Port:   port,
+			Target: "xmpp.golang.org.",
+		}
+	}
+	msg := &dnsmessage.Message{
+		Header: dnsmessage.Header{Response: true, RecursionAvailable: true},
+		Answers: []dnsmessage.RR{
+			&dnsmessage.CNAME{
+				Hdr:   dnsmessage.RRHeader{Name: "_xmpp-server._tcp.golang.org.", Type: dnsmessage.TypeCNAME, Class: dnsmessage.ClassINET},
+				CNAME: "_XMPP-Server._TCP.Google.COM.",
+			},
+			srv("_xmpp-server._tcp.google.com.", 5269),
+			// A record that could not be decoded is skipped.
+			&dnsmessage.RRHeader{Name: "_xmpp-server._tcp.google.com.", Type: dnsmessage.TypeSRV, Class: dnsmessage.ClassINET},
+			srv("_XMPP-SERVER._TCP.GOOGLE.COM.", 5270),
+			srv("_xmpp-client._tcp.google.com.", 5222),
+		},
+	}
+	cname, rrs, err := answer("_xmpp-server._tcp.golang.org.", "foo:53", msg, dnsmessage.TypeSRV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "_XMPP-Server._TCP.Google.COM."; cname != want {
+		t.Errorf("cname = %q; want %q", cname, want)
+	}
+	if len(rrs) != 2 || rrs[0].(*dnsmessage.SRV).Port != 5269 || rrs[1].(*dnsmessage.SRV).Port != 5270 {
+		t.Errorf("got records %v; want the two _xmpp-server SRV records", rrs)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"io"
 	"math/rand"
+	"net/dnsmessage"
 	"os"
 	"sync"
 	"time"
@@ -33,9 +34,14 @@ type dnsConn interface {
 
 	// dnsRoundTrip executes a single DNS transaction, returning a
 	// DNS response message for the provided DNS query message.
-	dnsRoundTrip(query *dnsMsg) (*dnsMsg, error)
+	dnsRoundTrip(query *dnsmessage.Message) (*dnsmessage.Message, error)
 }
 
+// maxDNSPacketSize is the UDP payload size advertised with EDNS(0)
+// in queries. Larger responses are truncated and retried over TCP;
+// 1232 bytes avoids IP fragmentation on nearly all paths.
+const maxDNSPacketSize = 1232
+
 // dnsPacketConn implements the dnsConn interface for RFC 1035's
 // "UDP usage" transport mechanism. Conn is a packet-oriented connection,
 // such as a *UDPConn.
@@ -43,23 +49,23 @@ type dnsPacketConn struct {
 	Conn
 }
 
-func (c *dnsPacketConn) dnsRoundTrip(query *dnsMsg) (*dnsMsg, error) {
-	b, ok := query.Pack()
-	if !ok {
+func (c *dnsPacketConn) dnsRoundTrip(query *dnsmessage.Message) (*dnsmessage.Message, error) {
+	b, err := query.Pack()
+	if err != nil {
 		return nil, errors.New("cannot marshal DNS message")
 	}
 	if _, err := c.Write(b); err != nil {
 		return nil, err
 	}
 
-	b = make([]byte, 512) // see RFC 1035
+	b = make([]byte, maxDNSPacketSize)
 	for {
 		n, err := c.Read(b)
 		if err != nil {
 			return nil, err
 		}
-		resp := &dnsMsg{}
-		if !resp.Unpack(b[:n]) || !resp.IsResponseTo(query) {
+		resp := &dnsmessage.Message{}
+		if resp.Unpack(b[:n]) != nil || !resp.IsResponseTo(query) {
 			// Ignore invalid responses as they may be malicious
 			// forgery attempts. Instead continue waiting until
 			// timeout. See golang.org/issue/13281.
@@ -76,9 +82,9 @@ type dnsStreamConn struct {
 	Conn
 }
 
-func (c *dnsStreamConn) dnsRoundTrip(query *dnsMsg) (*dnsMsg, error) {
-	b, ok := query.Pack()
-	if !ok {
+func (c *dnsStreamConn) dnsRoundTrip(query *dnsmessage.Message) (*dnsmessage.Message, error) {
+	b, err := query.Pack()
+	if err != nil {
 		return nil, errors.New("cannot marshal DNS message")
 	}
 	l := len(b)
@@ -99,26 +105,42 @@ func (c *dnsStreamConn) dnsRoundTrip(query *dnsMsg) (*dnsMsg, error) {
 	if err != nil {
 		return nil, err
 	}
-	resp := &dnsMsg{}
-	if !resp.Unpack(b[:n]) {
+	resp := &dnsmessage.Message{}
+	if resp.Unpack(b[:n]) != nil {
 		return nil, errors.New("cannot unmarshal DNS message")
 	}
 	if !resp.IsResponseTo(query) {
-		return nil, errors.New("invalid DNS response")
+		return nil, errInvalidDNSResponse
 	}
 	return resp, nil
 }
 
+var errInvalidDNSResponse = errors.New("invalid DNS response")
+
 // exchange sends a query on the connection and hopes for a response.
-func (r *Resolver) exchange(ctx context.Context, server, name string, qtype uint16, timeout time.Duration) (*dnsMsg, error) {
-	out := dnsMsg{
-		dnsMsgHdr: dnsMsgHdr{
-			recursion_desired: true,
+func (r *Resolver) exchange(ctx context.Context, server, name string, qtype dnsmessage.Type, timeout time.Duration) (*dnsmessage.Message, error) {
+	out := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			RecursionDesired: true,
 		},
-		question: []dnsQuestion{
-			{name, qtype, dnsClassINET},
+		Questions: []dnsmessage.Question{
+			{Name: name, Type: qtype, Class: dnsmessage.ClassINET},
 		},
 	}
+	out.SetEDNS0(maxDNSPacketSize, false)
+	if r.Exchange != nil {
+		ctx, cancel := context.WithDeadline(ctx, time.Now().Add(timeout))
+		defer cancel()
+		out.ID = uint16(rand.Int()) ^ uint16(time.Now().UnixNano())
+		in, err := r.Exchange(ctx, server, &out)
+		if err != nil {
+			return nil, mapErr(err)
+		}
+		if !in.IsResponseTo(&out) {
+			return nil, errInvalidDNSResponse
+		}
+		return in, nil
+	}
 	for _, network := range []string{"udp", "tcp"} {
 		// TODO(mdempsky): Refactor so defers from UDP-based
 		// exchanges happen before TCP-based exchange.
@@ -134,12 +156,12 @@ func (r *Resolver) exchange(ctx context.Context, server, name string, qtype uint
 		if d, ok := ctx.Deadline(); ok && !d.IsZero() {
 			c.SetDeadline(d)
 		}
-		out.id = uint16(rand.Int()) ^ uint16(time.Now().UnixNano())
+		out.ID = uint16(rand.Int()) ^ uint16(time.Now().UnixNano())
 		in, err := c.dnsRoundTrip(&out)
 		if err != nil {
 			return nil, mapErr(err)
 		}
-		if in.truncated { // see RFC 5966
+		if in.Truncated { // see RFC 5966
 			continue
 		}
 		return in, nil
@@ -149,7 +171,13 @@ func (r *Resolver) exchange(ctx context.Context, server, name string, qtype uint
 
 // Do a lookup for a single name, which must be rooted
 // (otherwise answer will not find the answers).
-func (r *Resolver) tryOneName(ctx context.Context, cfg *dnsConfig, name string, qtype uint16) (string, []dnsRR, error) {
+func (r *Resolver) tryOneName(ctx context.Context, cfg *dnsConfig, name string, qtype dnsmessage.Type) (string, []dnsmessage.RR, error) {
+	if r.Cache != nil {
+		if msg, server, ok := r.Cache.get(name, qtype, cfg.mtime, time.Now()); ok {
+			return answer(name, server, msg, qtype)
+		}
+	}
+
 	var lastErr error
 	serverOffset := cfg.serverOffset()
 	sLen := uint32(len(cfg.servers))
@@ -177,7 +205,7 @@ func (r *Resolver) tryOneName(ctx context.Context, cfg *dnsConfig, name string,
 			}
 			// libresolv continues to the next server when it receives
 			// an invalid referral response. See golang.org/issue/15434.
-			if msg.rcode == dnsRcodeSuccess && !msg.authoritative && !msg.recursion_available && len(msg.answer) == 0 && len(msg.extra) == 0 {
+			if isLameReferral(msg) {
 				lastErr = &DNSError{Err: "lame referral", Name: name, Server: server}
 				continue
 			}
@@ -186,7 +214,10 @@ func (r *Resolver) tryOneName(ctx context.Context, cfg *dnsConfig, name string,
 			// it means the response in msg was not useful and trying another
 			// server probably won't help. Return now in those cases.
 			// TODO: indicate this in a more obvious way, such as a field on DNSError?
-			if err == nil || msg.rcode == dnsRcodeSuccess || msg.rcode == dnsRcodeNameError {
+			if err == nil || msg.RCode == dnsmessage.RCodeSuccess || msg.RCode == dnsmessage.RCodeNameError {
+				if r.Cache != nil {
+					r.Cache.put(name, qtype, server, msg, err == nil, cfg.mtime, time.Now())
+				}
 				return cname, rrs, err
 			}
 			lastErr = err
@@ -195,15 +226,31 @@ func (r *Resolver) tryOneName(ctx context.Context, cfg *dnsConfig, name string,
 	return "", nil, lastErr
 }
 
+// isLameReferral reports whether msg is a successful response
+// carrying neither answers nor additional records, from a server that
+// is neither authoritative for the name nor willing to recurse.
+// The OPT record echoed by servers supporting EDNS(0) does not count.
+func isLameReferral(msg *dnsmessage.Message) bool {
+	if msg.RCode != dnsmessage.RCodeSuccess || msg.Authoritative || msg.RecursionAvailable || len(msg.Answers) > 0 {
+		return false
+	}
+	for _, rr := range msg.Additionals {
+		if _, ok := rr.(*dnsmessage.OPT); !ok {
+			return false
+		}
+	}
+	return true
+}
+
 // addrRecordList converts and returns a list of IP addresses from DNS
 // address records (both A and AAAA). Other record types are ignored.
-func addrRecordList(rrs []dnsRR) []IPAddr {
+func addrRecordList(rrs []dnsmessage.RR) []IPAddr {
 	addrs := make([]IPAddr, 0, 4)
 	for _, rr := range rrs {
 		switch rr := rr.(type) {
-		case *dnsRR_A:
-			addrs = append(addrs, IPAddr{IP: IPv4(byte(rr.A>>24), byte(rr.A>>16), byte(rr.A>>8), byte(rr.A))})
-		case *dnsRR_AAAA:
+		case *dnsmessage.A:
+			addrs = append(addrs, IPAddr{IP: IPv4(rr.A[0], rr.A[1], rr.A[2], rr.A[3])})
+		case *dnsmessage.AAAA:
 			ip := make(IP, IPv6len)
 			copy(ip, rr.AAAA[:])
 			addrs = append(addrs, IPAddr{IP: ip})
@@ -287,7 +334,7 @@ func (conf *resolverConfig) releaseSema() {
 	<-conf.ch
 }
 
-func (r *Resolver) lookup(ctx context.Context, name string, qtype uint16) (cname string, rrs []dnsRR, err error) {
+func (r *Resolver) lookup(ctx context.Context, name string, qtype dnsmessage.Type) (cname string, rrs []dnsmessage.RR, err error) {
 	if !isDomainName(name) {
 		// We used to use "invalid domain name" as the error,
 		// but that is a detail of the specific lookup mechanism.
@@ -471,16 +518,16 @@ func (r *Resolver) goLookupIPCNAMEOrder(ctx context.Context, name string, order
 	resolvConf.mu.RUnlock()
 	type racer struct {
 		cname string
-		rrs   []dnsRR
+		rrs   []dnsmessage.RR
 		error
 	}
 	lane := make(chan racer, 1)
-	qtypes := [...]uint16{dnsTypeA, dnsTypeAAAA}
+	qtypes := [...]dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA}
 	var lastErr error
 	for _, fqdn := range conf.nameList(name) {
 		for _, qtype := range qtypes {
 			dnsWaitGroup.Add(1)
-			go func(qtype uint16) {
+			go func(qtype dnsmessage.Type) {
 				defer dnsWaitGroup.Done()
 				cname, rrs, err := r.tryOneName(ctx, conf, fqdn, qtype)
 				lane <- racer{cname, rrs, err}
@@ -555,13 +602,13 @@ func (r *Resolver) goLookupPTR(ctx context.Context, addr string) ([]string, erro
 	if err != nil {
 		return nil, err
 	}
-	_, rrs, err := r.lookup(ctx, arpa, dnsTypePTR)
+	_, rrs, err := r.lookup(ctx, arpa, dnsmessage.TypePTR)
 	if err != nil {
 		return nil, err
 	}
 	ptrs := make([]string, len(rrs))
 	for i, rr := range rrs {
-		ptrs[i] = rr.(*dnsRR_PTR).Ptr
+		ptrs[i] = rr.(*dnsmessage.PTR).PTR
 	}
 	return ptrs, nil
 }
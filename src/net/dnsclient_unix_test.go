@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"internal/poll"
 	"io/ioutil"
+	"net/dnsmessage"
 	"os"
 	"path"
 	"reflect"
@@ -24,7 +25,7 @@ import (
 var goResolver = Resolver{PreferGo: true}
 
 // Test address from 192.0.2.0/24 block, reserved by RFC 5737 for documentation.
-const TestAddr uint32 = 0xc0000201
+var TestAddr = [4]byte{0xc0, 0x00, 0x02, 0x01}
 
 // Test address from 2001:db8::/32 block, reserved by RFC 3849 for documentation.
 var TestAddr6 = [16]byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
@@ -32,29 +33,29 @@ var TestAddr6 = [16]byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0
 var dnsTransportFallbackTests = []struct {
 	server  string
 	name    string
-	qtype   uint16
+	qtype   dnsmessage.Type
 	timeout int
-	rcode   int
+	rcode   dnsmessage.RCode
 }{
 	// Querying "com." with qtype=255 usually makes an answer
 	// which requires more than 512 bytes.
-	{"8.8.8.8:53", "com.", dnsTypeALL, 2, dnsRcodeSuccess},
-	{"8.8.4.4:53", "com.", dnsTypeALL, 4, dnsRcodeSuccess},
+	{"8.8.8.8:53", "com.", dnsmessage.TypeALL, 2, dnsmessage.RCodeSuccess},
+	{"8.8.4.4:53", "com.", dnsmessage.TypeALL, 4, dnsmessage.RCodeSuccess},
 }
 
 func TestDNSTransportFallback(t *testing.T) {
 	fake := fakeDNSServer{
-		rh: func(n, _ string, q *dnsMsg, _ time.Time) (*dnsMsg, error) {
-			r := &dnsMsg{
-				dnsMsgHdr: dnsMsgHdr{
-					id:       q.id,
-					response: true,
-					rcode:    dnsRcodeSuccess,
+		rh: func(n, _ string, q *dnsmessage.Message, _ time.Time) (*dnsmessage.Message, error) {
+			r := &dnsmessage.Message{
+				Header: dnsmessage.Header{
+					ID:       q.ID,
+					Response: true,
+					RCode:    dnsmessage.RCodeSuccess,
 				},
-				question: q.question,
+				Questions: q.Questions,
 			}
 			if n == "udp" {
-				r.truncated = true
+				r.Truncated = true
 			}
 			return r, nil
 		},
@@ -68,10 +69,10 @@ func TestDNSTransportFallback(t *testing.T) {
 			t.Error(err)
 			continue
 		}
-		switch msg.rcode {
+		switch msg.RCode {
 		case tt.rcode:
 		default:
-			t.Errorf("got %v from %v; want %v", msg.rcode, tt.server, tt.rcode)
+			t.Errorf("got %v from %v; want %v", msg.RCode, tt.server, tt.rcode)
 			continue
 		}
 	}
@@ -81,38 +82,38 @@ func TestDNSTransportFallback(t *testing.T) {
 // domain names.
 var specialDomainNameTests = []struct {
 	name  string
-	qtype uint16
-	rcode int
+	qtype dnsmessage.Type
+	rcode dnsmessage.RCode
 }{
 	// Name resolution APIs and libraries should not recognize the
 	// followings as special.
-	{"1.0.168.192.in-addr.arpa.", dnsTypePTR, dnsRcodeNameError},
-	{"test.", dnsTypeALL, dnsRcodeNameError},
-	{"example.com.", dnsTypeALL, dnsRcodeSuccess},
+	{"1.0.168.192.in-addr.arpa.", dnsmessage.TypePTR, dnsmessage.RCodeNameError},
+	{"test.", dnsmessage.TypeALL, dnsmessage.RCodeNameError},
+	{"example.com.", dnsmessage.TypeALL, dnsmessage.RCodeSuccess},
 
 	// Name resolution APIs and libraries should recognize the
 	// followings as special and should not send any queries.
 	// Though, we test those names here for verifying negative
 	// answers at DNS query-response interaction level.
-	{"localhost.", dnsTypeALL, dnsRcodeNameError},
-	{"invalid.", dnsTypeALL, dnsRcodeNameError},
+	{"localhost.", dnsmessage.TypeALL, dnsmessage.RCodeNameError},
+	{"invalid.", dnsmessage.TypeALL, dnsmessage.RCodeNameError},
 }
 
 func TestSpecialDomainName(t *testing.T) {
-	fake := fakeDNSServer{func(_, _ string, q *dnsMsg, _ time.Time) (*dnsMsg, error) {
-		r := &dnsMsg{
-			dnsMsgHdr: dnsMsgHdr{
-				id:       q.id,
-				response: true,
+	fake := fakeDNSServer{func(_, _ string, q *dnsmessage.Message, _ time.Time) (*dnsmessage.Message, error) {
+		r := &dnsmessage.Message{
+			Header: dnsmessage.Header{
+				ID:       q.ID,
+				Response: true,
 			},
-			question: q.question,
+			Questions: q.Questions,
 		}
 
-		switch q.question[0].Name {
+		switch q.Questions[0].Name {
 		case "example.com.":
-			r.rcode = dnsRcodeSuccess
+			r.RCode = dnsmessage.RCodeSuccess
 		default:
-			r.rcode = dnsRcodeNameError
+			r.RCode = dnsmessage.RCodeNameError
 		}
 
 		return r, nil
@@ -127,10 +128,10 @@ func TestSpecialDomainName(t *testing.T) {
 			t.Error(err)
 			continue
 		}
-		switch msg.rcode {
-		case tt.rcode, dnsRcodeServerFailure:
+		switch msg.RCode {
+		case tt.rcode, dnsmessage.RCodeServerFailure:
 		default:
-			t.Errorf("got %v from %v; want %v", msg.rcode, server, tt.rcode)
+			t.Errorf("got %v from %v; want %v", msg.RCode, server, tt.rcode)
 			continue
 		}
 	}
@@ -177,22 +178,22 @@ func TestAvoidDNSName(t *testing.T) {
 	}
 }
 
-var fakeDNSServerSuccessful = fakeDNSServer{func(_, _ string, q *dnsMsg, _ time.Time) (*dnsMsg, error) {
-	r := &dnsMsg{
-		dnsMsgHdr: dnsMsgHdr{
-			id:       q.id,
-			response: true,
+var fakeDNSServerSuccessful = fakeDNSServer{func(_, _ string, q *dnsmessage.Message, _ time.Time) (*dnsmessage.Message, error) {
+	r := &dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:       q.ID,
+			Response: true,
 		},
-		question: q.question,
-	}
-	if len(q.question) == 1 && q.question[0].Qtype == dnsTypeA {
-		r.answer = []dnsRR{
-			&dnsRR_A{
-				Hdr: dnsRR_Header{
-					Name:     q.question[0].Name,
-					Rrtype:   dnsTypeA,
-					Class:    dnsClassINET,
-					Rdlength: 4,
+		Questions: q.Questions,
+	}
+	if len(q.Questions) == 1 && q.Questions[0].Type == dnsmessage.TypeA {
+		r.Answers = []dnsmessage.RR{
+			&dnsmessage.A{
+				Hdr: dnsmessage.RRHeader{
+					Name:   q.Questions[0].Name,
+					Type:   dnsmessage.TypeA,
+					Class:  dnsmessage.ClassINET,
+					Length: 4,
 				},
 				A: TestAddr,
 			},
@@ -460,7 +461,7 @@ var goLookupIPWithResolverConfigTests = []struct {
 func TestGoLookupIPWithResolverConfig(t *testing.T) {
 	defer dnsWaitGroup.Wait()
 
-	fake := fakeDNSServer{func(n, s string, q *dnsMsg, _ time.Time) (*dnsMsg, error) {
+	fake := fakeDNSServer{func(n, s string, q *dnsmessage.Message, _ time.Time) (*dnsmessage.Message, error) {
 		switch s {
 		case "[2001:4860:4860::8888]:53", "8.8.8.8:53":
 			break
@@ -468,43 +469,43 @@ func TestGoLookupIPWithResolverConfig(t *testing.T) {
 			time.Sleep(10 * time.Millisecond)
 			return nil, poll.ErrTimeout
 		}
-		r := &dnsMsg{
-			dnsMsgHdr: dnsMsgHdr{
-				id:       q.id,
-				response: true,
+		r := &dnsmessage.Message{
+			Header: dnsmessage.Header{
+				ID:       q.ID,
+				Response: true,
 			},
-			question: q.question,
+			Questions: q.Questions,
 		}
-		for _, question := range q.question {
-			switch question.Qtype {
-			case dnsTypeA:
+		for _, question := range q.Questions {
+			switch question.Type {
+			case dnsmessage.TypeA:
 				switch question.Name {
 				case "hostname.as112.net.":
 					break
 				case "ipv4.google.com.":
-					r.answer = append(r.answer, &dnsRR_A{
-						Hdr: dnsRR_Header{
-							Name:     q.question[0].Name,
-							Rrtype:   dnsTypeA,
-							Class:    dnsClassINET,
-							Rdlength: 4,
+					r.Answers = append(r.Answers, &dnsmessage.A{
+						Hdr: dnsmessage.RRHeader{
+							Name:   q.Questions[0].Name,
+							Type:   dnsmessage.TypeA,
+							Class:  dnsmessage.ClassINET,
+							Length: 4,
 						},
 						A: TestAddr,
 					})
 				default:
 
 				}
-			case dnsTypeAAAA:
+			case dnsmessage.TypeAAAA:
 				switch question.Name {
 				case "hostname.as112.net.":
 					break
 				case "ipv6.google.com.":
-					r.answer = append(r.answer, &dnsRR_AAAA{
-						Hdr: dnsRR_Header{
-							Name:     q.question[0].Name,
-							Rrtype:   dnsTypeAAAA,
-							Class:    dnsClassINET,
-							Rdlength: 16,
+					r.Answers = append(r.Answers, &dnsmessage.AAAA{
+						Hdr: dnsmessage.RRHeader{
+							Name:   q.Questions[0].Name,
+							Type:   dnsmessage.TypeAAAA,
+							Class:  dnsmessage.ClassINET,
+							Length: 16,
 						},
 						AAAA: TestAddr6,
 					})
@@ -554,13 +555,13 @@ func TestGoLookupIPWithResolverConfig(t *testing.T) {
 func TestGoLookupIPOrderFallbackToFile(t *testing.T) {
 	defer dnsWaitGroup.Wait()
 
-	fake := fakeDNSServer{func(n, s string, q *dnsMsg, tm time.Time) (*dnsMsg, error) {
-		r := &dnsMsg{
-			dnsMsgHdr: dnsMsgHdr{
-				id:       q.id,
-				response: true,
+	fake := fakeDNSServer{func(n, s string, q *dnsmessage.Message, tm time.Time) (*dnsmessage.Message, error) {
+		r := &dnsmessage.Message{
+			Header: dnsmessage.Header{
+				ID:       q.ID,
+				Response: true,
 			},
-			question: q.question,
+			Questions: q.Questions,
 		}
 		return r, nil
 	}}
@@ -624,20 +625,20 @@ func TestErrorForOriginalNameWhenSearching(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	fake := fakeDNSServer{func(_, _ string, q *dnsMsg, _ time.Time) (*dnsMsg, error) {
-		r := &dnsMsg{
-			dnsMsgHdr: dnsMsgHdr{
-				id:       q.id,
-				response: true,
+	fake := fakeDNSServer{func(_, _ string, q *dnsmessage.Message, _ time.Time) (*dnsmessage.Message, error) {
+		r := &dnsmessage.Message{
+			Header: dnsmessage.Header{
+				ID:       q.ID,
+				Response: true,
 			},
-			question: q.question,
+			Questions: q.Questions,
 		}
 
-		switch q.question[0].Name {
+		switch q.Questions[0].Name {
 		case fqdn + ".servfail.":
-			r.rcode = dnsRcodeServerFailure
+			r.RCode = dnsmessage.RCodeServerFailure
 		default:
-			r.rcode = dnsRcodeNameError
+			r.RCode = dnsmessage.RCodeNameError
 		}
 
 		return r, nil
@@ -679,26 +680,26 @@ func TestIgnoreLameReferrals(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	fake := fakeDNSServer{func(_, s string, q *dnsMsg, _ time.Time) (*dnsMsg, error) {
+	fake := fakeDNSServer{func(_, s string, q *dnsmessage.Message, _ time.Time) (*dnsmessage.Message, error) {
 		t.Log(s, q)
-		r := &dnsMsg{
-			dnsMsgHdr: dnsMsgHdr{
-				id:       q.id,
-				response: true,
+		r := &dnsmessage.Message{
+			Header: dnsmessage.Header{
+				ID:       q.ID,
+				Response: true,
 			},
-			question: q.question,
+			Questions: q.Questions,
 		}
 
 		if s == "192.0.2.2:53" {
-			r.recursion_available = true
-			if q.question[0].Qtype == dnsTypeA {
-				r.answer = []dnsRR{
-					&dnsRR_A{
-						Hdr: dnsRR_Header{
-							Name:     q.question[0].Name,
-							Rrtype:   dnsTypeA,
-							Class:    dnsClassINET,
-							Rdlength: 4,
+			r.RecursionAvailable = true
+			if q.Questions[0].Type == dnsmessage.TypeA {
+				r.Answers = []dnsmessage.RR{
+					&dnsmessage.A{
+						Hdr: dnsmessage.RRHeader{
+							Name:   q.Questions[0].Name,
+							Type:   dnsmessage.TypeA,
+							Class:  dnsmessage.ClassINET,
+							Length: 4,
 						},
 						A: TestAddr,
 					},
@@ -766,7 +767,7 @@ func BenchmarkGoLookupIPWithBrokenNameServer(b *testing.B) {
 }
 
 type fakeDNSServer struct {
-	rh func(n, s string, q *dnsMsg, t time.Time) (*dnsMsg, error)
+	rh func(n, s string, q *dnsmessage.Message, t time.Time) (*dnsmessage.Message, error)
 }
 
 func (server *fakeDNSServer) DialContext(_ context.Context, n, s string) (Conn, error) {
@@ -778,7 +779,7 @@ type fakeDNSConn struct {
 	server *fakeDNSServer
 	n      string
 	s      string
-	q      *dnsMsg
+	q      *dnsmessage.Message
 	t      time.Time
 }
 
@@ -792,8 +793,8 @@ func (f *fakeDNSConn) Read(b []byte) (int, error) {
 		return 0, err
 	}
 
-	bb, ok := resp.Pack()
-	if !ok {
+	bb, err := resp.Pack()
+	if err != nil {
 		return 0, errors.New("cannot marshal DNS message")
 	}
 	if len(b) < len(bb) {
@@ -809,8 +810,8 @@ func (f *fakeDNSConn) ReadFrom(b []byte) (int, Addr, error) {
 }
 
 func (f *fakeDNSConn) Write(b []byte) (int, error) {
-	f.q = new(dnsMsg)
-	if !f.q.Unpack(b) {
+	f.q = new(dnsmessage.Message)
+	if f.q.Unpack(b) != nil {
 		return 0, errors.New("cannot unmarshal DNS message")
 	}
 	return len(b), nil
@@ -836,53 +837,53 @@ func TestIgnoreDNSForgeries(t *testing.T) {
 			return
 		}
 
-		msg := &dnsMsg{}
-		if !msg.Unpack(b[:n]) {
+		msg := &dnsmessage.Message{}
+		if msg.Unpack(b[:n]) != nil {
 			t.Error("invalid DNS query")
 			return
 		}
 
 		s.Write([]byte("garbage DNS response packet"))
 
-		msg.response = true
-		msg.id++ // make invalid ID
-		b, ok := msg.Pack()
-		if !ok {
+		msg.Response = true
+		msg.ID++ // make invalid ID
+		b, err = msg.Pack()
+		if err != nil {
 			t.Error("failed to pack DNS response")
 			return
 		}
 		s.Write(b)
 
-		msg.id-- // restore original ID
-		msg.answer = []dnsRR{
-			&dnsRR_A{
-				Hdr: dnsRR_Header{
-					Name:     "www.example.com.",
-					Rrtype:   dnsTypeA,
-					Class:    dnsClassINET,
-					Rdlength: 4,
+		msg.ID-- // restore original ID
+		msg.Answers = []dnsmessage.RR{
+			&dnsmessage.A{
+				Hdr: dnsmessage.RRHeader{
+					Name:   "www.example.com.",
+					Type:   dnsmessage.TypeA,
+					Class:  dnsmessage.ClassINET,
+					Length: 4,
 				},
 				A: TestAddr,
 			},
 		}
 
-		b, ok = msg.Pack()
-		if !ok {
+		b, err = msg.Pack()
+		if err != nil {
 			t.Error("failed to pack DNS response")
 			return
 		}
 		s.Write(b)
 	}()
 
-	msg := &dnsMsg{
-		dnsMsgHdr: dnsMsgHdr{
-			id: 42,
+	msg := &dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID: 42,
 		},
-		question: []dnsQuestion{
+		Questions: []dnsmessage.Question{
 			{
-				Name:   "www.example.com.",
-				Qtype:  dnsTypeA,
-				Qclass: dnsClassINET,
+				Name:  "www.example.com.",
+				Type:  dnsmessage.TypeA,
+				Class: dnsmessage.ClassINET,
 			},
 		},
 	}
@@ -893,7 +894,7 @@ func TestIgnoreDNSForgeries(t *testing.T) {
 		t.Fatalf("dnsRoundTripUDP failed: %v", err)
 	}
 
-	if got := resp.answer[0].(*dnsRR_A).A; got != TestAddr {
+	if got := resp.Answers[0].(*dnsmessage.A).A; got != TestAddr {
 		t.Errorf("got address %v, want %v", got, TestAddr)
 	}
 }
@@ -918,7 +919,7 @@ func TestRetryTimeout(t *testing.T) {
 
 	var deadline0 time.Time
 
-	fake := fakeDNSServer{func(_, s string, q *dnsMsg, deadline time.Time) (*dnsMsg, error) {
+	fake := fakeDNSServer{func(_, s string, q *dnsmessage.Message, deadline time.Time) (*dnsmessage.Message, error) {
 		t.Log(s, q, deadline)
 
 		if deadline.IsZero() {
@@ -979,7 +980,7 @@ func testRotate(t *testing.T, rotate bool, nameservers, wantServers []string) {
 	}
 
 	var usedServers []string
-	fake := fakeDNSServer{func(_, s string, q *dnsMsg, deadline time.Time) (*dnsMsg, error) {
+	fake := fakeDNSServer{func(_, s string, q *dnsmessage.Message, deadline time.Time) (*dnsmessage.Message, error) {
 		usedServers = append(usedServers, s)
 		return mockTXTResponse(q), nil
 	}}
@@ -997,22 +998,22 @@ func testRotate(t *testing.T, rotate bool, nameservers, wantServers []string) {
 	}
 }
 
-func mockTXTResponse(q *dnsMsg) *dnsMsg {
-	r := &dnsMsg{
-		dnsMsgHdr: dnsMsgHdr{
-			id:                  q.id,
-			response:            true,
-			recursion_available: true,
+func mockTXTResponse(q *dnsmessage.Message) *dnsmessage.Message {
+	r := &dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 q.ID,
+			Response:           true,
+			RecursionAvailable: true,
 		},
-		question: q.question,
-		answer: []dnsRR{
-			&dnsRR_TXT{
-				Hdr: dnsRR_Header{
-					Name:   q.question[0].Name,
-					Rrtype: dnsTypeTXT,
-					Class:  dnsClassINET,
+		Questions: q.Questions,
+		Answers: []dnsmessage.RR{
+			&dnsmessage.TXT{
+				Hdr: dnsmessage.RRHeader{
+					Name:  q.Questions[0].Name,
+					Type:  dnsmessage.TypeTXT,
+					Class: dnsmessage.ClassINET,
 				},
-				Txt: "ok",
+				TXT: []string{"ok"},
 			},
 		},
 	}
@@ -1080,21 +1081,21 @@ func TestStrictErrorsLookupIP(t *testing.T) {
 
 	cases := []struct {
 		desc          string
-		resolveWhich  func(quest *dnsQuestion) resolveWhichEnum
+		resolveWhich  func(quest *dnsmessage.Question) resolveWhichEnum
 		wantStrictErr error
 		wantLaxErr    error
 		wantIPs       []string
 	}{
 		{
 			desc: "No errors",
-			resolveWhich: func(quest *dnsQuestion) resolveWhichEnum {
+			resolveWhich: func(quest *dnsmessage.Question) resolveWhichEnum {
 				return resolveOK
 			},
 			wantIPs: []string{ip4, ip6},
 		},
 		{
 			desc: "searchX error fails in strict mode",
-			resolveWhich: func(quest *dnsQuestion) resolveWhichEnum {
+			resolveWhich: func(quest *dnsmessage.Question) resolveWhichEnum {
 				if quest.Name == searchX {
 					return resolveTimeout
 				}
@@ -1105,8 +1106,8 @@ func TestStrictErrorsLookupIP(t *testing.T) {
 		},
 		{
 			desc: "searchX IPv4-only timeout fails in strict mode",
-			resolveWhich: func(quest *dnsQuestion) resolveWhichEnum {
-				if quest.Name == searchX && quest.Qtype == dnsTypeA {
+			resolveWhich: func(quest *dnsmessage.Question) resolveWhichEnum {
+				if quest.Name == searchX && quest.Type == dnsmessage.TypeA {
 					return resolveTimeout
 				}
 				return resolveOK
@@ -1116,8 +1117,8 @@ func TestStrictErrorsLookupIP(t *testing.T) {
 		},
 		{
 			desc: "searchX IPv6-only servfail fails in strict mode",
-			resolveWhich: func(quest *dnsQuestion) resolveWhichEnum {
-				if quest.Name == searchX && quest.Qtype == dnsTypeAAAA {
+			resolveWhich: func(quest *dnsmessage.Question) resolveWhichEnum {
+				if quest.Name == searchX && quest.Type == dnsmessage.TypeAAAA {
 					return resolveServfail
 				}
 				return resolveOK
@@ -1127,7 +1128,7 @@ func TestStrictErrorsLookupIP(t *testing.T) {
 		},
 		{
 			desc: "searchY error always fails",
-			resolveWhich: func(quest *dnsQuestion) resolveWhichEnum {
+			resolveWhich: func(quest *dnsmessage.Question) resolveWhichEnum {
 				if quest.Name == searchY {
 					return resolveTimeout
 				}
@@ -1138,8 +1139,8 @@ func TestStrictErrorsLookupIP(t *testing.T) {
 		},
 		{
 			desc: "searchY IPv4-only socket error fails in strict mode",
-			resolveWhich: func(quest *dnsQuestion) resolveWhichEnum {
-				if quest.Name == searchY && quest.Qtype == dnsTypeA {
+			resolveWhich: func(quest *dnsmessage.Question) resolveWhichEnum {
+				if quest.Name == searchY && quest.Type == dnsmessage.TypeA {
 					return resolveOpError
 				}
 				return resolveOK
@@ -1149,8 +1150,8 @@ func TestStrictErrorsLookupIP(t *testing.T) {
 		},
 		{
 			desc: "searchY IPv6-only timeout fails in strict mode",
-			resolveWhich: func(quest *dnsQuestion) resolveWhichEnum {
-				if quest.Name == searchY && quest.Qtype == dnsTypeAAAA {
+			resolveWhich: func(quest *dnsmessage.Question) resolveWhichEnum {
+				if quest.Name == searchY && quest.Type == dnsmessage.TypeAAAA {
 					return resolveTimeout
 				}
 				return resolveOK
@@ -1161,22 +1162,22 @@ func TestStrictErrorsLookupIP(t *testing.T) {
 	}
 
 	for i, tt := range cases {
-		fake := fakeDNSServer{func(_, s string, q *dnsMsg, deadline time.Time) (*dnsMsg, error) {
+		fake := fakeDNSServer{func(_, s string, q *dnsmessage.Message, deadline time.Time) (*dnsmessage.Message, error) {
 			t.Log(s, q)
 
-			switch tt.resolveWhich(&q.question[0]) {
+			switch tt.resolveWhich(&q.Questions[0]) {
 			case resolveOK:
 				// Handle below.
 			case resolveOpError:
 				return nil, &OpError{Op: "write", Err: fmt.Errorf("socket on fire")}
 			case resolveServfail:
-				return &dnsMsg{
-					dnsMsgHdr: dnsMsgHdr{
-						id:       q.id,
-						response: true,
-						rcode:    dnsRcodeServerFailure,
+				return &dnsmessage.Message{
+					Header: dnsmessage.Header{
+						ID:       q.ID,
+						Response: true,
+						RCode:    dnsmessage.RCodeServerFailure,
 					},
-					question: q.question,
+					Questions: q.Questions,
 				}, nil
 			case resolveTimeout:
 				return nil, poll.ErrTimeout
@@ -1184,57 +1185,57 @@ func TestStrictErrorsLookupIP(t *testing.T) {
 				t.Fatal("Impossible resolveWhich")
 			}
 
-			switch q.question[0].Name {
+			switch q.Questions[0].Name {
 			case searchX, name + ".":
 				// Return NXDOMAIN to utilize the search list.
-				return &dnsMsg{
-					dnsMsgHdr: dnsMsgHdr{
-						id:       q.id,
-						response: true,
-						rcode:    dnsRcodeNameError,
+				return &dnsmessage.Message{
+					Header: dnsmessage.Header{
+						ID:       q.ID,
+						Response: true,
+						RCode:    dnsmessage.RCodeNameError,
 					},
-					question: q.question,
+					Questions: q.Questions,
 				}, nil
 			case searchY:
 				// Return records below.
 			default:
-				return nil, fmt.Errorf("Unexpected Name: %v", q.question[0].Name)
+				return nil, fmt.Errorf("Unexpected Name: %v", q.Questions[0].Name)
 			}
 
-			r := &dnsMsg{
-				dnsMsgHdr: dnsMsgHdr{
-					id:       q.id,
-					response: true,
+			r := &dnsmessage.Message{
+				Header: dnsmessage.Header{
+					ID:       q.ID,
+					Response: true,
 				},
-				question: q.question,
+				Questions: q.Questions,
 			}
-			switch q.question[0].Qtype {
-			case dnsTypeA:
-				r.answer = []dnsRR{
-					&dnsRR_A{
-						Hdr: dnsRR_Header{
-							Name:     q.question[0].Name,
-							Rrtype:   dnsTypeA,
-							Class:    dnsClassINET,
-							Rdlength: 4,
+			switch q.Questions[0].Type {
+			case dnsmessage.TypeA:
+				r.Answers = []dnsmessage.RR{
+					&dnsmessage.A{
+						Hdr: dnsmessage.RRHeader{
+							Name:   q.Questions[0].Name,
+							Type:   dnsmessage.TypeA,
+							Class:  dnsmessage.ClassINET,
+							Length: 4,
 						},
 						A: TestAddr,
 					},
 				}
-			case dnsTypeAAAA:
-				r.answer = []dnsRR{
-					&dnsRR_AAAA{
-						Hdr: dnsRR_Header{
-							Name:     q.question[0].Name,
-							Rrtype:   dnsTypeAAAA,
-							Class:    dnsClassINET,
-							Rdlength: 16,
+			case dnsmessage.TypeAAAA:
+				r.Answers = []dnsmessage.RR{
+					&dnsmessage.AAAA{
+						Hdr: dnsmessage.RRHeader{
+							Name:   q.Questions[0].Name,
+							Type:   dnsmessage.TypeAAAA,
+							Class:  dnsmessage.ClassINET,
+							Length: 16,
 						},
 						AAAA: TestAddr6,
 					},
 				}
 			default:
-				return nil, fmt.Errorf("Unexpected Qtype: %v", q.question[0].Qtype)
+				return nil, fmt.Errorf("Unexpected Type: %v", q.Questions[0].Type)
 			}
 			return r, nil
 		}}
@@ -1295,22 +1296,22 @@ func TestStrictErrorsLookupTXT(t *testing.T) {
 	const searchY = "test.y.golang.org."
 	const txt = "Hello World"
 
-	fake := fakeDNSServer{func(_, s string, q *dnsMsg, deadline time.Time) (*dnsMsg, error) {
+	fake := fakeDNSServer{func(_, s string, q *dnsmessage.Message, deadline time.Time) (*dnsmessage.Message, error) {
 		t.Log(s, q)
 
-		switch q.question[0].Name {
+		switch q.Questions[0].Name {
 		case searchX:
 			return nil, poll.ErrTimeout
 		case searchY:
 			return mockTXTResponse(q), nil
 		default:
-			return nil, fmt.Errorf("Unexpected Name: %v", q.question[0].Name)
+			return nil, fmt.Errorf("Unexpected Name: %v", q.Questions[0].Name)
 		}
 	}}
 
 	for _, strict := range []bool{true, false} {
 		r := Resolver{StrictErrors: strict, Dial: fake.DialContext}
-		_, rrs, err := r.lookup(context.Background(), name, dnsTypeTXT)
+		_, rrs, err := r.lookup(context.Background(), name, dnsmessage.TypeTXT)
 		var wantErr error
 		var wantRRs int
 		if strict {
@@ -1332,12 +1333,219 @@ func TestStrictErrorsLookupTXT(t *testing.T) {
 	}
 }
 
+func TestLookupRecords(t *testing.T) {
+	fake := fakeDNSServer{func(_, _ string, q *dnsmessage.Message, _ time.Time) (*dnsmessage.Message, error) {
+		r := &dnsmessage.Message{
+			Header: dnsmessage.Header{
+				ID:                 q.ID,
+				Response:           true,
+				RecursionAvailable: true,
+			},
+			Questions: q.Questions,
+		}
+		if q.EDNS0() == nil {
+			return nil, errors.New("query without EDNS(0) OPT record")
+		}
+		r.SetEDNS0(maxDNSPacketSize, false)
+		if q.Questions[0].Name != "golang.org." || q.Questions[0].Type != dnsmessage.TypeCAA {
+			r.RCode = dnsmessage.RCodeNameError
+			return r, nil
+		}
+		r.Answers = []dnsmessage.RR{
+			&dnsmessage.CNAME{
+				Hdr:   dnsmessage.RRHeader{Name: "golang.org.", Type: dnsmessage.TypeCNAME, Class: dnsmessage.ClassINET},
+				CNAME: "golang.example.",
+			},
+			&dnsmessage.CAA{
+				Hdr:   dnsmessage.RRHeader{Name: "golang.example.", Type: dnsmessage.TypeCAA, Class: dnsmessage.ClassINET},
+				Tag:   "issue",
+				Value: "ca.example",
+			},
+		}
+		return r, nil
+	}}
+	r := Resolver{PreferGo: true, Dial: fake.DialContext}
+
+	rrs, err := r.LookupRecords(context.Background(), "golang.org.", dnsmessage.TypeCAA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rrs) != 1 {
+		t.Fatalf("got %d records; want 1", len(rrs))
+	}
+	if caa, ok := rrs[0].(*dnsmessage.CAA); !ok || caa.Tag != "issue" || caa.Value != "ca.example" {
+		t.Errorf("got %#v; want CAA issue record", rrs[0])
+	}
+
+	_, err = r.LookupRecords(context.Background(), "golang.org.", dnsmessage.TypeTLSA)
+	if de, ok := err.(*DNSError); !ok || de.Err != errNoSuchHost.Error() {
+		t.Errorf("got %v; want no such host error", err)
+	}
+}
+
+func TestResolverExchange(t *testing.T) {
+	conf, err := newResolvConfTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conf.teardown()
+	if err := conf.writeAndUpdate([]string{"nameserver 192.0.2.53"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var servers []string
+	forge := false
+	r := Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (Conn, error) {
+			t.Errorf("Dial(%q, %q) called with Exchange set", network, address)
+			return nil, errors.New("unexpected Dial")
+		},
+		Exchange: func(ctx context.Context, server string, q *dnsmessage.Message) (*dnsmessage.Message, error) {
+			servers = append(servers, server)
+			if _, ok := ctx.Deadline(); !ok {
+				t.Error("Exchange called without deadline")
+			}
+			resp := &dnsmessage.Message{
+				Header: dnsmessage.Header{
+					ID:                 q.ID,
+					Response:           true,
+					RecursionAvailable: true,
+				},
+				Questions: q.Questions,
+				Answers: []dnsmessage.RR{
+					&dnsmessage.HTTPS{SVCB: dnsmessage.SVCB{
+						Hdr:      dnsmessage.RRHeader{Name: q.Questions[0].Name, Type: dnsmessage.TypeHTTPS, Class: dnsmessage.ClassINET},
+						Priority: 1,
+						Target:   ".",
+						Params:   []dnsmessage.SVCParam{{Key: dnsmessage.SVCParamALPN, Value: []byte("\x02h2")}},
+					}},
+				},
+			}
+			if forge {
+				resp.ID++
+			}
+			return resp, nil
+		},
+	}
+
+	rrs, err := r.LookupRecords(context.Background(), "golang.org.", dnsmessage.TypeHTTPS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rrs) != 1 {
+		t.Fatalf("got %d records; want 1", len(rrs))
+	}
+	https, ok := rrs[0].(*dnsmessage.HTTPS)
+	if !ok {
+		t.Fatalf("got %T; want *dnsmessage.HTTPS", rrs[0])
+	}
+	if alpn, _ := https.Param(dnsmessage.SVCParamALPN); string(alpn) != "\x02h2" {
+		t.Errorf("alpn = %q; want %q", alpn, "\x02h2")
+	}
+	if want := []string{"192.0.2.53:53"}; !reflect.DeepEqual(servers, want) {
+		t.Errorf("Exchange called for servers %v; want %v", servers, want)
+	}
+
+	forge = true
+	_, err = r.LookupRecords(context.Background(), "golang.org.", dnsmessage.TypeHTTPS)
+	if de, ok := err.(*DNSError); !ok || de.Err != errInvalidDNSResponse.Error() {
+		t.Errorf("got %v for response with wrong ID; want %v", err, errInvalidDNSResponse)
+	}
+}
+
+func TestResolverCache(t *testing.T) {
+	defer dnsWaitGroup.Wait()
+
+	conf, err := newResolvConfTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conf.teardown()
+	if err := conf.writeAndUpdate([]string{"nameserver 192.0.2.53"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	queries := 0
+	fake := fakeDNSServer{func(_, _ string, q *dnsmessage.Message, _ time.Time) (*dnsmessage.Message, error) {
+		mu.Lock()
+		queries++
+		mu.Unlock()
+		r := &dnsmessage.Message{
+			Header: dnsmessage.Header{
+				ID:                 q.ID,
+				Response:           true,
+				RecursionAvailable: true,
+			},
+			Questions: q.Questions,
+		}
+		switch q.Questions[0].Name {
+		case "www.golang.org.":
+			if q.Questions[0].Type == dnsmessage.TypeA {
+				r.Answers = []dnsmessage.RR{
+					&dnsmessage.A{
+						Hdr: dnsmessage.RRHeader{Name: "www.golang.org.", Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 300},
+						A:   TestAddr,
+					},
+				}
+				return r, nil
+			}
+		default:
+			r.RCode = dnsmessage.RCodeNameError
+		}
+		r.Authorities = []dnsmessage.RR{
+			&dnsmessage.SOA{
+				Hdr:    dnsmessage.RRHeader{Name: "golang.org.", Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET, TTL: 300},
+				NS:     "ns.golang.org.",
+				MBox:   "hostmaster.golang.org.",
+				MinTTL: 300,
+			},
+		}
+		return r, nil
+	}}
+	cache := new(DNSCache)
+	r := Resolver{PreferGo: true, Dial: fake.DialContext, Cache: cache}
+	lookup := func() {
+		if addrs, err := r.LookupIPAddr(context.Background(), "www.golang.org."); err != nil || len(addrs) != 1 {
+			t.Errorf("LookupIPAddr(www.golang.org.) = %v, %v; want one address", addrs, err)
+		}
+		if _, err := r.LookupRecords(context.Background(), "nx.golang.org.", dnsmessage.TypeTXT); err == nil {
+			t.Error("LookupRecords(nx.golang.org.) succeeded")
+		}
+	}
+
+	lookup()
+	if queries != 3 {
+		t.Fatalf("got %d queries; want 3", queries)
+	}
+	lookup()
+	if queries != 3 {
+		t.Errorf("got %d queries for cached names; want none", queries-3)
+	}
+	if stats := cache.Stats(); stats.Entries != 3 || stats.Hits != 3 || stats.NegativeHits != 2 {
+		t.Errorf("got %+v; want 3 entries, 3 hits and 2 negative hits", stats)
+	}
+
+	// Changing resolv.conf invalidates the cache.
+	if err := os.Chtimes(conf.path, time.Now(), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.forceUpdate(conf.path, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	lookup()
+	if queries != 6 {
+		t.Errorf("got %d queries after resolv.conf change; want 3", queries-3)
+	}
+}
+
 // Test for a race between uninstalling the test hooks and closing a
 // socket connection. This used to fail when testing with -race.
 func TestDNSGoroutineRace(t *testing.T) {
 	defer dnsWaitGroup.Wait()
 
-	fake := fakeDNSServer{func(n, s string, q *dnsMsg, t time.Time) (*dnsMsg, error) {
+	fake := fakeDNSServer{func(n, s string, q *dnsmessage.Message, t time.Time) (*dnsmessage.Message, error) {
 		time.Sleep(10 * time.Microsecond)
 		return nil, poll.ErrTimeout
 	}}
@@ -0,0 +1,460 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package dnsmessage provides DNS message assembly and parsing,
+// as described in RFC 1035, including EDNS(0) (RFC 6891).
+//
+// A Message holds a header, the question section and three sections
+// of resource records. Each resource record type is a struct whose
+// first field, Hdr, is the RRHeader common to all records; records
+// of types this package does not know are returned as *UnknownRR.
+//
+// Messages are packed without domain name compression. That keeps
+// the packed size of a message the sum of the sizes of its parts,
+// which is what Truncate relies on.
+//
+// The net package's resolver uses this package to speak DNS, and a
+// Resolver's Exchange hook is handed and returns values of its
+// Message type.
+package dnsmessage
+
+import (
+	"errors"
+	"strconv"
+)
+
+// A Type is the type of a resource record or of the records asked
+// for by a question.
+type Type uint16
+
+// Resource record types.
+const (
+	TypeA     Type = 1
+	TypeNS    Type = 2
+	TypeCNAME Type = 5
+	TypeSOA   Type = 6
+	TypePTR   Type = 12
+	TypeMX    Type = 15
+	TypeTXT   Type = 16
+	TypeAAAA  Type = 28
+	TypeSRV   Type = 33
+	TypeOPT   Type = 41
+	TypeTLSA  Type = 52
+	TypeSVCB  Type = 64
+	TypeHTTPS Type = 65
+	TypeCAA   Type = 257
+
+	// valid in questions only
+	TypeAXFR Type = 252
+	TypeALL  Type = 255
+)
+
+var typeNames = map[Type]string{
+	TypeA:     "A",
+	TypeNS:    "NS",
+	TypeCNAME: "CNAME",
+	TypeSOA:   "SOA",
+	TypePTR:   "PTR",
+	TypeMX:    "MX",
+	TypeTXT:   "TXT",
+	TypeAAAA:  "AAAA",
+	TypeSRV:   "SRV",
+	TypeOPT:   "OPT",
+	TypeTLSA:  "TLSA",
+	TypeSVCB:  "SVCB",
+	TypeHTTPS: "HTTPS",
+	TypeCAA:   "CAA",
+	TypeAXFR:  "AXFR",
+	TypeALL:   "ALL",
+}
+
+// String returns the mnemonic of t, such as "AAAA", or "TYPE" followed
+// by its number if it has none (RFC 3597).
+func (t Type) String() string {
+	if s, ok := typeNames[t]; ok {
+		return s
+	}
+	return "TYPE" + strconv.Itoa(int(t))
+}
+
+// A Class is the class of a resource record or question.
+type Class uint16
+
+// Resource record classes.
+const (
+	ClassINET   Class = 1
+	ClassCSNET  Class = 2
+	ClassCHAOS  Class = 3
+	ClassHESIOD Class = 4
+	ClassANY    Class = 255
+)
+
+// An RCode is the response code of a message.
+type RCode uint16
+
+// Response codes.
+const (
+	RCodeSuccess        RCode = 0
+	RCodeFormatError    RCode = 1
+	RCodeServerFailure  RCode = 2
+	RCodeNameError      RCode = 3
+	RCodeNotImplemented RCode = 4
+	RCodeRefused        RCode = 5
+)
+
+var rcodeNames = [...]string{
+	RCodeSuccess:        "NOERROR",
+	RCodeFormatError:    "FORMERR",
+	RCodeServerFailure:  "SERVFAIL",
+	RCodeNameError:      "NXDOMAIN",
+	RCodeNotImplemented: "NOTIMP",
+	RCodeRefused:        "REFUSED",
+}
+
+// String returns the mnemonic of r, such as "NXDOMAIN", or "RCODE"
+// followed by its number if it has none.
+func (r RCode) String() string {
+	if int(r) < len(rcodeNames) {
+		return rcodeNames[r]
+	}
+	return "RCODE" + strconv.Itoa(int(r))
+}
+
+var (
+	errBadName     = errors.New("dnsmessage: invalid domain name")
+	errBadField    = errors.New("dnsmessage: field value out of range")
+	errTooLong     = errors.New("dnsmessage: message too long")
+	errTooManyRRs  = errors.New("dnsmessage: too many records in section")
+	errShortHeader = errors.New("dnsmessage: message shorter than header")
+	errBadQuestion = errors.New("dnsmessage: invalid question")
+	errBadRR       = errors.New("dnsmessage: invalid resource record")
+)
+
+// Header bits.
+const (
+	bitQR = 1 << 15 // query/response (response=1)
+	bitAA = 1 << 10 // authoritative
+	bitTC = 1 << 9  // truncated
+	bitRD = 1 << 8  // recursion desired
+	bitRA = 1 << 7  // recursion available
+)
+
+// headerLen is the length of the wire format of a message header.
+const headerLen = 12
+
+// A Header is the header of a message, with its flag bits unpacked.
+type Header struct {
+	ID                 uint16
+	Response           bool
+	OpCode             int
+	Authoritative      bool
+	Truncated          bool
+	RecursionDesired   bool
+	RecursionAvailable bool
+	RCode              RCode
+}
+
+func (h *Header) walk(f walkFunc) bool {
+	return f(&h.ID, "ID", "") &&
+		f(&h.Response, "Response", "") &&
+		f(&h.OpCode, "OpCode", "") &&
+		f(&h.Authoritative, "Authoritative", "") &&
+		f(&h.Truncated, "Truncated", "") &&
+		f(&h.RecursionDesired, "RecursionDesired", "") &&
+		f(&h.RecursionAvailable, "RecursionAvailable", "") &&
+		f(&h.RCode, "RCode", "")
+}
+
+// bits returns the second 16-bit word of the wire format of h.
+func (h *Header) bits() uint16 {
+	bits := uint16(h.OpCode&0xF)<<11 | uint16(h.RCode&0xF)
+	if h.Response {
+		bits |= bitQR
+	}
+	if h.Authoritative {
+		bits |= bitAA
+	}
+	if h.Truncated {
+		bits |= bitTC
+	}
+	if h.RecursionDesired {
+		bits |= bitRD
+	}
+	if h.RecursionAvailable {
+		bits |= bitRA
+	}
+	return bits
+}
+
+func (h *Header) setBits(bits uint16) {
+	h.Response = bits&bitQR != 0
+	h.OpCode = int(bits>>11) & 0xF
+	h.Authoritative = bits&bitAA != 0
+	h.Truncated = bits&bitTC != 0
+	h.RecursionDesired = bits&bitRD != 0
+	h.RecursionAvailable = bits&bitRA != 0
+	h.RCode = RCode(bits & 0xF)
+}
+
+// A Question is an entry of the question section of a message.
+type Question struct {
+	Name  string
+	Type  Type
+	Class Class
+}
+
+func (q *Question) walk(f walkFunc) bool {
+	return f(&q.Name, "Name", "domain") &&
+		f(&q.Type, "Type", "") &&
+		f(&q.Class, "Class", "")
+}
+
+// A Message is a DNS message.
+type Message struct {
+	Header
+	Questions   []Question
+	Answers     []RR
+	Authorities []RR
+	Additionals []RR
+}
+
+// Pack returns the wire format of m. As a side effect, it sets the
+// Length field of the header of each of its resource records and
+// stores the upper bits of an extended m.RCode in its OPT record.
+func (m *Message) Pack() ([]byte, error) {
+	sections := [...][]RR{m.Answers, m.Authorities, m.Additionals}
+	if len(m.Questions) > 0xFFFF {
+		return nil, errTooManyRRs
+	}
+	for _, rrs := range sections {
+		if len(rrs) > 0xFFFF {
+			return nil, errTooManyRRs
+		}
+	}
+	if m.RCode > 0xF && m.EDNS0() == nil {
+		return nil, errBadField
+	}
+
+	msg := make([]byte, headerLen, 512)
+	putUint16(msg[0:], m.ID)
+	putUint16(msg[2:], m.bits())
+	putUint16(msg[4:], uint16(len(m.Questions)))
+	for i, rrs := range sections {
+		putUint16(msg[6+2*i:], uint16(len(rrs)))
+	}
+
+	var err error
+	for i := range m.Questions {
+		if msg, err = packFields(&m.Questions[i], msg); err != nil {
+			return nil, err
+		}
+	}
+	for _, rrs := range sections {
+		for _, rr := range rrs {
+			if rr, ok := rr.(*OPT); ok {
+				// The upper bits of an extended RCODE
+				// live in the OPT record.
+				rr.Hdr.TTL = rr.Hdr.TTL&0x00FFFFFF | uint32(m.RCode>>4)<<24
+			}
+			if msg, err = packRR(rr, msg); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(msg) > 0xFFFF {
+		return nil, errTooLong
+	}
+	return msg, nil
+}
+
+// Unpack sets m to the message whose wire format is msg.
+// If the message has an OPT record, the upper bits of its extended
+// RCODE are merged into m.RCode.
+func (m *Message) Unpack(msg []byte) error {
+	if len(msg) < headerLen {
+		return errShortHeader
+	}
+	*m = Message{}
+	m.ID = getUint16(msg[0:])
+	m.setBits(getUint16(msg[2:]))
+	qdcount := int(getUint16(msg[4:]))
+
+	off := headerLen
+	var ok bool
+	m.Questions = make([]Question, 0, min(qdcount, 4))
+	for i := 0; i < qdcount; i++ {
+		var q Question
+		if off, ok = unpackFields(&q, msg, off, len(msg)); !ok {
+			return errBadQuestion
+		}
+		m.Questions = append(m.Questions, q)
+	}
+	sections := [...]*[]RR{&m.Answers, &m.Authorities, &m.Additionals}
+	for i, rrs := range sections {
+		n := int(getUint16(msg[6+2*i:]))
+		*rrs = make([]RR, 0, min(n, 16))
+		for j := 0; j < n; j++ {
+			var rr RR
+			if rr, off, ok = unpackRR(msg, off); !ok {
+				return errBadRR
+			}
+			*rrs = append(*rrs, rr)
+		}
+	}
+	if opt := m.EDNS0(); opt != nil {
+		m.RCode |= RCode(opt.Hdr.TTL>>24) << 4
+	}
+	return nil
+}
+
+// IsResponseTo reports whether m is an acceptable response to query:
+// a response with the same ID and the same questions, comparing names
+// without regard to ASCII case.
+func (m *Message) IsResponseTo(query *Message) bool {
+	if !m.Response {
+		return false
+	}
+	if m.ID != query.ID {
+		return false
+	}
+	if len(m.Questions) != len(query.Questions) {
+		return false
+	}
+	for i, q := range m.Questions {
+		q2 := query.Questions[i]
+		if !equalASCIILabel(q.Name, q2.Name) || q.Type != q2.Type || q.Class != q2.Class {
+			return false
+		}
+	}
+	return true
+}
+
+// EDNS0 returns the OPT record in the additional section of m,
+// or nil if there is none.
+func (m *Message) EDNS0() *OPT {
+	for _, rr := range m.Additionals {
+		if rr, ok := rr.(*OPT); ok {
+			return rr
+		}
+	}
+	return nil
+}
+
+// SetEDNS0 adds an OPT record to the additional section of m, or
+// updates the one it has, advertising a UDP payload size of udpSize
+// bytes and, if dnssecOK is set, the ability to handle DNSSEC records.
+func (m *Message) SetEDNS0(udpSize int, dnssecOK bool) {
+	opt := m.EDNS0()
+	if opt == nil {
+		opt = &OPT{Hdr: RRHeader{Name: ".", Type: TypeOPT}}
+		m.Additionals = append(m.Additionals, opt)
+	}
+	if udpSize < 512 {
+		udpSize = 512
+	}
+	if udpSize > 0xFFFF {
+		udpSize = 0xFFFF
+	}
+	opt.Hdr.Class = Class(udpSize)
+	opt.Hdr.TTL = 0
+	if dnssecOK {
+		opt.Hdr.TTL = optDO
+	}
+}
+
+// Truncate removes resource records from the end of m so that its
+// wire format fits in size bytes, as a server does before sending a
+// response over UDP. An OPT record is never removed. If answer or
+// authority records had to be removed, m.Truncated is set, telling
+// the client to retry the query over TCP (RFC 2181, section 9).
+// If even the header, the questions and the OPT record do not fit
+// in size bytes, all other records are removed.
+func (m *Message) Truncate(size int) {
+	n := headerLen
+	for i := range m.Questions {
+		b, _ := packFields(&m.Questions[i], nil)
+		n += len(b)
+	}
+	opt := m.EDNS0()
+	if opt != nil {
+		b, _ := packRR(opt, nil)
+		n += len(b)
+	}
+
+	sections := [...]*[]RR{&m.Answers, &m.Authorities, &m.Additionals}
+	full := false
+	for i, rrs := range sections {
+		kept := (*rrs)[:0]
+		for _, rr := range *rrs {
+			if rr == RR(opt) {
+				kept = append(kept, rr)
+				continue
+			}
+			if !full {
+				b, _ := packRR(rr, nil)
+				if n+len(b) <= size {
+					n += len(b)
+					kept = append(kept, rr)
+					continue
+				}
+				full = true
+			}
+			if i < 2 {
+				m.Truncated = true
+			}
+		}
+		for j := len(kept); j < len(*rrs); j++ {
+			(*rrs)[j] = nil
+		}
+		*rrs = kept
+	}
+}
+
+// String returns a multi-line description of m for debugging.
+func (m *Message) String() string {
+	s := "DNS: " + printStruct(&m.Header) + "\n"
+	if len(m.Questions) > 0 {
+		s += "-- Questions\n"
+		for i := range m.Questions {
+			s += printStruct(&m.Questions[i]) + "\n"
+		}
+	}
+	for i, rrs := range [...][]RR{m.Answers, m.Authorities, m.Additionals} {
+		if len(rrs) == 0 {
+			continue
+		}
+		s += [...]string{"-- Answers\n", "-- Authorities\n", "-- Additionals\n"}[i]
+		for _, rr := range rrs {
+			s += printRR(rr) + "\n"
+		}
+	}
+	return s
+}
+
+func equalASCIILabel(x, y string) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := 0; i < len(x); i++ {
+		a := x[i]
+		b := y[i]
+		if 'A' <= a && a <= 'Z' {
+			a += 0x20
+		}
+		if 'A' <= b && b <= 'Z' {
+			b += 0x20
+		}
+		if a != b {
+			return false
+		}
+	}
+	return true
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,627 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dnsmessage
+
+import (
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQuestionPackUnpack(t *testing.T) {
+	want := Question{
+		Name:  ".",
+		Type:  TypeA,
+		Class: ClassINET,
+	}
+	buf, err := packFields(&want, nil)
+	if err != nil {
+		t.Fatal("packing failed:", err)
+	}
+	got := Question{}
+	n, ok := unpackFields(&got, buf, 0, len(buf))
+	if !ok {
+		t.Fatal("unpacking failed")
+	}
+	if n != len(buf) {
+		t.Errorf("unpacked different amount than packed: got n = %d, want = %d", n, len(buf))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %+v, want = %+v", got, want)
+	}
+}
+
+func TestDomainNamePackUnpack(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"", ".", true},
+		{".", ".", true},
+		{"google..com", "", false},
+		{"google.com", "google.com.", true},
+		{"google..com.", "", false},
+		{"google.com.", "google.com.", true},
+		{".google.com.", "", false},
+		{"www..google.com.", "", false},
+		{"www.google.com.", "www.google.com.", true},
+		{strings.Repeat("a", 64) + ".com.", "", false},
+		{strings.Repeat("a.", 128), "", false},
+	}
+
+	for _, test := range tests {
+		buf, ok := appendDomainName(nil, test.in)
+		if ok != test.ok {
+			t.Errorf("packing of %s: got ok = %t, want = %t", test.in, ok, test.ok)
+			continue
+		}
+		if !test.ok {
+			continue
+		}
+		got, n, ok := unpackDomainName(buf, 0)
+		if !ok {
+			t.Errorf("unpacking for %s failed", test.in)
+			continue
+		}
+		if n != len(buf) {
+			t.Errorf(
+				"unpacked different amount than packed for %s: got n = %d, want = %d",
+				test.in,
+				n,
+				len(buf),
+			)
+		}
+		if got != test.want {
+			t.Errorf("unpacking packing of %s: got = %s, want = %s", test.in, got, test.want)
+		}
+	}
+}
+
+func TestMessagePackUnpack(t *testing.T) {
+	want := Message{
+		Questions: []Question{{
+			Name:  ".",
+			Type:  TypeAAAA,
+			Class: ClassINET,
+		}},
+		Answers:     []RR{},
+		Authorities: []RR{},
+		Additionals: []RR{},
+	}
+	b, err := want.Pack()
+	if err != nil {
+		t.Fatal("packing failed:", err)
+	}
+	var got Message
+	if err := got.Unpack(b); err != nil {
+		t.Fatal("unpacking failed:", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %+v, want = %+v", got, want)
+	}
+}
+
+func TestRRPackUnpack(t *testing.T) {
+	hdr := func(name string, typ Type) RRHeader {
+		return RRHeader{Name: name, Type: typ, Class: ClassINET, TTL: 300}
+	}
+	rrs := []RR{
+		&A{Hdr: hdr("a.example.", TypeA), A: [4]byte{192, 0, 2, 1}},
+		&AAAA{Hdr: hdr("a.example.", TypeAAAA), AAAA: [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}},
+		&CNAME{Hdr: hdr("www.example.", TypeCNAME), CNAME: "a.example."},
+		&NS{Hdr: hdr("example.", TypeNS), NS: "ns1.example."},
+		&PTR{Hdr: hdr("1.2.0.192.in-addr.arpa.", TypePTR), PTR: "a.example."},
+		&MX{Hdr: hdr("example.", TypeMX), Pref: 10, MX: "mx.example."},
+		&SOA{Hdr: hdr("example.", TypeSOA), NS: "ns1.example.", MBox: "hostmaster.example.",
+			Serial: 2018010101, Refresh: 7200, Retry: 3600, Expire: 1209600, MinTTL: 300},
+		&TXT{Hdr: hdr("example.", TypeTXT), TXT: []string{"v=spf1 -all", "", "second"}},
+		&SRV{Hdr: hdr("_sip._udp.example.", TypeSRV), Priority: 1, Weight: 5, Port: 5060, Target: "sip.example."},
+		&CAA{Hdr: hdr("example.", TypeCAA), Flag: 128, Tag: "issue", Value: "ca.example; account=1"},
+		&TLSA{Hdr: hdr("_443._tcp.example.", TypeTLSA), Usage: 3, Selector: 1, MatchingType: 1,
+			CertData: []byte{0xde, 0xad, 0xbe, 0xef}},
+		&SVCB{Hdr: hdr("_dns.example.", TypeSVCB), Priority: 1, Target: "dns.example.",
+			Params: []SVCParam{{Key: SVCParamALPN, Value: []byte("\x03dot")}, {Key: SVCParamPort, Value: []byte{0x03, 0x55}}}},
+		&HTTPS{SVCB{Hdr: hdr("example.", TypeHTTPS), Priority: 0, Target: "cdn.example."}},
+		&UnknownRR{Hdr: hdr("example.", 65280), Data: []byte{1, 2, 3}},
+		&OPT{Hdr: RRHeader{Name: ".", Type: TypeOPT, Class: 4096}, Options: []Option{{Code: 10, Data: []byte("cookie12")}}},
+	}
+	for _, rr := range rrs {
+		b, err := packRR(rr, nil)
+		if err != nil {
+			t.Errorf("packRR(%v): %v", printRR(rr), err)
+			continue
+		}
+		got, n, ok := unpackRR(b, 0)
+		if !ok || n != len(b) {
+			t.Errorf("unpackRR(%v) = %v, %d; want true, %d", printRR(rr), ok, n, len(b))
+			continue
+		}
+		if !reflect.DeepEqual(got, rr) {
+			t.Errorf("got %v; want %v", printRR(got), printRR(rr))
+		}
+	}
+}
+
+func TestRRPackWire(t *testing.T) {
+	// An example record of RFC 8659, section 4.
+	caa := &CAA{
+		Hdr:   RRHeader{Name: "example.", Type: TypeCAA, Class: ClassINET, TTL: 3600},
+		Flag:  0,
+		Tag:   "issue",
+		Value: "ca.example.net",
+	}
+	b, err := packRR(caa, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "076578616d706c6500" + "0101" + "0001" + "00000e10" + "0015" +
+		"00" + "05" + hex.EncodeToString([]byte("issue")) + hex.EncodeToString([]byte("ca.example.net"))
+	if got := hex.EncodeToString(b); got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+	if caa.Hdr.Length != 21 {
+		t.Errorf("Length = %d; want 21", caa.Hdr.Length)
+	}
+}
+
+func TestParseSRVReply(t *testing.T) {
+	data, err := hex.DecodeString(dnsSRVReply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := new(Message)
+	if err := msg.Unpack(data); err != nil {
+		t.Fatal("unpacking packet failed:", err)
+	}
+	_ = msg.String() // exercise this code path
+	if g, e := len(msg.Answers), 5; g != e {
+		t.Errorf("len(msg.Answers) = %d; want %d", g, e)
+	}
+	for idx, rr := range msg.Answers {
+		if g, e := rr.Header().Type, TypeSRV; g != e {
+			t.Errorf("rr[%d].Header().Type = %v; want %v", idx, g, e)
+		}
+		if _, ok := rr.(*SRV); !ok {
+			t.Errorf("Answers[%d] = %T; want *SRV", idx, rr)
+		}
+	}
+	// repack and unpack.
+	data2, err := msg.Pack()
+	msg2 := new(Message)
+	msg2.Unpack(data2)
+	switch {
+	case err != nil:
+		t.Error("failed to repack message:", err)
+	case !reflect.DeepEqual(msg, msg2):
+		t.Error("repacked message differs from original")
+	}
+}
+
+func TestParseCorruptSRVReply(t *testing.T) {
+	data, err := hex.DecodeString(dnsSRVCorruptReply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := new(Message)
+	if err := msg.Unpack(data); err != nil {
+		t.Fatal("unpacking packet failed:", err)
+	}
+	_ = msg.String() // exercise this code path
+	if g, e := len(msg.Answers), 5; g != e {
+		t.Errorf("len(msg.Answers) = %d; want %d", g, e)
+	}
+	for idx, rr := range msg.Answers {
+		if g, e := rr.Header().Type, TypeSRV; g != e {
+			t.Errorf("rr[%d].Header().Type = %v; want %v", idx, g, e)
+		}
+		if idx == 4 {
+			if _, ok := rr.(*RRHeader); !ok {
+				t.Errorf("Answers[%d] = %T; want *RRHeader", idx, rr)
+			}
+		} else {
+			if _, ok := rr.(*SRV); !ok {
+				t.Errorf("Answers[%d] = %T; want *SRV", idx, rr)
+			}
+		}
+	}
+}
+
+func TestParseTXTReply(t *testing.T) {
+	expectedTxt1 := "v=spf1 redirect=_spf.google.com"
+	expectedTxt2 := "v=spf1 ip4:69.63.179.25 ip4:69.63.178.128/25 ip4:69.63.184.0/25 " +
+		"ip4:66.220.144.128/25 ip4:66.220.155.0/24 " +
+		"ip4:69.171.232.0/25 ip4:66.220.157.0/25 " +
+		"ip4:69.171.244.0/24 mx -all"
+
+	replies := []string{dnsTXTReply1, dnsTXTReply2}
+	expectedTxts := []string{expectedTxt1, expectedTxt2}
+
+	for i := range replies {
+		data, err := hex.DecodeString(replies[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msg := new(Message)
+		if err := msg.Unpack(data); err != nil {
+			t.Errorf("test %d: unpacking packet failed: %v", i, err)
+			continue
+		}
+
+		if len(msg.Answers) != 1 {
+			t.Errorf("test %d: len(msg.Answers) = %d; want 1", i, len(msg.Answers))
+			continue
+		}
+
+		rr := msg.Answers[0]
+		rrTXT, ok := rr.(*TXT)
+		if !ok {
+			t.Errorf("test %d: Answers[0] = %T; want *TXT", i, rr)
+			continue
+		}
+
+		if txt := strings.Join(rrTXT.TXT, ""); txt != expectedTxts[i] {
+			t.Errorf("test %d: TXT = %s; want %s", i, txt, expectedTxts[i])
+		}
+	}
+}
+
+func TestParseTXTCorruptDataLengthReply(t *testing.T) {
+	replies := []string{dnsTXTCorruptDataLengthReply1, dnsTXTCorruptDataLengthReply2}
+
+	for i := range replies {
+		data, err := hex.DecodeString(replies[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msg := new(Message)
+		if err := msg.Unpack(data); err == nil {
+			t.Errorf("test %d: expected to fail on unpacking corrupt packet", i)
+		}
+	}
+}
+
+func TestParseTXTCorruptTXTLengthReply(t *testing.T) {
+	replies := []string{dnsTXTCorruptTXTLengthReply1, dnsTXTCorruptTXTLengthReply2}
+
+	for i := range replies {
+		data, err := hex.DecodeString(replies[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msg := new(Message)
+		// Unpacking should succeed, but we should just get the header.
+		if err := msg.Unpack(data); err != nil {
+			t.Errorf("test %d: unpacking packet failed: %v", i, err)
+			continue
+		}
+
+		if len(msg.Answers) != 1 {
+			t.Errorf("test %d: len(msg.Answers) = %d; want 1", i, len(msg.Answers))
+			continue
+		}
+
+		rr := msg.Answers[0]
+		if _, justHeader := rr.(*RRHeader); !justHeader {
+			t.Errorf("test %d: rr = %T; expected *RRHeader", i, rr)
+		}
+	}
+}
+
+func TestEDNS0(t *testing.T) {
+	data, err := hex.DecodeString(dnsTXTReply1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg Message
+	if err := msg.Unpack(data); err != nil {
+		t.Fatal(err)
+	}
+	opt := msg.EDNS0()
+	if opt == nil {
+		t.Fatal("no OPT record in reply")
+	}
+	if opt.UDPSize() != 4096 || opt.Version() != 0 || opt.DNSSECOK() {
+		t.Errorf("got UDPSize() = %d, Version() = %d, DNSSECOK() = %v; want 4096, 0, false", opt.UDPSize(), opt.Version(), opt.DNSSECOK())
+	}
+
+	query := Message{
+		Header:    Header{ID: 1, RecursionDesired: true},
+		Questions: []Question{{Name: "example.", Type: TypeTLSA, Class: ClassINET}},
+	}
+	query.SetEDNS0(1232, true)
+	query.SetEDNS0(1232, true)
+	if n := len(query.Additionals); n != 1 {
+		t.Fatalf("got %d additional records after SetEDNS0; want 1", n)
+	}
+	// An extended RCODE (BADVERS) is split between the header and
+	// the OPT record.
+	query.RCode = 16
+	b, err := query.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rcode := b[3] & 0xF; rcode != 0 {
+		t.Errorf("header RCODE = %d; want 0", rcode)
+	}
+	var got Message
+	if err := got.Unpack(b); err != nil {
+		t.Fatal(err)
+	}
+	opt = got.EDNS0()
+	if opt == nil || opt.UDPSize() != 1232 || !opt.DNSSECOK() || got.RCode != 16 {
+		t.Errorf("got %v", &got)
+	}
+
+	query.Additionals = nil
+	if _, err := query.Pack(); err == nil {
+		t.Error("packed extended RCODE without OPT record")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	a := func(i byte) RR {
+		return &A{Hdr: RRHeader{Name: "example.", Type: TypeA, Class: ClassINET}, A: [4]byte{192, 0, 2, i}}
+	}
+	newMsg := func() *Message {
+		m := &Message{
+			Header:    Header{Response: true},
+			Questions: []Question{{Name: "example.", Type: TypeA, Class: ClassINET}},
+		}
+		for i := byte(0); i < 10; i++ {
+			m.Answers = append(m.Answers, a(i))
+		}
+		m.Authorities = []RR{&NS{Hdr: RRHeader{Name: "example.", Type: TypeNS, Class: ClassINET}, NS: "ns.example."}}
+		m.Additionals = []RR{a(100)}
+		m.SetEDNS0(1232, false)
+		return m
+	}
+	b, err := newMsg().Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const aLen = 9 + 10 + 4 // name, fixed header fields, address
+
+	tests := []struct {
+		size                 int
+		answers, auth, extra int // extra includes OPT
+		truncated            bool
+	}{
+		{len(b), 10, 1, 2, false},
+		{len(b) - 1, 10, 1, 1, false},
+		{len(b) - aLen, 10, 1, 1, false},
+		{len(b) - aLen - 1, 10, 0, 1, true},
+		{len(b) - 2*aLen - 30, 9, 0, 1, true},
+		{len(b) - 2*aLen - 32, 8, 0, 1, true},
+		{0, 0, 0, 1, true},
+	}
+	for _, tt := range tests {
+		m := newMsg()
+		m.Truncate(tt.size)
+		if len(m.Answers) != tt.answers || len(m.Authorities) != tt.auth || len(m.Additionals) != tt.extra || m.Truncated != tt.truncated {
+			t.Errorf("Truncate(%d): got %d, %d, %d records, Truncated = %v; want %d, %d, %d, %v",
+				tt.size, len(m.Answers), len(m.Authorities), len(m.Additionals), m.Truncated,
+				tt.answers, tt.auth, tt.extra, tt.truncated)
+		}
+		if m.EDNS0() == nil {
+			t.Errorf("Truncate(%d) removed OPT record", tt.size)
+		}
+		b, err := m.Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.size > 0 && len(b) > tt.size {
+			t.Errorf("Truncate(%d): packed to %d bytes", tt.size, len(b))
+		}
+	}
+}
+
+func TestIsResponseTo(t *testing.T) {
+	// Sample DNS query.
+	query := Message{
+		Header: Header{
+			ID: 42,
+		},
+		Questions: []Question{
+			{
+				Name:  "www.example.com.",
+				Type:  TypeA,
+				Class: ClassINET,
+			},
+		},
+	}
+
+	resp := query
+	resp.Response = true
+	if !resp.IsResponseTo(&query) {
+		t.Error("got false, want true")
+	}
+	resp.Questions = []Question{{Name: "WWW.Example.COM.", Type: TypeA, Class: ClassINET}}
+	if !resp.IsResponseTo(&query) {
+		t.Error("got false for response with name in different case, want true")
+	}
+
+	badResponses := []Message{
+		// Different ID.
+		{
+			Header: Header{
+				ID:       43,
+				Response: true,
+			},
+			Questions: []Question{
+				{
+					Name:  "www.example.com.",
+					Type:  TypeA,
+					Class: ClassINET,
+				},
+			},
+		},
+
+		// Different query name.
+		{
+			Header: Header{
+				ID:       42,
+				Response: true,
+			},
+			Questions: []Question{
+				{
+					Name:  "www.google.com.",
+					Type:  TypeA,
+					Class: ClassINET,
+				},
+			},
+		},
+
+		// Different query type.
+		{
+			Header: Header{
+				ID:       42,
+				Response: true,
+			},
+			Questions: []Question{
+				{
+					Name:  "www.example.com.",
+					Type:  TypeAAAA,
+					Class: ClassINET,
+				},
+			},
+		},
+
+		// Different query class.
+		{
+			Header: Header{
+				ID:       42,
+				Response: true,
+			},
+			Questions: []Question{
+				{
+					Name:  "www.example.com.",
+					Type:  TypeA,
+					Class: ClassCSNET,
+				},
+			},
+		},
+
+		// No questions.
+		{
+			Header: Header{
+				ID:       42,
+				Response: true,
+			},
+		},
+
+		// Extra questions.
+		{
+			Header: Header{
+				ID:       42,
+				Response: true,
+			},
+			Questions: []Question{
+				{
+					Name:  "www.example.com.",
+					Type:  TypeA,
+					Class: ClassINET,
+				},
+				{
+					Name:  "www.golang.org.",
+					Type:  TypeAAAA,
+					Class: ClassINET,
+				},
+			},
+		},
+	}
+
+	for i := range badResponses {
+		if badResponses[i].IsResponseTo(&query) {
+			t.Errorf("%v: got true, want false", i)
+		}
+	}
+}
+
+// Valid DNS SRV reply
+const dnsSRVReply = "0901818000010005000000000c5f786d70702d736572766572045f74637006676f6f67" +
+	"6c6503636f6d0000210001c00c002100010000012c00210014000014950c786d70702d" +
+	"73657276657234016c06676f6f676c6503636f6d00c00c002100010000012c00210014" +
+	"000014950c786d70702d73657276657232016c06676f6f676c6503636f6d00c00c0021" +
+	"00010000012c00210014000014950c786d70702d73657276657233016c06676f6f676c" +
+	"6503636f6d00c00c002100010000012c00200005000014950b786d70702d7365727665" +
+	"72016c06676f6f676c6503636f6d00c00c002100010000012c00210014000014950c78" +
+	"6d70702d73657276657231016c06676f6f676c6503636f6d00"
+
+// Corrupt DNS SRV reply, with its final RR having a bogus length
+// (perhaps it was truncated, or it's malicious) The mutation is the
+// capital "FF" below, instead of the proper "21".
+const dnsSRVCorruptReply = "0901818000010005000000000c5f786d70702d736572766572045f74637006676f6f67" +
+	"6c6503636f6d0000210001c00c002100010000012c00210014000014950c786d70702d" +
+	"73657276657234016c06676f6f676c6503636f6d00c00c002100010000012c00210014" +
+	"000014950c786d70702d73657276657232016c06676f6f676c6503636f6d00c00c0021" +
+	"00010000012c00210014000014950c786d70702d73657276657233016c06676f6f676c" +
+	"6503636f6d00c00c002100010000012c00200005000014950b786d70702d7365727665" +
+	"72016c06676f6f676c6503636f6d00c00c002100010000012c00FF0014000014950c78" +
+	"6d70702d73657276657231016c06676f6f676c6503636f6d00"
+
+// TXT reply with one <character-string>
+const dnsTXTReply1 = "b3458180000100010004000505676d61696c03636f6d0000100001c00c001000010000012c00" +
+	"201f763d737066312072656469726563743d5f7370662e676f6f676c652e636f6dc00" +
+	"c0002000100025d4c000d036e733406676f6f676c65c012c00c0002000100025d4c00" +
+	"06036e7331c057c00c0002000100025d4c0006036e7333c057c00c0002000100025d4" +
+	"c0006036e7332c057c06c00010001000248b50004d8ef200ac09000010001000248b5" +
+	"0004d8ef220ac07e00010001000248b50004d8ef240ac05300010001000248b50004d" +
+	"8ef260a0000291000000000000000"
+
+// TXT reply with more than one <character-string>.
+// See https://tools.ietf.org/html/rfc1035#section-3.3.14
+const dnsTXTReply2 = "a0a381800001000100020002045f7370660866616365626f6f6b03636f6d0000100001c00c0010000" +
+	"100000e1000af7f763d73706631206970343a36392e36332e3137392e3235206970343a36392e" +
+	"36332e3137382e3132382f3235206970343a36392e36332e3138342e302f3235206970343a363" +
+	"62e3232302e3134342e3132382f3235206970343a36362e3232302e3135352e302f3234206970" +
+	"343a36392e3137312e3233322e302f323520692e70343a36362e3232302e3135372e302f32352" +
+	"06970343a36392e3137312e3234342e302f3234206d78202d616c6cc0110002000100025d1500" +
+	"070161026e73c011c0110002000100025d1500040162c0ecc0ea0001000100025d15000445abe" +
+	"f0cc0fd0001000100025d15000445abff0c"
+
+// DataLength field should be sum of all TXT fields. In this case it's less.
+const dnsTXTCorruptDataLengthReply1 = "a0a381800001000100020002045f7370660866616365626f6f6b03636f6d0000100001c00c0010000" +
+	"100000e1000967f763d73706631206970343a36392e36332e3137392e3235206970343a36392e" +
+	"36332e3137382e3132382f3235206970343a36392e36332e3138342e302f3235206970343a363" +
+	"62e3232302e3134342e3132382f3235206970343a36362e3232302e3135352e302f3234206970" +
+	"343a36392e3137312e3233322e302f323520692e70343a36362e3232302e3135372e302f32352" +
+	"06970343a36392e3137312e3234342e302f3234206d78202d616c6cc0110002000100025d1500" +
+	"070161026e73c011c0110002000100025d1500040162c0ecc0ea0001000100025d15000445abe" +
+	"f0cc0fd0001000100025d15000445abff0c"
+
+// Same as above but DataLength is more than sum of TXT fields.
+const dnsTXTCorruptDataLengthReply2 = "a0a381800001000100020002045f7370660866616365626f6f6b03636f6d0000100001c00c0010000" +
+	"100000e1001227f763d73706631206970343a36392e36332e3137392e3235206970343a36392e" +
+	"36332e3137382e3132382f3235206970343a36392e36332e3138342e302f3235206970343a363" +
+	"62e3232302e3134342e3132382f3235206970343a36362e3232302e3135352e302f3234206970" +
+	"343a36392e3137312e3233322e302f323520692e70343a36362e3232302e3135372e302f32352" +
+	"06970343a36392e3137312e3234342e302f3234206d78202d616c6cc0110002000100025d1500" +
+	"070161026e73c011c0110002000100025d1500040162c0ecc0ea0001000100025d15000445abe" +
+	"f0cc0fd0001000100025d15000445abff0c"
+
+// TXT Length field is less than actual length.
+const dnsTXTCorruptTXTLengthReply1 = "a0a381800001000100020002045f7370660866616365626f6f6b03636f6d0000100001c00c0010000" +
+	"100000e1000af7f763d73706631206970343a36392e36332e3137392e3235206970343a36392e" +
+	"36332e3137382e3132382f3235206970343a36392e36332e3138342e302f3235206970343a363" +
+	"62e3232302e3134342e3132382f3235206970343a36362e3232302e3135352e302f3234206970" +
+	"343a36392e3137312e3233322e302f323520691470343a36362e3232302e3135372e302f32352" +
+	"06970343a36392e3137312e3234342e302f3234206d78202d616c6cc0110002000100025d1500" +
+	"070161026e73c011c0110002000100025d1500040162c0ecc0ea0001000100025d15000445abe" +
+	"f0cc0fd0001000100025d15000445abff0c"
+
+// TXT Length field is more than actual length.
+const dnsTXTCorruptTXTLengthReply2 = "a0a381800001000100020002045f7370660866616365626f6f6b03636f6d0000100001c00c0010000" +
+	"100000e1000af7f763d73706631206970343a36392e36332e3137392e3235206970343a36392e" +
+	"36332e3137382e3132382f3235206970343a36392e36332e3138342e302f3235206970343a363" +
+	"62e3232302e3134342e3132382f3235206970343a36362e3232302e3135352e302f3234206970" +
+	"343a36392e3137312e3233322e302f323520693370343a36362e3232302e3135372e302f32352" +
+	"06970343a36392e3137312e3234342e302f3234206d78202d616c6cc0110002000100025d1500" +
+	"070161026e73c011c0110002000100025d1500040162c0ecc0ea0001000100025d15000445abe" +
+	"f0cc0fd0001000100025d15000445abff0c"
@@ -0,0 +1,501 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Packing and unpacking.
+//
+// Each header, question and resource record type has a walk method
+// that is used by generic pack/unpack/printing routines. Thus, if we
+// need to define new record types, no new pack/unpack/printing code
+// needs to be written, unless they use a new kind of field.
+
+package dnsmessage
+
+import (
+	"net/netip"
+	"strconv"
+)
+
+// A walkFunc is called by a walk method for each field of its
+// structure, with a reference to that field, the name of the field
+// and a tag ("", "domain", "ipv4", "ipv6", "text") specifying
+// particular encodings. Possible concrete types for v are *uint8,
+// *uint16, *uint32, *Type, *Class, *string, []byte, *[]byte,
+// *[]string, *[]SVCParam and *[]Option, and *int, *bool and *RCode
+// in the case of Header.
+//
+// Fields of type *[]byte, *[]string, *[]SVCParam and *[]Option, and
+// *string fields tagged "text", extend to the end of the record
+// data, so they must come last. A []byte is of fixed length.
+//
+// Whenever f returns false, walk must stop and return false, and
+// otherwise return true.
+type walkFunc func(v interface{}, name, tag string) (ok bool)
+
+// A walker is a structure with a walk method.
+type walker interface {
+	walk(f walkFunc) bool
+}
+
+func putUint16(b []byte, v uint16) {
+	b[0] = byte(v >> 8)
+	b[1] = byte(v)
+}
+
+func getUint16(b []byte) uint16 {
+	return uint16(b[0])<<8 | uint16(b[1])
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+const hexDigit = "0123456789abcdef"
+
+// hexString returns the hexadecimal encoding of b.
+func hexString(b []byte) string {
+	s := make([]byte, 0, 2*len(b))
+	for _, v := range b {
+		s = append(s, hexDigit[v>>4], hexDigit[v&0xF])
+	}
+	return string(s)
+}
+
+// appendDomainName appends the wire format of the domain name s to
+// msg. Domain names are a sequence of counted strings split at the
+// dots. They end with a zero-length string.
+func appendDomainName(msg []byte, s string) ([]byte, bool) {
+	// Add trailing dot to canonicalize name.
+	if n := len(s); n == 0 || s[n-1] != '.' {
+		s += "."
+	}
+
+	// Allow root domain.
+	if s == "." {
+		return append(msg, 0), true
+	}
+	if len(s) > 254 {
+		return msg, false
+	}
+
+	// Emit sequence of counted strings, chopping at dots.
+	// We trade each dot byte for a length byte.
+	begin := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '.' {
+			if i-begin >= 1<<6 { // top two bits of length must be clear
+				return msg, false
+			}
+			if i-begin == 0 {
+				return msg, false
+			}
+			msg = append(msg, byte(i-begin))
+			msg = append(msg, s[begin:i]...)
+			begin = i + 1
+		}
+	}
+	return append(msg, 0), true
+}
+
+// unpackDomainName unpacks the domain name at msg[off:].
+// In addition to the simple sequences of counted strings above,
+// domain names are allowed to refer to strings elsewhere in the
+// packet, to avoid repeating common suffixes when returning
+// many entries in a single domain. The pointers are marked
+// by a length byte with the top two bits set. Ignoring those
+// two bits, that byte and the next give a 14 bit offset from msg[0]
+// where we should pick up the trail.
+// Note that if we jump elsewhere in the packet,
+// we return off1 == the offset after the first pointer we found,
+// which is where the next record will start.
+// In theory, the pointers are only allowed to jump backward.
+// We let them jump anywhere and stop jumping after a while.
+func unpackDomainName(msg []byte, off int) (s string, off1 int, ok bool) {
+	ptr := 0 // number of pointers followed
+Loop:
+	for {
+		if off >= len(msg) {
+			return "", len(msg), false
+		}
+		c := int(msg[off])
+		off++
+		switch c & 0xC0 {
+		case 0x00:
+			if c == 0x00 {
+				// end of name
+				break Loop
+			}
+			// literal string
+			if off+c > len(msg) {
+				return "", len(msg), false
+			}
+			s += string(msg[off:off+c]) + "."
+			off += c
+		case 0xC0:
+			// pointer to somewhere else in msg.
+			// remember location after first ptr,
+			// since that's how many bytes we consumed.
+			// also, don't follow too many pointers --
+			// maybe there's a loop.
+			if off >= len(msg) {
+				return "", len(msg), false
+			}
+			c1 := msg[off]
+			off++
+			if ptr == 0 {
+				off1 = off
+			}
+			if ptr++; ptr > 10 {
+				return "", len(msg), false
+			}
+			off = (c^0xC0)<<8 | int(c1)
+		default:
+			// 0x80 and 0x40 are reserved
+			return "", len(msg), false
+		}
+	}
+	if len(s) == 0 {
+		s = "."
+	}
+	if ptr == 0 {
+		off1 = off
+	}
+	return s, off1, true
+}
+
+// packFields appends the wire format of the fields of w to msg.
+func packFields(w walker, msg []byte) ([]byte, error) {
+	var err error
+	w.walk(func(field interface{}, name, tag string) bool {
+		switch fv := field.(type) {
+		default:
+			panic("dnsmessage: unknown packing type")
+		case *uint8:
+			msg = append(msg, *fv)
+		case *uint16:
+			msg = appendUint16(msg, *fv)
+		case *uint32:
+			msg = appendUint32(msg, *fv)
+		case *Type:
+			msg = appendUint16(msg, uint16(*fv))
+		case *Class:
+			msg = appendUint16(msg, uint16(*fv))
+		case []byte:
+			msg = append(msg, fv...)
+		case *[]byte:
+			msg = append(msg, *fv...)
+		case *string:
+			switch tag {
+			case "domain":
+				var ok bool
+				if msg, ok = appendDomainName(msg, *fv); !ok {
+					err = errBadName
+					return false
+				}
+			case "text":
+				msg = append(msg, *fv...)
+			default:
+				// Counted string: 1 byte length.
+				if len(*fv) > 255 {
+					err = errBadField
+					return false
+				}
+				msg = append(msg, byte(len(*fv)))
+				msg = append(msg, *fv...)
+			}
+		case *[]string:
+			for _, s := range *fv {
+				if len(s) > 255 {
+					err = errBadField
+					return false
+				}
+				msg = append(msg, byte(len(s)))
+				msg = append(msg, s...)
+			}
+		case *[]SVCParam:
+			for _, p := range *fv {
+				if len(p.Value) > 0xFFFF {
+					err = errBadField
+					return false
+				}
+				msg = appendUint16(msg, uint16(p.Key))
+				msg = appendUint16(msg, uint16(len(p.Value)))
+				msg = append(msg, p.Value...)
+			}
+		case *[]Option:
+			for _, o := range *fv {
+				if len(o.Data) > 0xFFFF {
+					err = errBadField
+					return false
+				}
+				msg = appendUint16(msg, o.Code)
+				msg = appendUint16(msg, uint16(len(o.Data)))
+				msg = append(msg, o.Data...)
+			}
+		}
+		return true
+	})
+	return msg, err
+}
+
+// unpackFields decodes msg[off:end] into the fields of w, and
+// returns off1 such that msg[off:off1] is the encoded data.
+// Domain names may point anywhere in msg.
+func unpackFields(w walker, msg []byte, off, end int) (off1 int, ok bool) {
+	ok = w.walk(func(field interface{}, name, tag string) bool {
+		switch fv := field.(type) {
+		default:
+			panic("dnsmessage: unknown packing type")
+		case *uint8:
+			if off+1 > end {
+				return false
+			}
+			*fv = msg[off]
+			off++
+		case *uint16, *Type, *Class:
+			if off+2 > end {
+				return false
+			}
+			v := getUint16(msg[off:])
+			switch fv := fv.(type) {
+			case *uint16:
+				*fv = v
+			case *Type:
+				*fv = Type(v)
+			case *Class:
+				*fv = Class(v)
+			}
+			off += 2
+		case *uint32:
+			if off+4 > end {
+				return false
+			}
+			*fv = uint32(getUint16(msg[off:]))<<16 | uint32(getUint16(msg[off+2:]))
+			off += 4
+		case []byte:
+			if off+len(fv) > end {
+				return false
+			}
+			off += copy(fv, msg[off:])
+		case *[]byte:
+			*fv = append([]byte(nil), msg[off:end]...)
+			off = end
+		case *string:
+			switch tag {
+			case "domain":
+				var s string
+				if s, off, ok = unpackDomainName(msg, off); !ok || off > end {
+					return false
+				}
+				*fv = s
+			case "text":
+				*fv = string(msg[off:end])
+				off = end
+			default:
+				if off >= end || off+1+int(msg[off]) > end {
+					return false
+				}
+				n := int(msg[off])
+				*fv = string(msg[off+1 : off+1+n])
+				off += 1 + n
+			}
+		case *[]string:
+			*fv = nil
+			for off < end {
+				n := int(msg[off])
+				if off+1+n > end {
+					return false
+				}
+				*fv = append(*fv, string(msg[off+1:off+1+n]))
+				off += 1 + n
+			}
+		case *[]SVCParam:
+			*fv = nil
+			for off < end {
+				if off+4 > end {
+					return false
+				}
+				key := SVCParamKey(getUint16(msg[off:]))
+				n := int(getUint16(msg[off+2:]))
+				off += 4
+				if off+n > end {
+					return false
+				}
+				*fv = append(*fv, SVCParam{Key: key, Value: append([]byte(nil), msg[off:off+n]...)})
+				off += n
+			}
+		case *[]Option:
+			*fv = nil
+			for off < end {
+				if off+4 > end {
+					return false
+				}
+				code := getUint16(msg[off:])
+				n := int(getUint16(msg[off+2:]))
+				off += 4
+				if off+n > end {
+					return false
+				}
+				*fv = append(*fv, Option{Code: code, Data: append([]byte(nil), msg[off:off+n]...)})
+				off += n
+			}
+		}
+		return true
+	})
+	if !ok {
+		return end, false
+	}
+	return off, true
+}
+
+// packRR appends the wire format of rr to msg,
+// setting the Length field of its header.
+func packRR(rr RR, msg []byte) ([]byte, error) {
+	h := rr.Header()
+	msg, ok := appendDomainName(msg, h.Name)
+	if !ok {
+		return nil, errBadName
+	}
+	msg = appendUint16(msg, uint16(h.Type))
+	msg = appendUint16(msg, uint16(h.Class))
+	msg = appendUint32(msg, h.TTL)
+	off := len(msg)
+	msg = append(msg, 0, 0) // Length, filled in below
+	msg, err := packFields(rr, msg)
+	if err != nil {
+		return nil, err
+	}
+	n := len(msg) - off - 2
+	if n > 0xFFFF {
+		return nil, errTooLong
+	}
+	h.Length = uint16(n)
+	putUint16(msg[off:], h.Length)
+	return msg, nil
+}
+
+// unpackRR decodes the resource record at msg[off:].
+// A record of a type with no constructor in rrMk is returned as an
+// *UnknownRR, and one whose data cannot be decoded as just its
+// *RRHeader.
+func unpackRR(msg []byte, off int) (rr RR, off1 int, ok bool) {
+	// unpack just the header, to find the rr type and length
+	var h RRHeader
+	if h.Name, off, ok = unpackDomainName(msg, off); !ok {
+		return nil, len(msg), false
+	}
+	if off+10 > len(msg) {
+		return nil, len(msg), false
+	}
+	h.Type = Type(getUint16(msg[off:]))
+	h.Class = Class(getUint16(msg[off+2:]))
+	h.TTL = uint32(getUint16(msg[off+4:]))<<16 | uint32(getUint16(msg[off+6:]))
+	h.Length = getUint16(msg[off+8:])
+	off += 10
+	end := off + int(h.Length)
+	if end > len(msg) {
+		// The data was cut off; keep the header,
+		// as for any record that cannot be decoded.
+		return &h, len(msg), true
+	}
+
+	mk, known := rrMk[h.Type]
+	if !known {
+		return &UnknownRR{Hdr: h, Data: append([]byte(nil), msg[off:end]...)}, end, true
+	}
+	rr = mk()
+	*rr.Header() = h
+	if off, ok = unpackFields(rr, msg, off, end); !ok || off != end {
+		return &h, end, true
+	}
+	return rr, end, true
+}
+
+// printStruct prints the fields of w. It prints fields tagged "ipv4"
+// or "ipv6" as IP addresses, and byte slices in hexadecimal.
+func printStruct(w walker) string {
+	s := "{"
+	i := 0
+	w.walk(func(val interface{}, name, tag string) bool {
+		i++
+		if i > 1 {
+			s += ", "
+		}
+		s += name + "="
+		switch v := val.(type) {
+		default:
+			// can't really happen.
+			s += "<unknown type>"
+		case *bool:
+			s += strconv.FormatBool(*v)
+		case *int:
+			s += strconv.Itoa(*v)
+		case *uint8:
+			s += strconv.Itoa(int(*v))
+		case *uint16:
+			s += strconv.Itoa(int(*v))
+		case *uint32:
+			s += strconv.FormatUint(uint64(*v), 10)
+		case *Type:
+			s += v.String()
+		case *Class:
+			s += strconv.Itoa(int(*v))
+		case *RCode:
+			s += v.String()
+		case *string:
+			s += *v
+		case []byte:
+			switch tag {
+			case "ipv4":
+				var a [4]byte
+				copy(a[:], v)
+				s += netip.AddrFrom4(a).String()
+			case "ipv6":
+				var a [16]byte
+				copy(a[:], v)
+				s += netip.AddrFrom16(a).String()
+			default:
+				s += hexString(v)
+			}
+		case *[]byte:
+			s += hexString(*v)
+		case *[]string:
+			for j, t := range *v {
+				if j > 0 {
+					s += " "
+				}
+				s += strconv.Quote(t)
+			}
+		case *[]SVCParam:
+			for j, p := range *v {
+				if j > 0 {
+					s += " "
+				}
+				s += "key" + strconv.Itoa(int(p.Key)) + "=" + hexString(p.Value)
+			}
+		case *[]Option:
+			for j, o := range *v {
+				if j > 0 {
+					s += " "
+				}
+				s += strconv.Itoa(int(o.Code)) + ":" + hexString(o.Data)
+			}
+		}
+		return true
+	})
+	s += "}"
+	return s
+}
+
+// printRR prints the header and then the data fields of rr.
+func printRR(rr RR) string {
+	h := rr.Header()
+	return "{Name=" + h.Name + ", Type=" + h.Type.String() +
+		", Class=" + strconv.Itoa(int(h.Class)) +
+		", TTL=" + strconv.FormatUint(uint64(h.TTL), 10) +
+		", Length=" + strconv.Itoa(int(h.Length)) + "} " + printStruct(rr)
+}
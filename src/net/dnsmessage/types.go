@@ -0,0 +1,356 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dnsmessage
+
+// An RRHeader is the header shared by all resource records.
+//
+// A record whose data could not be decoded is returned by
+// Message.Unpack as just its *RRHeader.
+type RRHeader struct {
+	Name   string
+	Type   Type
+	Class  Class
+	TTL    uint32
+	Length uint16 // length of data after header, set by Pack
+}
+
+// Header returns h.
+func (h *RRHeader) Header() *RRHeader {
+	return h
+}
+
+func (h *RRHeader) walk(f walkFunc) bool {
+	return true
+}
+
+// An RR is a resource record: one of the record types defined by
+// this package, *UnknownRR or *RRHeader.
+type RR interface {
+	// Header returns the header of the record.
+	Header() *RRHeader
+
+	// walk iterates over the data fields of the record,
+	// which follow the header in wire format.
+	walk(f walkFunc) bool
+}
+
+// Map of constructors for each known RR type.
+var rrMk = map[Type]func() RR{
+	TypeA:     func() RR { return new(A) },
+	TypeNS:    func() RR { return new(NS) },
+	TypeCNAME: func() RR { return new(CNAME) },
+	TypeSOA:   func() RR { return new(SOA) },
+	TypePTR:   func() RR { return new(PTR) },
+	TypeMX:    func() RR { return new(MX) },
+	TypeTXT:   func() RR { return new(TXT) },
+	TypeAAAA:  func() RR { return new(AAAA) },
+	TypeSRV:   func() RR { return new(SRV) },
+	TypeOPT:   func() RR { return new(OPT) },
+	TypeTLSA:  func() RR { return new(TLSA) },
+	TypeSVCB:  func() RR { return new(SVCB) },
+	TypeHTTPS: func() RR { return new(HTTPS) },
+	TypeCAA:   func() RR { return new(CAA) },
+}
+
+// An UnknownRR is a resource record of a type this package does not
+// know, holding its data undecoded (RFC 3597).
+type UnknownRR struct {
+	Hdr  RRHeader
+	Data []byte
+}
+
+func (rr *UnknownRR) Header() *RRHeader {
+	return &rr.Hdr
+}
+
+func (rr *UnknownRR) walk(f walkFunc) bool {
+	return f(&rr.Data, "Data", "")
+}
+
+// An A is an IPv4 address record.
+type A struct {
+	Hdr RRHeader
+	A   [4]byte
+}
+
+func (rr *A) Header() *RRHeader {
+	return &rr.Hdr
+}
+
+func (rr *A) walk(f walkFunc) bool {
+	return f(rr.A[:], "A", "ipv4")
+}
+
+// An AAAA is an IPv6 address record.
+type AAAA struct {
+	Hdr  RRHeader
+	AAAA [16]byte
+}
+
+func (rr *AAAA) Header() *RRHeader {
+	return &rr.Hdr
+}
+
+func (rr *AAAA) walk(f walkFunc) bool {
+	return f(rr.AAAA[:], "AAAA", "ipv6")
+}
+
+// A CNAME is a canonical name record.
+type CNAME struct {
+	Hdr   RRHeader
+	CNAME string
+}
+
+func (rr *CNAME) Header() *RRHeader {
+	return &rr.Hdr
+}
+
+func (rr *CNAME) walk(f walkFunc) bool {
+	return f(&rr.CNAME, "CNAME", "domain")
+}
+
+// An NS is a name server record.
+type NS struct {
+	Hdr RRHeader
+	NS  string
+}
+
+func (rr *NS) Header() *RRHeader {
+	return &rr.Hdr
+}
+
+func (rr *NS) walk(f walkFunc) bool {
+	return f(&rr.NS, "NS", "domain")
+}
+
+// A PTR is a domain name pointer record.
+type PTR struct {
+	Hdr RRHeader
+	PTR string
+}
+
+func (rr *PTR) Header() *RRHeader {
+	return &rr.Hdr
+}
+
+func (rr *PTR) walk(f walkFunc) bool {
+	return f(&rr.PTR, "PTR", "domain")
+}
+
+// An MX is a mail exchange record.
+type MX struct {
+	Hdr  RRHeader
+	Pref uint16
+	MX   string
+}
+
+func (rr *MX) Header() *RRHeader {
+	return &rr.Hdr
+}
+
+func (rr *MX) walk(f walkFunc) bool {
+	return f(&rr.Pref, "Pref", "") && f(&rr.MX, "MX", "domain")
+}
+
+// An SOA is a start of authority record.
+type SOA struct {
+	Hdr     RRHeader
+	NS      string
+	MBox    string
+	Serial  uint32
+	Refresh uint32
+	Retry   uint32
+	Expire  uint32
+	MinTTL  uint32
+}
+
+func (rr *SOA) Header() *RRHeader {
+	return &rr.Hdr
+}
+
+func (rr *SOA) walk(f walkFunc) bool {
+	return f(&rr.NS, "NS", "domain") &&
+		f(&rr.MBox, "MBox", "domain") &&
+		f(&rr.Serial, "Serial", "") &&
+		f(&rr.Refresh, "Refresh", "") &&
+		f(&rr.Retry, "Retry", "") &&
+		f(&rr.Expire, "Expire", "") &&
+		f(&rr.MinTTL, "MinTTL", "")
+}
+
+// A TXT is a text record, holding one or more character strings
+// of up to 255 bytes each.
+type TXT struct {
+	Hdr RRHeader
+	TXT []string
+}
+
+func (rr *TXT) Header() *RRHeader {
+	return &rr.Hdr
+}
+
+func (rr *TXT) walk(f walkFunc) bool {
+	return f(&rr.TXT, "TXT", "")
+}
+
+// An SRV is a service location record (RFC 2782).
+type SRV struct {
+	Hdr      RRHeader
+	Priority uint16
+	Weight   uint16
+	Port     uint16
+	Target   string
+}
+
+func (rr *SRV) Header() *RRHeader {
+	return &rr.Hdr
+}
+
+func (rr *SRV) walk(f walkFunc) bool {
+	return f(&rr.Priority, "Priority", "") &&
+		f(&rr.Weight, "Weight", "") &&
+		f(&rr.Port, "Port", "") &&
+		f(&rr.Target, "Target", "domain")
+}
+
+// An OPT is the EDNS(0) pseudo-record (RFC 6891). The Class of its
+// header holds the sender's UDP payload size, and its TTL holds the
+// upper bits of the extended RCODE, the EDNS version and flags.
+type OPT struct {
+	Hdr     RRHeader
+	Options []Option
+}
+
+// An Option is an EDNS(0) option.
+type Option struct {
+	Code uint16
+	Data []byte
+}
+
+// optDO is the DNSSEC OK flag in the TTL of an OPT record.
+const optDO = 1 << 15
+
+func (rr *OPT) Header() *RRHeader {
+	return &rr.Hdr
+}
+
+func (rr *OPT) walk(f walkFunc) bool {
+	return f(&rr.Options, "Options", "")
+}
+
+// UDPSize returns the largest UDP payload the sender of rr can receive.
+func (rr *OPT) UDPSize() int {
+	if rr.Hdr.Class < 512 {
+		return 512
+	}
+	return int(rr.Hdr.Class)
+}
+
+// Version returns the EDNS version of rr.
+func (rr *OPT) Version() int {
+	return int(rr.Hdr.TTL>>16) & 0xFF
+}
+
+// DNSSECOK reports whether the DNSSEC OK flag of rr is set.
+func (rr *OPT) DNSSECOK() bool {
+	return rr.Hdr.TTL&optDO != 0
+}
+
+// A TLSA is a record associating a TLS certificate or public key
+// with a service (RFC 6698).
+type TLSA struct {
+	Hdr          RRHeader
+	Usage        uint8
+	Selector     uint8
+	MatchingType uint8
+	CertData     []byte
+}
+
+func (rr *TLSA) Header() *RRHeader {
+	return &rr.Hdr
+}
+
+func (rr *TLSA) walk(f walkFunc) bool {
+	return f(&rr.Usage, "Usage", "") &&
+		f(&rr.Selector, "Selector", "") &&
+		f(&rr.MatchingType, "MatchingType", "") &&
+		f(&rr.CertData, "CertData", "")
+}
+
+// An SVCB is a service binding record (RFC 9460). A Priority of 0
+// marks an alias to Target; otherwise Params describe the service
+// endpoint at Target.
+type SVCB struct {
+	Hdr      RRHeader
+	Priority uint16
+	Target   string
+	Params   []SVCParam
+}
+
+// An SVCParamKey is the key of a service binding parameter.
+type SVCParamKey uint16
+
+// Service binding parameter keys.
+const (
+	SVCParamMandatory     SVCParamKey = 0
+	SVCParamALPN          SVCParamKey = 1
+	SVCParamNoDefaultALPN SVCParamKey = 2
+	SVCParamPort          SVCParamKey = 3
+	SVCParamIPv4Hint      SVCParamKey = 4
+	SVCParamECH           SVCParamKey = 5
+	SVCParamIPv6Hint      SVCParamKey = 6
+)
+
+// An SVCParam is a service binding parameter, holding its value in
+// wire format.
+type SVCParam struct {
+	Key   SVCParamKey
+	Value []byte
+}
+
+func (rr *SVCB) Header() *RRHeader {
+	return &rr.Hdr
+}
+
+func (rr *SVCB) walk(f walkFunc) bool {
+	return f(&rr.Priority, "Priority", "") &&
+		f(&rr.Target, "Target", "domain") &&
+		f(&rr.Params, "Params", "")
+}
+
+// Param returns the value of the parameter of rr with the given key
+// and whether rr has it.
+func (rr *SVCB) Param(key SVCParamKey) ([]byte, bool) {
+	for _, p := range rr.Params {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return nil, false
+}
+
+// An HTTPS is a service binding record for HTTPS origins, of the same
+// format as SVCB (RFC 9460, section 9).
+type HTTPS struct {
+	SVCB
+}
+
+// A CAA is a certification authority authorization record (RFC 8659).
+type CAA struct {
+	Hdr   RRHeader
+	Flag  uint8
+	Tag   string
+	Value string
+}
+
+func (rr *CAA) Header() *RRHeader {
+	return &rr.Hdr
+}
+
+func (rr *CAA) walk(f walkFunc) bool {
+	return f(&rr.Flag, "Flag", "") &&
+		f(&rr.Tag, "Tag", "") &&
+		f(&rr.Value, "Value", "text")
+}
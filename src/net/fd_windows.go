@@ -159,6 +159,22 @@ func (fd *netFD) readFrom(buf []byte) (int, syscall.Sockaddr, error) {
 	return n, sa, wrapSyscallError("wsarecvfrom", err)
 }
 
+func (fd *netFD) readFromInet4(buf []byte, from *syscall.SockaddrInet4) (int, error) {
+	n, sa, err := fd.readFrom(buf)
+	if sa, ok := sa.(*syscall.SockaddrInet4); ok {
+		*from = *sa
+	}
+	return n, err
+}
+
+func (fd *netFD) readFromInet6(buf []byte, from *syscall.SockaddrInet6) (int, error) {
+	n, sa, err := fd.readFrom(buf)
+	if sa, ok := sa.(*syscall.SockaddrInet6); ok {
+		*from = *sa
+	}
+	return n, err
+}
+
 func (fd *netFD) Write(buf []byte) (int, error) {
 	n, err := fd.pfd.Write(buf)
 	runtime.KeepAlive(fd)
@@ -188,6 +204,14 @@ func (fd *netFD) writeTo(buf []byte, sa syscall.Sockaddr) (int, error) {
 	return n, wrapSyscallError("wsasendto", err)
 }
 
+func (fd *netFD) writeToInet4(buf []byte, sa *syscall.SockaddrInet4) (int, error) {
+	return fd.writeTo(buf, sa)
+}
+
+func (fd *netFD) writeToInet6(buf []byte, sa *syscall.SockaddrInet6) (int, error) {
+	return fd.writeTo(buf, sa)
+}
+
 func (fd *netFD) accept() (*netFD, error) {
 	s, rawsa, rsan, errcall, err := fd.pfd.Accept(func() (syscall.Handle, error) {
 		return sysSocket(fd.family, fd.sotype, 0)
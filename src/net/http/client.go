@@ -80,7 +80,8 @@ type Client struct {
 	// The Jar is used to insert relevant cookies into every
 	// outbound Request and is updated with the cookie values
 	// of every inbound Response. The Jar is consulted for every
-	// redirect that the Client follows.
+	// redirect that the Client follows. See CookieJar for how the
+	// Client lets the Jar enforce the SameSite attribute of cookies.
 	//
 	// If Jar is nil, cookies are only sent if they are explicitly
 	// set on the Request.
@@ -104,6 +105,11 @@ type Client struct {
 	// RoundTripper implementations should use Request.Cancel
 	// instead of implementing CancelRequest.
 	Timeout time.Duration
+
+	// Retry specifies the policy for retrying failed requests.
+	// If nil, requests are sent once, although the Transport may
+	// itself retry some requests that fail on a reused connection.
+	Retry *RetryPolicy
 }
 
 // DefaultClient is the default Client and is used by Get, Head, and Post.
@@ -169,7 +175,7 @@ func refererForURL(lastReq, newReq *url.URL) string {
 // didTimeout is non-nil only if err != nil.
 func (c *Client) send(req *Request, deadline time.Time) (resp *Response, didTimeout func() bool, err error) {
 	if c.Jar != nil {
-		for _, cookie := range c.Jar.Cookies(req.URL) {
+		for _, cookie := range c.cookies(req) {
 			req.AddCookie(cookie)
 		}
 	}
@@ -179,7 +185,7 @@ func (c *Client) send(req *Request, deadline time.Time) (resp *Response, didTime
 	}
 	if c.Jar != nil {
 		if rc := resp.Cookies(); len(rc) > 0 {
-			c.Jar.SetCookies(req.URL, rc)
+			c.setCookies(req, rc)
 		}
 	}
 	return resp, nil, nil
@@ -612,7 +618,7 @@ func (c *Client) Do(req *Request) (*Response, error) {
 		reqs = append(reqs, req)
 		var err error
 		var didTimeout func() bool
-		if resp, didTimeout, err = c.send(req, deadline); err != nil {
+		if resp, didTimeout, err = c.sendRetry(req, deadline); err != nil {
 			// c.send() always closes req.Body
 			reqBodyClosed = true
 			if !deadline.IsZero() && didTimeout() {
@@ -754,6 +754,121 @@ func (j *RecordingJar) logf(format string, args ...interface{}) {
 	fmt.Fprintf(&j.log, format, args...)
 }
 
+// CrossSiteRecordingJar is a RecordingJar that also logs cross-site
+// calls.
+type CrossSiteRecordingJar struct {
+	RecordingJar
+}
+
+func (j *CrossSiteRecordingJar) SetCrossSiteCookies(u, site *url.URL, navigation bool, cookies []*Cookie) {
+	j.logf("SetCrossSiteCookies(%q, %q, %v, %v)\n", u, site, navigation, cookies)
+}
+
+func (j *CrossSiteRecordingJar) CrossSiteCookies(u, site *url.URL, navigation bool) []*Cookie {
+	j.logf("CrossSiteCookies(%q, %q, %v)\n", u, site, navigation)
+	return nil
+}
+
+func TestCrossSiteJarCalls(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		SetCookie(w, &Cookie{Name: "n", Value: r.URL.Path[1:]})
+		switch r.URL.Path {
+		case "/post":
+			Redirect(w, r, "http://secondhost.fake/second", StatusTemporaryRedirect)
+		case "/get":
+			Redirect(w, r, "http://secondhost.fake/second", StatusFound)
+		case "/second":
+			if r.Method == "GET" {
+				Redirect(w, r, "http://thirdhost.fake/third", StatusFound)
+			}
+		}
+	}))
+	defer ts.Close()
+	jar := new(CrossSiteRecordingJar)
+	c := ts.Client()
+	c.Jar = jar
+	c.Transport.(*Transport).Dial = func(_ string, _ string) (net.Conn, error) {
+		return net.Dial("tcp", ts.Listener.Addr().String())
+	}
+	if _, err := c.Get("http://firsthost.fake/get"); err != nil {
+		t.Fatal(err)
+	}
+	req, _ := NewRequest("POST", "http://firsthost.fake/post", strings.NewReader("body"))
+	if _, err := c.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	got := jar.log.String()
+	want := `Cookies("http://firsthost.fake/get")
+SetCookie("http://firsthost.fake/get", [n=get])
+CrossSiteCookies("http://secondhost.fake/second", "http://firsthost.fake/get", true)
+SetCrossSiteCookies("http://secondhost.fake/second", "http://firsthost.fake/get", true, [n=second])
+CrossSiteCookies("http://thirdhost.fake/third", "http://firsthost.fake/get", true)
+SetCrossSiteCookies("http://thirdhost.fake/third", "http://firsthost.fake/get", true, [n=third])
+Cookies("http://firsthost.fake/post")
+SetCookie("http://firsthost.fake/post", [n=post])
+CrossSiteCookies("http://secondhost.fake/second", "http://firsthost.fake/post", false)
+SetCrossSiteCookies("http://secondhost.fake/second", "http://firsthost.fake/post", false, [n=second])
+`
+	if got != want {
+		t.Errorf("Got Jar calls:\n%s\nWant:\n%s", got, want)
+	}
+}
+
+// A Client following a cross-site redirect must not send
+// SameSite=Strict cookies.
+func TestClientRedirectSameSite(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		switch r.Host {
+		case "other.fake":
+			Redirect(w, r, "http://site.fake/check", StatusFound)
+			return
+		}
+		if r.URL.Path == "/set" {
+			SetCookie(w, &Cookie{Name: "strict", Value: "1", SameSite: SameSiteStrictMode})
+			SetCookie(w, &Cookie{Name: "lax", Value: "2", SameSite: SameSiteLaxMode})
+			return
+		}
+		var names []string
+		for _, c := range r.Cookies() {
+			names = append(names, c.Name)
+		}
+		io.WriteString(w, strings.Join(names, " "))
+	}))
+	defer ts.Close()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := ts.Client()
+	c.Jar = jar
+	c.Transport.(*Transport).Dial = func(_ string, _ string) (net.Conn, error) {
+		return net.Dial("tcp", ts.Listener.Addr().String())
+	}
+	get := func(url string) string {
+		res, err := c.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		b, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	get("http://site.fake/set")
+	if got, want := get("http://site.fake/check"), "strict lax"; got != want {
+		t.Errorf("same-site request sent cookies %q; want %q", got, want)
+	}
+	if got, want := get("http://other.fake/"), "lax"; got != want {
+		t.Errorf("cross-site redirect sent cookies %q; want %q", got, want)
+	}
+}
+
 func TestStreamingGet_h1(t *testing.T) { testStreamingGet(t, h1Mode) }
 func TestStreamingGet_h2(t *testing.T) { testStreamingGet(t, h2Mode) }
 
@@ -977,6 +1092,7 @@ func TestResponseSetsTLSConnectionState(t *testing.T) {
 	c := ts.Client()
 	tr := c.Transport.(*Transport)
 	tr.TLSClientConfig.CipherSuites = []uint16{tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA}
+	tr.TLSClientConfig.MaxVersion = tls.VersionTLS12 // CipherSuites does not apply to TLS 1.3
 	tr.Dial = func(netw, addr string) (net.Conn, error) {
 		return net.Dial(netw, ts.Listener.Addr().String())
 	}
@@ -1854,3 +1970,301 @@ func TestTransportBodyReadError(t *testing.T) {
 		t.Errorf("close calls = %d; want 1", closeCalls)
 	}
 }
+
+func TestClientRetryStatus(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	var attempts int32
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) != "payload" {
+			t.Errorf("attempt %d: body = %q", atomic.LoadInt32(&attempts), body)
+		}
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer ts.Close()
+
+	c := &Client{Retry: &RetryPolicy{MinBackoff: time.Millisecond}}
+	req, _ := NewRequest("POST", ts.URL, strings.NewReader("payload"))
+	req.Header.Set("Idempotency-Key", "1")
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != 200 || string(body) != "ok" {
+		t.Errorf("got %v %q; want 200 ok", res.Status, body)
+	}
+	if n := atomic.LoadInt32(&attempts); n != 3 {
+		t.Errorf("server saw %d attempts; want 3", n)
+	}
+}
+
+func TestClientRetryGivesUp(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	var attempts int32
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		n := atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(StatusBadGateway)
+		fmt.Fprintf(w, "attempt %d", n)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		method string
+		header string
+		policy RetryPolicy
+		want   int32
+	}{
+		{"GET", "", RetryPolicy{MinBackoff: time.Millisecond}, 3},
+		{"GET", "", RetryPolicy{MinBackoff: time.Millisecond, MaxAttempts: 5}, 5},
+		{"GET", "", RetryPolicy{StatusCodes: []int{StatusServiceUnavailable}}, 1},
+		{"POST", "", RetryPolicy{MinBackoff: time.Millisecond}, 1},
+		{"POST", "X-Idempotency-Key", RetryPolicy{MinBackoff: time.Millisecond}, 3},
+	}
+	for _, tt := range tests {
+		atomic.StoreInt32(&attempts, 0)
+		policy := tt.policy
+		c := &Client{Retry: &policy}
+		req, _ := NewRequest(tt.method, ts.URL, nil)
+		if tt.header != "" {
+			req.Header.Set(tt.header, "x")
+		}
+		res, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if want := fmt.Sprintf("attempt %d", tt.want); res.StatusCode != StatusBadGateway || string(body) != want {
+			t.Errorf("%s %+v: got %v %q; want 502 %q", tt.method, tt.policy, res.Status, body, want)
+		}
+	}
+}
+
+func TestClientRetryAfter(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	var attempts int32
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Header().Set("Retry-After", r.FormValue("after"))
+			w.WriteHeader(StatusTooManyRequests)
+		}
+	}))
+	defer ts.Close()
+
+	c := &Client{Retry: &RetryPolicy{MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Second}}
+
+	// Longer than MaxBackoff: the 429 response is returned.
+	res, err := c.Get(ts.URL + "?after=120")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != StatusTooManyRequests || atomic.LoadInt32(&attempts) != 1 {
+		t.Errorf("got %v after %d attempts; want 429 after 1", res.Status, atomic.LoadInt32(&attempts))
+	}
+
+	if testing.Short() {
+		t.Skip("skipping Retry-After delay in short mode")
+	}
+	atomic.StoreInt32(&attempts, 0)
+	t0 := time.Now()
+	res, err = c.Get(ts.URL + "?after=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if d := time.Since(t0); res.StatusCode != 200 || d < time.Second {
+		t.Errorf("got %v after %v; want 200 after at least 1s", res.Status, d)
+	}
+}
+
+func TestClientRetryNetworkError(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	var attempts int32
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			c, _, _ := w.(Hijacker).Hijack()
+			c.Close()
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer ts.Close()
+
+	res, err := ts.Client().Get(ts.URL)
+	if err == nil {
+		res.Body.Close()
+		t.Fatal("Get without retries succeeded")
+	}
+
+	atomic.StoreInt32(&attempts, 0)
+	c := ts.Client()
+	c.Retry = &RetryPolicy{MinBackoff: time.Millisecond}
+	res, err = c.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if n := atomic.LoadInt32(&attempts); n != 2 {
+		t.Errorf("server saw %d attempts; want 2", n)
+	}
+
+	atomic.StoreInt32(&attempts, 0)
+	c.Retry = &RetryPolicy{RetryError: func(error) bool { return false }}
+	if res, err := c.Get(ts.URL); err == nil {
+		res.Body.Close()
+		t.Error("Get succeeded; want the error of the first attempt")
+	}
+}
+
+func TestClientRetryCanceled(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	var attempts int32
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	c := &Client{Retry: &RetryPolicy{MinBackoff: time.Hour, MaxBackoff: time.Hour}}
+
+	// Canceled during the backoff after the first attempt, the request
+	// fails rather than returning the 503 response.
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := NewRequest("GET", ts.URL, nil)
+	time.AfterFunc(50*time.Millisecond, cancel)
+	res, err := c.Do(req.WithContext(ctx))
+	if err == nil {
+		res.Body.Close()
+		t.Fatalf("got %v; want an error", res.Status)
+	}
+	if ue, ok := err.(*url.Error); !ok || ue.Err != context.Canceled {
+		t.Errorf("context canceled: got error %v; want %v", err, context.Canceled)
+	}
+	if n := atomic.LoadInt32(&attempts); n != 1 {
+		t.Errorf("server saw %d attempts; want 1", n)
+	}
+
+	atomic.StoreInt32(&attempts, 0)
+	cancelc := make(chan struct{})
+	req, _ = NewRequest("GET", ts.URL, nil)
+	req.Cancel = cancelc
+	time.AfterFunc(50*time.Millisecond, func() { close(cancelc) })
+	res, err = c.Do(req)
+	if err == nil {
+		res.Body.Close()
+		t.Fatalf("got %v; want an error", res.Status)
+	}
+	if ue, ok := err.(*url.Error); !ok || ue.Err != ExportErrRequestCanceled {
+		t.Errorf("Request.Cancel closed: got error %v; want %v", err, ExportErrRequestCanceled)
+	}
+}
+
+func TestClientRetryUpgrade(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		conn, _, err := w.(Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nConnection: upgrade\r\nUpgrade: foo\r\n\r\n")
+	}))
+	defer ts.Close()
+
+	c := ts.Client()
+	c.Retry = &RetryPolicy{MinBackoff: time.Millisecond}
+	req, _ := NewRequest("GET", ts.URL, nil)
+	req.Header.Set("Upgrade", "foo")
+	req.Header.Set("Connection", "upgrade")
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != StatusSwitchingProtocols {
+		t.Fatalf("got %v; want 101", res.Status)
+	}
+	if _, ok := res.Body.(io.ReadWriteCloser); !ok {
+		t.Errorf("Body is a %T; want an io.ReadWriteCloser", res.Body)
+	}
+}
+
+func TestClientHedgedRequestGetBodyError(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		time.Sleep(200 * time.Millisecond)
+		io.WriteString(w, "slow")
+	}))
+	defer ts.Close()
+
+	var getBodyCalls int32
+	c := &Client{Retry: &RetryPolicy{HedgeDelay: 10 * time.Millisecond, MaxAttempts: 5}}
+	req, _ := NewRequest("PUT", ts.URL, strings.NewReader("payload"))
+	req.GetBody = func() (io.ReadCloser, error) {
+		atomic.AddInt32(&getBodyCalls, 1)
+		return nil, errors.New("no body")
+	}
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "slow" {
+		t.Errorf("body = %q; want the response to the first attempt", body)
+	}
+	if n := atomic.LoadInt32(&getBodyCalls); n != 1 {
+		t.Errorf("GetBody called %d times; want hedging to stop after the first failure", n)
+	}
+}
+
+func TestClientHedgedRequest(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	var attempts int32
+	slowDone := make(chan struct{})
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			// The first attempt is stuck until the client gives up on it.
+			<-r.Context().Done()
+			close(slowDone)
+			return
+		}
+		io.WriteString(w, "fast")
+	}))
+	defer ts.Close()
+
+	c := &Client{Retry: &RetryPolicy{HedgeDelay: 20 * time.Millisecond}}
+	res, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "fast" {
+		t.Errorf("body = %q; want the hedged response", body)
+	}
+	select {
+	case <-slowDone:
+	case <-time.After(5 * time.Second):
+		t.Error("first attempt was not canceled")
+	}
+	if n := atomic.LoadInt32(&attempts); n != 2 {
+		t.Errorf("server saw %d attempts; want 2", n)
+	}
+}
@@ -0,0 +1,344 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Server-side response compression.
+
+package http
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CompressHandler returns a handler that serves requests by calling h,
+// compressing its responses with gzip or deflate when the client's
+// Accept-Encoding header allows it.
+//
+// A response is compressed only if h did not set a Content-Encoding
+// header, its status code allows a body and is not 206 Partial
+// Content, its body is at least 512 bytes long, and its content type,
+// as set by h or detected with DetectContentType, is not already
+// compressed, such as most image, audio and video formats. Whenever
+// the response could be compressed, "Accept-Encoding" is added to
+// its Vary header. Compressed responses have no Content-Length or
+// Accept-Ranges headers, and a strong ETag set by h is made weak.
+//
+// The ResponseWriter passed to h implements Flusher and CloseNotifier,
+// and also Hijacker and Pusher when the underlying ResponseWriter does.
+// Flush writes out the response compressed so far. A Flush before 512
+// bytes of the body have been written marks the response as streamed:
+// its header is sent at once, and the response is compressed if its
+// content type, set by h or detected from what was written, allows.
+// Hijack fails once compressed data has been written.
+func CompressHandler(h Handler) Handler {
+	return HandlerFunc(func(w ResponseWriter, r *Request) {
+		cw := &compressWriter{
+			w:        w,
+			encoding: acceptedEncoding(r.Header["Accept-Encoding"]),
+		}
+		h.ServeHTTP(cw.wrap(), r)
+		cw.close()
+	})
+}
+
+// compressMinSize is the length below which response bodies are not
+// compressed. It is also enough to detect their content type.
+const compressMinSize = sniffLen
+
+// acceptedEncoding returns the content coding, "gzip" or "deflate",
+// preferred by a client sending the Accept-Encoding header values vs,
+// or "" if it accepts neither. See RFC 7231, section 5.3.4.
+func acceptedEncoding(vs []string) string {
+	qs := map[string]float64{}
+	for _, v := range vs {
+		for _, s := range strings.Split(v, ",") {
+			coding, q := s, 1.0
+			if i := strings.Index(s, ";"); i >= 0 {
+				coding = s[:i]
+				p := strings.TrimSpace(s[i+1:])
+				if len(p) < 2 || (p[0] != 'q' && p[0] != 'Q') || p[1] != '=' {
+					continue
+				}
+				f, err := strconv.ParseFloat(p[2:], 64)
+				if err != nil || f < 0 || f > 1 {
+					continue
+				}
+				q = f
+			}
+			coding = strings.ToLower(strings.TrimSpace(coding))
+			if coding == "x-gzip" {
+				coding = "gzip"
+			}
+			qs[coding] = q
+		}
+	}
+	best, bestQ := "", 0.0
+	for _, coding := range []string{"gzip", "deflate"} {
+		q, ok := qs[coding]
+		if !ok {
+			q = qs["*"]
+		}
+		if q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+// isCompressible reports whether a body with content type ct is
+// worth compressing.
+func isCompressible(ct string) bool {
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	if i := strings.Index(mt, "/"); i >= 0 {
+		switch mt[:i] {
+		case "text":
+			return true
+		case "image":
+			return mt == "image/svg+xml" || mt == "image/bmp" || mt == "image/vnd.microsoft.icon"
+		case "audio", "video":
+			return mt == "audio/wave" || mt == "audio/basic"
+		}
+	}
+	switch mt {
+	case "application/octet-stream",
+		"application/ogg",
+		"application/pdf",
+		"application/x-gzip",
+		"application/gzip",
+		"application/x-rar-compressed",
+		"application/zip",
+		"application/font-woff",
+		"font/woff",
+		"font/woff2",
+		"multipart/byteranges":
+		return false
+	}
+	return true
+}
+
+var (
+	gzipWriterPool sync.Pool // of *gzip.Writer
+	zlibWriterPool sync.Pool // of *zlib.Writer
+)
+
+func newCompressor(encoding string, w io.Writer) io.WriteCloser {
+	switch encoding {
+	case "gzip":
+		if zw, ok := gzipWriterPool.Get().(*gzip.Writer); ok {
+			zw.Reset(w)
+			return zw
+		}
+		return gzip.NewWriter(w)
+	case "deflate":
+		// The "deflate" content coding is the zlib format,
+		// not raw DEFLATE. See RFC 7230, section 4.2.2.
+		if zw, ok := zlibWriterPool.Get().(*zlib.Writer); ok {
+			zw.Reset(w)
+			return zw
+		}
+		return zlib.NewWriter(w)
+	}
+	panic("http: unknown content coding " + encoding)
+}
+
+func putCompressor(zw io.WriteCloser) {
+	switch zw := zw.(type) {
+	case *gzip.Writer:
+		zw.Reset(nil)
+		gzipWriterPool.Put(zw)
+	case *zlib.Writer:
+		zw.Reset(nil)
+		zlibWriterPool.Put(zw)
+	}
+}
+
+// A compressWriter is the ResponseWriter passed to the handler by
+// CompressHandler. It buffers the beginning of the body until it
+// knows whether to compress it.
+type compressWriter struct {
+	w        ResponseWriter
+	encoding string // accepted by the client, or ""
+
+	code    int    // status code passed to WriteHeader, or 0
+	buf     []byte // beginning of the body, until decided
+	decided bool
+	zw      io.WriteCloser // compressor, if compressing
+	err     error          // sticky write error
+}
+
+// wrap returns cw as a ResponseWriter that implements the same
+// optional interfaces as cw.w.
+func (cw *compressWriter) wrap() ResponseWriter {
+	_, hj := cw.w.(Hijacker)
+	_, pu := cw.w.(Pusher)
+	switch {
+	case hj && pu:
+		return compressHijackPushWriter{cw}
+	case hj:
+		return compressHijackWriter{cw}
+	case pu:
+		return compressPushWriter{cw}
+	}
+	return cw
+}
+
+func (cw *compressWriter) Header() Header { return cw.w.Header() }
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.code != 0 || cw.decided {
+		return
+	}
+	cw.code = code
+	if !bodyAllowedForStatus(code) || code == StatusPartialContent {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.code == 0 {
+		cw.WriteHeader(StatusOK)
+	}
+	var buffered int
+	if !cw.decided {
+		if len(cw.buf)+len(p) < compressMinSize {
+			cw.buf = append(cw.buf, p...)
+			return len(p), nil
+		}
+		// Buffer enough of p to detect its content type.
+		buffered = compressMinSize - len(cw.buf)
+		cw.buf = append(cw.buf, p[:buffered]...)
+		p = p[buffered:]
+		cw.decide(true)
+	}
+	if cw.err != nil {
+		// The buffered part of p was consumed, even though
+		// writing it failed.
+		return buffered, cw.err
+	}
+	var n int
+	var err error
+	if cw.zw != nil {
+		n, err = cw.zw.Write(p)
+	} else {
+		n, err = cw.w.Write(p)
+	}
+	return buffered + n, err
+}
+
+// decide chooses whether to compress the response, writes its
+// header, and then the buffered beginning of its body. Bodies
+// shorter than compressMinSize are compressed only if big is set.
+func (cw *compressWriter) decide(big bool) {
+	if cw.decided || cw.code == 0 {
+		return
+	}
+	cw.decided = true
+	buf := cw.buf
+	cw.buf = nil
+
+	h := cw.w.Header()
+	if bodyAllowedForStatus(cw.code) && cw.code != StatusPartialContent &&
+		h.get("Content-Encoding") == "" && h.get("Content-Range") == "" {
+		ct, haveType := h["Content-Type"]
+		if !haveType && len(buf) > 0 {
+			ct = []string{DetectContentType(buf)}
+			h["Content-Type"] = ct
+		}
+		if len(ct) > 0 && isCompressible(ct[0]) {
+			if !hasToken(strings.Join(h["Vary"], ","), "accept-encoding") {
+				h.Add("Vary", "Accept-Encoding")
+			}
+			if cw.encoding != "" && big {
+				h.Set("Content-Encoding", cw.encoding)
+				h.Del("Content-Length")
+				h.Del("Accept-Ranges")
+				if etag := h.get("Etag"); strings.HasPrefix(etag, `"`) {
+					h.Set("Etag", "W/"+etag)
+				}
+				cw.zw = newCompressor(cw.encoding, cw.w)
+			}
+		}
+	}
+	cw.w.WriteHeader(cw.code)
+	if len(buf) == 0 {
+		return
+	}
+	if cw.zw != nil {
+		_, cw.err = cw.zw.Write(buf)
+	} else {
+		_, cw.err = cw.w.Write(buf)
+	}
+}
+
+func (cw *compressWriter) Flush() {
+	if cw.code == 0 {
+		cw.WriteHeader(StatusOK)
+	}
+	cw.decide(true)
+	switch zw := cw.zw.(type) {
+	case *gzip.Writer:
+		zw.Flush()
+	case *zlib.Writer:
+		zw.Flush()
+	}
+	if f, ok := cw.w.(Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *compressWriter) CloseNotify() <-chan bool {
+	if cn, ok := cw.w.(CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+// close finishes the response after the handler has returned.
+func (cw *compressWriter) close() {
+	cw.decide(false)
+	if cw.zw != nil {
+		cw.zw.Close()
+		putCompressor(cw.zw)
+		cw.zw = nil
+	}
+}
+
+func (cw *compressWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if cw.zw != nil {
+		return nil, nil, errors.New("http: Hijack of a compressed response")
+	}
+	cw.decide(false)
+	return cw.w.(Hijacker).Hijack()
+}
+
+func (cw *compressWriter) push(target string, opts *PushOptions) error {
+	return cw.w.(Pusher).Push(target, opts)
+}
+
+type compressHijackWriter struct{ *compressWriter }
+
+func (w compressHijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+type compressPushWriter struct{ *compressWriter }
+
+func (w compressPushWriter) Push(target string, opts *PushOptions) error { return w.push(target, opts) }
+
+type compressHijackPushWriter struct{ *compressWriter }
+
+func (w compressHijackPushWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+func (w compressHijackPushWriter) Push(target string, opts *PushOptions) error {
+	return w.push(target, opts)
+}
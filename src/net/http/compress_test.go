@@ -0,0 +1,304 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"io/ioutil"
+	. "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAcceptedEncoding(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"deflate", "deflate"},
+		{"gzip, deflate, br", "gzip"},
+		{"deflate, gzip", "gzip"},
+		{"DEFLATE, X-GZIP", "gzip"},
+		{"br", ""},
+		{"identity", ""},
+		{"*", "gzip"},
+		{"*;q=0", ""},
+		{"gzip;q=0, *", "deflate"},
+		{"gzip;q=0.5, deflate", "deflate"},
+		{"gzip;q=0.5, deflate;q=0.8", "deflate"},
+		{"gzip ; q=0.9 , deflate;q=0.1", "gzip"},
+		{"gzip;q=0", ""},
+		{"gzip;q=bad, deflate;q=0.1", "deflate"},
+	}
+	for _, tt := range tests {
+		var vs []string
+		if tt.in != "" {
+			vs = []string{tt.in}
+		}
+		if got := ExportAcceptedEncoding(vs); got != tt.want {
+			t.Errorf("acceptedEncoding(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+var compressText = strings.Repeat("<html><body>Hello, compressed world.</body></html>\n", 40)
+
+func TestCompressHandler(t *testing.T) {
+	png := "\x89PNG\x0D\x0A\x1A\x0A" + strings.Repeat("\x00", 1000)
+	tests := []struct {
+		name     string
+		accept   string
+		header   Header // set by the handler
+		code     int
+		body     string
+		wantEnc  string
+		wantVary bool
+	}{
+		{name: "gzip", accept: "gzip", body: compressText, wantEnc: "gzip", wantVary: true},
+		{name: "deflate", accept: "deflate", body: compressText, wantEnc: "deflate", wantVary: true},
+		{name: "not accepted", accept: "br", body: compressText, wantVary: true},
+		{name: "tiny", accept: "gzip", body: "<html>tiny</html>", wantVary: true},
+		{name: "sniffed image", accept: "gzip", body: png},
+		{name: "image", accept: "gzip", header: Header{"Content-Type": {"image/jpeg"}}, body: compressText},
+		{name: "svg", accept: "gzip", header: Header{"Content-Type": {"image/svg+xml"}}, body: compressText, wantEnc: "gzip", wantVary: true},
+		{name: "already encoded", accept: "gzip", header: Header{"Content-Encoding": {"br"}}, body: compressText},
+		{name: "partial", accept: "gzip", header: Header{"Content-Range": {"bytes 0-9/100"}}, code: StatusPartialContent, body: compressText},
+		{name: "not found", accept: "gzip", code: StatusNotFound, body: compressText, wantEnc: "gzip", wantVary: true},
+		{name: "content length", accept: "gzip", header: Header{"Content-Length": {"2040"}}, body: compressText, wantEnc: "gzip", wantVary: true},
+		{name: "vary", accept: "gzip", header: Header{"Vary": {"Cookie, accept-encoding"}}, body: compressText, wantEnc: "gzip"},
+	}
+	for _, tt := range tests {
+		h := CompressHandler(HandlerFunc(func(w ResponseWriter, r *Request) {
+			for k, v := range tt.header {
+				w.Header()[k] = v
+			}
+			if tt.code != 0 {
+				w.WriteHeader(tt.code)
+			}
+			// Write in small pieces, to exercise buffering.
+			for s := tt.body; s != ""; {
+				n := 100
+				if n > len(s) {
+					n = len(s)
+				}
+				io.WriteString(w, s[:n])
+				s = s[n:]
+			}
+		}))
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", tt.accept)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		wantCode := tt.code
+		if wantCode == 0 {
+			wantCode = StatusOK
+		}
+		if rec.Code != wantCode {
+			t.Errorf("%s: status = %d; want %d", tt.name, rec.Code, wantCode)
+		}
+		enc := rec.HeaderMap.Get("Content-Encoding")
+		if _, ok := tt.header["Content-Encoding"]; ok {
+			if enc != tt.header.Get("Content-Encoding") {
+				t.Errorf("%s: Content-Encoding changed to %q", tt.name, enc)
+			}
+		} else if enc != tt.wantEnc {
+			t.Errorf("%s: Content-Encoding = %q; want %q", tt.name, enc, tt.wantEnc)
+		}
+		vary := strings.Join(rec.HeaderMap["Vary"], ", ")
+		if got := strings.Contains(vary, "Accept-Encoding"); got != tt.wantVary {
+			t.Errorf("%s: Vary = %q; want Accept-Encoding added: %v", tt.name, vary, tt.wantVary)
+		}
+		body := rec.Body.Bytes()
+		if tt.wantEnc != "" {
+			if cl := rec.HeaderMap.Get("Content-Length"); cl != "" {
+				t.Errorf("%s: compressed response has Content-Length %q", tt.name, cl)
+			}
+			var zr io.Reader
+			var err error
+			if tt.wantEnc == "gzip" {
+				zr, err = gzip.NewReader(bytes.NewReader(body))
+			} else {
+				zr, err = zlib.NewReader(bytes.NewReader(body))
+			}
+			if err == nil {
+				body, err = ioutil.ReadAll(zr)
+			}
+			if err != nil {
+				t.Errorf("%s: decoding body: %v", tt.name, err)
+				continue
+			}
+		}
+		if string(body) != tt.body {
+			t.Errorf("%s: body = %q; want %q", tt.name, body, tt.body)
+		}
+	}
+}
+
+func TestCompressHandlerHeaders(t *testing.T) {
+	h := CompressHandler(HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Etag", `"abc"`)
+		io.WriteString(w, compressText)
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if got := rec.HeaderMap.Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q; want the sniffed type", got)
+	}
+	if got := rec.HeaderMap.Get("Etag"); got != `W/"abc"` {
+		t.Errorf("Etag = %q; want a weak ETag", got)
+	}
+	if got := rec.HeaderMap.Get("Accept-Ranges"); got != "" {
+		t.Errorf("Accept-Ranges = %q; want none", got)
+	}
+}
+
+// errorResponseWriter is a ResponseWriter whose body writes fail.
+type errorResponseWriter struct {
+	*httptest.ResponseRecorder
+}
+
+var errBodyWrite = errors.New("body write failed")
+
+func (w errorResponseWriter) Write(p []byte) (int, error) { return 0, errBodyWrite }
+
+// When writing the buffered beginning of the body fails, Write
+// reports the part of p it buffered as written.
+func TestCompressHandlerWriteError(t *testing.T) {
+	h := CompressHandler(HandlerFunc(func(w ResponseWriter, r *Request) {
+		if n, err := io.WriteString(w, compressText[:100]); n != 100 || err != nil {
+			t.Errorf("first Write = %d, %v; want 100, nil", n, err)
+		}
+		n, err := io.WriteString(w, compressText[100:])
+		if want := 512 - 100; n != want || err != errBodyWrite {
+			t.Errorf("second Write = %d, %v; want %d, %v", n, err, want, errBodyWrite)
+		}
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	h.ServeHTTP(errorResponseWriter{httptest.NewRecorder()}, req)
+}
+
+func TestCompressHandlerInterfaces_h1(t *testing.T) { testCompressHandlerInterfaces(t, h1Mode) }
+func TestCompressHandlerInterfaces_h2(t *testing.T) { testCompressHandlerInterfaces(t, h2Mode) }
+
+func testCompressHandlerInterfaces(t *testing.T, h2 bool) {
+	setParallel(t)
+	defer afterTest(t)
+	cst := newClientServerTest(t, h2, CompressHandler(HandlerFunc(func(w ResponseWriter, r *Request) {
+		if _, ok := w.(Flusher); !ok {
+			t.Error("ResponseWriter is not a Flusher")
+		}
+		if _, ok := w.(CloseNotifier); !ok {
+			t.Error("ResponseWriter is not a CloseNotifier")
+		}
+		if _, ok := w.(Hijacker); ok == h2 {
+			t.Errorf("ResponseWriter is a Hijacker: %v; want %v", ok, !h2)
+		}
+		if _, ok := w.(Pusher); ok != h2 {
+			t.Errorf("ResponseWriter is a Pusher: %v; want %v", ok, h2)
+		}
+	})))
+	defer cst.close()
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+}
+
+func TestCompressHandlerFlush_h1(t *testing.T) { testCompressHandlerFlush(t, h1Mode) }
+func TestCompressHandlerFlush_h2(t *testing.T) { testCompressHandlerFlush(t, h2Mode) }
+
+func testCompressHandlerFlush(t *testing.T, h2 bool) {
+	setParallel(t)
+	defer afterTest(t)
+	release := make(chan bool)
+	cst := newClientServerTest(t, h2, CompressHandler(HandlerFunc(func(w ResponseWriter, r *Request) {
+		io.WriteString(w, compressText)
+		w.(Flusher).Flush()
+		<-release
+		io.WriteString(w, "the end\n")
+	})))
+	defer cst.close()
+	defer close(release)
+
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if !res.Uncompressed {
+		t.Fatal("response was not compressed")
+	}
+	// The flushed part of the body arrives while the handler is blocked.
+	br := bufio.NewReader(res.Body)
+	buf := make([]byte, len(compressText))
+	if _, err := io.ReadFull(br, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != compressText {
+		t.Errorf("got %q; want %q", buf, compressText)
+	}
+	release <- true
+	rest, err := ioutil.ReadAll(br)
+	if err != nil || string(rest) != "the end\n" {
+		t.Errorf("rest of body = %q, %v; want %q", rest, err, "the end\n")
+	}
+}
+
+func TestCompressHandlerFlushFirst_h1(t *testing.T) { testCompressHandlerFlushFirst(t, h1Mode) }
+func TestCompressHandlerFlushFirst_h2(t *testing.T) { testCompressHandlerFlushFirst(t, h2Mode) }
+
+// A Flush before the first Write settles whether the response is
+// compressed; later Writes must not change it.
+func testCompressHandlerFlushFirst(t *testing.T, h2 bool) {
+	setParallel(t)
+	defer afterTest(t)
+	body := strings.Repeat(compressText, 5)
+	cst := newClientServerTest(t, h2, CompressHandler(HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.URL.Path == "/text" {
+			w.Header().Set("Content-Type", "text/plain")
+		}
+		w.(Flusher).Flush()
+		io.WriteString(w, body)
+	})))
+	defer cst.close()
+
+	for _, tt := range []struct {
+		path     string
+		wantComp bool
+	}{
+		{"/", false},    // unknown content type when flushed
+		{"/text", true}, // compressible content type
+	} {
+		res, err := cst.c.Get(cst.ts.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.path, err)
+		}
+		if res.Uncompressed != tt.wantComp {
+			t.Errorf("%s: compressed = %v; want %v", tt.path, res.Uncompressed, tt.wantComp)
+		}
+		if string(got) != body {
+			t.Errorf("%s: body = %.20q...; want %.20q...", tt.path, got, body)
+		}
+	}
+}
@@ -32,10 +32,38 @@ type Cookie struct {
 	MaxAge   int
 	Secure   bool
 	HttpOnly bool
+	SameSite SameSite
+
+	// Partitioned asks browsers to store the cookie separately for
+	// each top-level site that embeds the cookie's site, as proposed
+	// by CHIPS (Cookies Having Independent Partitioned State).
+	// Partitioned cookies must also be Secure.
+	Partitioned bool
+
 	Raw      string
 	Unparsed []string // Raw text of unparsed attribute-value pairs
 }
 
+// SameSite allows a server to define a cookie attribute making it impossible for
+// the browser to send this cookie along with cross-site requests. The main
+// goal is to mitigate the risk of cross-origin information leakage, and provide
+// some protection against cross-site request forgery attacks.
+//
+// See https://tools.ietf.org/html/draft-ietf-httpbis-rfc6265bis for details.
+type SameSite int
+
+const (
+	// SameSiteDefaultMode omits the SameSite attribute. When
+	// parsing, it means the attribute had an unrecognized value.
+	SameSiteDefaultMode SameSite = iota + 1
+	SameSiteLaxMode
+	SameSiteStrictMode
+
+	// SameSiteNoneMode sends the cookie along with cross-site
+	// requests. Browsers only accept it for Secure cookies.
+	SameSiteNoneMode
+)
+
 // readSetCookies parses all "Set-Cookie" values from
 // the header h and returns the successfully parsed Cookies.
 func readSetCookies(h Header) []*Cookie {
@@ -90,6 +118,21 @@ func readSetCookies(h Header) []*Cookie {
 			case "httponly":
 				c.HttpOnly = true
 				continue
+			case "samesite":
+				switch strings.ToLower(val) {
+				case "lax":
+					c.SameSite = SameSiteLaxMode
+				case "strict":
+					c.SameSite = SameSiteStrictMode
+				case "none":
+					c.SameSite = SameSiteNoneMode
+				default:
+					c.SameSite = SameSiteDefaultMode
+				}
+				continue
+			case "partitioned":
+				c.Partitioned = true
+				continue
 			case "domain":
 				c.Domain = val
 				continue
@@ -188,6 +231,19 @@ func (c *Cookie) String() string {
 	if c.Secure {
 		b.WriteString("; Secure")
 	}
+	switch c.SameSite {
+	case SameSiteDefaultMode:
+		// Skip, default mode is obtained by not emitting the attribute.
+	case SameSiteLaxMode:
+		b.WriteString("; SameSite=Lax")
+	case SameSiteStrictMode:
+		b.WriteString("; SameSite=Strict")
+	case SameSiteNoneMode:
+		b.WriteString("; SameSite=None")
+	}
+	if c.Partitioned {
+		b.WriteString("; Partitioned")
+	}
 	return b.String()
 }
 
@@ -3,11 +3,22 @@
 // license that can be found in the LICENSE file.
 
 // Package cookiejar implements an in-memory RFC 6265-compliant http.CookieJar.
+//
+// The jar also implements the SameSite attribute and the "__Secure-" and
+// "__Host-" cookie name prefixes of RFC 6265bis, and partitioned cookies,
+// which are kept separately for each top-level site. Its persistent
+// cookies can be saved to a file and loaded again.
+//
+// Cookies and SetCookies handle requests made by the site itself, and
+// CrossSiteCookies and SetCrossSiteCookies requests made on behalf of
+// another site. An http.Client uses CrossSiteCookies for redirects.
 package cookiejar
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
@@ -93,6 +104,8 @@ type entry struct {
 	Value      string
 	Domain     string
 	Path       string
+	SameSite   string // "Strict", "Lax", "None" or ""
+	Partition  string // for a partitioned cookie, the top-level site, like "https://example.com"
 	Secure     bool
 	HttpOnly   bool
 	Persistent bool
@@ -107,9 +120,14 @@ type entry struct {
 	seqNum uint64
 }
 
-// id returns the domain;path;name triple of e as an id.
+// id returns the domain;path;name triple of e as an id, followed by
+// ";partition" for a partitioned cookie.
 func (e *entry) id() string {
-	return fmt.Sprintf("%s;%s;%s", e.Domain, e.Path, e.Name)
+	id := fmt.Sprintf("%s;%s;%s", e.Domain, e.Path, e.Name)
+	if e.Partition != "" {
+		id += ";" + e.Partition
+	}
+	return id
 }
 
 // shouldSend determines whether e's cookie qualifies to be included in a
@@ -147,15 +165,87 @@ func hasDotSuffix(s, suffix string) bool {
 	return len(s) > len(suffix) && s[len(s)-len(suffix)-1] == '.' && s[len(s)-len(suffix):] == suffix
 }
 
+// sendCrossSite reports whether e's cookie qualifies to be included in a
+// cross-site request, according to its SameSite attribute. Cookies
+// without the attribute are treated as SameSite=Lax, as RFC 6265bis
+// recommends.
+func (e *entry) sendCrossSite(navigation bool) bool {
+	switch e.SameSite {
+	case "None":
+		return true
+	case "Strict":
+		return false
+	}
+	return navigation
+}
+
 // Cookies implements the Cookies method of the http.CookieJar interface.
+// The request is taken to be made by u's own site, so the SameSite
+// attribute does not restrict the cookies returned, and partitioned
+// cookies are those of u's site. See CrossSiteCookies.
 //
 // It returns an empty slice if the URL's scheme is not HTTP or HTTPS.
 func (j *Jar) Cookies(u *url.URL) (cookies []*http.Cookie) {
 	return j.cookies(u, time.Now())
 }
 
+// CrossSiteCookies is like Cookies, but for a request to u that was
+// initiated by a document from site, such as a link, a form or an
+// embedded resource of a web page. If site and u are not same-site,
+// that is, if their schemes or their registrable domains differ, the
+// SameSite attributes of the cookies are enforced:
+//   - SameSite=Strict cookies are not returned;
+//   - SameSite=Lax cookies, and cookies without a SameSite attribute,
+//     are returned only if navigation is set, which means that the
+//     request is a top-level navigation with a safe method like GET;
+//   - SameSite=None cookies are returned.
+//
+// The top-level site, whose partitioned cookies are returned, is u's
+// site for a navigation, and site's otherwise.
+func (j *Jar) CrossSiteCookies(u, site *url.URL, navigation bool) (cookies []*http.Cookie) {
+	return j.crossSiteCookies(u, site, navigation, time.Now())
+}
+
 // cookies is like Cookies but takes the current time as a parameter.
 func (j *Jar) cookies(u *url.URL, now time.Time) (cookies []*http.Cookie) {
+	return j.selectCookies(u, u, true, true, now)
+}
+
+// crossSiteCookies is like CrossSiteCookies but takes the current time as a
+// parameter.
+func (j *Jar) crossSiteCookies(u, site *url.URL, navigation bool, now time.Time) (cookies []*http.Cookie) {
+	return j.selectCookies(u, site, j.sameSite(u, site), navigation, now)
+}
+
+// site returns the site of u: its scheme and its registrable domain,
+// which is used as the jar key, like "https://example.com".
+func (j *Jar) site(u *url.URL) (string, error) {
+	host, err := canonicalHost(u.Host)
+	if err != nil {
+		return "", err
+	}
+	return u.Scheme + "://" + jarKey(host, j.psList), nil
+}
+
+// sameSite reports whether u and site are same-site.
+func (j *Jar) sameSite(u, site *url.URL) bool {
+	s1, err1 := j.site(u)
+	s2, err2 := j.site(site)
+	return err1 == nil && err2 == nil && s1 == s2
+}
+
+// topLevelSite returns the top-level site of a request to u initiated
+// by site, as described for CrossSiteCookies.
+func (j *Jar) topLevelSite(u, site *url.URL, navigation bool) (string, error) {
+	if navigation {
+		return j.site(u)
+	}
+	return j.site(site)
+}
+
+// selectCookies implements cookies and crossSiteCookies. sameSite
+// reports whether u and site are same-site.
+func (j *Jar) selectCookies(u, site *url.URL, sameSite, navigation bool, now time.Time) (cookies []*http.Cookie) {
 	if u.Scheme != "http" && u.Scheme != "https" {
 		return cookies
 	}
@@ -164,6 +254,10 @@ func (j *Jar) cookies(u *url.URL, now time.Time) (cookies []*http.Cookie) {
 		return cookies
 	}
 	key := jarKey(host, j.psList)
+	top, err := j.topLevelSite(u, site, navigation)
+	if err != nil {
+		return cookies
+	}
 
 	j.mu.Lock()
 	defer j.mu.Unlock()
@@ -187,7 +281,10 @@ func (j *Jar) cookies(u *url.URL, now time.Time) (cookies []*http.Cookie) {
 			modified = true
 			continue
 		}
-		if !e.shouldSend(https, host, path) {
+		if !e.shouldSend(https, host, path) || !sameSite && !e.sendCrossSite(navigation) {
+			continue
+		}
+		if e.Partition != "" && e.Partition != top {
 			continue
 		}
 		e.LastAccess = now
@@ -223,14 +320,37 @@ func (j *Jar) cookies(u *url.URL, now time.Time) (cookies []*http.Cookie) {
 }
 
 // SetCookies implements the SetCookies method of the http.CookieJar interface.
+// The response is taken to be to a request made by u's own site, as
+// for Cookies. See SetCrossSiteCookies.
 //
 // It does nothing if the URL's scheme is not HTTP or HTTPS.
 func (j *Jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
 	j.setCookies(u, cookies, time.Now())
 }
 
+// SetCrossSiteCookies is like SetCookies, but for the response to a
+// request to u initiated by a document from site, as described for
+// CrossSiteCookies. If site and u are not same-site and the request
+// is not a navigation, only SameSite=None cookies are accepted.
+// Partitioned cookies are stored for the top-level site of the request.
+func (j *Jar) SetCrossSiteCookies(u, site *url.URL, navigation bool, cookies []*http.Cookie) {
+	j.setCrossSiteCookies(u, site, navigation, cookies, time.Now())
+}
+
 // setCookies is like SetCookies but takes the current time as parameter.
 func (j *Jar) setCookies(u *url.URL, cookies []*http.Cookie, now time.Time) {
+	j.storeCookies(u, u, true, true, cookies, now)
+}
+
+// setCrossSiteCookies is like SetCrossSiteCookies but takes the current
+// time as parameter.
+func (j *Jar) setCrossSiteCookies(u, site *url.URL, navigation bool, cookies []*http.Cookie, now time.Time) {
+	j.storeCookies(u, site, j.sameSite(u, site), navigation, cookies, now)
+}
+
+// storeCookies implements setCookies and setCrossSiteCookies. sameSite
+// reports whether u and site are same-site.
+func (j *Jar) storeCookies(u, site *url.URL, sameSite, navigation bool, cookies []*http.Cookie, now time.Time) {
 	if len(cookies) == 0 {
 		return
 	}
@@ -243,6 +363,10 @@ func (j *Jar) setCookies(u *url.URL, cookies []*http.Cookie, now time.Time) {
 	}
 	key := jarKey(host, j.psList)
 	defPath := defaultPath(u.Path)
+	top, err := j.topLevelSite(u, site, navigation)
+	if err != nil {
+		return
+	}
 
 	j.mu.Lock()
 	defer j.mu.Unlock()
@@ -250,11 +374,22 @@ func (j *Jar) setCookies(u *url.URL, cookies []*http.Cookie, now time.Time) {
 	submap := j.entries[key]
 
 	modified := false
+	https := u.Scheme == "https"
 	for _, cookie := range cookies {
 		e, remove, err := j.newEntry(cookie, now, defPath, host)
 		if err != nil {
 			continue
 		}
+		if err := checkSecurity(cookie, https, e.Path); err != nil {
+			continue
+		}
+		if !sameSite && !navigation && cookie.SameSite != http.SameSiteNoneMode {
+			// RFC 6265bis section 5.6, step 15.
+			continue
+		}
+		if cookie.Partitioned {
+			e.Partition = top
+		}
 		id := e.id()
 		if remove {
 			if submap != nil {
@@ -418,13 +553,49 @@ func (j *Jar) newEntry(c *http.Cookie, now time.Time, defPath, host string) (e e
 	e.Secure = c.Secure
 	e.HttpOnly = c.HttpOnly
 
+	switch c.SameSite {
+	case http.SameSiteStrictMode:
+		e.SameSite = "Strict"
+	case http.SameSiteLaxMode:
+		e.SameSite = "Lax"
+	case http.SameSiteNoneMode:
+		e.SameSite = "None"
+	}
+
 	return e, false, nil
 }
 
+// checkSecurity implements the requirements of RFC 6265bis for cookies
+// received from a URL with the given scheme, whose path attribute is
+// path: SameSite=None and partitioned cookies must be secure, cookies
+// whose name starts with "__Secure-" must be secure and set over
+// HTTPS, and cookies whose name starts with "__Host-" must also be
+// host-only and have the path "/".
+func checkSecurity(c *http.Cookie, https bool, path string) error {
+	if (c.SameSite == http.SameSiteNoneMode || c.Partitioned) && !c.Secure {
+		return errInsecureCookie
+	}
+	name := strings.ToLower(c.Name)
+	switch {
+	case strings.HasPrefix(name, "__secure-"):
+		if !c.Secure || !https {
+			return errSecurePrefix
+		}
+	case strings.HasPrefix(name, "__host-"):
+		if !c.Secure || !https || c.Domain != "" || path != "/" {
+			return errHostPrefix
+		}
+	}
+	return nil
+}
+
 var (
 	errIllegalDomain   = errors.New("cookiejar: illegal cookie domain attribute")
 	errMalformedDomain = errors.New("cookiejar: malformed cookie domain attribute")
 	errNoHostname      = errors.New("cookiejar: no host name available (IP only)")
+	errInsecureCookie  = errors.New("cookiejar: SameSite=None or partitioned cookie without Secure attribute")
+	errSecurePrefix    = errors.New("cookiejar: __Secure- cookie not set securely")
+	errHostPrefix      = errors.New("cookiejar: __Host- cookie not set securely for the host's root path")
 )
 
 // endOfTime is the time when session (non-persistent) cookies expire.
@@ -491,3 +662,121 @@ func (j *Jar) domainAndType(host, domain string) (string, bool, error) {
 
 	return domain, false, nil
 }
+
+// Save writes the persistent cookies in the jar to w, in a JSON format
+// that Load reads. Session cookies, which have neither a Max-Age nor
+// an Expires attribute, and expired cookies are not saved.
+func (j *Jar) Save(w io.Writer) error {
+	return j.save(w, time.Now())
+}
+
+// save is like Save but takes the current time as a parameter.
+func (j *Jar) save(w io.Writer, now time.Time) error {
+	j.mu.Lock()
+	var saved []entry
+	for _, submap := range j.entries {
+		for _, e := range submap {
+			if e.Persistent && e.Expires.After(now) {
+				saved = append(saved, e)
+			}
+		}
+	}
+	j.mu.Unlock()
+
+	// Keep the order in which the cookies were created, so that
+	// Load restores the order of cookies with the same creation time.
+	sort.Slice(saved, func(i, j int) bool { return saved[i].seqNum < saved[j].seqNum })
+	if saved == nil {
+		saved = []entry{}
+	}
+	return json.NewEncoder(w).Encode(saved)
+}
+
+// Load reads cookies written by Save from r and adds them to the jar,
+// replacing any cookies with the same name, domain and path. Cookies
+// that have expired since they were saved are ignored.
+//
+// If r does not hold cookies written by Save, Load returns an error
+// and leaves the jar unchanged.
+func (j *Jar) Load(r io.Reader) error {
+	return j.load(r, time.Now())
+}
+
+// load is like Load but takes the current time as a parameter.
+func (j *Jar) load(r io.Reader, now time.Time) error {
+	var loaded []entry
+	if err := json.NewDecoder(r).Decode(&loaded); err != nil {
+		return err
+	}
+	for i := range loaded {
+		if !j.validEntry(&loaded[i]) {
+			return errMalformedSave
+		}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, e := range loaded {
+		if !e.Expires.After(now) {
+			continue
+		}
+		key := jarKey(e.Domain, j.psList)
+		submap := j.entries[key]
+		if submap == nil {
+			submap = make(map[string]entry)
+			j.entries[key] = submap
+		}
+		e.seqNum = j.nextSeqNum
+		j.nextSeqNum++
+		submap[e.id()] = e
+	}
+	return nil
+}
+
+var errMalformedSave = errors.New("cookiejar: malformed saved cookies")
+
+// validEntry reports whether the loaded entry e is one that setCookies
+// could have stored, so that a saved file cannot widen the scope of a
+// cookie or weaken its security requirements.
+func (j *Jar) validEntry(e *entry) bool {
+	if e.Name == "" || e.Domain == "" || !strings.HasPrefix(e.Path, "/") ||
+		!e.Persistent || e.Creation.IsZero() || e.LastAccess.IsZero() {
+		return false
+	}
+	if host, err := canonicalHost(e.Domain); err != nil || host != e.Domain {
+		return false
+	}
+	if !e.HostOnly {
+		// A domain cookie needs a domain attribute that is neither
+		// an IP address nor a public suffix.
+		if domain, hostOnly, err := j.domainAndType(e.Domain, e.Domain); err != nil || hostOnly || domain != e.Domain {
+			return false
+		}
+	}
+	switch e.SameSite {
+	case "", "Strict", "Lax":
+	case "None":
+		if !e.Secure {
+			return false
+		}
+	default:
+		return false
+	}
+	if e.Partition != "" {
+		u, err := url.Parse(e.Partition)
+		if err != nil || u.Scheme != "http" && u.Scheme != "https" || !e.Secure {
+			return false
+		}
+		if site, err := j.site(u); err != nil || site != e.Partition {
+			return false
+		}
+	}
+	name := strings.ToLower(e.Name)
+	switch {
+	case strings.HasPrefix(name, "__secure-"):
+		return e.Secure
+	case strings.HasPrefix(name, "__host-"):
+		return e.Secure && e.HostOnly && e.Path == "/"
+	}
+	return true
+}
@@ -5,6 +5,7 @@
 package cookiejar
 
 import (
+	"bytes"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -1320,3 +1321,291 @@ func TestIssue19384(t *testing.T) {
 		}
 	}
 }
+
+// securityTests contains jarTests for the RFC 6265bis requirements on
+// SameSite=None cookies and on cookie name prefixes. Each jarTest has
+// to be performed on a fresh, empty Jar.
+var securityTests = [...]jarTest{
+	{
+		"SameSite=None cookies must be secure.",
+		"https://www.host.test/",
+		[]string{
+			"a=1; SameSite=None",
+			"b=2; SameSite=None; Secure",
+			"c=3; SameSite=Lax",
+			"d=4; Partitioned",
+			"e=5; Partitioned; Secure; SameSite=None",
+		},
+		"b=2 c=3 e=5",
+		[]query{{"https://www.host.test", "b=2 c=3 e=5"}},
+	},
+	{
+		"__Secure- cookies must be secure and set over https.",
+		"https://www.host.test/",
+		[]string{
+			"__Secure-a=1",
+			"__Secure-b=2; Secure",
+			"__secure-c=3",
+			"__Secure-d=4; Secure; Domain=host.test; Path=/foo",
+		},
+		"__Secure-b=2 __Secure-d=4",
+		[]query{{"https://www.host.test/foo", "__Secure-d=4 __Secure-b=2"}},
+	},
+	{
+		"__Secure- cookies are not set over http.",
+		"http://www.host.test/",
+		[]string{"__Secure-a=1; Secure"},
+		"",
+		[]query{{"https://www.host.test", ""}},
+	},
+	{
+		"__Host- cookies must be host-only and for the root path.",
+		"https://www.host.test/dir/page",
+		[]string{
+			"__Host-a=1; Secure",
+			"__Host-b=2; Secure; Path=/",
+			"__Host-c=3; Secure; Path=/; Domain=www.host.test",
+			"__Host-d=4; Path=/",
+			"__HOST-e=5; Secure; Path=/dir",
+		},
+		"__Host-b=2",
+		[]query{{"https://www.host.test", "__Host-b=2"}},
+	},
+}
+
+func TestSecurity(t *testing.T) {
+	for _, test := range securityTests {
+		jar := newTestJar()
+		test.run(t, jar)
+	}
+}
+
+func TestCrossSiteCookies(t *testing.T) {
+	jar := newTestJar()
+	jar.setCookies(mustParseURL("https://www.host.test/"), []*http.Cookie{
+		{Name: "none", Value: "1", Secure: true, SameSite: http.SameSiteNoneMode},
+		{Name: "lax", Value: "2", SameSite: http.SameSiteLaxMode},
+		{Name: "strict", Value: "3", SameSite: http.SameSiteStrictMode},
+		{Name: "default", Value: "4"},
+	}, tNow)
+
+	tests := []struct {
+		site       string
+		navigation bool
+		want       string
+	}{
+		{"https://www.host.test/", false, "none=1 lax=2 strict=3 default=4"},
+		{"https://www.host.test/page", false, "none=1 lax=2 strict=3 default=4"},
+		{"https://other.host.test/", false, "none=1 lax=2 strict=3 default=4"},
+		{"http://www.host.test/", false, "none=1"},
+		{"https://www.other.test/", false, "none=1"},
+		{"https://www.other.test/", true, "none=1 lax=2 default=4"},
+		{"https://www.host.other.test/", true, "none=1 lax=2 default=4"},
+	}
+	now := tNow
+	for _, tt := range tests {
+		now = now.Add(time.Second)
+		var s []string
+		for _, c := range jar.crossSiteCookies(mustParseURL("https://www.host.test/"), mustParseURL(tt.site), tt.navigation, now) {
+			s = append(s, c.Name+"="+c.Value)
+		}
+		if got := strings.Join(s, " "); got != tt.want {
+			t.Errorf("site %q, navigation %v: got %q; want %q", tt.site, tt.navigation, got, tt.want)
+		}
+	}
+}
+
+func TestSetCrossSiteCookies(t *testing.T) {
+	cookies := []*http.Cookie{
+		{Name: "none", Value: "1", Secure: true, SameSite: http.SameSiteNoneMode},
+		{Name: "lax", Value: "2", SameSite: http.SameSiteLaxMode},
+		{Name: "default", Value: "3"},
+	}
+	tests := []struct {
+		site       string
+		navigation bool
+		want       string
+	}{
+		{"https://other.host.test/", false, "none=1 lax=2 default=3"},
+		{"https://www.other.test/", true, "none=1 lax=2 default=3"},
+		{"https://www.other.test/", false, "none=1"},
+		{"http://www.host.test/", false, "none=1"},
+	}
+	u := mustParseURL("https://www.host.test/")
+	for _, tt := range tests {
+		jar := newTestJar()
+		jar.setCrossSiteCookies(u, mustParseURL(tt.site), tt.navigation, cookies, tNow)
+		var s []string
+		for _, c := range jar.cookies(u, tNow) {
+			s = append(s, c.Name+"="+c.Value)
+		}
+		if got := strings.Join(s, " "); got != tt.want {
+			t.Errorf("site %q, navigation %v: got %q; want %q", tt.site, tt.navigation, got, tt.want)
+		}
+	}
+}
+
+func TestPartitionedCookies(t *testing.T) {
+	jar := newTestJar()
+	u := mustParseURL("https://embed.test/widget")
+	siteA := mustParseURL("https://www.a.test/")
+	siteB := mustParseURL("https://www.b.test/")
+	partitioned := func(v string) []*http.Cookie {
+		return []*http.Cookie{{Name: "p", Value: v, Secure: true, SameSite: http.SameSiteNoneMode, Partitioned: true}}
+	}
+	jar.setCrossSiteCookies(u, siteA, false, partitioned("a"), tNow)
+	jar.setCrossSiteCookies(u, siteB, false, partitioned("b"), tNow)
+	jar.setCookies(u, partitioned("own"), tNow)
+	jar.setCookies(u, []*http.Cookie{{Name: "u", Value: "1", Secure: true, SameSite: http.SameSiteNoneMode}}, tNow)
+
+	tests := []struct {
+		site       *url.URL
+		navigation bool
+		want       string
+	}{
+		{siteA, false, "p=a u=1"},
+		{siteB, false, "p=b u=1"},
+		{mustParseURL("https://www.c.test/"), false, "u=1"},
+		{siteA, true, "p=own u=1"},
+		{u, false, "p=own u=1"},
+	}
+	now := tNow
+	for _, tt := range tests {
+		now = now.Add(time.Second)
+		var s []string
+		for _, c := range jar.crossSiteCookies(u, tt.site, tt.navigation, now) {
+			s = append(s, c.Name+"="+c.Value)
+		}
+		sort.Strings(s)
+		if got := strings.Join(s, " "); got != tt.want {
+			t.Errorf("site %q, navigation %v: got %q; want %q", tt.site, tt.navigation, got, tt.want)
+		}
+	}
+
+	// Deleting a partitioned cookie leaves the other partitions alone.
+	jar.setCrossSiteCookies(u, siteA, false, []*http.Cookie{
+		{Name: "p", Secure: true, SameSite: http.SameSiteNoneMode, Partitioned: true, MaxAge: -1},
+	}, now)
+	for site, want := range map[*url.URL]int{siteA: 1, siteB: 2} {
+		if got := len(jar.crossSiteCookies(u, site, false, now)); got != want {
+			t.Errorf("site %q after deletion: got %d cookies; want %d", site, got, want)
+		}
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	jar := newTestJar()
+	jar.setCookies(mustParseURL("https://www.host.test/dir/"), []*http.Cookie{
+		{Name: "a", Value: "1", MaxAge: 3600},
+		{Name: "b", Value: "2", MaxAge: 3600, Domain: "host.test", Path: "/", Secure: true, SameSite: http.SameSiteStrictMode},
+		{Name: "c", Value: "3", MaxAge: 3600, HttpOnly: true},
+		{Name: "session", Value: "4"},
+		{Name: "short", Value: "5", MaxAge: 10},
+	}, tNow)
+	jar.setCookies(mustParseURL("http://www.other.test/"), []*http.Cookie{
+		{Name: "d", Value: "6", Expires: tNow.Add(time.Hour)},
+	}, tNow)
+
+	var buf bytes.Buffer
+	if err := jar.save(&buf, tNow.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	saved := buf.String()
+
+	loaded := newTestJar()
+	// Cookies in the jar are replaced by loaded ones.
+	loaded.setCookies(mustParseURL("https://www.host.test/dir/"), []*http.Cookie{
+		{Name: "a", Value: "old"},
+		{Name: "e", Value: "7"},
+	}, tNow)
+	now := tNow.Add(time.Minute) // "short" has expired meanwhile
+	if err := loaded.load(strings.NewReader(saved), now); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []struct{ url, want string }{
+		{"https://www.host.test/dir/x", "e=7 a=1 c=3 b=2"},
+		{"http://www.host.test/dir/x", "e=7 a=1 c=3"},
+		{"https://sub.host.test/", "b=2"},
+		{"http://www.other.test/", "d=6"},
+	} {
+		var s []string
+		for _, c := range loaded.cookies(mustParseURL(q.url), now) {
+			s = append(s, c.Name+"="+c.Value)
+		}
+		if got := strings.Join(s, " "); got != q.want {
+			t.Errorf("Cookies(%q) = %q; want %q", q.url, got, q.want)
+		}
+	}
+	if got := loaded.crossSiteCookies(mustParseURL("https://www.host.test/"), mustParseURL("https://x.test/"), true, now); len(got) != 0 {
+		t.Errorf("SameSite=Strict cookie was not restored: got cross-site cookies %v", got)
+	}
+
+	// Partitioned cookies keep their partition.
+	partitioned := newTestJar()
+	partitioned.setCrossSiteCookies(mustParseURL("https://embed.test/"), mustParseURL("https://www.a.test/"), false, []*http.Cookie{
+		{Name: "p", Value: "1", MaxAge: 3600, Secure: true, SameSite: http.SameSiteNoneMode, Partitioned: true},
+	}, tNow)
+	buf.Reset()
+	if err := partitioned.save(&buf, tNow); err != nil {
+		t.Fatal(err)
+	}
+	partitioned = newTestJar()
+	if err := partitioned.load(&buf, now); err != nil {
+		t.Fatal(err)
+	}
+	for site, want := range map[string]int{"https://a.test/": 1, "https://b.test/": 0} {
+		if got := len(partitioned.crossSiteCookies(mustParseURL("https://embed.test/"), mustParseURL(site), false, now)); got != want {
+			t.Errorf("loaded partitioned cookies for site %q: got %d; want %d", site, got, want)
+		}
+	}
+
+	// Saving the loaded jar keeps the cookies' attributes.
+	buf.Reset()
+	if err := loaded.save(&buf, now); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"Creation":"`+tNow.Format(time.RFC3339Nano)+`"`) {
+		t.Errorf("saved cookies lost their creation time:\n%s", buf.String())
+	}
+
+	for _, bad := range []string{
+		"",
+		"{}",
+		`[{"Name":"a","Domain":"host.test","Path":"/"}]`,
+		`[{"Name":"a","Domain":"Host.test","Path":"/","Persistent":true}]`,
+		savedEntry("www.host.test", `,"SameSite":"Weird"`),
+		savedEntry("www.host.test", `,"SameSite":"None"`),
+		savedEntry("www.host.test", `,"Partition":"https://a.test"`),
+		savedEntry("www.host.test", `,"Secure":true,"Partition":"https://www.a.test"`),
+		savedEntry("www.host.test", `,"Secure":true,"Partition":"ftp://a.test"`),
+		// Only host-only cookies are allowed for IP addresses and
+		// public suffixes.
+		savedEntry("10.0.0.1", ""),
+		savedEntry("test", ""),
+		savedEntry("co.uk", ""),
+		savedEntry("www.host.test", `,"Name":"__Secure-a"`),
+		savedEntry("www.host.test", `,"Name":"__Host-a","Secure":true`),
+	} {
+		if err := newTestJar().load(strings.NewReader(bad), now); err == nil {
+			t.Errorf("Load(%q) succeeded; want error", bad)
+		}
+	}
+	for _, good := range []string{
+		savedEntry("www.host.test", ""),
+		savedEntry("10.0.0.1", `,"HostOnly":true`),
+		savedEntry("co.uk", `,"HostOnly":true`),
+		savedEntry("www.host.test", `,"Secure":true,"SameSite":"None","Partition":"https://a.test"`),
+		savedEntry("www.host.test", `,"Name":"__Host-a","Secure":true,"HostOnly":true`),
+	} {
+		if err := newTestJar().load(strings.NewReader(good), now); err != nil {
+			t.Errorf("Load(%q) = %v; want success", good, err)
+		}
+	}
+}
+
+// savedEntry returns a saved jar with a single domain cookie for domain,
+// with the given JSON fields added to or replacing its attributes.
+func savedEntry(domain, fields string) string {
+	return fmt.Sprintf(`[{"Name":"a","Value":"1","Domain":%q,"Path":"/","Persistent":true,`+
+		`"Expires":"2013-01-02T00:00:00Z","Creation":"2013-01-01T00:00:00Z","LastAccess":"2013-01-01T00:00:00Z"%s}]`, domain, fields)
+}
@@ -31,6 +31,7 @@ var (
 	ExportHttp2ConfigureServer        = http2ConfigureServer
 	Export_shouldCopyHeaderOnRedirect = shouldCopyHeaderOnRedirect
 	Export_writeStatusLine            = writeStatusLine
+	ExportAcceptedEncoding            = acceptedEncoding
 )
 
 func init() {
@@ -62,6 +63,14 @@ func SetPendingDialHooks(before, after func()) {
 
 func SetTestHookServerServe(fn func(*Server, net.Listener)) { testHookServerServe = fn }
 
+// SetMuxGo110 sets whether ServeMux behaves as with GODEBUG=httpmuxgo110=1,
+// and returns a function that restores the previous setting.
+func SetMuxGo110(v bool) (restore func()) {
+	old := muxGo110
+	muxGo110 = v
+	return func() { muxGo110 = old }
+}
+
 func NewTestTimeoutHandler(handler Handler, ch <-chan time.Time) Handler {
 	ctx, cancel := context.WithCancel(context.Background())
 	go func() {
@@ -146,6 +155,17 @@ func (t *Transport) IdleConnCountForTesting(cacheKey string) int {
 	return 0
 }
 
+func (t *Transport) HostConnCountForTesting(cacheKey string) int {
+	t.connCountMu.Lock()
+	defer t.connCountMu.Unlock()
+	for k, n := range t.connPerHostCount {
+		if k.String() == cacheKey {
+			return n
+		}
+	}
+	return 0
+}
+
 func (t *Transport) IdleConnChMapSizeForTesting() int {
 	t.idleMu.Lock()
 	defer t.idleMu.Unlock()
@@ -159,7 +179,7 @@ func (t *Transport) IsIdleForTesting() bool {
 }
 
 func (t *Transport) RequestIdleConnChForTesting() {
-	t.getIdleConnCh(connectMethod{nil, "http", "example.com"})
+	t.getIdleConnCh(connectMethod{nil, "http", "example.com", false})
 }
 
 func (t *Transport) PutIdleTestConn() bool {
@@ -168,7 +188,7 @@ func (t *Transport) PutIdleTestConn() bool {
 		t:        t,
 		conn:     c,                   // dummy
 		closech:  make(chan struct{}), // so it can be closed
-		cacheKey: connectMethodKey{"", "http", "example.com"},
+		cacheKey: connectMethodKey{"", "http", "example.com", false},
 	}) == nil
 }
 
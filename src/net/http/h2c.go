@@ -0,0 +1,160 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP/2 over cleartext TCP ("h2c"), for Server.UnencryptedHTTP2 and
+// Transport.UnencryptedHTTP2. See RFC 7540, sections 3.2 to 3.4.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"golang_org/x/net/lex/httplex"
+)
+
+// h2cServer returns the HTTP/2 server used for unencrypted
+// connections, configuring it on first use.
+func (srv *Server) h2cServer() *http2Server {
+	srv.h2cOnce.Do(func() {
+		conf := &http2Server{
+			NewWriteScheduler: func() http2WriteScheduler { return http2NewPriorityWriteScheduler(nil) },
+		}
+		conf.state = &http2serverInternalState{activeConns: make(map[*http2serverConn]struct{})}
+		http2configureServer18(srv, conf)
+		srv.RegisterOnShutdown(conf.state.startGracefulShutdown)
+		srv.h2c = conf
+	})
+	return srv.h2c
+}
+
+// hasH2Preface reports whether the client opened the connection with
+// the HTTP/2 connection preface, that is, with prior knowledge that
+// the server speaks HTTP/2.
+func (c *conn) hasH2Preface() bool {
+	if d := c.server.readHeaderTimeout(); d != 0 {
+		c.rwc.SetReadDeadline(time.Now().Add(d))
+	}
+	peek := func(n int) bool {
+		c.r.setReadLimit(int64(n - c.bufr.Buffered()))
+		p, err := c.bufr.Peek(n)
+		c.r.setInfiniteReadLimit()
+		return err == nil && string(p) == http2ClientPreface[:n]
+	}
+	// Look at the request line first. Any HTTP/1 request is at least
+	// as long, but may be shorter than the whole preface.
+	return peek(len("PRI * HTTP/2.0")) && peek(len(http2ClientPreface))
+}
+
+// h2cUpgrade reports whether req asks to upgrade its connection to
+// HTTP/2 with "Upgrade: h2c" in a way that c can honor, and if so,
+// returns the payload of the client's HTTP2-Settings header.
+func (c *conn) h2cUpgrade(req *Request) (settings []byte, ok bool) {
+	if !c.server.UnencryptedHTTP2 || c.tlsState != nil {
+		return nil, false
+	}
+	// The request is answered over HTTP/2, so its body would have to
+	// be read before switching. Such requests are served over HTTP/1.1
+	// instead, which the client must accept.
+	if req.ProtoMajor != 1 || req.ProtoMinor != 1 || req.Method == "CONNECT" || req.Body != NoBody {
+		return nil, false
+	}
+	if !httplex.HeaderValuesContainsToken(req.Header["Upgrade"], "h2c") ||
+		!httplex.HeaderValuesContainsToken(req.Header["Connection"], "Upgrade") ||
+		!httplex.HeaderValuesContainsToken(req.Header["Connection"], "HTTP2-Settings") {
+		return nil, false
+	}
+	vs := req.Header["Http2-Settings"]
+	if len(vs) != 1 {
+		return nil, false
+	}
+	settings, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(vs[0], "="))
+	if err != nil || len(settings)%6 != 0 {
+		return nil, false
+	}
+	return settings, true
+}
+
+// serveH2C serves HTTP/2 on c until the connection is done.
+// For a connection upgraded from HTTP/1.1, req is the request that
+// asked for the upgrade, which is answered on stream 1, and settings
+// are the client's initial settings.
+func (c *conn) serveH2C(ctx context.Context, req *Request, settings []byte) {
+	if req != nil {
+		io.WriteString(c.bufw, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: h2c\r\n\r\n")
+		if err := c.bufw.Flush(); err != nil {
+			return
+		}
+		for _, k := range []string{"Connection", "Upgrade", "Http2-Settings", "Keep-Alive"} {
+			delete(req.Header, k)
+		}
+	}
+	buffered, _ := c.bufr.Peek(c.bufr.Buffered())
+	nc := &h2cConn{
+		Conn: c.rwc,
+		r:    io.MultiReader(bytes.NewReader(append([]byte(nil), buffered...)), c.rwc),
+	}
+	c.server.h2cServer().ServeConn(nc, &http2ServeConnOpts{
+		Context:        ctx,
+		Handler:        serverHandler{c.server},
+		BaseConfig:     c.server,
+		Settings:       settings,
+		UpgradeRequest: req,
+	})
+}
+
+// h2cConn is a connection handed to the HTTP/2 server after the
+// HTTP/1 server has already read its first bytes into a buffer.
+type h2cConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *h2cConn) Read(p []byte) (int, error) { return c.r.Read(p) }
+
+// h2cTransport is the HTTP/2 transport for "http" URLs when
+// Transport.UnencryptedHTTP2 is set. It has its own connection
+// pool, so cleartext and TLS connections to the same address are
+// never mixed up.
+type h2cTransport struct {
+	t2   *http2Transport
+	pool *http2clientConnPool
+}
+
+func newH2CTransport(t1 *Transport) *h2cTransport {
+	pool := new(http2clientConnPool)
+	t2 := &http2Transport{
+		ConnPool:  http2noDialClientConnPool{pool},
+		AllowHTTP: true,
+		t1:        t1,
+	}
+	pool.t = t2
+	return &h2cTransport{t2: t2, pool: pool}
+}
+
+// RoundTrip sends req on a pooled HTTP/2 connection. It returns
+// ErrSkipAltProtocol if there is none, so that the Transport dials
+// a new one.
+func (h *h2cTransport) RoundTrip(req *Request) (*Response, error) {
+	return http2noDialH2RoundTripper{h.t2}.RoundTrip(req)
+}
+
+// addConn starts HTTP/2 with prior knowledge on c, a new connection
+// to authority, and returns the RoundTripper for it.
+func (h *h2cTransport) addConn(authority string, c net.Conn) RoundTripper {
+	addr := http2authorityAddr("http", authority)
+	if used, err := h.pool.addConnIfNeeded(addr, h.t2, c); err != nil {
+		go c.Close()
+		return http2erringRoundTripper{err}
+	} else if !used {
+		// Another dial to the same address won the race.
+		go c.Close()
+	}
+	return h.t2
+}
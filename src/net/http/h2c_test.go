@@ -0,0 +1,229 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	. "net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"golang_org/x/net/http2/hpack"
+)
+
+func newH2CServer(enable bool, h HandlerFunc) *httptest.Server {
+	ts := httptest.NewUnstartedServer(h)
+	ts.Config.UnencryptedHTTP2 = enable
+	ts.Start()
+	return ts
+}
+
+func TestH2CPriorKnowledge(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	type connKey struct{}
+	var conns int32
+	ts := httptest.NewUnstartedServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.ProtoMajor != 2 {
+			t.Errorf("request proto = %q; want HTTP/2.0", r.Proto)
+		}
+		if r.Context().Value(connKey{}) != "h2c" {
+			t.Error("request context lacks the ConnContext value")
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s %s", r.Method, r.URL.Path, body)
+	}))
+	ts.Config.UnencryptedHTTP2 = true
+	ts.Config.ConnContext = func(ctx context.Context, c net.Conn) context.Context {
+		atomic.AddInt32(&conns, 1)
+		return context.WithValue(ctx, connKey{}, "h2c")
+	}
+	ts.Start()
+	defer ts.Close()
+
+	tr := &Transport{UnencryptedHTTP2: true}
+	defer tr.CloseIdleConnections()
+	c := &Client{Transport: tr}
+	for i, body := range []string{"", "one", "two"} {
+		method := "GET"
+		if body != "" {
+			method = "POST"
+		}
+		req, _ := NewRequest(method, fmt.Sprintf("%s/%d", ts.URL, i), strings.NewReader(body))
+		res, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.ProtoMajor != 2 {
+			t.Errorf("response proto = %q; want HTTP/2.0", res.Proto)
+		}
+		if want := fmt.Sprintf("%s /%d %s", method, i, body); string(got) != want {
+			t.Errorf("response body = %q; want %q", got, want)
+		}
+	}
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Errorf("server saw %d connections; want 1", n)
+	}
+}
+
+// h2cUpgradeRequest is an HTTP/1.1 request asking to upgrade to h2c,
+// with empty client settings.
+const h2cUpgradeRequest = "GET /upgrade?x=1 HTTP/1.1\r\n" +
+	"Host: example.com\r\n" +
+	"Connection: Upgrade, HTTP2-Settings\r\n" +
+	"Upgrade: h2c\r\n" +
+	"HTTP2-Settings: \r\n" +
+	"X-Test: yes\r\n" +
+	"\r\n"
+
+func TestH2CUpgrade(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	ts := newH2CServer(true, func(w ResponseWriter, r *Request) {
+		for _, k := range []string{"Connection", "Upgrade", "Http2-Settings"} {
+			if v, ok := r.Header[k]; ok {
+				t.Errorf("handler saw %s header %q", k, v)
+			}
+		}
+		fmt.Fprintf(w, "%s %s %s %s %s", r.Proto, r.Method, r.Host, r.RequestURI, r.Header.Get("X-Test"))
+	})
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, h2cUpgradeRequest)
+	br := bufio.NewReader(conn)
+	res, err := ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != StatusSwitchingProtocols || res.Header.Get("Upgrade") != "h2c" {
+		t.Fatalf("response = %v %v; want 101 with Upgrade: h2c", res.Status, res.Header)
+	}
+
+	// Start HTTP/2: the preface, and an empty SETTINGS frame.
+	io.WriteString(conn, "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n")
+	conn.Write([]byte{0, 0, 0, 4, 0, 0, 0, 0, 0})
+
+	// The response to the upgrade request arrives on stream 1.
+	var status string
+	var body bytes.Buffer
+	dec := hpack.NewDecoder(4096, func(f hpack.HeaderField) {
+		if f.Name == ":status" {
+			status = f.Value
+		}
+	})
+	for {
+		var hdr [9]byte
+		if _, err := io.ReadFull(br, hdr[:]); err != nil {
+			t.Fatalf("reading frame: %v", err)
+		}
+		length := int(hdr[0])<<16 | int(hdr[1])<<8 | int(hdr[2])
+		typ, flags := hdr[3], hdr[4]
+		stream := binary.BigEndian.Uint32(hdr[5:]) & (1<<31 - 1)
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			t.Fatalf("reading frame payload: %v", err)
+		}
+		if stream != 1 {
+			continue
+		}
+		switch typ {
+		case 0x1: // HEADERS
+			if _, err := dec.Write(payload); err != nil {
+				t.Fatal(err)
+			}
+		case 0x0: // DATA
+			body.Write(payload)
+		case 0x3: // RST_STREAM
+			t.Fatal("stream 1 was reset")
+		}
+		if flags&0x1 != 0 { // END_STREAM
+			break
+		}
+	}
+	if status != "200" {
+		t.Errorf(":status = %q; want 200", status)
+	}
+	if got, want := body.String(), "HTTP/2.0 GET example.com /upgrade?x=1 yes"; got != want {
+		t.Errorf("body = %q; want %q", got, want)
+	}
+}
+
+func TestH2CUpgradeIgnored(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	tests := []struct {
+		name    string
+		enabled bool
+		req     string
+	}{
+		{"disabled", false, h2cUpgradeRequest},
+		{"with body", true, strings.Replace(h2cUpgradeRequest, "X-Test: yes\r\n", "Content-Length: 5\r\n", 1) + "hello"},
+		{"bad settings", true, strings.Replace(h2cUpgradeRequest, "HTTP2-Settings: ", "HTTP2-Settings: AAA", 1)},
+	}
+	for _, tt := range tests {
+		ts := newH2CServer(tt.enabled, func(w ResponseWriter, r *Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			fmt.Fprintf(w, "%s %s", r.Proto, body)
+		})
+		conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(conn, tt.req)
+		res, err := ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, res.ContentLength))
+		if res.StatusCode != 200 || !strings.HasPrefix(string(body), "HTTP/1.1") {
+			t.Errorf("%s: got %v %q; want a 200 response over HTTP/1.1", tt.name, res.Status, body)
+		}
+		conn.Close()
+		ts.Close()
+	}
+}
+
+func TestH2CTransportUsesHTTP1ForUpgrades(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	ts := newH2CServer(true, func(w ResponseWriter, r *Request) {
+		fmt.Fprint(w, r.Proto)
+	})
+	defer ts.Close()
+
+	tr := &Transport{UnencryptedHTTP2: true}
+	defer tr.CloseIdleConnections()
+	req, _ := NewRequest("GET", ts.URL, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "HTTP/1.1" {
+		t.Errorf("server saw %q; want HTTP/1.1", body)
+	}
+}
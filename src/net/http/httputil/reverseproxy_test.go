@@ -14,6 +14,7 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -147,15 +148,20 @@ func TestReverseProxy(t *testing.T) {
 func TestReverseProxyStripHeadersPresentInConnection(t *testing.T) {
 	const fakeConnectionToken = "X-Fake-Connection-Token"
 	const backendResponse = "I am the backend"
+
+	// someConnHeader is some arbitrary header to be declared as a hop-by-hop header
+	// in the Request's Connection header.
+	const someConnHeader = "X-Some-Conn-Header"
+
 	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if c := r.Header.Get(fakeConnectionToken); c != "" {
 			t.Errorf("handler got header %q = %q; want empty", fakeConnectionToken, c)
 		}
-		if c := r.Header.Get("Upgrade"); c != "" {
-			t.Errorf("handler got header %q = %q; want empty", "Upgrade", c)
+		if c := r.Header.Get(someConnHeader); c != "" {
+			t.Errorf("handler got header %q = %q; want empty", someConnHeader, c)
 		}
-		w.Header().Set("Connection", "Upgrade, "+fakeConnectionToken)
-		w.Header().Set("Upgrade", "should be deleted")
+		w.Header().Set("Connection", someConnHeader+", "+fakeConnectionToken)
+		w.Header().Set(someConnHeader, "should be deleted")
 		w.Header().Set(fakeConnectionToken, "should be deleted")
 		io.WriteString(w, backendResponse)
 	}))
@@ -167,15 +173,15 @@ func TestReverseProxyStripHeadersPresentInConnection(t *testing.T) {
 	proxyHandler := NewSingleHostReverseProxy(backendURL)
 	frontend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		proxyHandler.ServeHTTP(w, r)
-		if c := r.Header.Get("Upgrade"); c != "original value" {
-			t.Errorf("handler modified header %q = %q; want %q", "Upgrade", c, "original value")
+		if c := r.Header.Get(someConnHeader); c != "original value" {
+			t.Errorf("handler modified header %q = %q; want %q", someConnHeader, c, "original value")
 		}
 	}))
 	defer frontend.Close()
 
 	getReq, _ := http.NewRequest("GET", frontend.URL, nil)
-	getReq.Header.Set("Connection", "Upgrade, "+fakeConnectionToken)
-	getReq.Header.Set("Upgrade", "original value")
+	getReq.Header.Set("Connection", someConnHeader+", "+fakeConnectionToken)
+	getReq.Header.Set(someConnHeader, "original value")
 	getReq.Header.Set(fakeConnectionToken, "should be deleted")
 	res, err := frontend.Client().Do(getReq)
 	if err != nil {
@@ -189,8 +195,8 @@ func TestReverseProxyStripHeadersPresentInConnection(t *testing.T) {
 	if got, want := string(bodyBytes), backendResponse; got != want {
 		t.Errorf("got body %q; want %q", got, want)
 	}
-	if c := res.Header.Get("Upgrade"); c != "" {
-		t.Errorf("handler got header %q = %q; want empty", "Upgrade", c)
+	if c := res.Header.Get(someConnHeader); c != "" {
+		t.Errorf("handler got header %q = %q; want empty", someConnHeader, c)
 	}
 	if c := res.Header.Get(fakeConnectionToken); c != "" {
 		t.Errorf("handler got header %q = %q; want empty", fakeConnectionToken, c)
@@ -813,3 +819,291 @@ func (cc *checkCloser) Close() error {
 func (cc *checkCloser) Read(b []byte) (int, error) {
 	return len(b), nil
 }
+
+func TestReverseProxyErrorHandler(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hangup" {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		}
+	}))
+	defer backend.Close()
+	backendURL, _ := url.Parse(backend.URL)
+	modErr := errors.New("ModifyResponse error")
+	rproxy := NewSingleHostReverseProxy(backendURL)
+	rproxy.ModifyResponse = func(res *http.Response) error {
+		if res.Request.URL.Path == "/modify" {
+			return modErr
+		}
+		return nil
+	}
+	var gotErr error
+	rproxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		gotErr = err
+		w.WriteHeader(http.StatusTeapot)
+	}
+	frontend := httptest.NewServer(rproxy)
+	defer frontend.Close()
+
+	tests := []struct {
+		path     string
+		wantCode int
+		wantErr  bool
+	}{
+		{"/ok", http.StatusOK, false},
+		{"/hangup", http.StatusTeapot, true},
+		{"/modify", http.StatusTeapot, true},
+	}
+	for _, tt := range tests {
+		gotErr = nil
+		res, err := frontend.Client().Get(frontend.URL + tt.path)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.path, err)
+		}
+		res.Body.Close()
+		if res.StatusCode != tt.wantCode {
+			t.Errorf("%s: status = %d; want %d", tt.path, res.StatusCode, tt.wantCode)
+		}
+		if (gotErr != nil) != tt.wantErr {
+			t.Errorf("%s: ErrorHandler error = %v; want error: %v", tt.path, gotErr, tt.wantErr)
+		}
+	}
+	if gotErr != modErr {
+		t.Errorf("ErrorHandler got %v; want the ModifyResponse error", gotErr)
+	}
+}
+
+func TestReverseProxyDirectorAndRewrite(t *testing.T) {
+	rproxy := &ReverseProxy{
+		Director: func(*http.Request) {},
+		Rewrite:  func(*ProxyRequest) {},
+		Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+			t.Error("request sent with both Director and Rewrite set")
+			return nil, errors.New("unreachable")
+		}),
+		ErrorLog: log.New(ioutil.Discard, "", 0),
+	}
+	rec := httptest.NewRecorder()
+	rproxy.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status = %d; want %d", rec.Code, http.StatusBadGateway)
+	}
+}
+
+func TestReverseProxyRewrite(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, k := range []string{"X-Forwarded-For", "X-Forwarded-Host", "X-Forwarded-Proto", "Forwarded"} {
+			w.Header()["Got-"+k] = r.Header[k]
+		}
+		w.Header().Set("Got-Host", r.Host)
+		w.Header().Set("Got-Path", r.URL.Path)
+		w.Header().Set("Got-Fake-Hop", r.Header.Get(fakeHopHeader))
+	}))
+	defer backend.Close()
+	backendURL, _ := url.Parse(backend.URL + "/base")
+	rproxy := &ReverseProxy{
+		Rewrite: func(r *ProxyRequest) {
+			r.SetURL(backendURL)
+			r.SetXForwarded()
+			// Headers added by Rewrite are not removed as hop-by-hop headers.
+			r.Out.Header.Set(fakeHopHeader, "kept")
+		},
+	}
+	frontend := httptest.NewServer(rproxy)
+	defer frontend.Close()
+
+	req, _ := http.NewRequest("GET", frontend.URL+"/dir", nil)
+	req.Host = "example.com"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("Forwarded", "for=1.2.3.4")
+	res, err := frontend.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	want := map[string]string{
+		"Got-X-Forwarded-For":   "127.0.0.1",
+		"Got-X-Forwarded-Host":  "example.com",
+		"Got-X-Forwarded-Proto": "http",
+		"Got-Forwarded":         "",
+		"Got-Host":              backendURL.Host,
+		"Got-Path":              "/base/dir",
+		"Got-Fake-Hop":          "kept",
+	}
+	for k, v := range want {
+		if got := res.Header.Get(k); got != v {
+			t.Errorf("backend %s = %q; want %q", strings.TrimPrefix(k, "Got-"), got, v)
+		}
+	}
+}
+
+func TestReverseProxyTrailersTE(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Header().Set("Got-Te", r.Header.Get("Te"))
+		io.WriteString(w, "body")
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	defer backend.Close()
+	backendURL, _ := url.Parse(backend.URL)
+	frontend := httptest.NewServer(NewSingleHostReverseProxy(backendURL))
+	defer frontend.Close()
+
+	req, _ := http.NewRequest("GET", frontend.URL, nil)
+	req.Header.Set("Te", "gzip, trailers")
+	res, err := frontend.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if got := res.Header.Get("Got-Te"); got != "trailers" {
+		t.Errorf("backend got Te %q; want %q", got, "trailers")
+	}
+	if got := res.Trailer.Get("Grpc-Status"); got != "0" {
+		t.Errorf("Grpc-Status trailer = %q; want %q", got, "0")
+	}
+}
+
+func TestReverseProxyFlushImmediately(t *testing.T) {
+	for _, tt := range []struct {
+		name          string
+		contentType   string
+		flushInterval time.Duration
+	}{
+		{"negative FlushInterval", "text/plain", -1},
+		{"event stream", "text/event-stream; charset=utf-8", 0},
+	} {
+		release := make(chan bool)
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", tt.contentType)
+			io.WriteString(w, "data: first\n\n")
+			w.(http.Flusher).Flush()
+			<-release
+			io.WriteString(w, "data: second\n\n")
+		}))
+		backendURL, _ := url.Parse(backend.URL)
+		rproxy := NewSingleHostReverseProxy(backendURL)
+		rproxy.FlushInterval = tt.flushInterval
+		frontend := httptest.NewServer(rproxy)
+
+		res, err := frontend.Client().Get(frontend.URL)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		// The first event arrives while the backend is still blocked.
+		br := bufio.NewReader(res.Body)
+		line, err := br.ReadString('\n')
+		if err != nil || line != "data: first\n" {
+			t.Errorf("%s: first line = %q, %v; want %q", tt.name, line, err, "data: first\n")
+		}
+		close(release)
+		rest, _ := ioutil.ReadAll(br)
+		if string(rest) != "\ndata: second\n\n" {
+			t.Errorf("%s: rest of body = %q", tt.name, rest)
+		}
+		res.Body.Close()
+		frontend.Close()
+		backend.Close()
+	}
+}
+
+func TestReverseProxyWebSocket(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" || r.Header.Get("Connection") != "Upgrade" {
+			t.Errorf("backend got Upgrade %q, Connection %q; want websocket, Upgrade",
+				r.Header.Get("Upgrade"), r.Header.Get("Connection"))
+			http.Error(w, "not an upgrade", http.StatusBadRequest)
+			return
+		}
+		c, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer c.Close()
+		io.WriteString(c, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: WebSocket\r\n\r\n")
+		for {
+			line, err := brw.ReadString('\n')
+			if err != nil {
+				return
+			}
+			io.WriteString(c, "backend got "+line)
+		}
+	}))
+	defer backend.Close()
+	backendURL, _ := url.Parse(backend.URL)
+	rproxy := NewSingleHostReverseProxy(backendURL)
+	rproxy.ErrorLog = log.New(ioutil.Discard, "", 0) // quiet for tests
+	frontend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Header", "X-Value")
+		rproxy.ServeHTTP(w, r)
+	}))
+	defer frontend.Close()
+
+	conn, err := net.Dial("tcp", frontend.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: example.com\r\nConnection: keep-alive, Upgrade\r\nUpgrade: websocket\r\n\r\n")
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %v; want 101", res.Status)
+	}
+	if got := res.Header.Get("Upgrade"); got != "WebSocket" {
+		t.Errorf("Upgrade header = %q; want WebSocket", got)
+	}
+	if got := res.Header.Get("X-Header"); got != "X-Value" {
+		t.Errorf("X-Header = %q; want X-Value", got)
+	}
+	for _, msg := range []string{"hello\n", "world\n"} {
+		io.WriteString(conn, msg)
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "backend got " + msg; line != want {
+			t.Errorf("got %q; want %q", line, want)
+		}
+	}
+}
+
+func TestReverseProxyUpgradeMismatch(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, _, _ := w.(http.Hijacker).Hijack()
+		defer c.Close()
+		io.WriteString(c, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: other\r\n\r\n")
+	}))
+	defer backend.Close()
+	backendURL, _ := url.Parse(backend.URL)
+	rproxy := NewSingleHostReverseProxy(backendURL)
+	var gotErr error
+	rproxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		gotErr = err
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	frontend := httptest.NewServer(rproxy)
+	defer frontend.Close()
+
+	req, _ := http.NewRequest("GET", frontend.URL, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	res, err := frontend.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadGateway {
+		t.Errorf("status = %v; want 502", res.Status)
+	}
+	if gotErr == nil || !strings.Contains(gotErr.Error(), `"other"`) {
+		t.Errorf("ErrorHandler got %v; want a protocol mismatch error", gotErr)
+	}
+}
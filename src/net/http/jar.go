@@ -14,6 +14,13 @@ import (
 // goroutines.
 //
 // The net/http/cookiejar package provides a CookieJar implementation.
+//
+// For the redirects it follows, a Client uses the CrossSiteCookies and
+// SetCrossSiteCookies methods of the jar instead of Cookies and
+// SetCookies if the jar has them, like the jar in net/http/cookiejar,
+// so that the jar can enforce the SameSite attribute of cookies. The
+// site that initiated a redirect is the URL of the Client's original
+// request, and a redirect is a navigation if its method is GET or HEAD.
 type CookieJar interface {
 	// SetCookies handles the receipt of the cookies in a reply for the
 	// given URL.  It may or may not choose to save the cookies, depending
@@ -25,3 +32,46 @@ type CookieJar interface {
 	// restrictions such as in RFC 6265.
 	Cookies(u *url.URL) []*Cookie
 }
+
+// crossSiteCookieJar is a CookieJar that enforces the SameSite
+// attribute of cookies.
+type crossSiteCookieJar interface {
+	CrossSiteCookies(u, site *url.URL, navigation bool) []*Cookie
+	SetCrossSiteCookies(u, site *url.URL, navigation bool, cookies []*Cookie)
+}
+
+// cookieSite returns the site that initiated req, which is the URL of
+// the first request in the chain of redirects that led to req, and
+// whether req is a navigation. It returns a nil site if req is not a
+// redirect.
+func cookieSite(req *Request) (site *url.URL, navigation bool) {
+	first := req
+	for first.Response != nil && first.Response.Request != nil {
+		first = first.Response.Request
+	}
+	if first == req {
+		return nil, false
+	}
+	return first.URL, req.Method == "" || req.Method == "GET" || req.Method == "HEAD"
+}
+
+// cookies returns the cookies of c.Jar for req.
+func (c *Client) cookies(req *Request) []*Cookie {
+	if jar, ok := c.Jar.(crossSiteCookieJar); ok {
+		if site, navigation := cookieSite(req); site != nil {
+			return jar.CrossSiteCookies(req.URL, site, navigation)
+		}
+	}
+	return c.Jar.Cookies(req.URL)
+}
+
+// setCookies stores the cookies of the response to req in c.Jar.
+func (c *Client) setCookies(req *Request, cookies []*Cookie) {
+	if jar, ok := c.Jar.(crossSiteCookieJar); ok {
+		if site, navigation := cookieSite(req); site != nil {
+			jar.SetCrossSiteCookies(req.URL, site, navigation, cookies)
+			return
+		}
+	}
+	c.Jar.SetCookies(req.URL, cookies)
+}
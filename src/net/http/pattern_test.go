@@ -0,0 +1,364 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePattern(t *testing.T) {
+	lit := func(name string) segment {
+		return segment{s: name}
+	}
+	wild := func(name string) segment {
+		return segment{s: name, wild: true}
+	}
+	multi := func(name string) segment {
+		s := wild(name)
+		s.multi = true
+		return s
+	}
+
+	for _, test := range []struct {
+		in   string
+		want pattern
+	}{
+		{"/", pattern{segments: []segment{multi("")}}},
+		{"/a", pattern{segments: []segment{lit("a")}}},
+		{"/a/", pattern{segments: []segment{lit("a"), multi("")}}},
+		{"/path/to/something", pattern{segments: []segment{lit("path"), lit("to"), lit("something")}}},
+		{"/{w1}/lit/{w2}", pattern{segments: []segment{wild("w1"), lit("lit"), wild("w2")}}},
+		{"/{w1}/lit/{w2}/", pattern{segments: []segment{wild("w1"), lit("lit"), wild("w2"), multi("")}}},
+		{"example.com/", pattern{host: "example.com", segments: []segment{multi("")}}},
+		{"GET /", pattern{method: "GET", segments: []segment{multi("")}}},
+		{"POST example.com/foo/{w}", pattern{method: "POST", host: "example.com", segments: []segment{lit("foo"), wild("w")}}},
+		{"/{$}", pattern{segments: []segment{lit("/")}}},
+		{"DELETE example.com/a/{foo12}/{$}", pattern{method: "DELETE", host: "example.com", segments: []segment{lit("a"), wild("foo12"), lit("/")}}},
+		{"/foo/{$}", pattern{segments: []segment{lit("foo"), lit("/")}}},
+		{"/{a}/foo/{rest...}", pattern{segments: []segment{wild("a"), lit("foo"), multi("rest")}}},
+		{"//", pattern{segments: []segment{lit(""), multi("")}}},
+		{"/foo///./../bar", pattern{segments: []segment{lit("foo"), lit(""), lit(""), lit("."), lit(".."), lit("bar")}}},
+		{"a.com/foo//", pattern{host: "a.com", segments: []segment{lit("foo"), lit(""), multi("")}}},
+		{"/%61%62/%7b/%", pattern{segments: []segment{lit("ab"), lit("{"), lit("%")}}},
+		{"GET\t  /", pattern{method: "GET", segments: []segment{multi("")}}},
+	} {
+		got := mustParsePattern(t, test.in)
+		if !reflect.DeepEqual(got.segments, test.want.segments) ||
+			got.method != test.want.method || got.host != test.want.host {
+			t.Errorf("%q:\ngot  %#v\nwant %#v", test.in, got, &test.want)
+		}
+	}
+}
+
+func TestParsePatternError(t *testing.T) {
+	for _, test := range []struct {
+		in       string
+		contains string
+	}{
+		{"", "empty pattern"},
+		{"A=B /", "at offset 0: invalid method"},
+		{"a.com", "at offset 0: host/path missing /"},
+		{"/{w}x", "at offset 1: bad wildcard segment"},
+		{"/x{w}", "at offset 1: bad wildcard segment"},
+		{"/{wx", "at offset 1: bad wildcard segment"},
+		{"/a/{/}/c", "at offset 3: bad wildcard segment"},
+		{"/a/{%61}/c", "at offset 3: bad wildcard name"},
+		{"/{a$}", "at offset 1: bad wildcard name"},
+		{"/{}", "at offset 1: empty wildcard"},
+		{"POST a.com/x/{}/y", "at offset 13: empty wildcard"},
+		{"/{...}", "at offset 1: empty wildcard"},
+		{"/{$...}", "at offset 1: bad wildcard"},
+		{"/{$}/", "at offset 1: {$} not at end"},
+		{"/{$}/x", "at offset 1: {$} not at end"},
+		{"/abc/{$}/x", "at offset 5: {$} not at end"},
+		{"/{a...}/", "at offset 1: {...} wildcard not at end"},
+		{"/{a...}/x", "at offset 1: {...} wildcard not at end"},
+		{"{a}/b", "at offset 0: host contains '{' (missing initial '/'?)"},
+		{"/a/{x}/b/{x...}", "at offset 9: duplicate wildcard name"},
+		{"GET //", "at offset 4: non-CONNECT pattern with unclean path"},
+	} {
+		_, err := parsePattern(test.in)
+		if err == nil || !strings.Contains(err.Error(), test.contains) {
+			t.Errorf("%q:\ngot %v, want error containing %q", test.in, err, test.contains)
+		}
+	}
+}
+
+func TestIsValidWildcardName(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want bool
+	}{
+		{"", false},
+		{"a", true},
+		{"abc", true},
+		{"a1", true},
+		{"a_b_c", true},
+		{"_", true},
+		{"1", false},
+		{"a b", false},
+		{"a.b", false},
+		{"ü", true},
+	} {
+		if got := isValidWildcardName(test.in); got != test.want {
+			t.Errorf("%q: got %t, want %t", test.in, got, test.want)
+		}
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	for _, test := range []struct {
+		pat     string
+		path    string
+		want    []string
+		wantOK  bool
+		isExact bool
+	}{
+		{"/", "/", nil, true, true},
+		{"/", "/a", nil, true, false},
+		{"/{$}", "/", nil, true, true},
+		{"/{$}", "/a", nil, false, false},
+		{"/a", "/a", nil, true, true},
+		{"/a", "/a/", nil, false, false},
+		{"/a/", "/a", nil, false, false},
+		{"/a/", "/a/", nil, true, true},
+		{"/a/", "/a/b/c", nil, true, false},
+		{"/a/{x}", "/a/b", []string{"b"}, true, true},
+		{"/a/{x}", "/a/b/c", nil, false, false},
+		{"/a/{x}/{$}", "/a/b/", []string{"b"}, true, true},
+		{"/a/{x...}", "/a/b/c", []string{"b/c"}, true, false},
+		{"/a/{x...}", "/a/", []string{""}, true, true},
+		{"/{x}/{y}", "/a%2Fb/c", []string{"a/b", "c"}, true, true},
+		{"/a%2fb/", "/a%2Fb/c", nil, true, false},
+		{"/a/b/", "/a%2Fb/c", nil, false, false},
+	} {
+		pat := mustParsePattern(t, test.pat)
+		got, ok := pat.matchPath(test.path)
+		if ok != test.wantOK || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q.matchPath(%q) = %q, %t; want %q, %t", test.pat, test.path, got, ok, test.want, test.wantOK)
+		}
+		if ok {
+			if got := pat.exactMatch(test.path); got != test.isExact {
+				t.Errorf("%q.exactMatch(%q) = %t, want %t", test.pat, test.path, got, test.isExact)
+			}
+		}
+	}
+}
+
+func TestComparePaths(t *testing.T) {
+	for _, test := range []struct {
+		p1, p2 string
+		want   relationship
+	}{
+		// A non-final pattern segment can have one of two values: literal or
+		// single wildcard. A final pattern segment can have one of 5: empty
+		// (trailing slash), literal, dollar, single wildcard, or multi
+		// wildcard. Trailing slash and multi wildcard are the same.
+
+		// A literal should be more specific than anything it overlaps, except itself.
+		{"/a", "/a", equivalent},
+		{"/a", "/b", disjoint},
+		{"/a", "/", moreSpecific},
+		{"/a", "/{$}", disjoint},
+		{"/a", "/{x}", moreSpecific},
+		{"/a", "/{x...}", moreSpecific},
+
+		// Adding a segment doesn't change that.
+		{"/b/a", "/b/a", equivalent},
+		{"/b/a", "/b/b", disjoint},
+		{"/b/a", "/b/", moreSpecific},
+		{"/b/a", "/b/{$}", disjoint},
+		{"/b/a", "/b/{x}", moreSpecific},
+		{"/b/a", "/b/{x...}", moreSpecific},
+		{"/{z}/a", "/{z}/a", equivalent},
+		{"/{z}/a", "/{z}/b", disjoint},
+		{"/{z}/a", "/{z}/", moreSpecific},
+		{"/{z}/a", "/{z}/{$}", disjoint},
+		{"/{z}/a", "/{z}/{x}", moreSpecific},
+		{"/{z}/a", "/{z}/{x...}", moreSpecific},
+
+		// Single wildcard on left.
+		{"/{z}", "/a", moreGeneral},
+		{"/{z}", "/a/b", disjoint},
+		{"/{z}", "/{$}", disjoint},
+		{"/{z}", "/{x}", equivalent},
+		{"/", "/{z}", moreGeneral},
+		{"/{x...}", "/{z}", moreGeneral},
+		{"/b/{z}", "/b/a", moreGeneral},
+		{"/b/{z}", "/b/{x}", equivalent},
+		{"/b/{z}", "/b/", moreSpecific},
+
+		// Trailing slash and multi wildcard on left.
+		{"/", "/", equivalent},
+		{"/", "/{x...}", equivalent},
+		{"/", "/{$}", moreGeneral},
+		{"/b/", "/b/{x...}", equivalent},
+		{"/b/", "/b/{$}", moreGeneral},
+
+		// Dollar on left.
+		{"/{$}", "/a", disjoint},
+		{"/{$}", "/{x}", disjoint},
+		{"/{$}", "/{$}", equivalent},
+		{"/{$}", "/", moreSpecific},
+
+		// Patterns that overlap.
+		{"/{x}/a", "/a/{y}", overlaps},
+		{"/a/{x...}", "/{y}/b", overlaps},
+		{"/a/{x}", "/{y}/b/c", disjoint},
+	} {
+		got := mustParsePattern(t, test.p1).comparePaths(mustParsePattern(t, test.p2))
+		if got != test.want {
+			t.Errorf("%s vs %s: got %s, want %s", test.p1, test.p2, got, test.want)
+		}
+	}
+}
+
+func TestConflictsWith(t *testing.T) {
+	for _, test := range []struct {
+		p1, p2 string
+		want   bool
+	}{
+		{"/a", "/a", true},
+		{"/a", "/ab", false},
+		{"/a/b/cd", "/a/b/cd", true},
+		{"/a/b/cd", "/a/b/c", false},
+		{"/a/b/c", "/a/c/c", false},
+		{"/{x}", "/{y}", true},
+		{"/{x}", "/a", false}, // more specific
+		{"/{x}/{y}", "/{x}/a", false},
+		{"/{x}/{y}", "/{x}/a/b", false},
+		{"/{x}", "/a/{y}", false},
+		{"/{x}/{y}", "/{x}/a/", false},
+		{"/{x}", "/a/{y...}", false},           // more specific
+		{"/{x}/a/{y}", "/{x}/a/{y...}", false}, // more specific
+		{"/{x}/{y}", "/{x}/a/{$}", false},      // more specific
+		{"/{x}/{y}/{$}", "/{x}/a/{$}", false},
+		{"/a/{x}", "/{x}/b", true},
+		{"/", "GET /", false},
+		{"/", "GET /foo", false},
+		{"GET /", "GET /foo", false},
+		{"GET /", "/foo", true},
+		{"GET /foo", "HEAD /", true},
+		{"GET /", "HEAD /", false},
+		{"GET /", "POST /", false},
+		{"/", "a.com/", false},
+		{"/a", "a.com/", false},
+		{"a.com/", "b.com/", false},
+	} {
+		pat1 := mustParsePattern(t, test.p1)
+		pat2 := mustParsePattern(t, test.p2)
+		got := pat1.conflictsWith(pat2)
+		if got != test.want {
+			t.Errorf("%q.conflictsWith(%q) = %t, want %t", test.p1, test.p2, got, test.want)
+		}
+		// conflictsWith should be commutative.
+		got = pat2.conflictsWith(pat1)
+		if got != test.want {
+			t.Errorf("%q.conflictsWith(%q) = %t, want %t", test.p2, test.p1, got, test.want)
+		}
+	}
+}
+
+func TestDescribeConflict(t *testing.T) {
+	for _, test := range []struct {
+		p1, p2 string
+		want   string
+	}{
+		{"/a/{x}", "/a/{y}", "the same requests"},
+		{"/", "/{m...}", "the same requests"},
+		{"/a/{x}", "/{y}/b", "both match some paths"},
+		{"/a", "GET /{x}", "matches more methods than GET /{x}, but has a more specific path pattern"},
+		{"GET /{x}", "/a", "matches fewer methods than /a, but has a more general path pattern"},
+	} {
+		got := describeConflict(mustParsePattern(t, test.p1), mustParsePattern(t, test.p2))
+		if !strings.Contains(got, test.want) {
+			t.Errorf("%s vs. %s:\ngot:\n%s\nwhich does not contain %q",
+				test.p1, test.p2, got, test.want)
+		}
+	}
+}
+
+func TestCommonPath(t *testing.T) {
+	for _, test := range []struct {
+		p1, p2 string
+		want   string
+	}{
+		{"/a/{x}", "/{x}/a", "/a/a"},
+		{"/a/{z}/", "/{z}/a/", "/a/a/"},
+		{"/a/{z}/{m...}", "/{z}/a/", "/a/a/"},
+		{"/{z}/{$}", "/a/", "/a/"},
+		{"/{z}/{$}", "/a/{x...}", "/a/"},
+		{"/a/{z}/{$}", "/{z}/a/", "/a/a/"},
+		{"/a/{x}/b/{y...}", "/{x}/c/{y...}", "/a/c/b/"},
+		{"/a/{x}/b/", "/{x}/c/{y...}", "/a/c/b/"},
+		{"/a/{x}/b/{$}", "/{x}/c/{y...}", "/a/c/b/"},
+		{"/a/{z}/{x...}", "/{z}/b/{y...}", "/a/b/"},
+	} {
+		pat1 := mustParsePattern(t, test.p1)
+		pat2 := mustParsePattern(t, test.p2)
+		if pat1.comparePaths(pat2) != overlaps {
+			t.Fatalf("%s does not overlap %s", test.p1, test.p2)
+		}
+		got := commonPath(pat1, pat2)
+		if got != test.want {
+			t.Errorf("%s vs. %s: got %q, want %q", test.p1, test.p2, got, test.want)
+		}
+	}
+}
+
+func TestDifferencePath(t *testing.T) {
+	for _, test := range []struct {
+		p1, p2 string
+		want   string
+	}{
+		{"/a/{x}", "/{x}/a", "/a/x"},
+		{"/{x}/a", "/a/{x}", "/x/a"},
+		{"/a/{z}/", "/{z}/a/", "/a/z/"},
+		{"/{z}/a/", "/a/{z}/", "/z/a/"},
+		{"/{a}/a/", "/a/{z}/", "/ax/a/"},
+		{"/a/{z}/{x...}", "/{z}/b/{y...}", "/a/z/"},
+		{"/{z}/b/{y...}", "/a/{z}/{x...}", "/z/b/"},
+		{"/a/b/", "/a/b/c", "/a/b/"},
+		{"/a/b/{x...}", "/a/b/c", "/a/b/"},
+		{"/a/b/{x...}", "/a/b/c/d", "/a/b/"},
+		{"/a/b/{x...}", "/a/b/c/d/", "/a/b/"},
+		{"/a/{z}/{m...}", "/{z}/a/", "/a/z/"},
+		{"/{z}/a/", "/a/{z}/{m...}", "/z/a/"},
+		{"/{z}/{$}", "/a/", "/z/"},
+		{"/a/", "/{z}/{$}", "/a/x"},
+		{"/{z}/{$}", "/a/{x...}", "/z/"},
+		{"/a/{foo...}", "/{z}/{$}", "/a/foo"},
+		{"/a/{z}/{$}", "/{z}/a/", "/a/z/"},
+		{"/{z}/a/", "/a/{z}/{$}", "/z/a/x"},
+		{"/a/{x}/b/{y...}", "/{x}/c/{y...}", "/a/x/b/"},
+		{"/{x}/c/{y...}", "/a/{x}/b/{y...}", "/x/c/"},
+		{"/a/{c}/b/", "/{x}/c/{y...}", "/a/cx/b/"},
+		{"/{x}/c/{y...}", "/a/{c}/b/", "/x/c/"},
+		{"/a/{x}/b/{$}", "/{x}/c/{y...}", "/a/x/b/"},
+		{"/{x}/c/{y...}", "/a/{x}/b/{$}", "/x/c/"},
+	} {
+		pat1 := mustParsePattern(t, test.p1)
+		pat2 := mustParsePattern(t, test.p2)
+		rel := pat1.comparePaths(pat2)
+		if rel != overlaps && rel != moreGeneral {
+			t.Fatalf("%s vs. %s are %s, need overlaps or moreGeneral", pat1, pat2, rel)
+		}
+		got := differencePath(pat1, pat2)
+		if got != test.want {
+			t.Errorf("%s vs. %s: got %q, want %q", test.p1, test.p2, got, test.want)
+		}
+	}
+}
+
+func mustParsePattern(t *testing.T, s string) *pattern {
+	t.Helper()
+	p, err := parsePattern(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
@@ -17,6 +17,8 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+
+	"golang_org/x/net/lex/httplex"
 )
 
 var respExcludeHeader = map[string]bool{
@@ -63,6 +65,10 @@ type Response struct {
 	//
 	// The Body is automatically dechunked if the server replied
 	// with a "chunked" Transfer-Encoding.
+	//
+	// On a successful "101 Switching Protocols" response, as used
+	// by WebSockets, the Body also implements io.Writer, and writes
+	// to it go directly to the underlying connection.
 	Body io.ReadCloser
 
 	// ContentLength records the length of the associated content. The
@@ -333,3 +339,23 @@ func (r *Response) closeBody() {
 		r.Body.Close()
 	}
 }
+
+// bodyIsWritable reports whether the Body supports writing. The
+// Transport returns Writable bodies for 101 Switching Protocols
+// responses.
+// The Transport uses this method to determine whether a persistent
+// connection is done being managed from its perspective. Once we
+// return a writable response body to a user, the net/http package is
+// done managing that connection.
+func (r *Response) bodyIsWritable() bool {
+	_, ok := r.Body.(io.Writer)
+	return ok
+}
+
+// isProtocolSwitch reports whether r is a response to a successful
+// protocol upgrade.
+func (r *Response) isProtocolSwitch() bool {
+	return r.StatusCode == StatusSwitchingProtocols &&
+		r.Header.Get("Upgrade") != "" &&
+		httplex.HeaderValuesContainsToken(r.Header["Connection"], "Upgrade")
+}
@@ -0,0 +1,351 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP client retries and hedged requests.
+
+package http
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A RetryPolicy specifies when and how a Client retries a request
+// that failed with an error or with a retryable status code.
+//
+// Only requests that are safe to send more than once are retried:
+// their method must be GET, HEAD, OPTIONS, TRACE, PUT or DELETE, or
+// they must have an Idempotency-Key or X-Idempotency-Key header, and
+// they must have no body, or a GetBody function to obtain a fresh
+// copy of it. NewRequest sets GetBody for common body types.
+// Requests to upgrade the connection to another protocol are never
+// retried, so that the body of a 101 Switching Protocols response
+// stays writable.
+//
+// A RetryPolicy applies to each request of a redirect chain on its own.
+// The Client's Timeout bounds the whole sequence of attempts.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a request is sent,
+	// counting the first attempt and any hedged requests.
+	// If zero, 3 is used.
+	MaxAttempts int
+
+	// StatusCodes lists the response status codes that are retried.
+	// If nil, 429, 502, 503 and 504 are retried.
+	StatusCodes []int
+
+	// RetryError, if non-nil, reports whether a request that failed
+	// with err should be retried. If nil, all errors are retried.
+	// Requests canceled by their context or Cancel channel, and
+	// requests that exceeded the Client's Timeout, are never retried.
+	// A request canceled while waiting to be retried fails with
+	// the cancelation error, not the response to the last attempt.
+	RetryError func(err error) bool
+
+	// MinBackoff is the base delay before the first retry. Each
+	// further retry doubles it, up to MaxBackoff. The actual delay
+	// is chosen at random between half and all of that value.
+	// If zero, MinBackoff is 100 milliseconds and MaxBackoff is
+	// 10 seconds.
+	//
+	// A response with a Retry-After header delays the next attempt
+	// by at least the time the server asks for. If that is longer
+	// than MaxBackoff, the response is returned without retrying.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// HedgeDelay, if positive, enables hedged requests: if no
+	// response to the requests in flight arrives within HedgeDelay,
+	// another copy of the request is sent without canceling them.
+	// The first response that isn't retried is returned, and the
+	// other requests are canceled.
+	HedgeDelay time.Duration
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts > 0 {
+		return p.MaxAttempts
+	}
+	return 3
+}
+
+func (p *RetryPolicy) backoffLimits() (min, max time.Duration) {
+	min, max = p.MinBackoff, p.MaxBackoff
+	if min <= 0 {
+		min = 100 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 10 * time.Second
+	}
+	if max < min {
+		max = min
+	}
+	return min, max
+}
+
+// backoff returns the randomized delay before the n'th retry,
+// counting from zero.
+func (p *RetryPolicy) backoff(n int) time.Duration {
+	min, max := p.backoffLimits()
+	d := max
+	if n < 32 {
+		if b := min << uint(n); b > 0 && b < max {
+			d = b
+		}
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func (p *RetryPolicy) retryStatus(code int) bool {
+	if p.StatusCodes == nil {
+		switch code {
+		case StatusTooManyRequests, StatusBadGateway, StatusServiceUnavailable, StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	for _, c := range p.StatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// canRetry reports whether req may be sent more than once.
+func (p *RetryPolicy) canRetry(req *Request) bool {
+	if req.Body != nil && req.Body != NoBody && req.GetBody == nil {
+		return false
+	}
+	if hasToken(req.Header.get("Connection"), "upgrade") {
+		return false
+	}
+	switch valueOrDefault(req.Method, "GET") {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return true
+	}
+	_, ok := req.Header["Idempotency-Key"]
+	if !ok {
+		_, ok = req.Header["X-Idempotency-Key"]
+	}
+	return ok
+}
+
+// retryAfter parses the Retry-After header of resp, either a number
+// of seconds or an HTTP date.
+func retryAfter(resp *Response) (time.Duration, bool) {
+	v := strings.TrimSpace(resp.Header.get("Retry-After"))
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if secs < 0 || secs > 1<<31 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// An attempt is one request sent by Client.sendRetry.
+type attempt struct {
+	resp       *Response
+	didTimeout func() bool
+	err        error
+	cancel     context.CancelFunc
+}
+
+// discard cancels the attempt and closes its response body, after
+// reading a bit of it so that the connection can be reused.
+func (a *attempt) discard() {
+	if a.resp != nil {
+		const maxBodySlurpSize = 2 << 10
+		if a.resp.ContentLength == -1 || a.resp.ContentLength <= maxBodySlurpSize {
+			io.CopyN(ioutil.Discard, a.resp.Body, maxBodySlurpSize)
+		}
+		a.resp.Body.Close()
+	}
+	a.cancel()
+}
+
+// result returns the outcome of the attempt to the caller of
+// Client.send, who becomes responsible for its response body.
+func (a *attempt) result() (*Response, func() bool, error) {
+	if a.err != nil {
+		a.cancel()
+		return nil, a.didTimeout, a.err
+	}
+	a.resp.Body = &cancelOnCloseBody{ReadCloser: a.resp.Body, cancel: a.cancel}
+	return a.resp, nil, nil
+}
+
+// cancelOnCloseBody cancels the context of its request when closed.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// sendRetry is like send, but follows the Client's RetryPolicy.
+// Like send, it always closes req.Body.
+func (c *Client) sendRetry(req *Request, deadline time.Time) (resp *Response, didTimeout func() bool, err error) {
+	p := c.Retry
+	if p == nil || !p.canRetry(req) {
+		return c.send(req, deadline)
+	}
+
+	max := p.maxAttempts()
+	results := make(chan *attempt, max)
+	sent := 0
+	inflight := make(map[*attempt]bool)
+	start := func() bool {
+		areq := new(Request)
+		*areq = *req
+		areq.Header = cloneHeader(req.Header) // send adds cookies
+		if sent > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return false
+			}
+			areq.Body = body
+		}
+		ctx, cancel := context.WithCancel(req.Context())
+		areq.ctx = ctx
+		a := &attempt{cancel: cancel}
+		sent++
+		inflight[a] = true
+		go func() {
+			a.resp, a.didTimeout, a.err = c.send(areq, deadline)
+			results <- a
+		}()
+		return true
+	}
+	// finish returns a's outcome, and cancels the other
+	// attempts still in flight.
+	finish := func(a *attempt) (*Response, func() bool, error) {
+		if n := len(inflight); n > 0 {
+			for other := range inflight {
+				other.cancel()
+			}
+			go func() {
+				for i := 0; i < n; i++ {
+					(<-results).discard()
+				}
+			}()
+		}
+		return a.result()
+	}
+	canceled := func() bool {
+		if req.Context().Err() != nil {
+			return true
+		}
+		select {
+		case <-req.Cancel:
+			return true
+		default:
+			return false
+		}
+	}
+
+	start()
+	hedging := p.HedgeDelay > 0
+	var hedge *time.Timer
+	var hedgeC <-chan time.Time
+	var last *attempt
+	for retries := 0; ; {
+		if hedging && sent < max && hedge == nil {
+			hedge = time.NewTimer(p.HedgeDelay)
+			hedgeC = hedge.C
+		}
+		var a *attempt
+		select {
+		case a = <-results:
+			delete(inflight, a)
+		case <-hedgeC:
+			hedge, hedgeC = nil, nil
+			// If the request can't be copied, wait for the
+			// attempts in flight instead.
+			hedging = start()
+			continue
+		}
+		if hedge != nil {
+			hedge.Stop()
+			hedge, hedgeC = nil, nil
+		}
+
+		var delay time.Duration
+		retry := false
+		if a.err != nil {
+			retry = !canceled() && !a.didTimeout() && (p.RetryError == nil || p.RetryError(a.err))
+		} else if p.retryStatus(a.resp.StatusCode) {
+			retry = true
+			if d, ok := retryAfter(a.resp); ok {
+				_, maxBackoff := p.backoffLimits()
+				retry = d <= maxBackoff
+				delay = d
+			}
+		}
+		if !retry {
+			if last != nil {
+				last.discard()
+			}
+			return finish(a)
+		}
+		if last != nil {
+			last.discard()
+		}
+		last = a
+
+		if len(inflight) > 0 {
+			// Another attempt may still succeed.
+			continue
+		}
+		if sent >= max {
+			return finish(last)
+		}
+		if b := p.backoff(retries); b > delay {
+			delay = b
+		}
+		retries++
+		if !deadline.IsZero() && time.Now().Add(delay).After(deadline) {
+			return finish(last)
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-req.Context().Done():
+		case <-req.Cancel:
+		}
+		t.Stop()
+		if canceled() {
+			// No attempt is in flight, so the response to the
+			// last one is all there is to drop.
+			last.discard()
+			if err := req.Context().Err(); err != nil {
+				return nil, alwaysFalse, err
+			}
+			return nil, alwaysFalse, errRequestCanceled
+		}
+		if !start() {
+			return finish(last)
+		}
+	}
+}
@@ -581,6 +581,226 @@ func TestServeWithSlashRedirectForHostPatterns(t *testing.T) {
 	}
 }
 
+func TestServeMuxMethodsAndWildcards(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+
+	mux := NewServeMux()
+	mux.Handle("GET /item/{id}", stringHandler("GET /item/{id}"))
+	mux.Handle("POST /item/{id}", stringHandler("POST /item/{id}"))
+	mux.Handle("GET /item/new", stringHandler("GET /item/new"))
+	mux.Handle("/files/{path...}", stringHandler("/files/{path...}"))
+	mux.Handle("/{$}", stringHandler("/{$}"))
+	mux.Handle("example.com/item/{id}", stringHandler("example.com/item/{id}"))
+
+	tests := []struct {
+		method string
+		url    string
+		code   int
+		want   string
+		allow  string
+	}{
+		{"GET", "http://a.com/", 200, "/{$}", ""},
+		{"GET", "http://a.com/x", 404, "", ""},
+		{"GET", "http://a.com/item/1", 200, "GET /item/{id}", ""},
+		{"HEAD", "http://a.com/item/1", 200, "GET /item/{id}", ""},
+		{"POST", "http://a.com/item/1", 200, "POST /item/{id}", ""},
+		{"PUT", "http://a.com/item/1", 405, "", "GET, HEAD, POST"},
+		{"GET", "http://a.com/item/new", 200, "GET /item/new", ""},
+		{"POST", "http://a.com/item/new", 200, "POST /item/{id}", ""},
+		{"DELETE", "http://a.com/item/new", 405, "", "GET, HEAD, POST"},
+		{"GET", "http://a.com/files/a/b/c", 200, "/files/{path...}", ""},
+		{"GET", "http://a.com/files/", 200, "/files/{path...}", ""},
+		{"PUT", "http://example.com/item/1", 200, "example.com/item/{id}", ""},
+	}
+	for _, tt := range tests {
+		req, _ := NewRequest(tt.method, tt.url, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if got, want := w.Code, tt.code; got != want {
+			t.Errorf("%s %s: Status = %d; want %d", tt.method, tt.url, got, want)
+		}
+		if got, want := w.HeaderMap.Get("Result"), tt.want; got != want {
+			t.Errorf("%s %s: Result = %q; want %q", tt.method, tt.url, got, want)
+		}
+		if got, want := w.HeaderMap.Get("Allow"), tt.allow; got != want {
+			t.Errorf("%s %s: Allow = %q; want %q", tt.method, tt.url, got, want)
+		}
+	}
+}
+
+func TestPathValue(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		url     string
+		want    map[string]string
+	}{
+		{
+			"/{a}/is/{b}/{c...}",
+			"/now/is/the/time/for/all",
+			map[string]string{
+				"a": "now",
+				"b": "the",
+				"c": "time/for/all",
+				"d": "",
+			},
+		},
+		{
+			"/names/{name}/{other...}",
+			"/names/%2fjohn/address",
+			map[string]string{
+				"name":  "/john",
+				"other": "address",
+			},
+		},
+		{
+			"/names/{name}/{other...}",
+			"/names/john%2Fdoe/there/is%2F/more",
+			map[string]string{
+				"name":  "john/doe",
+				"other": "there/is//more",
+			},
+		},
+	} {
+		mux := NewServeMux()
+		mux.HandleFunc(test.pattern, func(w ResponseWriter, r *Request) {
+			for name, want := range test.want {
+				if got := r.PathValue(name); got != want {
+					t.Errorf("%q, %q: PathValue(%q) = %q, want %q", test.pattern, test.url, name, got, want)
+				}
+			}
+		})
+		req, _ := NewRequest("GET", "http://a.com"+test.url, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Errorf("%q, %q: Status = %d; want 200", test.pattern, test.url, w.Code)
+		}
+	}
+}
+
+func TestSetPathValue(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/a/{b}/c/{d...}", func(_ ResponseWriter, r *Request) {
+		kvs := map[string]string{
+			"b": "X",
+			"d": "Y",
+			"a": "Z",
+		}
+		for k, v := range kvs {
+			r.SetPathValue(k, v)
+		}
+		for k, w := range kvs {
+			if g := r.PathValue(k); g != w {
+				t.Errorf("got %q, want %q", g, w)
+			}
+		}
+	})
+	req, _ := NewRequest("GET", "http://a.com/a/b/c/d/e", nil)
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestServeMuxRegisterConflict(t *testing.T) {
+	for _, test := range []struct {
+		p1, p2  string
+		wantErr string
+	}{
+		{"/a/{x}", "/a/{y}", "matches the same requests as"},
+		{"/a/{x}", "/{y}/b", "both match some paths"},
+		{"GET /", "/index.html", "matches more methods than GET /"},
+		{"/a", "/a", "matches the same requests as"},
+		{"GET /{x}", "GET /{$}/", "parsing"},
+		{"/a", "BAD:METHOD /b", "invalid method"},
+	} {
+		mux := NewServeMux()
+		mux.Handle(test.p1, stringHandler(test.p1))
+		func() {
+			defer func() {
+				r := recover()
+				if r == nil {
+					t.Errorf("%q then %q: no panic", test.p1, test.p2)
+					return
+				}
+				err, ok := r.(error)
+				if !ok || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("%q then %q: panic %v; want error containing %q", test.p1, test.p2, r, test.wantErr)
+				}
+			}()
+			mux.Handle(test.p2, stringHandler(test.p2))
+		}()
+	}
+}
+
+// Handler returns the pattern that will match after a trailing-slash
+// redirect, not the redirected path.
+func TestServeMuxHandlerRedirectPattern(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle("example.com/images/", stringHandler("images"))
+	mux.Handle("GET /items/{id}/", stringHandler("items"))
+
+	for _, tt := range []struct {
+		method, url string
+		want        string
+	}{
+		{"GET", "http://example.com/images", "example.com/images/"},
+		{"GET", "http://example.com/items/3", "GET /items/{id}/"},
+		{"CONNECT", "http://example.com/images", "example.com/images/"},
+	} {
+		req, _ := NewRequest(tt.method, tt.url, nil)
+		h, pattern := mux.Handler(req)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != StatusMovedPermanently {
+			t.Errorf("%s %s: Status = %d; want 301", tt.method, tt.url, w.Code)
+		}
+		if pattern != tt.want {
+			t.Errorf("%s %s: pattern = %q; want %q", tt.method, tt.url, pattern, tt.want)
+		}
+	}
+}
+
+func TestServeMuxGo110(t *testing.T) {
+	defer SetMuxGo110(true)()
+
+	mux := NewServeMux()
+	for _, pattern := range []string{"/{x}", "/a{b}", "GET /y", "/dir/"} {
+		mux.Handle(pattern, stringHandler(pattern))
+	}
+	for _, tt := range []struct {
+		path    string
+		code    int
+		pattern string
+	}{
+		{"/{x}", 200, "/{x}"},
+		{"/a{b}", 200, "/a{b}"},
+		{"/z", 404, ""},
+		{"/y", 404, ""},
+		{"/dir", 301, "/dir/"},
+		{"/dir/x", 200, "/dir/"},
+	} {
+		r := &Request{
+			Method: "GET",
+			Host:   "example.com",
+			URL:    &url.URL{Path: tt.path},
+		}
+		h, pattern := mux.Handler(r)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if pattern != tt.pattern || w.Code != tt.code {
+			t.Errorf("%s = %d, %q, want %d, %q", tt.path, w.Code, pattern, tt.code, tt.pattern)
+		}
+		if _, ok := h.(stringHandler); ok != (tt.code == 200) {
+			t.Errorf("%s: handler %T", tt.path, h)
+		}
+	}
+
+	// The host "GET " is matched literally.
+	r := &Request{Method: "GET", Host: "GET ", URL: &url.URL{Path: "/y"}}
+	if _, pattern := mux.Handler(r); pattern != "GET /y" {
+		t.Errorf("host %q: pattern = %q, want %q", r.Host, pattern, "GET /y")
+	}
+}
+
 func BenchmarkServeMux(b *testing.B) {
 
 	type test struct {
@@ -4826,6 +5046,82 @@ func testServerContext_LocalAddrContextKey(t *testing.T, h2 bool) {
 	}
 }
 
+func TestServerContext_BaseAndConnContext_h1(t *testing.T) {
+	testServerContext_BaseAndConnContext(t, h1Mode)
+}
+func TestServerContext_BaseAndConnContext_h2(t *testing.T) {
+	testServerContext_BaseAndConnContext(t, h2Mode)
+}
+func testServerContext_BaseAndConnContext(t *testing.T, h2 bool) {
+	setParallel(t)
+	defer afterTest(t)
+	type baseKey struct{}
+	type connKey struct{}
+	ch := make(chan context.Context, 1)
+	var addr net.Addr
+	var conns int32
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		ch <- r.Context()
+	}), func(ts *httptest.Server) {
+		addr = ts.Listener.Addr()
+		ts.Config.BaseContext = func(l net.Listener) context.Context {
+			if l.Addr() != addr {
+				t.Errorf("BaseContext listener address = %v; want %v", l.Addr(), addr)
+			}
+			return context.WithValue(context.Background(), baseKey{}, "base")
+		}
+		ts.Config.ConnContext = func(ctx context.Context, c net.Conn) context.Context {
+			if got, want := ctx.Value(baseKey{}), "base"; got != want {
+				t.Errorf("in ConnContext, base context key = %#v; want %q", got, want)
+			}
+			if _, ok := ctx.Value(ServerContextKey).(*Server); !ok {
+				t.Error("in ConnContext, missing ServerContextKey")
+			}
+			n := atomic.AddInt32(&conns, 1)
+			return context.WithValue(ctx, connKey{}, n)
+		}
+	})
+	defer cst.close()
+	for i := 0; i < 2; i++ {
+		res, err := cst.c.Get(cst.ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		ctx := <-ch
+		if got, want := ctx.Value(baseKey{}), "base"; got != want {
+			t.Errorf("base context key = %#v; want %q", got, want)
+		}
+		// Both requests share one connection.
+		if got, want := ctx.Value(connKey{}), int32(1); got != want {
+			t.Errorf("conn context key = %#v; want %v", got, want)
+		}
+		if _, ok := ctx.Value(LocalAddrContextKey).(net.Addr); !ok {
+			t.Error("missing LocalAddrContextKey")
+		}
+	}
+}
+
+func TestServerConnContextNilPanics(t *testing.T) {
+	ln := newLocalListener(t)
+	srv := &Server{
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context { return nil },
+	}
+	errc := make(chan interface{}, 1)
+	go func() {
+		defer func() { errc <- recover() }()
+		srv.Serve(ln)
+	}()
+	c, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if got := <-errc; got != "ConnContext returned nil" {
+		t.Errorf("recovered %v; want ConnContext panic", got)
+	}
+}
+
 // https://golang.org/issue/15960
 func TestHandlerSetTransferEncodingChunked(t *testing.T) {
 	setParallel(t)
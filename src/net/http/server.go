@@ -22,6 +22,7 @@ import (
 	"os"
 	"path"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -1748,7 +1749,7 @@ func (c *conn) serve(ctx context.Context) {
 		*c.tlsState = tlsConn.ConnectionState()
 		if proto := c.tlsState.NegotiatedProtocol; validNPN(proto) {
 			if fn := c.server.TLSNextProto[proto]; fn != nil {
-				h := initNPNRequest{tlsConn, serverHandler{c.server}}
+				h := initNPNRequest{ctx, tlsConn, serverHandler{c.server}}
 				fn(c.server, tlsConn, h)
 			}
 			return
@@ -1765,6 +1766,12 @@ func (c *conn) serve(ctx context.Context) {
 	c.bufr = newBufioReader(c.r)
 	c.bufw = newBufioWriterSize(checkConnErrorWriter{c}, 4<<10)
 
+	if c.server.UnencryptedHTTP2 && c.tlsState == nil && c.hasH2Preface() {
+		c.setState(c.rwc, StateActive)
+		c.serveH2C(ctx, nil, nil)
+		return
+	}
+
 	for {
 		w, err := c.readRequest(ctx)
 		if c.r.remain != c.server.initialReadLimitSize() {
@@ -1798,6 +1805,12 @@ func (c *conn) serve(ctx context.Context) {
 			return
 		}
 
+		if settings, ok := c.h2cUpgrade(w.req); ok {
+			w.cancelCtx()
+			c.serveH2C(ctx, w.req, settings)
+			return
+		}
+
 		// Expect 100 Continue support
 		req := w.req
 		if req.expectsContinue() {
@@ -2090,45 +2103,172 @@ func RedirectHandler(url string, code int) Handler {
 // patterns and calls the handler for the pattern that
 // most closely matches the URL.
 //
-// Patterns name fixed, rooted paths, like "/favicon.ico",
-// or rooted subtrees, like "/images/" (note the trailing slash).
-// Longer patterns take precedence over shorter ones, so that
-// if there are handlers registered for both "/images/"
-// and "/images/thumbnails/", the latter handler will be
-// called for paths beginning "/images/thumbnails/" and the
-// former will receive requests for any other paths in the
-// "/images/" subtree.
-//
-// Note that since a pattern ending in a slash names a rooted subtree,
-// the pattern "/" matches all paths not matched by other registered
-// patterns, not just the URL with Path == "/".
-//
-// If a subtree has been registered and a request is received naming the
-// subtree root without its trailing slash, ServeMux redirects that
-// request to the subtree root (adding the trailing slash). This behavior can
-// be overridden with a separate registration for the path without
-// the trailing slash. For example, registering "/images/" causes ServeMux
+// Patterns
+//
+// Patterns can match the method, host and path of a request.
+// Some examples:
+//
+//	"/index.html" matches the path "/index.html" for any host and method.
+//	"GET /static/" matches a GET request whose path begins with "/static/".
+//	"example.com/" matches any request to the host "example.com".
+//	"example.com/{$}" matches requests with host "example.com" and path "/".
+//	"/b/{bucket}/o/{objectname...}" matches paths whose first segment is "b"
+//	and whose third segment is "o". The name "bucket" denotes the second
+//	segment and "objectname" denotes the remainder of the path.
+//
+// In general, a pattern looks like
+//
+//	[METHOD ][HOST]/[PATH]
+//
+// All three parts are optional; "/" is a valid pattern.
+// If METHOD is present, it must be followed by at least one space or tab.
+//
+// Literal (that is, non-wildcard) parts of a pattern match
+// the corresponding parts of a request case-sensitively.
+//
+// A pattern with no method matches every method. A pattern
+// with the method GET matches both GET and HEAD requests.
+// Otherwise, the method must match exactly.
+//
+// A pattern with no host matches every host.
+// A pattern with a host matches URLs on that host only.
+//
+// A path can include wildcard segments of the form {NAME} or {NAME...}.
+// For example, "/b/{bucket}/o/{objectname...}".
+// The wildcard name must be a valid Go identifier.
+// Wildcards must be full path segments: they must be preceded by a slash and followed by
+// either a slash or the end of the string.
+// For example, "/b_{bucket}" is not a valid pattern.
+//
+// Normally a wildcard matches only a single path segment,
+// ending at the next literal slash (not %2F) in the request URL.
+// But if the "..." is present, then the wildcard matches the remainder of the URL path, including slashes.
+// (Therefore it is invalid for a "..." wildcard to appear anywhere but at the end of a pattern.)
+// The match for a wildcard can be obtained by calling Request.PathValue with the wildcard's name.
+// A trailing slash in a path acts as an anonymous "..." wildcard.
+//
+// The special wildcard {$} matches only the end of the URL.
+// For example, the pattern "/{$}" matches only the path "/",
+// whereas the pattern "/" matches every path.
+//
+// For matching, both pattern paths and incoming request paths are unescaped segment by segment.
+// So, for example, the path "/a%2Fb/100%25" is treated as having two segments, "a/b" and "100%".
+// The pattern "/a%2fb/" matches it, but the pattern "/a/b/" does not.
+//
+// Precedence
+//
+// If two or more patterns match a request, then the most specific pattern takes precedence.
+// A pattern P1 is more specific than P2 if P1 matches a strict subset of P2’s requests;
+// that is, if P2 matches all the requests of P1 and more.
+// If neither is more specific, then the patterns conflict.
+// There is one exception to this rule:
+// if two patterns would otherwise conflict and one has a host while the other does not,
+// then the pattern with the host takes precedence.
+// If a pattern passed to ServeMux.Handle or ServeMux.HandleFunc conflicts with
+// another pattern that is already registered, those functions panic.
+//
+// As an example of the general rule, "/images/thumbnails/" is more specific than "/images/",
+// so both can be registered.
+// The former matches paths beginning with "/images/thumbnails/"
+// and the latter will match any other path in the "/images/" subtree.
+//
+// As another example, consider the patterns "GET /" and "/index.html":
+// both match a GET request for "/index.html", but the former pattern
+// matches all other GET and HEAD requests, while the latter matches any
+// request for "/index.html" that uses a different method.
+// The patterns conflict.
+//
+// Trailing-slash redirection
+//
+// Consider a ServeMux with a handler for a subtree, registered using a trailing slash or "..." wildcard.
+// If the ServeMux receives a request for the subtree root without a trailing slash,
+// it redirects the request by adding the trailing slash.
+// This behavior can be overridden with a separate registration for the path without
+// the trailing slash or "..." wildcard. For example, registering "/images/" causes ServeMux
 // to redirect a request for "/images" to "/images/", unless "/images" has
 // been registered separately.
 //
-// Patterns may optionally begin with a host name, restricting matches to
-// URLs on that host only. Host-specific patterns take precedence over
-// general patterns, so that a handler might register for the two patterns
-// "/codesearch" and "codesearch.google.com/" without also taking over
-// requests for "http://www.google.com/".
+// Request sanitizing
+//
+// ServeMux also takes care of sanitizing the URL request path and the Host
+// header, stripping the port number and redirecting any request containing . or
+// .. segments or repeated slashes to an equivalent, cleaner URL.
+//
+// Method Not Allowed
 //
-// ServeMux also takes care of sanitizing the URL request path,
-// redirecting any request containing . or .. elements or repeated slashes
-// to an equivalent, cleaner URL.
+// If a request's path matches a registered pattern but its method does not,
+// ServeMux replies with a 405 Method Not Allowed status and an Allow header
+// listing the methods that the matching patterns accept.
+//
+// Compatibility
+//
+// The pattern syntax and matching behavior of ServeMux changed in Go 1.11.
+// Setting the GODEBUG environment variable to "httpmuxgo110=1" restores the
+// Go 1.10 behavior. The setting is read once, at program startup.
+//
+// The incompatible changes are:
+//
+//	Go 1.10 treated every pattern as a literal path or subtree, so "/{x}"
+//	matched only the path "/{x}", and "GET /x" was a pattern for the
+//	host "GET ". Go 1.11 reads these as a wildcard and a method.
+//
+//	Go 1.10 accepted any non-empty pattern. Go 1.11 panics when
+//	registering a syntactically invalid pattern, such as "/a{x}",
+//	or one that conflicts with an already registered pattern.
+//
+//	Go 1.11 unescapes patterns and request paths segment by segment, so
+//	"/%61" matches the path "/a", and a %2F in a request path does not
+//	separate segments. Go 1.10 matched patterns against the unescaped path.
+//
+// Matching a request only considers the patterns for its host whose first
+// path segment is the request's first segment or a wildcard, but
+// registering a pattern checks it against every registered pattern
+// with the same host.
 type ServeMux struct {
-	mu    sync.RWMutex
-	m     map[string]muxEntry
-	hosts bool // whether any patterns contain hostnames
+	mu     sync.RWMutex
+	index  map[muxIndexKey][]*muxEntry // registered patterns, for matching
+	es     []*muxEntry                 // registered patterns, in registration order
+	mux110 serveMux110                 // used instead when muxGo110 is set
+}
+
+// A muxIndexKey groups the registered patterns of a ServeMux by host and
+// first path segment. Patterns whose first segment is not a literal,
+// such as "/" and "/{x}", are grouped under their host with wild set.
+type muxIndexKey struct {
+	host string
+	seg  string
+	wild bool
+}
+
+func indexKey(p *pattern) muxIndexKey {
+	s := p.segments[0]
+	if s.wild || s.s == "/" {
+		return muxIndexKey{host: p.host, wild: true}
+	}
+	return muxIndexKey{host: p.host, seg: s.s}
+}
+
+// firstSegment returns the unescaped first segment of the escaped path.
+func firstSegment(path string) string {
+	path = strings.TrimPrefix(path, "/")
+	if i := strings.IndexByte(path, '/'); i >= 0 {
+		path = path[:i]
+	}
+	return pathUnescape(path)
+}
+
+// candidates returns the registered patterns with the given host that
+// may match path, in two lists. The caller must hold mux.mu.
+func (mux *ServeMux) candidates(host, path string) [2][]*muxEntry {
+	return [2][]*muxEntry{
+		mux.index[muxIndexKey{host: host, seg: firstSegment(path)}],
+		mux.index[muxIndexKey{host: host, wild: true}],
+	}
 }
 
 type muxEntry struct {
-	h       Handler
-	pattern string
+	h   Handler
+	pat *pattern
 }
 
 // NewServeMux allocates and returns a new ServeMux.
@@ -2139,19 +2279,6 @@ var DefaultServeMux = &defaultServeMux
 
 var defaultServeMux ServeMux
 
-// Does path match pattern?
-func pathMatch(pattern, path string) bool {
-	if len(pattern) == 0 {
-		// should not happen
-		return false
-	}
-	n := len(pattern)
-	if pattern[n-1] != '/' {
-		return pattern == path
-	}
-	return len(path) >= n && path[0:n] == pattern
-}
-
 // Return the canonical path for p, eliminating . and .. elements.
 func cleanPath(p string) string {
 	if p == "" {
@@ -2182,66 +2309,104 @@ func stripHostPort(h string) string {
 	return host
 }
 
-// Find a handler on a handler map given a path string.
-// Most-specific (longest) pattern wins.
-func (mux *ServeMux) match(path string) (h Handler, pattern string) {
-	// Check for exact match first.
-	v, ok := mux.m[path]
-	if ok {
-		return v.h, v.pattern
+// match finds the most specific registered pattern that matches
+// the given host, method and escaped path, along with the values
+// of its wildcards. Patterns with a host take precedence over
+// patterns without one. The caller must hold mux.mu.
+func (mux *ServeMux) match(host, method, path string) (e *muxEntry, matches []string) {
+	if host != "" {
+		if e, matches = mux.matchHost(host, method, path); e != nil {
+			return e, matches
+		}
 	}
+	return mux.matchHost("", method, path)
+}
 
-	// Check for longest valid match.
-	var n = 0
-	for k, v := range mux.m {
-		if !pathMatch(k, path) {
-			continue
-		}
-		if h == nil || len(k) > n {
-			n = len(k)
-			h = v.h
-			pattern = v.pattern
+// matchHost is like match but only considers patterns
+// whose host is exactly host.
+func (mux *ServeMux) matchHost(host, method, path string) (best *muxEntry, matches []string) {
+	for _, es := range mux.candidates(host, path) {
+		for _, e := range es {
+			if !e.pat.matchMethod(method) {
+				continue
+			}
+			m, ok := e.pat.matchPath(path)
+			if !ok {
+				continue
+			}
+			// Two registered patterns with the same host that match the
+			// same request never conflict, so one of them is more specific.
+			if best == nil || e.pat.comparePathsAndMethods(best.pat) == moreSpecific {
+				best, matches = e, m
+			}
 		}
 	}
-	return
+	return best, matches
 }
 
-// redirectToPathSlash determines if the given path needs appending "/" to it.
-// This occurs when a handler for path + "/" was already registered, but
-// not for path itself. If the path needs appending to, it creates a new
-// URL, setting the path to u.Path + "/" and returning true to indicate so.
-func (mux *ServeMux) redirectToPathSlash(host, path string, u *url.URL) (*url.URL, bool) {
-	if !mux.shouldRedirect(host, path) {
-		return u, false
+// matchOrRedirect looks up a registered pattern for the request. If there
+// is no exact match for path but path+"/" has one, and u is not nil,
+// matchOrRedirect returns a URL to redirect to instead, along with the
+// pattern that will match after the redirect.
+func (mux *ServeMux) matchOrRedirect(host, method, path string, u *url.URL) (_ *muxEntry, matches []string, redirectTo *url.URL) {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+
+	e, matches := mux.match(host, method, path)
+	// If we have an exact match, or we were asked not to try trailing-slash
+	// redirection, or the URL already has a trailing slash, then we're done.
+	if (e == nil || !e.pat.exactMatch(path)) && u != nil && !strings.HasSuffix(path, "/") {
+		// If there is an exact match with a trailing slash, then redirect.
+		path += "/"
+		if e2, _ := mux.match(host, method, path); e2 != nil && e2.pat.exactMatch(path) {
+			return e2, nil, &url.URL{Path: cleanPath(u.Path) + "/", RawQuery: u.RawQuery}
+		}
 	}
-	path = path + "/"
-	u = &url.URL{Path: path, RawQuery: u.RawQuery}
-	return u, true
+	return e, matches, nil
 }
 
-// shouldRedirect reports whether the given path and host should be redirected to
-// path+"/". This should happen if a handler is registered for path+"/" but
-// not path -- see comments at ServeMux.
-func (mux *ServeMux) shouldRedirect(host, path string) bool {
-	p := []string{path, host + path}
+// matchingMethods returns a sorted list of all methods that would match
+// with the given host and path.
+func (mux *ServeMux) matchingMethods(host, path string) []string {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
 
-	for _, c := range p {
-		if _, exist := mux.m[c]; exist {
-			return false
+	hosts := []string{""}
+	if host != "" {
+		hosts = append(hosts, host)
+	}
+	ms := map[string]bool{}
+	add := func(path string) {
+		for _, host := range hosts {
+			for _, es := range mux.candidates(host, path) {
+				for _, e := range es {
+					if _, ok := e.pat.matchPath(path); !ok {
+						continue
+					}
+					if e.pat.method == "" {
+						// A pattern without a method matches every method,
+						// so a method cannot be disallowed.
+						continue
+					}
+					ms[e.pat.method] = true
+					if e.pat.method == "GET" {
+						ms["HEAD"] = true
+					}
+				}
+			}
 		}
 	}
-
-	n := len(path)
-	if n == 0 {
-		return false
+	add(path)
+	// matchOrRedirect will try appending a trailing slash if there is no match.
+	if !strings.HasSuffix(path, "/") {
+		add(path + "/")
 	}
-	for _, c := range p {
-		if _, exist := mux.m[c+"/"]; exist {
-			return path[n-1] != '/'
-		}
+	methods := make([]string, 0, len(ms))
+	for m := range ms {
+		methods = append(methods, m)
 	}
-
-	return false
+	sort.Strings(methods)
+	return methods
 }
 
 // Handler returns the handler to use for the given request,
@@ -2259,58 +2424,77 @@ func (mux *ServeMux) shouldRedirect(host, path string) bool {
 //
 // If there is no registered handler that applies to the request,
 // Handler returns a ``page not found'' handler and an empty pattern.
+// If a registered pattern matches the request's path but not its method,
+// Handler returns a ``method not allowed'' handler and an empty pattern.
 func (mux *ServeMux) Handler(r *Request) (h Handler, pattern string) {
+	if muxGo110 {
+		return mux.mux110.findHandler(r)
+	}
+	h, pattern, _, _ = mux.findHandler(r)
+	return
+}
 
+// findHandler finds a handler for a request.
+// If there is a matching handler, it returns it and the pattern that matched.
+// Otherwise it returns a Redirect or NotFound handler, and for a redirect the
+// pattern that will match after it.
+func (mux *ServeMux) findHandler(r *Request) (h Handler, patStr string, _ *pattern, matches []string) {
+	var e *muxEntry
+	host := r.URL.Host
+	escapedPath := r.URL.EscapedPath()
+	path := escapedPath
 	// CONNECT requests are not canonicalized.
 	if r.Method == "CONNECT" {
 		// If r.URL.Path is /tree and its handler is not registered,
 		// the /tree -> /tree/ redirect applies to CONNECT requests
 		// but the path canonicalization does not.
-		if u, ok := mux.redirectToPathSlash(r.URL.Host, r.URL.Path, r.URL); ok {
-			return RedirectHandler(u.String(), StatusMovedPermanently), u.Path
+		re, _, u := mux.matchOrRedirect(host, r.Method, path, r.URL)
+		if u != nil {
+			return RedirectHandler(u.String(), StatusMovedPermanently), re.pat.String(), nil, nil
+		}
+		// Redo the match, this time with r.Host instead of r.URL.Host.
+		// Pass a nil URL to skip the trailing-slash redirect logic.
+		host = r.Host
+		e, matches, _ = mux.matchOrRedirect(host, r.Method, path, nil)
+	} else {
+		// All other requests have any port stripped and path cleaned
+		// before passing to mux.handler.
+		host = stripHostPort(r.Host)
+		path = cleanPath(path)
+
+		// If the given path is /tree and its handler is not registered,
+		// redirect for /tree/.
+		var u *url.URL
+		e, matches, u = mux.matchOrRedirect(host, r.Method, path, r.URL)
+		if u != nil {
+			return RedirectHandler(u.String(), StatusMovedPermanently), e.pat.String(), nil, nil
+		}
+		if path != escapedPath {
+			// Redirect to cleaned path.
+			patStr := ""
+			if e != nil {
+				patStr = e.pat.String()
+			}
+			u := *r.URL
+			u.Path = cleanPath(r.URL.Path)
+			u.RawPath = path
+			return RedirectHandler(u.String(), StatusMovedPermanently), patStr, nil, nil
 		}
-
-		return mux.handler(r.Host, r.URL.Path)
-	}
-
-	// All other requests have any port stripped and path cleaned
-	// before passing to mux.handler.
-	host := stripHostPort(r.Host)
-	path := cleanPath(r.URL.Path)
-
-	// If the given path is /tree and its handler is not registered,
-	// redirect for /tree/.
-	if u, ok := mux.redirectToPathSlash(host, path, r.URL); ok {
-		return RedirectHandler(u.String(), StatusMovedPermanently), u.Path
-	}
-
-	if path != r.URL.Path {
-		_, pattern = mux.handler(host, path)
-		url := *r.URL
-		url.Path = path
-		return RedirectHandler(url.String(), StatusMovedPermanently), pattern
-	}
-
-	return mux.handler(host, r.URL.Path)
-}
-
-// handler is the main implementation of Handler.
-// The path is known to be in canonical form, except for CONNECT methods.
-func (mux *ServeMux) handler(host, path string) (h Handler, pattern string) {
-	mux.mu.RLock()
-	defer mux.mu.RUnlock()
-
-	// Host-specific pattern takes precedence over generic ones
-	if mux.hosts {
-		h, pattern = mux.match(host + path)
 	}
-	if h == nil {
-		h, pattern = mux.match(path)
-	}
-	if h == nil {
-		h, pattern = NotFoundHandler(), ""
+	if e == nil {
+		// We didn't find a match with the request method. To distinguish between
+		// Not Found and Method Not Allowed, see if there is another pattern that
+		// matches except for the method.
+		allowedMethods := mux.matchingMethods(host, path)
+		if len(allowedMethods) > 0 {
+			return HandlerFunc(func(w ResponseWriter, r *Request) {
+				w.Header().Set("Allow", strings.Join(allowedMethods, ", "))
+				Error(w, StatusText(StatusMethodNotAllowed), StatusMethodNotAllowed)
+			}), "", nil, nil
+		}
+		return NotFoundHandler(), "", nil, nil
 	}
-	return
+	return e.h, e.pat.String(), e.pat, matches
 }
 
 // ServeHTTP dispatches the request to the handler whose
@@ -2323,51 +2507,97 @@ func (mux *ServeMux) ServeHTTP(w ResponseWriter, r *Request) {
 		w.WriteHeader(StatusBadRequest)
 		return
 	}
-	h, _ := mux.Handler(r)
+	var h Handler
+	if muxGo110 {
+		h, _ = mux.mux110.findHandler(r)
+	} else {
+		h, _, r.pat, r.matches = mux.findHandler(r)
+	}
 	h.ServeHTTP(w, r)
 }
 
 // Handle registers the handler for the given pattern.
-// If a handler already exists for pattern, Handle panics.
+// If the given pattern conflicts with one that is already registered,
+// Handle panics.
 func (mux *ServeMux) Handle(pattern string, handler Handler) {
-	mux.mu.Lock()
-	defer mux.mu.Unlock()
-
-	if pattern == "" {
-		panic("http: invalid pattern")
-	}
-	if handler == nil {
-		panic("http: nil handler")
-	}
-	if _, exist := mux.m[pattern]; exist {
-		panic("http: multiple registrations for " + pattern)
-	}
-
-	if mux.m == nil {
-		mux.m = make(map[string]muxEntry)
-	}
-	mux.m[pattern] = muxEntry{h: handler, pattern: pattern}
-
-	if pattern[0] != '/' {
-		mux.hosts = true
-	}
+	mux.register(pattern, handler)
 }
 
 // HandleFunc registers the handler function for the given pattern.
+// If the given pattern conflicts with one that is already registered,
+// HandleFunc panics.
 func (mux *ServeMux) HandleFunc(pattern string, handler func(ResponseWriter, *Request)) {
-	mux.Handle(pattern, HandlerFunc(handler))
+	if handler == nil {
+		panic("http: nil handler")
+	}
+	mux.register(pattern, HandlerFunc(handler))
 }
 
 // Handle registers the handler for the given pattern
 // in the DefaultServeMux.
 // The documentation for ServeMux explains how patterns are matched.
-func Handle(pattern string, handler Handler) { DefaultServeMux.Handle(pattern, handler) }
+func Handle(pattern string, handler Handler) { DefaultServeMux.register(pattern, handler) }
 
 // HandleFunc registers the handler function for the given pattern
 // in the DefaultServeMux.
 // The documentation for ServeMux explains how patterns are matched.
 func HandleFunc(pattern string, handler func(ResponseWriter, *Request)) {
-	DefaultServeMux.HandleFunc(pattern, handler)
+	if handler == nil {
+		panic("http: nil handler")
+	}
+	DefaultServeMux.register(pattern, HandlerFunc(handler))
+}
+
+// register registers handler for pattern, panicking on any error.
+// It must be called directly by the exported registration functions,
+// so that the location of their caller can be recorded.
+func (mux *ServeMux) register(patstr string, handler Handler) {
+	if muxGo110 {
+		mux.mux110.handle(patstr, handler)
+		return
+	}
+	if err := mux.registerErr(patstr, handler); err != nil {
+		panic(err)
+	}
+}
+
+func (mux *ServeMux) registerErr(patstr string, handler Handler) error {
+	if patstr == "" {
+		return errors.New("http: invalid pattern")
+	}
+	if handler == nil {
+		return errors.New("http: nil handler")
+	}
+
+	pat, err := parsePattern(patstr)
+	if err != nil {
+		return fmt.Errorf("http: parsing %q: %v", patstr, err)
+	}
+
+	// Get the location of the caller of the exported function,
+	// for better conflict error messages.
+	if _, file, line, ok := runtime.Caller(3); ok {
+		pat.loc = fmt.Sprintf("%s:%d", file, line)
+	} else {
+		pat.loc = "unknown location"
+	}
+
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	for _, e := range mux.es {
+		if pat.conflictsWith(e.pat) {
+			return fmt.Errorf("http: pattern %q (registered at %s) conflicts with pattern %q (registered at %s):\n%s",
+				pat, pat.loc, e.pat, e.pat.loc, describeConflict(pat, e.pat))
+		}
+	}
+	e := &muxEntry{h: handler, pat: pat}
+	mux.es = append(mux.es, e)
+	if mux.index == nil {
+		mux.index = make(map[muxIndexKey][]*muxEntry)
+	}
+	k := indexKey(pat)
+	mux.index[k] = append(mux.index[k], e)
+	return nil
 }
 
 // Serve accepts incoming HTTP connections on the listener l,
@@ -2465,10 +2695,34 @@ type Server struct {
 	// If nil, logging is done via the log package's standard logger.
 	ErrorLog *log.Logger
 
+	// BaseContext optionally specifies a function that returns
+	// the base context for incoming requests on this server.
+	// The provided Listener is the specific Listener that's
+	// about to start accepting requests.
+	// If BaseContext is nil, the default is context.Background().
+	// If non-nil, it must return a non-nil context.
+	BaseContext func(net.Listener) context.Context
+
+	// ConnContext optionally specifies a function that modifies
+	// the context used for a new connection c. The provided ctx
+	// is derived from the base context and has a ServerContextKey
+	// value.
+	ConnContext func(ctx context.Context, c net.Conn) context.Context
+
+	// UnencryptedHTTP2, if true, enables HTTP/2 on connections that
+	// don't use TLS ("h2c"). A client may start HTTP/2 on such a
+	// connection by sending the HTTP/2 connection preface ("prior
+	// knowledge"), or by sending an HTTP/1.1 request without a body
+	// that asks to upgrade to "h2c". Other HTTP/1.x requests are
+	// served as usual.
+	UnencryptedHTTP2 bool
+
 	disableKeepAlives int32     // accessed atomically.
 	inShutdown        int32     // accessed atomically (non-zero means we're in Shutdown)
 	nextProtoOnce     sync.Once // guards setupHTTP2_* init
 	nextProtoErr      error     // result of http2.ConfigureServer if used
+	h2cOnce           sync.Once // guards h2c
+	h2c               *http2Server
 
 	mu         sync.Mutex
 	listeners  map[net.Listener]struct{}
@@ -2754,7 +3008,13 @@ func (srv *Server) Serve(l net.Listener) error {
 	srv.trackListener(l, true)
 	defer srv.trackListener(l, false)
 
-	baseCtx := context.Background() // base is always background, per Issue 16220
+	baseCtx := context.Background()
+	if srv.BaseContext != nil {
+		baseCtx = srv.BaseContext(l)
+		if baseCtx == nil {
+			panic("BaseContext returned a nil context")
+		}
+	}
 	ctx := context.WithValue(baseCtx, ServerContextKey, srv)
 	for {
 		rw, e := l.Accept()
@@ -2780,9 +3040,16 @@ func (srv *Server) Serve(l net.Listener) error {
 			return e
 		}
 		tempDelay = 0
+		connCtx := ctx
+		if cc := srv.ConnContext; cc != nil {
+			connCtx = cc(connCtx, rw)
+			if connCtx == nil {
+				panic("ConnContext returned nil")
+			}
+		}
 		c := srv.newConn(rw)
 		c.setState(c.rwc, StateNew) // before Serve can return
-		go c.serve(ctx)
+		go c.serve(connCtx)
 	}
 }
 
@@ -3232,10 +3499,17 @@ func (globalOptionsHandler) ServeHTTP(w ResponseWriter, r *Request) {
 // uninitialized fields in its *Request. Such partially-initialized
 // Requests come from NPN protocol handlers.
 type initNPNRequest struct {
-	c *tls.Conn
-	h serverHandler
+	ctx context.Context
+	c   *tls.Conn
+	h   serverHandler
 }
 
+// BaseContext is an exported but unadvertised http.Handler method
+// recognized by x/net/http2 to pass down a context; the TLSNextProto
+// API predates context support so we shoehorn through the only
+// interface we have available.
+func (h initNPNRequest) BaseContext() context.Context { return h.ctx }
+
 func (h initNPNRequest) ServeHTTP(rw ResponseWriter, req *Request) {
 	if req.TLS == nil {
 		req.TLS = &tls.ConnectionState{}
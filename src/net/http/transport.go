@@ -95,6 +95,10 @@ type Transport struct {
 	altMu    sync.Mutex   // guards changing altProto only
 	altProto atomic.Value // of nil or map[string]RoundTripper, key is URI scheme
 
+	connCountMu          sync.Mutex
+	connPerHostCount     map[connectMethodKey]int
+	connPerHostAvailable map[connectMethodKey]chan struct{}
+
 	// Proxy specifies a function to return a proxy for a given
 	// Request. If the function returns a non-nil error, the
 	// request is aborted with the provided error.
@@ -162,6 +166,20 @@ type Transport struct {
 	// DefaultMaxIdleConnsPerHost is used.
 	MaxIdleConnsPerHost int
 
+	// MaxConnsPerHost optionally limits the total number of
+	// connections per host, including connections in the dialing,
+	// active, and idle states. On limit violation, dials will block
+	// until a connection to the host is closed, an idle connection
+	// becomes available, or the request is canceled.
+	//
+	// Zero means no limit.
+	//
+	// For HTTP/2, this currently only controls the number of new
+	// connections being created at a time, instead of the total
+	// number. In practice, hosts using HTTP/2 only have about one
+	// idle connection, though.
+	MaxConnsPerHost int
+
 	// IdleConnTimeout is the maximum amount of time an idle
 	// (keep-alive) connection will remain idle before closing
 	// itself.
@@ -195,6 +213,14 @@ type Transport struct {
 	// automatically.
 	TLSNextProto map[string]func(authority string, c *tls.Conn) RoundTripper
 
+	// UnencryptedHTTP2, if true, makes the Transport send requests
+	// for "http" URLs using HTTP/2 over cleartext TCP ("h2c"), with
+	// prior knowledge that the server supports it. Such servers
+	// include an http.Server with UnencryptedHTTP2 set. Requests
+	// sent through an HTTP proxy or asking for a protocol upgrade,
+	// such as WebSocket handshakes, still use HTTP/1.1.
+	UnencryptedHTTP2 bool
+
 	// ProxyConnectHeader optionally specifies headers to send to
 	// proxies during CONNECT requests.
 	ProxyConnectHeader Header
@@ -210,6 +236,7 @@ type Transport struct {
 	// h2transport (via onceSetNextProtoDefaults)
 	nextProtoOnce sync.Once
 	h2transport   *http2Transport // non-nil if http2 wired up
+	h2c           *h2cTransport   // non-nil if UnencryptedHTTP2
 
 	// TODO: tunable on max per-host TCP dials in flight (Issue 13957)
 }
@@ -217,6 +244,9 @@ type Transport struct {
 // onceSetNextProtoDefaults initializes TLSNextProto.
 // It must be called via t.nextProtoOnce.Do.
 func (t *Transport) onceSetNextProtoDefaults() {
+	if t.UnencryptedHTTP2 {
+		t.h2c = newH2CTransport(t)
+	}
 	if strings.Contains(os.Getenv("GODEBUG"), "http2client=0") {
 		return
 	}
@@ -376,11 +406,16 @@ func (t *Transport) RoundTrip(req *Request) (*Response, error) {
 	}
 
 	altProto, _ := t.altProto.Load().(map[string]RoundTripper)
-	if altRT := altProto[scheme]; altRT != nil {
+	if altRT := altProto[scheme]; altRT != nil && t.useRegisteredProtocol(req) {
 		if resp, err := altRT.RoundTrip(req); err != ErrSkipAltProtocol {
 			return resp, err
 		}
 	}
+	if t.h2c != nil && scheme == "http" && t.useRegisteredProtocol(req) {
+		if resp, err := t.h2c.RoundTrip(req); err != ErrSkipAltProtocol {
+			return resp, err
+		}
+	}
 	if !isHTTP {
 		req.closeBody()
 		return nil, &badStringError{"unsupported protocol scheme", scheme}
@@ -547,6 +582,9 @@ func (t *Transport) CloseIdleConnections() {
 	if t2 := t.h2transport; t2 != nil {
 		t2.CloseIdleConnections()
 	}
+	if h2c := t.h2c; h2c != nil {
+		h2c.t2.CloseIdleConnections()
+	}
 }
 
 // CancelRequest cancels an in-flight request by closing its connection.
@@ -615,12 +653,26 @@ func (e *envOnce) reset() {
 	e.val = ""
 }
 
+// useRegisteredProtocol reports whether an alternate protocol (as registered
+// with Transport.RegisterProtocol) should be respected for this request.
+func (t *Transport) useRegisteredProtocol(req *Request) bool {
+	if req.URL.Scheme == "https" && req.requiresHTTP1() {
+		// If this request requires HTTP/1, don't use the
+		// "https" alternate protocol, which is used by the
+		// HTTP/2 code to take over requests if there's an
+		// existing cached HTTP/2 connection.
+		return false
+	}
+	return true
+}
+
 func (t *Transport) connectMethodForRequest(treq *transportRequest) (cm connectMethod, err error) {
 	if port := treq.URL.Port(); !validPort(port) {
 		return cm, fmt.Errorf("invalid URL port %q", port)
 	}
 	cm.targetScheme = treq.URL.Scheme
 	cm.targetAddr = canonicalAddr(treq.URL)
+	cm.onlyH1 = treq.requiresHTTP1()
 	if t.Proxy != nil {
 		cm.proxyURL, err = t.Proxy(treq.Request)
 		if err == nil && cm.proxyURL != nil {
@@ -657,6 +709,7 @@ var (
 	errReadLoopExiting    = errors.New("http: persistConn.readLoop exiting")
 	errIdleConnTimeout    = errors.New("http: idle connection timeout")
 	errNotCachingH2Conn   = errors.New("http: not caching alternate protocol's connections")
+	errCallerOwnsConn     = errors.New("read loop ending; caller owns writable underlying conn")
 
 	// errServerClosedIdle is not seen by users for idempotent requests, but may be
 	// seen by a user if the server shuts down an idle connection and sends its FIN
@@ -907,6 +960,50 @@ func (t *Transport) dial(ctx context.Context, network, addr string) (net.Conn, e
 	return zeroDialer.DialContext(ctx, network, addr)
 }
 
+// incHostConnCount increments the number of connections to the host
+// identified by cmKey and returns nil if it is below MaxConnsPerHost.
+// Otherwise it leaves the count unchanged and returns a channel that
+// is closed when a connection to the host goes away.
+func (t *Transport) incHostConnCount(cmKey connectMethodKey) <-chan struct{} {
+	t.connCountMu.Lock()
+	defer t.connCountMu.Unlock()
+	if t.connPerHostCount[cmKey] < t.MaxConnsPerHost {
+		if t.connPerHostCount == nil {
+			t.connPerHostCount = make(map[connectMethodKey]int)
+		}
+		t.connPerHostCount[cmKey]++
+		return nil
+	}
+	ch, ok := t.connPerHostAvailable[cmKey]
+	if !ok {
+		if t.connPerHostAvailable == nil {
+			t.connPerHostAvailable = make(map[connectMethodKey]chan struct{})
+		}
+		ch = make(chan struct{})
+		t.connPerHostAvailable[cmKey] = ch
+	}
+	return ch
+}
+
+// decHostConnCount decrements the number of connections to the
+// host identified by cmKey and wakes up any getConn calls waiting
+// for the count to drop below MaxConnsPerHost. It must only be
+// called for connections counted by incHostConnCount.
+func (t *Transport) decHostConnCount(cmKey connectMethodKey) {
+	t.connCountMu.Lock()
+	defer t.connCountMu.Unlock()
+	n := t.connPerHostCount[cmKey]
+	if n == 1 {
+		delete(t.connPerHostCount, cmKey)
+	} else {
+		t.connPerHostCount[cmKey] = n - 1
+	}
+	if ch, ok := t.connPerHostAvailable[cmKey]; ok {
+		close(ch)
+		delete(t.connPerHostAvailable, cmKey)
+	}
+}
+
 // getConn dials and creates a new persistConn to the target as
 // specified in the connectMethod. This includes doing a proxy CONNECT
 // and/or setting up TLS.  If this doesn't return an error, the persistConn
@@ -953,12 +1050,48 @@ func (t *Transport) getConn(treq *transportRequest, cm connectMethod) (*persistC
 	cancelc := make(chan error, 1)
 	t.setReqCanceler(req, func(err error) { cancelc <- err })
 
+	idleConnCh := t.getIdleConnCh(cm)
+	cmKey := cm.key()
+	counted := false // whether the dial below holds a MaxConnsPerHost slot
+	if t.MaxConnsPerHost > 0 {
+		for {
+			avail := t.incHostConnCount(cmKey)
+			if avail == nil {
+				// Count below the per-host limit; proceed to dial.
+				counted = true
+				break
+			}
+			select {
+			case <-avail:
+				// A connection to the host went away; try again.
+			case pc := <-idleConnCh:
+				if trace != nil && trace.GotConn != nil {
+					trace.GotConn(httptrace.GotConnInfo{Conn: pc.conn, Reused: pc.isReused()})
+				}
+				return pc, nil
+			case <-req.Cancel:
+				return nil, errRequestCanceledConn
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			case err := <-cancelc:
+				if err == errRequestCanceled {
+					err = errRequestCanceledConn
+				}
+				return nil, err
+			}
+		}
+	}
+
 	go func() {
-		pc, err := t.dialConn(ctx, cm)
+		pc, err := t.dialConn(ctx, cm, counted)
+		if counted && (err != nil || pc.alt != nil) {
+			// Failed dials don't hold a connection, and connections
+			// handed to an alternate protocol aren't tracked by us.
+			t.decHostConnCount(cmKey)
+		}
 		dialc <- dialRes{pc, err}
 	}()
 
-	idleConnCh := t.getIdleConnCh(cm)
 	select {
 	case v := <-dialc:
 		// Our dial finished.
@@ -1052,6 +1185,9 @@ func (pconn *persistConn) addTLS(name string, trace *httptrace.ClientTrace) erro
 	if cfg.ServerName == "" {
 		cfg.ServerName = name
 	}
+	if pconn.cacheKey.onlyH1 {
+		cfg.NextProtos = nil
+	}
 	plainConn := pconn.conn
 	tlsConn := tls.Client(plainConn, cfg)
 	errc := make(chan error, 2)
@@ -1093,10 +1229,11 @@ func (pconn *persistConn) addTLS(name string, trace *httptrace.ClientTrace) erro
 	return nil
 }
 
-func (t *Transport) dialConn(ctx context.Context, cm connectMethod) (*persistConn, error) {
+func (t *Transport) dialConn(ctx context.Context, cm connectMethod, counted bool) (*persistConn, error) {
 	pconn := &persistConn{
 		t:             t,
 		cacheKey:      cm.key(),
+		hostCounted:   counted,
 		reqch:         make(chan requestAndChan, 1),
 		writech:       make(chan writeRequest, 1),
 		closech:       make(chan struct{}),
@@ -1231,6 +1368,9 @@ func (t *Transport) dialConn(ctx context.Context, cm connectMethod) (*persistCon
 			return &persistConn{alt: next(cm.targetAddr, pconn.conn.(*tls.Conn))}, nil
 		}
 	}
+	if t.h2c != nil && cm.targetScheme == "http" && cm.proxyURL == nil && !cm.onlyH1 {
+		return &persistConn{alt: t.h2c.addConn(cm.targetAddr, pconn.conn)}, nil
+	}
 
 	pconn.br = bufio.NewReader(pconn)
 	pconn.bw = bufio.NewWriter(persistConnWriter{pconn})
@@ -1335,6 +1475,7 @@ type connectMethod struct {
 	// then targetAddr is not included in the connect method key, because the socket can
 	// be reused for different targetAddr values.
 	targetAddr string
+	onlyH1     bool // whether to disable HTTP/2 and force HTTP/1
 }
 
 func (cm *connectMethod) key() connectMethodKey {
@@ -1350,6 +1491,7 @@ func (cm *connectMethod) key() connectMethodKey {
 		proxy:  proxyStr,
 		scheme: cm.targetScheme,
 		addr:   targetAddr,
+		onlyH1: cm.onlyH1,
 	}
 }
 
@@ -1384,11 +1526,16 @@ func (cm *connectMethod) tlsHost() string {
 // a URL.
 type connectMethodKey struct {
 	proxy, scheme, addr string
+	onlyH1              bool
 }
 
 func (k connectMethodKey) String() string {
 	// Only used by tests.
-	return fmt.Sprintf("%s|%s|%s", k.proxy, k.scheme, k.addr)
+	var h1 string
+	if k.onlyH1 {
+		h1 = ",h1"
+	}
+	return fmt.Sprintf("%s|%s%s|%s", k.proxy, k.scheme, h1, k.addr)
 }
 
 // persistConn wraps a connection, usually a persistent one
@@ -1399,9 +1546,15 @@ type persistConn struct {
 	// If it's non-nil, the rest of the fields are unused.
 	alt RoundTripper
 
-	t         *Transport
-	cacheKey  connectMethodKey
-	conn      net.Conn
+	t        *Transport
+	cacheKey connectMethodKey
+	conn     net.Conn
+
+	// hostCounted reports whether the connection holds one of the
+	// MaxConnsPerHost slots of its host. It is guarded by
+	// t.connCountMu.
+	hostCounted bool
+
 	tlsState  *tls.ConnectionState
 	br        *bufio.Reader       // from conn
 	bw        *bufio.Writer       // to conn
@@ -1637,16 +1790,17 @@ func (pc *persistConn) readLoop() {
 		pc.numExpectedResponses--
 		pc.mu.Unlock()
 
+		bodyWritable := resp.bodyIsWritable()
 		hasBody := rc.req.Method != "HEAD" && resp.ContentLength != 0
 
-		if resp.Close || rc.req.Close || resp.StatusCode <= 199 {
+		if resp.Close || rc.req.Close || resp.StatusCode <= 199 || bodyWritable {
 			// Don't do keep-alive on error if either party requested a close
 			// or we get an unexpected informational (1xx) response.
 			// StatusCode 100 is already handled above.
 			alive = false
 		}
 
-		if !hasBody {
+		if !hasBody || bodyWritable {
 			pc.t.setReqCanceler(rc.req, nil)
 
 			// Put the idle conn back into the pool before we send the response
@@ -1662,6 +1816,11 @@ func (pc *persistConn) readLoop() {
 
 			select {
 			case rc.ch <- responseAndError{res: resp}:
+				if bodyWritable {
+					// The caller now owns the connection; don't
+					// close it when this loop exits.
+					closeErr = errCallerOwnsConn
+				}
 			case <-rc.callerGone:
 				return
 			}
@@ -1785,10 +1944,57 @@ func (pc *persistConn) readResponse(rc requestAndChan, trace *httptrace.ClientTr
 			return
 		}
 	}
+	if resp.isProtocolSwitch() {
+		body := newReadWriteCloserBody(pc.br, pc.conn)
+		body.release = pc.releaseHostConn
+		resp.Body = body
+	}
+
 	resp.TLS = pc.tlsState
 	return
 }
 
+func newReadWriteCloserBody(br *bufio.Reader, rwc io.ReadWriteCloser) *readWriteCloserBody {
+	body := &readWriteCloserBody{ReadWriteCloser: rwc}
+	if br.Buffered() != 0 {
+		body.br = br
+	}
+	return body
+}
+
+// readWriteCloserBody is the Response.Body type used when we want to
+// give users write access to the Body through the underlying
+// connection (TCP, unless using custom dialers). This is then
+// the concrete type for a Response.Body on the 101 Switching
+// Protocols response, as used by WebSockets.
+type readWriteCloserBody struct {
+	br *bufio.Reader // used until empty
+	io.ReadWriteCloser
+	release func() // if non-nil, called on Close
+}
+
+func (b *readWriteCloserBody) Close() error {
+	err := b.ReadWriteCloser.Close()
+	if b.release != nil {
+		b.release()
+	}
+	return err
+}
+
+func (b *readWriteCloserBody) Read(p []byte) (n int, err error) {
+	if b.br != nil {
+		if n := b.br.Buffered(); len(p) > n {
+			p = p[:n]
+		}
+		n, err = b.br.Read(p)
+		if b.br.Buffered() == 0 {
+			b.br = nil
+		}
+		return n, err
+	}
+	return b.ReadWriteCloser.Read(p)
+}
+
 // waitForContinue returns the function to block until
 // any response, timeout or connection close. After any of them,
 // the function returns a bool which indicates if the body should be sent.
@@ -2109,6 +2315,19 @@ func (pc *persistConn) close(err error) {
 	pc.closeLocked(err)
 }
 
+// releaseHostConn gives up the connection's MaxConnsPerHost slot,
+// if it holds one. Only the first call has any effect.
+func (pc *persistConn) releaseHostConn() {
+	t := pc.t
+	t.connCountMu.Lock()
+	counted := pc.hostCounted
+	pc.hostCounted = false
+	t.connCountMu.Unlock()
+	if counted {
+		t.decHostConnCount(pc.cacheKey)
+	}
+}
+
 func (pc *persistConn) closeLocked(err error) {
 	if err == nil {
 		panic("nil error")
@@ -2125,7 +2344,12 @@ func (pc *persistConn) closeLocked(err error) {
 			// freelist for http2. That's done by the
 			// alternate protocol's RoundTripper.
 		} else {
-			pc.conn.Close()
+			if err != errCallerOwnsConn {
+				pc.conn.Close()
+				pc.releaseHostConn()
+			}
+			// Otherwise the slot is released when the caller
+			// closes the response body.
 			close(pc.closech)
 		}
 	}
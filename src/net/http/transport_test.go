@@ -393,6 +393,166 @@ func TestTransportReadToEndReusesConn(t *testing.T) {
 	}
 }
 
+func TestTransportMaxConnsPerHost(t *testing.T) {
+	defer afterTest(t)
+	var mu sync.Mutex
+	var open, maxOpen, dials int
+	ts := httptest.NewUnstartedServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		time.Sleep(5 * time.Millisecond)
+		io.WriteString(w, "ok")
+	}))
+	ts.Config.ConnState = func(c net.Conn, state ConnState) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch state {
+		case StateNew:
+			dials++
+			open++
+			if open > maxOpen {
+				maxOpen = open
+			}
+		case StateClosed, StateHijacked:
+			open--
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	c := ts.Client()
+	tr := c.Transport.(*Transport)
+	tr.MaxConnsPerHost = 1
+	defer tr.CloseIdleConnections()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := c.Get(ts.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := ioutil.ReadAll(resp.Body); err != nil {
+				t.Errorf("ReadAll: %v", err)
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if maxOpen != 1 {
+		t.Errorf("max open conns = %d; want 1", maxOpen)
+	}
+	if dials != 1 {
+		t.Errorf("dials = %d; want 1", dials)
+	}
+}
+
+func TestTransportMaxConnsPerHostContextCancel(t *testing.T) {
+	defer afterTest(t)
+	unblock := make(chan bool)
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		<-unblock
+	}))
+	defer ts.Close()
+
+	c := ts.Client()
+	tr := c.Transport.(*Transport)
+	tr.MaxConnsPerHost = 1
+	tr.DisableKeepAlives = true
+	defer tr.CloseIdleConnections()
+
+	// Occupy the only allowed connection.
+	errc := make(chan error, 1)
+	go func() {
+		resp, err := c.Get(ts.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		errc <- err
+	}()
+
+	// Wait for the first request to dial, then check that the second
+	// gives up when its context expires instead of dialing.
+	waitCondition(5*time.Second, 5*time.Millisecond, func() bool {
+		return tr.HostConnCountForTesting("|http|"+ts.Listener.Addr().String()) == 1
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := NewRequest("GET", ts.URL, nil)
+	_, err := c.Do(req.WithContext(ctx))
+	if err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Errorf("second request: got err %v; want %v", err, context.DeadlineExceeded)
+	}
+
+	close(unblock)
+	if err := <-errc; err != nil {
+		t.Errorf("first request: %v", err)
+	}
+
+	// With the first connection closed, a new request can dial.
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+// A connection switched to another protocol keeps its MaxConnsPerHost
+// slot until the caller closes the response body.
+func TestTransportMaxConnsPerHostUpgrade(t *testing.T) {
+	defer afterTest(t)
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		conn, brw, err := w.(Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: foo\r\n\r\n")
+		brw.Flush()
+		io.Copy(ioutil.Discard, brw)
+	}))
+	defer ts.Close()
+
+	c := ts.Client()
+	tr := c.Transport.(*Transport)
+	tr.MaxConnsPerHost = 1
+	defer tr.CloseIdleConnections()
+	key := "|http|" + ts.Listener.Addr().String()
+
+	req, _ := NewRequest("GET", ts.URL, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "foo")
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != StatusSwitchingProtocols {
+		t.Fatalf("status = %d; want 101", resp.StatusCode)
+	}
+	if _, ok := resp.Body.(io.Writer); !ok {
+		t.Fatalf("body of type %T is not writable", resp.Body)
+	}
+
+	// Give the read loop time to hand over the connection.
+	time.Sleep(50 * time.Millisecond)
+	if n := tr.HostConnCountForTesting(key); n != 1 {
+		t.Errorf("with the upgraded connection open, host conn count = %d; want 1", n)
+	}
+	resp.Body.Close()
+	if n := tr.HostConnCountForTesting(key); n != 0 {
+		t.Errorf("after closing the upgraded connection, host conn count = %d; want 0", n)
+	}
+	resp.Body.Close()
+	if n := tr.HostConnCountForTesting(key); n != 0 {
+		t.Errorf("after closing the body twice, host conn count = %d; want 0", n)
+	}
+}
+
 func TestTransportMaxPerHostIdleConns(t *testing.T) {
 	defer afterTest(t)
 	resch := make(chan string)
@@ -4365,3 +4525,54 @@ func TestNoBodyOnChunked304Response(t *testing.T) {
 		t.Errorf("Unexpected body on 304 response")
 	}
 }
+
+func TestTransportResponseBodyWritableOnProtocolSwitch(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	done := make(chan struct{})
+	defer close(done)
+	cst := newClientServerTest(t, h1Mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		conn, _, err := w.(Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		io.WriteString(conn, "HTTP/1.1 101 Switching Protocols Hi\r\nConnection: upgRADe\r\nUpgrade: foo\r\n\r\nSome buffered data\n")
+		bs := bufio.NewScanner(conn)
+		bs.Scan()
+		fmt.Fprintf(conn, "%s\n", strings.ToUpper(bs.Text()))
+		<-done
+	}))
+	defer cst.close()
+
+	req, _ := NewRequest("GET", cst.ts.URL, nil)
+	req.Header.Set("Upgrade", "foo")
+	req.Header.Set("Connection", "upgrade")
+	res, err := cst.c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 101 {
+		t.Fatalf("expected 101 switching protocols; got %v, %v", res.Status, res.Header)
+	}
+	rwc, ok := res.Body.(io.ReadWriteCloser)
+	if !ok {
+		t.Fatalf("expected a ReadWriteCloser; got a %T", res.Body)
+	}
+	defer rwc.Close()
+	bs := bufio.NewScanner(rwc)
+	if !bs.Scan() {
+		t.Fatalf("expected readable input")
+	}
+	if got, want := bs.Text(), "Some buffered data"; got != want {
+		t.Errorf("read %q; want %q", got, want)
+	}
+	io.WriteString(rwc, "echo\n")
+	if !bs.Scan() {
+		t.Fatalf("expected another line")
+	}
+	if got, want := bs.Text(), "ECHO"; got != want {
+		t.Errorf("read %q; want %q", got, want)
+	}
+}
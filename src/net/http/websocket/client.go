@@ -0,0 +1,157 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrBadHandshake is returned by Dial when the server's response
+// to the opening handshake is not a valid WebSocket handshake.
+var ErrBadHandshake = errors.New("websocket: bad handshake")
+
+// A Dialer contains options for connecting to a WebSocket server.
+// The zero value is a valid Dialer that uses http.DefaultClient.
+type Dialer struct {
+	// Client sends the opening handshake. If nil, http.DefaultClient
+	// is used. The connection is made by the Client's Transport,
+	// so its proxy, TLS and dialing configuration apply.
+	//
+	// The Transport must return a Response.Body that implements
+	// io.Writer for "101 Switching Protocols" responses, as
+	// http.Transport does, and the Client's Timeout must be zero.
+	Client *http.Client
+
+	// Header specifies additional request headers for the
+	// opening handshake, such as Origin or Cookie.
+	Header http.Header
+
+	// Subprotocols lists the subprotocols requested from the
+	// server, in order of preference.
+	Subprotocols []string
+
+	// EnableCompression offers the permessage-deflate
+	// extension to the server.
+	EnableCompression bool
+}
+
+// Dial opens a WebSocket connection to urlStr using the zero Dialer.
+// See Dialer.Dial.
+func Dial(ctx context.Context, urlStr string) (*Conn, *http.Response, error) {
+	var d Dialer
+	return d.Dial(ctx, urlStr)
+}
+
+// Dial opens a WebSocket connection to urlStr, which must have the ws,
+// wss, http or https scheme. The context bounds the opening handshake;
+// once Dial returns, canceling it has no effect on the connection.
+//
+// Dial returns the server's handshake response, if it received one,
+// even on failure. If the response is not a valid handshake, the error
+// is ErrBadHandshake and the response Body holds the beginning of the
+// server's reply.
+func (d *Dialer) Dial(ctx context.Context, urlStr string) (*Conn, *http.Response, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	case "http", "https":
+	default:
+		return nil, nil, errors.New("websocket: unsupported URL scheme " + u.Scheme)
+	}
+	u.Fragment = ""
+
+	var k [16]byte
+	if _, err := io.ReadFull(rand.Reader, k[:]); err != nil {
+		return nil, nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(k[:])
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, vs := range d.Header {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if len(d.Subprotocols) > 0 {
+		req.Header.Set("Sec-WebSocket-Protocol", strings.Join(d.Subprotocols, ", "))
+	}
+	if d.EnableCompression {
+		req.Header.Set("Sec-WebSocket-Extensions", deflateParams)
+	}
+
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rwc, compress, ok := d.checkResponse(resp, key)
+	if !ok {
+		// Keep the beginning of the reply, for error reporting.
+		buf := make([]byte, 1024)
+		n, _ := io.ReadFull(resp.Body, buf)
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(buf[:n]))
+		return nil, resp, ErrBadHandshake
+	}
+	return newConn(rwc, nil, false, resp.Header.Get("Sec-WebSocket-Protocol"), compress), resp, nil
+}
+
+// checkResponse reports whether resp completes the opening handshake
+// started with key, and if so, returns the connection and whether
+// compression was negotiated.
+func (d *Dialer) checkResponse(resp *http.Response, key string) (rwc io.ReadWriteCloser, compress, ok bool) {
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		!headerContainsToken(resp.Header, "Upgrade", "websocket") ||
+		!headerContainsToken(resp.Header, "Connection", "upgrade") ||
+		resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		return nil, false, false
+	}
+	if p := resp.Header.Get("Sec-WebSocket-Protocol"); p != "" {
+		found := false
+		for _, q := range d.Subprotocols {
+			if p == q {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false, false
+		}
+	}
+	exts := parseExtensions(resp.Header)
+	if !d.EnableCompression && len(exts) > 0 {
+		return nil, false, false
+	}
+	compress, ok = checkDeflateResponse(exts)
+	if !ok {
+		return nil, false, false
+	}
+	rwc, ok = resp.Body.(io.ReadWriteCloser)
+	return rwc, compress, ok
+}
@@ -0,0 +1,165 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements the permessage-deflate extension (RFC 7692).
+// Both directions are negotiated without context takeover, so each
+// message is compressed independently of the previous ones.
+
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// flateTail is the end of a sync flush, which the sender of a
+// compressed message removes from its data (RFC 7692, section 7.2.1).
+var flateTail = []byte{0x00, 0x00, 0xff, 0xff}
+
+// flateReaderTail is appended to the data of a compressed message before
+// decompressing it: the removed flateTail, followed by an empty final
+// block so that the decompressor reports io.EOF at the end of the message.
+const flateReaderTail = "\x00\x00\xff\xff\x01\x00\x00\xff\xff"
+
+func newFlateReader(r io.Reader) io.Reader {
+	return flate.NewReader(io.MultiReader(r, strings.NewReader(flateReaderTail)))
+}
+
+func newFlateWriter() *flate.Writer {
+	// NewWriter only fails for invalid compression levels.
+	fw, _ := flate.NewWriter(nil, flate.BestSpeed)
+	return fw
+}
+
+// compressWriter compresses the payload of a message into a messageWriter.
+type compressWriter struct {
+	w  *messageWriter
+	fw *flate.Writer
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.w.closed {
+		return 0, errWriterClosed
+	}
+	return cw.fw.Write(p)
+}
+
+func (cw *compressWriter) Close() error {
+	w := cw.w
+	if w.closed {
+		return errWriterClosed
+	}
+	if err := cw.fw.Flush(); err != nil && w.err == nil {
+		w.err = err
+	}
+	// The message writer held back the end of the stream,
+	// which is now the tail of the sync flush.
+	if bytes.HasSuffix(w.buf, flateTail) {
+		w.buf = w.buf[:len(w.buf)-len(flateTail)]
+	}
+	return w.Close()
+}
+
+// deflateParams are the parameters with which permessage-deflate is
+// accepted by a server, and which a client asks for.
+const deflateParams = "permessage-deflate; server_no_context_takeover; client_no_context_takeover"
+
+// An extension is one element of a Sec-WebSocket-Extensions header.
+type extension struct {
+	name   string
+	params map[string]string
+}
+
+// parseExtensions parses the Sec-WebSocket-Extensions header values in h.
+// Parameters without a value map to the empty string.
+func parseExtensions(h http.Header) []extension {
+	var exts []extension
+	for _, v := range h["Sec-Websocket-Extensions"] {
+		for _, e := range strings.Split(v, ",") {
+			parts := strings.Split(e, ";")
+			ext := extension{name: strings.TrimSpace(parts[0])}
+			if ext.name == "" {
+				continue
+			}
+			for _, p := range parts[1:] {
+				k, v := strings.TrimSpace(p), ""
+				if i := strings.IndexByte(k, '='); i >= 0 {
+					k, v = strings.TrimSpace(k[:i]), strings.TrimSpace(k[i+1:])
+					if uv, err := strconv.Unquote(v); err == nil {
+						v = uv
+					}
+				}
+				if ext.params == nil {
+					ext.params = make(map[string]string)
+				}
+				ext.params[k] = v
+			}
+			exts = append(exts, ext)
+		}
+	}
+	return exts
+}
+
+// acceptDeflate reports whether the server can accept one of the
+// permessage-deflate offers among the client's extensions.
+func acceptDeflate(offers []extension) bool {
+Offers:
+	for _, ext := range offers {
+		if ext.name != "permessage-deflate" {
+			continue
+		}
+		for k, v := range ext.params {
+			switch k {
+			case "client_no_context_takeover", "server_no_context_takeover", "client_max_window_bits":
+			case "server_max_window_bits":
+				// The compressor always uses the largest window.
+				if v != "15" {
+					continue Offers
+				}
+			default:
+				continue Offers
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// checkDeflateResponse reports whether the extensions the server
+// accepted are compatible with the client's permessage-deflate offer,
+// and whether compression was negotiated.
+func checkDeflateResponse(exts []extension) (compress, ok bool) {
+	switch len(exts) {
+	case 0:
+		return false, true
+	case 1:
+	default:
+		return false, false
+	}
+	ext := exts[0]
+	if ext.name != "permessage-deflate" {
+		return false, false
+	}
+	if _, ok := ext.params["server_no_context_takeover"]; !ok {
+		// The client asked for it, so the server must agree.
+		return false, false
+	}
+	for k, v := range ext.params {
+		switch k {
+		case "server_no_context_takeover", "client_no_context_takeover":
+		case "server_max_window_bits":
+			// The decompressor handles any window size.
+			if n, err := strconv.Atoi(v); err != nil || n < 8 || n > 15 {
+				return false, false
+			}
+		default:
+			return false, false
+		}
+	}
+	return true, true
+}
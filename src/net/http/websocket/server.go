@@ -0,0 +1,178 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// An Upgrader upgrades HTTP requests to WebSocket connections.
+// The zero value is a valid Upgrader that accepts same-origin
+// requests without a subprotocol or compression.
+type Upgrader struct {
+	// Subprotocols lists the subprotocols supported by the server,
+	// in order of preference. The first one that the client also
+	// requested is selected.
+	Subprotocols []string
+
+	// CheckOrigin reports whether the request's Origin header is
+	// acceptable. If CheckOrigin is nil, requests with an Origin
+	// header whose host differs from the request's Host are rejected.
+	CheckOrigin func(r *http.Request) bool
+
+	// EnableCompression enables the permessage-deflate extension
+	// when the client offers it.
+	EnableCompression bool
+}
+
+// Upgrade performs the server side of the WebSocket opening handshake
+// for r and returns the resulting connection. The headers in
+// responseHeader, which may be nil, are added to the handshake response.
+//
+// If the request is not a valid WebSocket handshake, Upgrade replies
+// with an HTTP error and returns an error.
+//
+// Upgrade hijacks the HTTP connection, so the Server reports it to its
+// ConnState hook as StateHijacked, and the Server's read and write
+// timeouts no longer apply; use Conn.SetReadDeadline and
+// Conn.SetWriteDeadline instead.
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request, responseHeader http.Header) (*Conn, error) {
+	if r.Method != "GET" {
+		w.Header().Set("Allow", "GET")
+		return nil, handshakeError(w, http.StatusMethodNotAllowed, "request method is not GET")
+	}
+	if !headerContainsToken(r.Header, "Connection", "upgrade") {
+		return nil, handshakeError(w, http.StatusBadRequest, "'upgrade' token not found in 'Connection' header")
+	}
+	if !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		return nil, handshakeError(w, http.StatusBadRequest, "'websocket' token not found in 'Upgrade' header")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, handshakeError(w, http.StatusUpgradeRequired, "unsupported version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if b, err := base64.StdEncoding.DecodeString(key); err != nil || len(b) != 16 {
+		return nil, handshakeError(w, http.StatusBadRequest, "invalid 'Sec-WebSocket-Key' header")
+	}
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, handshakeError(w, http.StatusForbidden, "request origin not allowed")
+	}
+
+	subprotocol := u.selectSubprotocol(r)
+	compress := u.EnableCompression && acceptDeflate(parseExtensions(r.Header))
+
+	h, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, handshakeError(w, http.StatusInternalServerError, "response does not implement http.Hijacker")
+	}
+	nc, brw, err := h.Hijack()
+	if err != nil {
+		return nil, handshakeError(w, http.StatusInternalServerError, err.Error())
+	}
+	if brw.Reader.Buffered() > 0 {
+		// The client must wait for the handshake
+		// response before sending frames.
+		nc.Close()
+		return nil, errors.New("websocket: client sent data before handshake is complete")
+	}
+
+	bw := bufio.NewWriter(nc)
+	bw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: ")
+	bw.WriteString(acceptKey(key))
+	bw.WriteString("\r\n")
+	if subprotocol != "" {
+		bw.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	if compress {
+		bw.WriteString("Sec-WebSocket-Extensions: " + deflateParams + "\r\n")
+	}
+	if responseHeader != nil {
+		responseHeader.Write(bw)
+	}
+	bw.WriteString("\r\n")
+	if err := bw.Flush(); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return newConn(nc, brw.Reader, true, subprotocol, compress), nil
+}
+
+// selectSubprotocol returns the most preferred subprotocol
+// requested by the client, or the empty string.
+func (u *Upgrader) selectSubprotocol(r *http.Request) string {
+	requested := headerTokens(r.Header, "Sec-WebSocket-Protocol")
+	for _, p := range u.Subprotocols {
+		for _, q := range requested {
+			if p == q {
+				return p
+			}
+		}
+	}
+	return ""
+}
+
+func handshakeError(w http.ResponseWriter, code int, msg string) error {
+	http.Error(w, http.StatusText(code), code)
+	return errors.New("websocket: " + msg)
+}
+
+// sameOrigin reports whether the request has no Origin header,
+// or one whose host matches the request's Host.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header["Origin"]
+	if len(origin) == 0 {
+		return true
+	}
+	u, err := url.Parse(origin[0])
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// acceptKey returns the Sec-WebSocket-Accept value for the given
+// Sec-WebSocket-Key (RFC 6455, section 4.2.2).
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key))
+	h.Write([]byte("258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerTokens returns the comma-separated tokens of all the
+// values of the named header.
+func headerTokens(h http.Header, name string) []string {
+	var tokens []string
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tokens = append(tokens, t)
+			}
+		}
+	}
+	return tokens
+}
+
+// headerContainsToken reports whether the named header
+// contains token, compared case-insensitively.
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, t := range headerTokens(h, name) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
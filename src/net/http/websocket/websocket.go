@@ -0,0 +1,901 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package websocket implements the WebSocket protocol defined in RFC 6455,
+// including the permessage-deflate extension defined in RFC 7692.
+//
+// A server accepts WebSocket connections by calling Upgrader.Upgrade from
+// an HTTP handler. A client opens one with Dial or Dialer.Dial, which
+// send the opening handshake through an http.Client, so they use the
+// proxy, TLS and dialing configuration of its Transport.
+//
+// A Conn supports one concurrent reader and one concurrent message
+// writer. Ping, Close and CloseWithStatus may be called concurrently
+// with the other methods.
+package websocket
+
+import (
+	"bufio"
+	"compress/flate"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// MessageType is the type of a WebSocket data message.
+type MessageType int
+
+// The data message types defined in RFC 6455, section 5.6.
+const (
+	TextMessage   MessageType = 1 // UTF-8 encoded text
+	BinaryMessage MessageType = 2 // arbitrary binary data
+)
+
+func (t MessageType) String() string {
+	switch t {
+	case TextMessage:
+		return "text"
+	case BinaryMessage:
+		return "binary"
+	}
+	return "MessageType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// StatusCode is a status code sent in a close frame,
+// as defined in RFC 6455, section 7.4.
+type StatusCode int
+
+const (
+	StatusNormalClosure           StatusCode = 1000
+	StatusGoingAway               StatusCode = 1001
+	StatusProtocolError           StatusCode = 1002
+	StatusUnsupportedData         StatusCode = 1003
+	StatusNoStatusReceived        StatusCode = 1005 // never sent; no status code was present
+	StatusAbnormalClosure         StatusCode = 1006 // never sent; no close frame was received
+	StatusInvalidFramePayloadData StatusCode = 1007
+	StatusPolicyViolation         StatusCode = 1008
+	StatusMessageTooBig           StatusCode = 1009
+	StatusMandatoryExtension      StatusCode = 1010
+	StatusInternalError           StatusCode = 1011
+)
+
+// validSendCode reports whether code may be sent in a close frame.
+func validSendCode(code StatusCode) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011:
+		return true
+	case code >= 3000 && code <= 4999:
+		// Registered with IANA or reserved for private use.
+		return true
+	}
+	return false
+}
+
+// CloseError is returned by the read methods of a Conn after the peer
+// has sent a close frame.
+type CloseError struct {
+	Code   StatusCode
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	s := "websocket: close " + strconv.Itoa(int(e.Code))
+	if e.Reason != "" {
+		s += ": " + e.Reason
+	}
+	return s
+}
+
+var (
+	// ErrCloseSent is returned when writing a data message
+	// after a close frame has been sent.
+	ErrCloseSent = errors.New("websocket: close sent")
+
+	// ErrReadLimit is returned when reading a message
+	// that is larger than the read limit set for the Conn.
+	ErrReadLimit = errors.New("websocket: read limit exceeded")
+
+	errWriteInProgress = errors.New("websocket: previous message writer not closed")
+	errWriterClosed    = errors.New("websocket: write to closed message writer")
+	errNoDeadlines     = errors.New("websocket: underlying connection does not support deadlines")
+	errInvalidUTF8     = errors.New("websocket: invalid UTF-8 in text message")
+)
+
+// ProtocolError is returned when the peer violates the WebSocket protocol.
+// The connection is failed with StatusProtocolError.
+type ProtocolError struct {
+	ErrorString string
+}
+
+func (e *ProtocolError) Error() string { return "websocket: protocol error: " + e.ErrorString }
+
+// opcode is a frame opcode, as defined in RFC 6455, section 5.2.
+type opcode byte
+
+const (
+	opContinuation opcode = 0
+	opText         opcode = 1
+	opBinary       opcode = 2
+	opClose        opcode = 8
+	opPing         opcode = 9
+	opPong         opcode = 10
+)
+
+func (op opcode) isControl() bool { return op&8 != 0 }
+
+const (
+	finalBit = 0x80
+	rsv1Bit  = 0x40
+	rsv2Bit  = 0x20
+	rsv3Bit  = 0x10
+	maskBit  = 0x80
+
+	maxControlPayload = 125
+
+	// maxFrameHeaderSize is the size of the largest frame header:
+	// two bytes, an 8 byte extended length and a 4 byte mask key.
+	maxFrameHeaderSize = 2 + 8 + 4
+
+	// fragmentSize is the payload size at which a message writer
+	// returned by NextWriter sends a frame.
+	fragmentSize = 32 << 10
+
+	// closeTimeout bounds how long Close waits for the
+	// peer to answer a close frame.
+	closeTimeout = 5 * time.Second
+)
+
+// Conn is a WebSocket connection.
+type Conn struct {
+	rwc         io.ReadWriteCloser
+	isServer    bool
+	subprotocol string
+	compress    bool // permessage-deflate was negotiated
+
+	// readSem is held by the goroutine reading from br.
+	readSem chan struct{}
+
+	// Read state; guarded by readSem.
+	br            *bufio.Reader
+	readErr       error
+	readLimit     int64
+	readFrameLeft int64  // bytes left in the current data frame
+	readFinal     bool   // current data frame is the last of its message
+	readMask      []byte // mask key of the current data frame, or nil
+	readMaskPos   int
+	reader        *messageReader // current message reader, or nil
+	fragmented    bool           // inside a fragmented data message
+	pongHandler   func(data []byte)
+
+	closeReceived chan struct{} // closed when a close frame is read
+
+	// wmu serializes frame writes.
+	wmu       sync.Mutex
+	bw        *bufio.Writer
+	closeSent bool
+	wscratch  []byte // masked payload copy, for clients
+	writer    *messageWriter
+	flate     *flate.Writer // reused by compressed message writers
+}
+
+func newConn(rwc io.ReadWriteCloser, br *bufio.Reader, isServer bool, subprotocol string, compress bool) *Conn {
+	if br == nil {
+		br = bufio.NewReader(rwc)
+	}
+	return &Conn{
+		rwc:           rwc,
+		isServer:      isServer,
+		subprotocol:   subprotocol,
+		compress:      compress,
+		readSem:       make(chan struct{}, 1),
+		br:            br,
+		closeReceived: make(chan struct{}),
+		bw:            bufio.NewWriter(rwc),
+	}
+}
+
+// Subprotocol returns the subprotocol negotiated during the opening
+// handshake, or the empty string if none was.
+func (c *Conn) Subprotocol() string { return c.subprotocol }
+
+// LocalAddr returns the local network address, if known.
+func (c *Conn) LocalAddr() net.Addr {
+	if nc, ok := c.rwc.(interface{ LocalAddr() net.Addr }); ok {
+		return nc.LocalAddr()
+	}
+	return nil
+}
+
+// RemoteAddr returns the remote network address, if known.
+func (c *Conn) RemoteAddr() net.Addr {
+	if nc, ok := c.rwc.(interface{ RemoteAddr() net.Addr }); ok {
+		return nc.RemoteAddr()
+	}
+	return nil
+}
+
+// SetReadDeadline sets the deadline for future reads from the underlying
+// connection. After a read has timed out, the Conn is unusable.
+// A zero value for t means reads will not time out.
+// It returns an error if the underlying connection doesn't support deadlines.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	if nc, ok := c.rwc.(interface{ SetReadDeadline(time.Time) error }); ok {
+		return nc.SetReadDeadline(t)
+	}
+	return errNoDeadlines
+}
+
+// SetWriteDeadline sets the deadline for future writes to the underlying
+// connection. After a write has timed out, the Conn is unusable.
+// A zero value for t means writes will not time out.
+// It returns an error if the underlying connection doesn't support deadlines.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	if nc, ok := c.rwc.(interface{ SetWriteDeadline(time.Time) error }); ok {
+		return nc.SetWriteDeadline(t)
+	}
+	return errNoDeadlines
+}
+
+// SetReadLimit sets the maximum size in bytes of a message read from the
+// peer. If a message exceeds the limit, the connection is closed with
+// StatusMessageTooBig and the read returns ErrReadLimit.
+// A limit of zero or less means no limit, which is the default.
+func (c *Conn) SetReadLimit(n int64) {
+	c.readSem <- struct{}{}
+	c.readLimit = n
+	<-c.readSem
+}
+
+// SetPongHandler sets the function called, from the goroutine reading
+// from c, when a pong frame is received. The argument is the
+// pong's application data.
+func (c *Conn) SetPongHandler(h func(data []byte)) {
+	c.readSem <- struct{}{}
+	c.pongHandler = h
+	<-c.readSem
+}
+
+// Frame writing.
+
+// writeFrame writes a single frame, masking the payload for clients.
+func (c *Conn) writeFrame(op opcode, final, rsv1 bool, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if op == opClose {
+		c.closeSent = true
+	}
+
+	var hdr [maxFrameHeaderSize]byte
+	b0 := byte(op)
+	if final {
+		b0 |= finalBit
+	}
+	if rsv1 {
+		b0 |= rsv1Bit
+	}
+	hdr[0] = b0
+	n := 2
+	switch l := len(payload); {
+	case l <= 125:
+		hdr[1] = byte(l)
+	case l <= 0xffff:
+		hdr[1] = 126
+		binary.BigEndian.PutUint16(hdr[2:], uint16(l))
+		n += 2
+	default:
+		hdr[1] = 127
+		binary.BigEndian.PutUint64(hdr[2:], uint64(l))
+		n += 8
+	}
+	if !c.isServer {
+		// Clients must mask every frame with an unpredictable key
+		// (RFC 6455, section 5.3).
+		hdr[1] |= maskBit
+		key := hdr[n : n+4]
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return err
+		}
+		n += 4
+		if cap(c.wscratch) < len(payload) {
+			c.wscratch = make([]byte, len(payload))
+		}
+		masked := c.wscratch[:len(payload)]
+		copy(masked, payload)
+		maskBytes(key, 0, masked)
+		payload = masked
+	}
+	if _, err := c.bw.Write(hdr[:n]); err != nil {
+		return err
+	}
+	if _, err := c.bw.Write(payload); err != nil {
+		return err
+	}
+	return c.bw.Flush()
+}
+
+// maskBytes XORs b with key, starting at position pos in the key,
+// and returns the key position following b.
+func maskBytes(key []byte, pos int, b []byte) int {
+	for i := range b {
+		b[i] ^= key[pos&3]
+		pos++
+	}
+	return pos & 3
+}
+
+// Ping sends a ping frame with the given application data, which must be
+// at most 125 bytes long. The peer answers with a pong frame, which is
+// passed to the handler set with SetPongHandler.
+func (c *Conn) Ping(data []byte) error {
+	if len(data) > maxControlPayload {
+		return errors.New("websocket: ping data too long")
+	}
+	return c.writeFrame(opPing, true, false, data)
+}
+
+// writeClose sends a close frame with the given status.
+// StatusNoStatusReceived sends a close frame without a status.
+func (c *Conn) writeClose(code StatusCode, reason string) error {
+	var payload []byte
+	if code != StatusNoStatusReceived {
+		payload = make([]byte, 2, 2+len(reason))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		payload = append(payload, reason...)
+	}
+	return c.writeFrame(opClose, true, false, payload)
+}
+
+// Close performs the closing handshake with StatusNormalClosure
+// and closes the underlying connection.
+func (c *Conn) Close() error {
+	return c.CloseWithStatus(StatusNormalClosure, "")
+}
+
+// CloseWithStatus sends a close frame with the given status code and
+// reason, waits a few seconds for the peer to answer with its own close
+// frame, and closes the underlying connection. Data messages received
+// in the meantime are discarded unless another goroutine is reading them.
+func (c *Conn) CloseWithStatus(code StatusCode, reason string) error {
+	if !validSendCode(code) {
+		return errors.New("websocket: invalid close status code " + strconv.Itoa(int(code)))
+	}
+	if len(reason) > maxControlPayload-2 {
+		return errors.New("websocket: close reason too long")
+	}
+	err := c.writeClose(code, reason)
+	if err == ErrCloseSent {
+		err = nil
+	}
+	if err == nil {
+		c.waitClose()
+	}
+	if cerr := c.rwc.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// waitClose waits until the peer's close frame has been read, reading
+// frames itself when no other goroutine is.
+func (c *Conn) waitClose() {
+	timer := time.NewTimer(closeTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-c.closeReceived:
+			return
+		case <-timer.C:
+			return
+		case c.readSem <- struct{}{}:
+			done := make(chan struct{})
+			go func() {
+				select {
+				case <-timer.C:
+					// Unblock the read below.
+					c.rwc.Close()
+				case <-done:
+				}
+			}()
+			for c.readErr == nil {
+				if c.reader != nil {
+					c.discardMessage()
+					continue
+				}
+				c.nextFrame()
+			}
+			close(done)
+			<-c.readSem
+			return
+		}
+	}
+}
+
+// failConnection sends a close frame with code, records err as the
+// permanent read error and returns it. The caller must hold readSem.
+func (c *Conn) failConnection(code StatusCode, err error) error {
+	c.writeClose(code, "")
+	c.readErr = err
+	return err
+}
+
+// Frame reading. The following methods must be called with readSem held.
+
+// nextFrame reads frame headers, handling control frames, until it
+// finds the start of a data frame, and returns its opcode.
+func (c *Conn) nextFrame() (opcode, error) {
+	for c.readErr == nil {
+		op, err := c.readFrameHeader()
+		if err != nil {
+			c.readErr = err
+			break
+		}
+		if !op.isControl() {
+			return op, nil
+		}
+	}
+	return 0, c.readErr
+}
+
+// readFrameHeader reads one frame header. Control frames are handled
+// completely; for data frames, it sets up the per-frame read state.
+func (c *Conn) readFrameHeader() (opcode, error) {
+	var hdr [8]byte
+	if _, err := io.ReadFull(c.br, hdr[:2]); err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	final := hdr[0]&finalBit != 0
+	rsv1 := hdr[0]&rsv1Bit != 0
+	op := opcode(hdr[0] & 0xf)
+	masked := hdr[1]&maskBit != 0
+
+	if hdr[0]&(rsv2Bit|rsv3Bit) != 0 {
+		return 0, c.protocolError("reserved bits set")
+	}
+	switch op {
+	case opContinuation, opText, opBinary, opClose, opPing, opPong:
+	default:
+		return 0, c.protocolError("unknown opcode " + strconv.Itoa(int(op)))
+	}
+	if masked != c.isServer {
+		if c.isServer {
+			return 0, c.protocolError("unmasked client frame")
+		}
+		return 0, c.protocolError("masked server frame")
+	}
+
+	var n int64
+	switch l := hdr[1] &^ maskBit; l {
+	case 126:
+		if _, err := io.ReadFull(c.br, hdr[:2]); err != nil {
+			return 0, unexpectedEOF(err)
+		}
+		n = int64(binary.BigEndian.Uint16(hdr[:2]))
+	case 127:
+		if _, err := io.ReadFull(c.br, hdr[:8]); err != nil {
+			return 0, unexpectedEOF(err)
+		}
+		u := binary.BigEndian.Uint64(hdr[:8])
+		if u>>63 != 0 {
+			return 0, c.protocolError("invalid payload length")
+		}
+		n = int64(u)
+	default:
+		n = int64(l)
+	}
+	var key []byte
+	if masked {
+		key = make([]byte, 4)
+		if _, err := io.ReadFull(c.br, key); err != nil {
+			return 0, unexpectedEOF(err)
+		}
+	}
+
+	if op.isControl() {
+		if !final || n > maxControlPayload {
+			return 0, c.protocolError("invalid control frame")
+		}
+		if rsv1 {
+			return 0, c.protocolError("compressed control frame")
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(c.br, payload); err != nil {
+			return 0, unexpectedEOF(err)
+		}
+		if key != nil {
+			maskBytes(key, 0, payload)
+		}
+		return op, c.handleControl(op, payload)
+	}
+
+	switch {
+	case op == opContinuation && !c.fragmented:
+		return 0, c.protocolError("continuation frame outside of a fragmented message")
+	case op != opContinuation && c.fragmented:
+		return 0, c.protocolError("data frame inside a fragmented message")
+	case rsv1 && (op == opContinuation || !c.compress):
+		return 0, c.protocolError("unexpected compressed frame")
+	}
+	c.fragmented = !final
+	c.readFinal = final
+	c.readFrameLeft = n
+	c.readMask = key
+	c.readMaskPos = 0
+	if op != opContinuation {
+		c.reader = &messageReader{c: c, compressed: rsv1}
+	}
+	return op, nil
+}
+
+func (c *Conn) handleControl(op opcode, payload []byte) error {
+	switch op {
+	case opPing:
+		err := c.writeFrame(opPong, true, false, payload)
+		if err != nil && err != ErrCloseSent {
+			return err
+		}
+	case opPong:
+		if c.pongHandler != nil {
+			c.pongHandler(payload)
+		}
+	case opClose:
+		ce := &CloseError{Code: StatusNoStatusReceived}
+		switch len(payload) {
+		case 0:
+		case 1:
+			return c.protocolError("invalid close frame")
+		default:
+			ce.Code = StatusCode(binary.BigEndian.Uint16(payload))
+			ce.Reason = string(payload[2:])
+			if !validSendCode(ce.Code) {
+				return c.protocolError("invalid close status code")
+			}
+			if !utf8.ValidString(ce.Reason) {
+				return c.failConnection(StatusInvalidFramePayloadData, errInvalidUTF8)
+			}
+		}
+		// Echo the status back, completing the closing handshake
+		// (RFC 6455, section 5.5.1).
+		c.writeClose(ce.Code, "")
+		close(c.closeReceived)
+		return ce
+	}
+	return nil
+}
+
+func (c *Conn) protocolError(s string) error {
+	return c.failConnection(StatusProtocolError, &ProtocolError{s})
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// readPayload reads data frame payload bytes of the current message
+// into p, crossing into continuation frames as needed. It returns
+// io.EOF at the end of the message.
+func (c *Conn) readPayload(p []byte) (int, error) {
+	for c.readFrameLeft == 0 {
+		if c.readFinal {
+			return 0, io.EOF
+		}
+		if _, err := c.nextFrame(); err != nil {
+			return 0, err
+		}
+	}
+	if int64(len(p)) > c.readFrameLeft {
+		p = p[:c.readFrameLeft]
+	}
+	n, err := c.br.Read(p)
+	c.readFrameLeft -= int64(n)
+	if c.readMask != nil {
+		c.readMaskPos = maskBytes(c.readMask, c.readMaskPos, p[:n])
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		c.readErr = err
+	}
+	return n, err
+}
+
+// discardMessage reads and discards the rest of the current message.
+func (c *Conn) discardMessage() {
+	r := c.reader
+	c.reader = nil
+	if r.eof {
+		return
+	}
+	var buf [512]byte
+	for {
+		if _, err := c.readPayload(buf[:]); err != nil {
+			return
+		}
+	}
+}
+
+// NextReader returns the type of the next data message received from the
+// peer and a reader for its payload. Any unread part of the previous
+// message is discarded. Ping and pong frames are handled while reading.
+//
+// Once the peer has sent a close frame, NextReader returns a *CloseError.
+// Other errors are permanent too; once NextReader returns an error,
+// all later calls return the same error.
+func (c *Conn) NextReader() (MessageType, io.Reader, error) {
+	c.readSem <- struct{}{}
+	defer func() { <-c.readSem }()
+
+	if c.reader != nil {
+		c.discardMessage()
+	}
+	op, err := c.nextFrame()
+	if err != nil {
+		return 0, nil, err
+	}
+	r := c.reader
+	var rd io.Reader = r
+	if r.compressed {
+		rd = newFlateReader(r)
+	}
+	if c.readLimit > 0 {
+		// Limit the message after decompression, which
+		// protects against small but highly compressed messages.
+		rd = &limitedReader{r: rd, c: c, left: c.readLimit}
+	}
+	if op == opText {
+		return TextMessage, &utf8Reader{r: rd, c: c}, nil
+	}
+	return BinaryMessage, rd, nil
+}
+
+// ReadMessage reads the next data message from the peer.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	typ, r, err := c.NextReader()
+	if err != nil {
+		return 0, nil, err
+	}
+	p, err := ioutil.ReadAll(r)
+	return typ, p, err
+}
+
+// messageReader reads the (possibly compressed) payload of a message.
+type messageReader struct {
+	c          *Conn
+	compressed bool
+	eof        bool
+}
+
+func (r *messageReader) Read(p []byte) (int, error) {
+	c := r.c
+	c.readSem <- struct{}{}
+	defer func() { <-c.readSem }()
+
+	if c.reader != r {
+		return 0, io.EOF
+	}
+	if r.eof {
+		return 0, io.EOF
+	}
+	n, err := c.readPayload(p)
+	if err == io.EOF {
+		r.eof = true
+	}
+	return n, err
+}
+
+// limitedReader fails the connection if a message exceeds the read limit.
+type limitedReader struct {
+	r    io.Reader
+	c    *Conn
+	left int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.left -= int64(n)
+	if l.left < 0 {
+		c := l.c
+		c.readSem <- struct{}{}
+		err = c.failConnection(StatusMessageTooBig, ErrReadLimit)
+		<-c.readSem
+	}
+	return n, err
+}
+
+// utf8Reader checks that a text message is valid UTF-8.
+type utf8Reader struct {
+	r       io.Reader
+	c       *Conn
+	partial []byte // incomplete trailing rune from the previous read
+}
+
+func (u *utf8Reader) Read(p []byte) (int, error) {
+	n, err := u.r.Read(p)
+	if !u.valid(p[:n], err == io.EOF) {
+		c := u.c
+		c.readSem <- struct{}{}
+		err = c.failConnection(StatusInvalidFramePayloadData, errInvalidUTF8)
+		<-c.readSem
+	}
+	return n, err
+}
+
+// valid reports whether b, following the bytes seen so far, is valid UTF-8.
+func (u *utf8Reader) valid(b []byte, eof bool) bool {
+	if len(u.partial) > 0 {
+		b = append(u.partial, b...)
+		u.partial = nil
+	}
+	// Hold back an incomplete rune at the end of b.
+	cut := len(b)
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	if !utf8.Valid(b[:cut]) {
+		return false
+	}
+	if cut < len(b) {
+		if eof {
+			return false
+		}
+		u.partial = append([]byte(nil), b[cut:]...)
+	}
+	return true
+}
+
+// NextWriter returns a writer for the next data message of the given type.
+// The message is sent in fragments as the writer's buffer fills up and
+// completed when the writer is closed. The writer must be closed before
+// the next message is written.
+func (c *Conn) NextWriter(typ MessageType) (io.WriteCloser, error) {
+	var op opcode
+	switch typ {
+	case TextMessage:
+		op = opText
+	case BinaryMessage:
+		op = opBinary
+	default:
+		return nil, errors.New("websocket: invalid message type " + typ.String())
+	}
+	w := &messageWriter{c: c, op: op, compress: c.compress}
+	c.wmu.Lock()
+	closeSent, busy := c.closeSent, c.writer != nil
+	if !closeSent && !busy {
+		c.writer = w
+	}
+	c.wmu.Unlock()
+	if closeSent {
+		return nil, ErrCloseSent
+	}
+	if busy {
+		return nil, errWriteInProgress
+	}
+	if w.compress {
+		if c.flate == nil {
+			c.flate = newFlateWriter()
+		}
+		c.flate.Reset(w)
+		return &compressWriter{w: w, fw: c.flate}, nil
+	}
+	return w, nil
+}
+
+// WriteMessage writes a complete data message of the given type.
+func (c *Conn) WriteMessage(typ MessageType, data []byte) error {
+	if !c.compress && (typ == TextMessage || typ == BinaryMessage) {
+		// Fast path: send the message in a single frame
+		// without copying it into a message writer.
+		c.wmu.Lock()
+		busy := c.writer != nil
+		c.wmu.Unlock()
+		if busy {
+			return errWriteInProgress
+		}
+		return c.writeFrame(opcode(typ), true, false, data)
+	}
+	w, err := c.NextWriter(typ)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// messageWriter buffers the payload of a message and sends it in
+// fragments of at most fragmentSize bytes.
+type messageWriter struct {
+	c        *Conn
+	op       opcode // opcode of the next frame
+	compress bool
+	buf      []byte
+	err      error
+	closed   bool
+}
+
+func (w *messageWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errWriterClosed
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	n := len(p)
+	for len(p) > 0 {
+		if len(w.buf) >= fragmentSize {
+			if err := w.flushFrame(false); err != nil {
+				return n - len(p), err
+			}
+		}
+		if w.buf == nil {
+			w.buf = make([]byte, 0, fragmentSize)
+		}
+		m := cap(w.buf) - len(w.buf)
+		if m > len(p) {
+			m = len(p)
+		}
+		w.buf = append(w.buf, p[:m]...)
+		p = p[m:]
+	}
+	return n, nil
+}
+
+// flushFrame sends the buffered payload as a frame.
+func (w *messageWriter) flushFrame(final bool) error {
+	payload := w.buf
+	var tail []byte
+	if w.compress && !final {
+		// Hold back the last bytes of the compressed stream, in case
+		// they are the end of a sync flush marker, which the final
+		// frame must not include (RFC 7692, section 7.2.1).
+		if len(payload) <= len(flateTail) {
+			return nil
+		}
+		payload, tail = payload[:len(payload)-len(flateTail)], payload[len(payload)-len(flateTail):]
+	}
+	// Only the first frame of a compressed message has RSV1 set.
+	rsv1 := w.compress && w.op != opContinuation
+	err := w.c.writeFrame(w.op, final, rsv1, payload)
+	w.op = opContinuation
+	w.buf = append(w.buf[:0], tail...)
+	if err != nil {
+		w.err = err
+	}
+	return err
+}
+
+func (w *messageWriter) Close() error {
+	if w.closed {
+		return errWriterClosed
+	}
+	w.closed = true
+	c := w.c
+	c.wmu.Lock()
+	c.writer = nil
+	c.wmu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	return w.flushFrame(true)
+}
@@ -0,0 +1,464 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// echoHandler echoes every message it receives until the client closes
+// the connection.
+func echoHandler(t *testing.T, u *Upgrader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := u.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade: %v", err)
+			return
+		}
+		defer c.Close()
+		for {
+			typ, r, err := c.NextReader()
+			if err != nil {
+				if _, ok := err.(*CloseError); !ok {
+					t.Errorf("server NextReader: %v", err)
+				}
+				return
+			}
+			w, err := c.NextWriter(typ)
+			if err != nil {
+				t.Errorf("server NextWriter: %v", err)
+				return
+			}
+			if _, err := io.Copy(w, r); err != nil {
+				t.Errorf("server Copy: %v", err)
+				return
+			}
+			if err := w.Close(); err != nil {
+				t.Errorf("server writer Close: %v", err)
+				return
+			}
+		}
+	})
+}
+
+func wsURL(ts *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(ts.URL, "http")
+}
+
+func TestEcho(t *testing.T) {
+	big := bytes.Repeat([]byte("0123456789abcdef"), 20000) // several fragments
+	for _, compress := range []bool{false, true} {
+		ts := httptest.NewServer(echoHandler(t, &Upgrader{EnableCompression: true}))
+		d := &Dialer{EnableCompression: compress}
+		c, _, err := d.Dial(context.Background(), wsURL(ts))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.compress != compress {
+			t.Errorf("compress = %v; want %v", c.compress, compress)
+		}
+		for _, m := range []struct {
+			typ  MessageType
+			data []byte
+		}{
+			{TextMessage, []byte("hello, world")},
+			{BinaryMessage, []byte{0, 1, 2, 3, 0xff}},
+			{TextMessage, nil},
+			{BinaryMessage, big},
+		} {
+			if err := c.WriteMessage(m.typ, m.data); err != nil {
+				t.Fatalf("compress=%v: WriteMessage: %v", compress, err)
+			}
+			typ, data, err := c.ReadMessage()
+			if err != nil {
+				t.Fatalf("compress=%v: ReadMessage: %v", compress, err)
+			}
+			if typ != m.typ || !bytes.Equal(data, m.data) {
+				t.Errorf("compress=%v: got %v message of %d bytes; want %v message of %d bytes",
+					compress, typ, len(data), m.typ, len(m.data))
+			}
+		}
+
+		// Write a message in pieces, so that it's fragmented.
+		w, err := c.NextWriter(TextMessage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 100; i++ {
+			w.Write(big[:1000])
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, data, err := c.ReadMessage(); err != nil || len(data) != 100*1000 {
+			t.Errorf("compress=%v: fragmented message: got %d bytes, %v; want %d bytes", compress, len(data), err, 100*1000)
+		}
+
+		if err := c.Close(); err != nil {
+			t.Errorf("Close: %v", err)
+		}
+		ts.Close()
+	}
+}
+
+func TestSubprotocol(t *testing.T) {
+	u := &Upgrader{Subprotocols: []string{"v2.example", "v1.example"}}
+	ts := httptest.NewServer(echoHandler(t, u))
+	defer ts.Close()
+
+	d := &Dialer{Subprotocols: []string{"v1.example", "v2.example"}}
+	c, resp, err := d.Dial(context.Background(), wsURL(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if got, want := c.Subprotocol(), "v2.example"; got != want {
+		t.Errorf("Subprotocol = %q; want %q", got, want)
+	}
+	if got, want := resp.Header.Get("Sec-WebSocket-Protocol"), "v2.example"; got != want {
+		t.Errorf("Sec-WebSocket-Protocol = %q; want %q", got, want)
+	}
+}
+
+func TestHandshakeErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var u Upgrader
+		if _, err := u.Upgrade(w, r, nil); err == nil {
+			t.Errorf("Upgrade succeeded for %v", r.Header)
+		}
+	}))
+	defer ts.Close()
+
+	valid := func() http.Header {
+		return http.Header{
+			"Connection":            {"keep-alive, Upgrade"},
+			"Upgrade":               {"websocket"},
+			"Sec-Websocket-Version": {"13"},
+			"Sec-Websocket-Key":     {"dGhlIHNhbXBsZSBub25jZQ=="},
+		}
+	}
+	for _, tt := range []struct {
+		name   string
+		method string
+		edit   func(http.Header)
+		code   int
+	}{
+		{"method", "POST", func(h http.Header) {}, http.StatusMethodNotAllowed},
+		{"connection", "GET", func(h http.Header) { h.Del("Connection") }, http.StatusBadRequest},
+		{"upgrade", "GET", func(h http.Header) { h.Set("Upgrade", "h2c") }, http.StatusBadRequest},
+		{"version", "GET", func(h http.Header) { h.Set("Sec-Websocket-Version", "8") }, http.StatusUpgradeRequired},
+		{"key", "GET", func(h http.Header) { h.Set("Sec-Websocket-Key", "c2hvcnQ=") }, http.StatusBadRequest},
+		{"origin", "GET", func(h http.Header) { h.Set("Origin", "http://evil.example") }, http.StatusForbidden},
+	} {
+		req, _ := http.NewRequest(tt.method, ts.URL, nil)
+		req.Header = valid()
+		tt.edit(req.Header)
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.code {
+			t.Errorf("%s: status = %d; want %d", tt.name, resp.StatusCode, tt.code)
+		}
+		if tt.code == http.StatusUpgradeRequired && resp.Header.Get("Sec-Websocket-Version") != "13" {
+			t.Errorf("%s: Sec-WebSocket-Version = %q; want 13", tt.name, resp.Header.Get("Sec-Websocket-Version"))
+		}
+	}
+
+	// A Dialer reports a non-WebSocket server as a bad handshake.
+	ts2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no websockets here", http.StatusNotFound)
+	}))
+	defer ts2.Close()
+	_, resp, err := Dial(context.Background(), wsURL(ts2))
+	if err != ErrBadHandshake {
+		t.Fatalf("Dial err = %v; want ErrBadHandshake", err)
+	}
+	if body, _ := ioutil.ReadAll(resp.Body); resp.StatusCode != 404 || !bytes.Contains(body, []byte("no websockets here")) {
+		t.Errorf("Dial response = %d %q; want 404 with error body", resp.StatusCode, body)
+	}
+}
+
+func TestServerTimeoutsAndConnState(t *testing.T) {
+	var mu sync.Mutex
+	var states []http.ConnState
+	ts := httptest.NewUnstartedServer(echoHandler(t, &Upgrader{}))
+	ts.Config.ReadTimeout = 50 * time.Millisecond
+	ts.Config.WriteTimeout = 50 * time.Millisecond
+	ts.Config.ConnState = func(c net.Conn, s http.ConnState) {
+		mu.Lock()
+		states = append(states, s)
+		mu.Unlock()
+	}
+	ts.Start()
+	defer ts.Close()
+
+	c, _, err := Dial(context.Background(), wsURL(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// The Server's timeouts must not apply to the upgraded connection.
+	time.Sleep(100 * time.Millisecond)
+	if err := c.WriteMessage(TextMessage, []byte("still there?")); err != nil {
+		t.Fatal(err)
+	}
+	if _, data, err := c.ReadMessage(); err != nil || string(data) != "still there?" {
+		t.Fatalf("ReadMessage = %q, %v", data, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(states) == 0 || states[len(states)-1] != http.StateHijacked {
+		t.Errorf("conn states = %v; want last state %v", states, http.StateHijacked)
+	}
+}
+
+func TestTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(echoHandler(t, &Upgrader{}))
+	defer ts.Close()
+
+	d := &Dialer{Client: ts.Client()}
+	c, resp, err := d.Dial(context.Background(), "wss"+strings.TrimPrefix(ts.URL, "https"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if resp.TLS == nil {
+		t.Error("handshake response was not over TLS")
+	}
+	if err := c.WriteMessage(BinaryMessage, []byte("secret")); err != nil {
+		t.Fatal(err)
+	}
+	if _, data, err := c.ReadMessage(); err != nil || string(data) != "secret" {
+		t.Fatalf("ReadMessage = %q, %v", data, err)
+	}
+}
+
+func TestPingPong(t *testing.T) {
+	ts := httptest.NewServer(echoHandler(t, &Upgrader{}))
+	defer ts.Close()
+
+	c, _, err := Dial(context.Background(), wsURL(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	pong := make(chan string, 1)
+	c.SetPongHandler(func(data []byte) { pong <- string(data) })
+	if err := c.Ping([]byte("are you there")); err != nil {
+		t.Fatal(err)
+	}
+	// Pongs are handled while reading the next message.
+	if err := c.WriteMessage(TextMessage, []byte("msg")); err != nil {
+		t.Fatal(err)
+	}
+	if _, data, err := c.ReadMessage(); err != nil || string(data) != "msg" {
+		t.Fatalf("ReadMessage = %q, %v", data, err)
+	}
+	select {
+	case got := <-pong:
+		if got != "are you there" {
+			t.Errorf("pong data = %q", got)
+		}
+	default:
+		t.Error("no pong received")
+	}
+}
+
+func TestCloseHandshake(t *testing.T) {
+	done := make(chan error, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var u Upgrader
+		c, err := u.Upgrade(w, r, nil)
+		if err != nil {
+			done <- err
+			return
+		}
+		_, _, err = c.ReadMessage()
+		c.Close()
+		done <- err
+	}))
+	defer ts.Close()
+
+	c, _, err := Dial(context.Background(), wsURL(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CloseWithStatus(StatusGoingAway, "bye"); err != nil {
+		t.Errorf("CloseWithStatus: %v", err)
+	}
+	select {
+	case <-c.closeReceived:
+	default:
+		t.Error("client did not receive the server's close frame")
+	}
+	err = <-done
+	if ce, ok := err.(*CloseError); !ok || ce.Code != StatusGoingAway || ce.Reason != "bye" {
+		t.Errorf("server read error = %v; want close 1001 with reason", err)
+	}
+	if err := c.WriteMessage(TextMessage, []byte("late")); err != ErrCloseSent {
+		t.Errorf("WriteMessage after close = %v; want ErrCloseSent", err)
+	}
+}
+
+// rawFrame returns a frame with the given first header byte and payload,
+// masked as if sent by a client.
+func rawFrame(b0 byte, payload []byte) []byte {
+	f := []byte{b0}
+	switch n := len(payload); {
+	case n <= 125:
+		f = append(f, maskBit|byte(n))
+	default:
+		f = append(f, maskBit|126, 0, 0)
+		binary.BigEndian.PutUint16(f[2:], uint16(n))
+	}
+	key := []byte{1, 2, 3, 4}
+	f = append(f, key...)
+	p := append([]byte(nil), payload...)
+	maskBytes(key, 0, p)
+	return append(f, p...)
+}
+
+// newPipeConn returns a server Conn reading the given raw input, and a
+// reader for what it sends back.
+func newPipeConn(input []byte) (*Conn, *bufio.Reader) {
+	cc, sc := net.Pipe()
+	go func() {
+		cc.Write(input)
+	}()
+	return newConn(sc, nil, true, "", false), bufio.NewReader(cc)
+}
+
+func TestProtocolErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		input []byte
+		code  StatusCode
+	}{
+		{"unmasked", []byte{finalBit | byte(opText), 1, 'x'}, StatusProtocolError},
+		{"reserved bits", rawFrame(finalBit|rsv2Bit|byte(opText), []byte("x")), StatusProtocolError},
+		{"unnegotiated compression", rawFrame(finalBit|rsv1Bit|byte(opText), []byte("x")), StatusProtocolError},
+		{"unknown opcode", rawFrame(finalBit|3, nil), StatusProtocolError},
+		{"long control frame", rawFrame(finalBit|byte(opPing), make([]byte, 126)), StatusProtocolError},
+		{"fragmented control frame", rawFrame(byte(opPing), nil), StatusProtocolError},
+		{"bare continuation", rawFrame(finalBit|byte(opContinuation), []byte("x")), StatusProtocolError},
+		{"interleaved message", append(rawFrame(byte(opText), []byte("a")), rawFrame(finalBit|byte(opText), []byte("b"))...), StatusProtocolError},
+		{"invalid utf-8", rawFrame(finalBit|byte(opText), []byte("a\xffb")), StatusInvalidFramePayloadData},
+		{"truncated utf-8", rawFrame(finalBit|byte(opText), []byte("a\xe2\x82")), StatusInvalidFramePayloadData},
+	} {
+		c, out := newPipeConn(tt.input)
+		errc := make(chan error, 1)
+		go func() {
+			_, _, err := c.ReadMessage()
+			errc <- err
+		}()
+		// The Conn answers with a close frame carrying the status.
+		hdr := make([]byte, 4)
+		if _, err := io.ReadFull(out, hdr); err != nil {
+			t.Errorf("%s: reading close frame: %v", tt.name, err)
+			continue
+		}
+		if opcode(hdr[0]&0xf) != opClose || StatusCode(binary.BigEndian.Uint16(hdr[2:])) != tt.code {
+			t.Errorf("%s: sent frame % x; want close with status %d", tt.name, hdr, tt.code)
+		}
+		if err := <-errc; err == nil {
+			t.Errorf("%s: ReadMessage succeeded", tt.name)
+		}
+		c.rwc.Close()
+	}
+}
+
+func TestFragmentedMessageWithControlFrames(t *testing.T) {
+	var in []byte
+	in = append(in, rawFrame(byte(opText), []byte("hello, "))...)
+	in = append(in, rawFrame(finalBit|byte(opPing), []byte("p"))...)
+	in = append(in, rawFrame(byte(opContinuation), []byte("wor"))...)
+	in = append(in, rawFrame(finalBit|byte(opContinuation), []byte("ld"))...)
+	c, out := newPipeConn(in)
+	defer c.rwc.Close()
+
+	// Read the pong concurrently; net.Pipe is unbuffered.
+	pong := make(chan []byte, 1)
+	go func() {
+		f := make([]byte, 3)
+		io.ReadFull(out, f)
+		pong <- f
+	}()
+	typ, data, err := c.ReadMessage()
+	if err != nil || typ != TextMessage || string(data) != "hello, world" {
+		t.Fatalf("ReadMessage = %v, %q, %v; want text message %q", typ, data, err, "hello, world")
+	}
+	if f := <-pong; !bytes.Equal(f, []byte{finalBit | byte(opPong), 1, 'p'}) {
+		t.Errorf("pong frame = % x", f)
+	}
+}
+
+func TestReadLimit(t *testing.T) {
+	c, out := newPipeConn(rawFrame(finalBit|byte(opBinary), make([]byte, 200)))
+	defer c.rwc.Close()
+	go io.Copy(ioutil.Discard, out)
+	c.SetReadLimit(100)
+	if _, _, err := c.ReadMessage(); err != ErrReadLimit {
+		t.Errorf("ReadMessage err = %v; want ErrReadLimit", err)
+	}
+}
+
+func TestUTF8Reader(t *testing.T) {
+	// Split a valid string at every position, including
+	// inside multi-byte runes.
+	s := []byte("aé€𝄞z")
+	for i := 0; i <= len(s); i++ {
+		var u utf8Reader
+		if !u.valid(s[:i], false) || !u.valid(s[i:], true) {
+			t.Errorf("split at %d: reported invalid", i)
+		}
+	}
+	var u utf8Reader
+	if u.valid([]byte("a\xc3"), true) {
+		t.Error("truncated rune at EOF reported valid")
+	}
+}
+
+func TestParseExtensions(t *testing.T) {
+	h := http.Header{"Sec-Websocket-Extensions": {
+		`permessage-deflate; client_max_window_bits, permessage-deflate; server_max_window_bits="10"`,
+		"x-foo",
+	}}
+	exts := parseExtensions(h)
+	if len(exts) != 3 {
+		t.Fatalf("got %d extensions; want 3", len(exts))
+	}
+	if _, ok := exts[0].params["client_max_window_bits"]; !ok || exts[0].name != "permessage-deflate" {
+		t.Errorf("first extension = %+v", exts[0])
+	}
+	if exts[1].params["server_max_window_bits"] != "10" {
+		t.Errorf("second extension = %+v", exts[1])
+	}
+	if exts[2].name != "x-foo" {
+		t.Errorf("third extension = %+v", exts[2])
+	}
+	if acceptDeflate(exts[1:2]) {
+		t.Error("accepted a reduced server window")
+	}
+	if !acceptDeflate(exts) {
+		t.Error("rejected an acceptable offer")
+	}
+}
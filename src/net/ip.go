@@ -12,7 +12,10 @@
 
 package net
 
-import _ "unsafe" // for go:linkname
+import (
+	"net/netip"
+	_ "unsafe" // for go:linkname
+)
 
 // IP address lengths (bytes).
 const (
@@ -485,6 +488,41 @@ func (n *IPNet) Contains(ip IP) bool {
 // Network returns the address's network name, "ip+net".
 func (n *IPNet) Network() string { return "ip+net" }
 
+// Prefix returns n as a netip.Prefix. It reports false if n is nil,
+// if its mask is not in the canonical form, or if the lengths of its
+// IP address and mask do not match. An IPv4 network whose address is
+// stored in 16-byte form converts to an IPv4 prefix.
+func (n *IPNet) Prefix() (netip.Prefix, bool) {
+	if n == nil {
+		return netip.Prefix{}, false
+	}
+	ones, bits := n.Mask.Size()
+	if bits == 0 {
+		return netip.Prefix{}, false
+	}
+	ip, ok := netip.AddrFromSlice(n.IP)
+	if !ok {
+		return netip.Prefix{}, false
+	}
+	if bits == 8*IPv4len {
+		ip = ip.Unmap()
+	}
+	if ip.BitLen() != bits {
+		return netip.Prefix{}, false
+	}
+	return netip.PrefixFrom(ip, ones), true
+}
+
+// IPNetFromPrefix returns the IPNet for p, or nil if p is not valid.
+// The IP address is not masked; use p.Masked() for that.
+func IPNetFromPrefix(p netip.Prefix) *IPNet {
+	if !p.IsValid() {
+		return nil
+	}
+	ip := p.Addr()
+	return &IPNet{IP: ip.AsSlice(), Mask: CIDRMask(p.Bits(), ip.BitLen())}
+}
+
 // String returns the CIDR notation of n like "192.0.2.1/24"
 // or "2001:db8::/48" as defined in RFC 4632 and RFC 4291.
 // If the mask is not in the canonical form, it returns the
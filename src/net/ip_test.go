@@ -7,6 +7,7 @@ package net
 import (
 	"bytes"
 	"math/rand"
+	"net/netip"
 	"reflect"
 	"runtime"
 	"testing"
@@ -399,6 +400,40 @@ func TestIPNetString(t *testing.T) {
 	}
 }
 
+var ipNetPrefixTests = []struct {
+	in  *IPNet
+	out string // or "" if not convertible
+}{
+	{&IPNet{IP: IPv4(192, 168, 1, 0), Mask: CIDRMask(26, 32)}, "192.168.1.0/26"},
+	{&IPNet{IP: IP{192, 168, 1, 0}, Mask: CIDRMask(26, 32)}, "192.168.1.0/26"},
+	{&IPNet{IP: ParseIP("2001:db8::"), Mask: CIDRMask(55, 128)}, "2001:db8::/55"},
+	{&IPNet{IP: IPv4(192, 168, 1, 0), Mask: CIDRMask(120, 128)}, "::ffff:192.168.1.0/120"},
+	{&IPNet{IP: IP{192, 168, 1, 0}, Mask: CIDRMask(120, 128)}, ""},
+	{&IPNet{IP: ParseIP("2001:db8::"), Mask: CIDRMask(24, 32)}, ""},
+	{&IPNet{IP: IPv4(192, 168, 1, 0), Mask: IPv4Mask(255, 0, 255, 0)}, ""},
+	{nil, ""},
+}
+
+func TestIPNetPrefix(t *testing.T) {
+	for _, tt := range ipNetPrefixTests {
+		p, ok := tt.in.Prefix()
+		if ok != (tt.out != "") || ok && p.String() != tt.out {
+			t.Errorf("IPNet(%v).Prefix() = %v, %v, want %q", tt.in, p, ok, tt.out)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		n := IPNetFromPrefix(p)
+		if n.String() != tt.in.String() || !n.Contains(tt.in.IP) {
+			t.Errorf("IPNetFromPrefix(%v) = %v, want %v", p, n, tt.in)
+		}
+	}
+	if n := IPNetFromPrefix(netip.Prefix{}); n != nil {
+		t.Errorf("IPNetFromPrefix(zero Prefix) = %v, want nil", n)
+	}
+}
+
 var cidrMaskTests = []struct {
 	ones int
 	bits int
@@ -8,6 +8,7 @@ import (
 	"context"
 	"internal/nettrace"
 	"internal/singleflight"
+	"net/dnsmessage"
 	"sync"
 )
 
@@ -133,6 +134,24 @@ type Resolver struct {
 	// If nil, the default dialer is used.
 	Dial func(ctx context.Context, network, address string) (Conn, error)
 
+	// Exchange optionally specifies a function that Go's built-in
+	// DNS resolver uses, in place of Dial and the UDP and TCP
+	// transports of RFC 1035, to send each query to a DNS server and
+	// receive its response. It allows the resolver to use transports
+	// such as DNS over TLS (RFC 7858) or DNS over HTTPS (RFC 8484).
+	// The server parameter is the address of a name server from the
+	// system configuration, as a literal "host:port", which Exchange
+	// may ignore in favor of a server of its own. The query must not
+	// be modified. A response is used only if it is a response to the
+	// query, as reported by its IsResponseTo method, and is not
+	// retried if it is truncated.
+	Exchange func(ctx context.Context, server string, query *dnsmessage.Message) (*dnsmessage.Message, error)
+
+	// Cache optionally specifies a cache for the responses received
+	// by Go's built-in DNS resolver. If nil, every lookup queries
+	// the name servers.
+	Cache *DNSCache
+
 	// TODO(bradfitz): optional interface impl override hook
 	// TODO(bradfitz): Timeout time.Duration?
 }
@@ -361,6 +380,18 @@ func (r *Resolver) LookupTXT(ctx context.Context, name string) ([]string, error)
 	return r.lookupTXT(ctx, name)
 }
 
+// LookupRecords returns the DNS resource records of type typ for the
+// given domain name, such as *dnsmessage.CAA records for TypeCAA or
+// *dnsmessage.HTTPS records for TypeHTTPS. CNAME records leading to
+// them are followed, and records of types that package dnsmessage
+// does not know are returned as *dnsmessage.UnknownRR.
+//
+// LookupRecords always uses Go's built-in DNS resolver, and so is
+// not supported on Windows or Plan 9.
+func (r *Resolver) LookupRecords(ctx context.Context, name string, typ dnsmessage.Type) ([]dnsmessage.RR, error) {
+	return r.lookupRecords(ctx, name, typ)
+}
+
 // LookupAddr performs a reverse lookup for the given address, returning a list
 // of names mapping to that address.
 //
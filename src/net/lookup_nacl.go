@@ -8,6 +8,7 @@ package net
 
 import (
 	"context"
+	"net/dnsmessage"
 	"syscall"
 )
 
@@ -50,3 +51,7 @@ func (*Resolver) lookupTXT(ctx context.Context, name string) (txts []string, err
 func (*Resolver) lookupAddr(ctx context.Context, addr string) (ptrs []string, err error) {
 	return nil, syscall.ENOPROTOOPT
 }
+
+func (*Resolver) lookupRecords(ctx context.Context, name string, qtype dnsmessage.Type) ([]dnsmessage.RR, error) {
+	return nil, syscall.ENOPROTOOPT
+}
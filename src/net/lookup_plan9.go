@@ -8,7 +8,9 @@ import (
 	"context"
 	"errors"
 	"io"
+	"net/dnsmessage"
 	"os"
+	"syscall"
 )
 
 func query(ctx context.Context, filename, query string, bufSize int) (res []string, err error) {
@@ -305,3 +307,7 @@ func (*Resolver) lookupAddr(ctx context.Context, addr string) (name []string, er
 	}
 	return
 }
+
+func (*Resolver) lookupRecords(ctx context.Context, name string, qtype dnsmessage.Type) ([]dnsmessage.RR, error) {
+	return nil, &DNSError{Err: syscall.EPLAN9.Error(), Name: name}
+}
@@ -8,6 +8,7 @@ package net
 
 import (
 	"context"
+	"net/dnsmessage"
 	"sync"
 )
 
@@ -134,13 +135,13 @@ func (r *Resolver) lookupSRV(ctx context.Context, service, proto, name string) (
 	} else {
 		target = "_" + service + "._" + proto + "." + name
 	}
-	cname, rrs, err := r.lookup(ctx, target, dnsTypeSRV)
+	cname, rrs, err := r.lookup(ctx, target, dnsmessage.TypeSRV)
 	if err != nil {
 		return "", nil, err
 	}
 	srvs := make([]*SRV, len(rrs))
 	for i, rr := range rrs {
-		rr := rr.(*dnsRR_SRV)
+		rr := rr.(*dnsmessage.SRV)
 		srvs[i] = &SRV{Target: rr.Target, Port: rr.Port, Priority: rr.Priority, Weight: rr.Weight}
 	}
 	byPriorityWeight(srvs).sort()
@@ -148,43 +149,52 @@ func (r *Resolver) lookupSRV(ctx context.Context, service, proto, name string) (
 }
 
 func (r *Resolver) lookupMX(ctx context.Context, name string) ([]*MX, error) {
-	_, rrs, err := r.lookup(ctx, name, dnsTypeMX)
+	_, rrs, err := r.lookup(ctx, name, dnsmessage.TypeMX)
 	if err != nil {
 		return nil, err
 	}
 	mxs := make([]*MX, len(rrs))
 	for i, rr := range rrs {
-		rr := rr.(*dnsRR_MX)
-		mxs[i] = &MX{Host: rr.Mx, Pref: rr.Pref}
+		rr := rr.(*dnsmessage.MX)
+		mxs[i] = &MX{Host: rr.MX, Pref: rr.Pref}
 	}
 	byPref(mxs).sort()
 	return mxs, nil
 }
 
 func (r *Resolver) lookupNS(ctx context.Context, name string) ([]*NS, error) {
-	_, rrs, err := r.lookup(ctx, name, dnsTypeNS)
+	_, rrs, err := r.lookup(ctx, name, dnsmessage.TypeNS)
 	if err != nil {
 		return nil, err
 	}
 	nss := make([]*NS, len(rrs))
 	for i, rr := range rrs {
-		nss[i] = &NS{Host: rr.(*dnsRR_NS).Ns}
+		nss[i] = &NS{Host: rr.(*dnsmessage.NS).NS}
 	}
 	return nss, nil
 }
 
 func (r *Resolver) lookupTXT(ctx context.Context, name string) ([]string, error) {
-	_, rrs, err := r.lookup(ctx, name, dnsTypeTXT)
+	_, rrs, err := r.lookup(ctx, name, dnsmessage.TypeTXT)
 	if err != nil {
 		return nil, err
 	}
 	txts := make([]string, len(rrs))
 	for i, rr := range rrs {
-		txts[i] = rr.(*dnsRR_TXT).Txt
+		// A TXT record's character strings are
+		// reported as one string.
+		for _, s := range rr.(*dnsmessage.TXT).TXT {
+			txts[i] += s
+		}
 	}
 	return txts, nil
 }
 
+func (r *Resolver) lookupRecords(ctx context.Context, name string, qtype dnsmessage.Type) ([]dnsmessage.RR, error) {
+	_, rrs, err := r.lookup(ctx, name, qtype)
+	return rrs, err
+}
+
 func (r *Resolver) lookupAddr(ctx context.Context, addr string) ([]string, error) {
 	if !r.PreferGo && systemConf().canUseCgo() {
 		if ptrs, err, ok := cgoLookupPTR(ctx, addr); ok {
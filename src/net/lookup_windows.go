@@ -6,6 +6,7 @@ package net
 
 import (
 	"context"
+	"net/dnsmessage"
 	"os"
 	"runtime"
 	"syscall"
@@ -357,3 +358,7 @@ Cname:
 	}
 	return name
 }
+
+func (*Resolver) lookupRecords(ctx context.Context, name string, qtype dnsmessage.Type) ([]dnsmessage.RR, error) {
+	return nil, &DNSError{Err: syscall.EWINDOWS.Error(), Name: name}
+}
@@ -0,0 +1,495 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netip_test
+
+import (
+	"encoding/json"
+	. "net/netip"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+var parseAddrTests = []struct {
+	in   string
+	want Addr
+	str  string // String of want, if different from in
+}{
+	{in: "0.0.0.0", want: AddrFrom4([4]byte{})},
+	{in: "192.168.140.255", want: AddrFrom4([4]byte{192, 168, 140, 255})},
+	{in: "::", want: IPv6Unspecified()},
+	{in: "::1", want: AddrFrom16([16]byte{15: 1})},
+	{in: "fd7a:115c:a1e0:ab12:4843:cd96:626b:430b", want: AddrFrom16([16]byte{0xfd, 0x7a, 0x11, 0x5c, 0xa1, 0xe0, 0xab, 0x12, 0x48, 0x43, 0xcd, 0x96, 0x62, 0x6b, 0x43, 0x0b})},
+	{in: "FD9E:1A04:F01D::1", want: AddrFrom16([16]byte{0xfd, 0x9e, 0x1a, 0x04, 0xf0, 0x1d, 15: 1}), str: "fd9e:1a04:f01d::1"},
+	{in: "fe80::1cc0:3e8c:119f:c2e1%ens18", want: AddrFrom16([16]byte{0xfe, 0x80, 8: 0x1c, 0xc0, 0x3e, 0x8c, 0x11, 0x9f, 0xc2, 0xe1}).WithZone("ens18")},
+	{in: "::ffff:192.168.140.255", want: AddrFrom16([16]byte{10: 0xff, 0xff, 192, 168, 140, 255})},
+	{in: "::ffff:c0a8:8cff", want: AddrFrom16([16]byte{10: 0xff, 0xff, 192, 168, 140, 255}), str: "::ffff:192.168.140.255"},
+	{in: "1:0:0:1::", want: AddrFrom16([16]byte{1: 1, 7: 1}), str: "1:0:0:1::"},
+	{in: "1:0:0:0:1:0:0:0", want: AddrFrom16([16]byte{1: 1, 9: 1}), str: "1::1:0:0:0"},
+	{in: "2001:db8:0:0:1:0:0:1", want: AddrFrom16([16]byte{0x20, 0x01, 0x0d, 0xb8, 9: 1, 15: 1}), str: "2001:db8::1:0:0:1"},
+	{in: "2001:db8::1:0", want: AddrFrom16([16]byte{0x20, 0x01, 0x0d, 0xb8, 13: 1}), str: "2001:db8::1:0"},
+	{in: "2001:db8:1:1:1:1:1:0", want: AddrFrom16([16]byte{0x20, 0x01, 0x0d, 0xb8, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 0}), str: "2001:db8:1:1:1:1:1:0"},
+}
+
+var parseAddrErrorTests = []string{
+	"",
+	"bad",
+	"1.2.3",
+	"1.2.3.4.5",
+	"1.2.3.256",
+	"01.2.3.4",
+	"1..2.3",
+	"1.2.3.4%eth0",
+	"%eth0",
+	"::1%",
+	":::1",
+	"1:2:3:4:5:6:7:8:9",
+	"1:2:3:4:5:6:7",
+	"12345::",
+	"1::2::3",
+	"1:2:3:4:5:6:7:8::",
+	"::ffff:1.2.3.4.5",
+	"1:2:3:4:5:6:7:1.2.3.4",
+	"fe80::1cc0:3e8c:119f:c2e1:",
+	"[::1]",
+}
+
+func TestParseAddr(t *testing.T) {
+	for _, tt := range parseAddrTests {
+		got, err := ParseAddr(tt.in)
+		if err != nil {
+			t.Errorf("ParseAddr(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAddr(%q) = %#v; want %#v", tt.in, got, tt.want)
+		}
+		str := tt.str
+		if str == "" {
+			str = tt.in
+		}
+		if s := got.String(); s != str {
+			t.Errorf("ParseAddr(%q).String() = %q; want %q", tt.in, s, str)
+		}
+		if got2, err := ParseAddr(got.String()); err != nil || got2 != got {
+			t.Errorf("ParseAddr(%q) did not round trip: %v, %v", got.String(), got2, err)
+		}
+	}
+	for _, in := range parseAddrErrorTests {
+		if got, err := ParseAddr(in); err == nil {
+			t.Errorf("ParseAddr(%q) = %v; want error", in, got)
+		}
+	}
+}
+
+func TestAddrString(t *testing.T) {
+	tests := []struct {
+		ip   Addr
+		want string
+	}{
+		{Addr{}, "invalid IP"},
+		{IPv4Unspecified(), "0.0.0.0"},
+		{AddrFrom16([16]byte{}), "::"},
+		{AddrFrom16([16]byte{0x20, 0x01, 0x0d, 0xb8, 7: 1, 15: 1}), "2001:db8:0:1::1"},
+		{AddrFrom16([16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}).WithZone("eth0"), "2001:db8::1%eth0"},
+		{AddrFrom16([16]byte{10: 0xff, 0xff, 1, 2, 3, 4}).WithZone("eth0"), "::ffff:1.2.3.4%eth0"},
+	}
+	for _, tt := range tests {
+		if got := tt.ip.String(); got != tt.want {
+			t.Errorf("String() = %q; want %q", got, tt.want)
+		}
+	}
+}
+
+func TestAddrProperties(t *testing.T) {
+	tests := []struct {
+		ip                                   string
+		loopback, multicast, private, global bool
+		linkLocal, unspecified               bool
+	}{
+		{ip: "127.0.0.1", loopback: true},
+		{ip: "::1", loopback: true},
+		{ip: "::ffff:127.0.0.1", loopback: true},
+		{ip: "224.0.0.1", multicast: true},
+		{ip: "ff02::1", multicast: true},
+		{ip: "10.1.2.3", private: true, global: true},
+		{ip: "172.16.0.1", private: true, global: true},
+		{ip: "192.168.1.1", private: true, global: true},
+		{ip: "fd00::1", private: true, global: true},
+		{ip: "8.8.8.8", global: true},
+		{ip: "2001:4860:4860::8888", global: true},
+		{ip: "169.254.1.1", linkLocal: true},
+		{ip: "fe80::1%eth0", linkLocal: true},
+		{ip: "0.0.0.0", unspecified: true},
+		{ip: "::", unspecified: true},
+		{ip: "255.255.255.255"},
+	}
+	for _, tt := range tests {
+		ip := MustParseAddr(tt.ip)
+		if got := ip.IsLoopback(); got != tt.loopback {
+			t.Errorf("%s.IsLoopback() = %v", tt.ip, got)
+		}
+		if got := ip.IsMulticast(); got != tt.multicast {
+			t.Errorf("%s.IsMulticast() = %v", tt.ip, got)
+		}
+		if got := ip.IsPrivate(); got != tt.private {
+			t.Errorf("%s.IsPrivate() = %v", tt.ip, got)
+		}
+		if got := ip.IsGlobalUnicast(); got != tt.global {
+			t.Errorf("%s.IsGlobalUnicast() = %v", tt.ip, got)
+		}
+		if got := ip.IsLinkLocalUnicast(); got != tt.linkLocal {
+			t.Errorf("%s.IsLinkLocalUnicast() = %v", tt.ip, got)
+		}
+		if got := ip.IsUnspecified(); got != tt.unspecified {
+			t.Errorf("%s.IsUnspecified() = %v", tt.ip, got)
+		}
+	}
+	var zero Addr
+	if zero.IsValid() || zero.IsUnspecified() || zero.IsGlobalUnicast() || zero.IsLoopback() {
+		t.Error("zero Addr has properties of a valid address")
+	}
+}
+
+func TestAddrConversions(t *testing.T) {
+	v4 := MustParseAddr("1.2.3.4")
+	mapped := MustParseAddr("::ffff:1.2.3.4")
+	if !v4.Is4() || v4.Is6() || v4.Is4In6() || v4.BitLen() != 32 {
+		t.Errorf("%v: wrong address family", v4)
+	}
+	if mapped.Is4() || !mapped.Is6() || !mapped.Is4In6() || mapped.BitLen() != 128 {
+		t.Errorf("%v: wrong address family", mapped)
+	}
+	if v4 == mapped {
+		t.Errorf("%v == %v", v4, mapped)
+	}
+	if mapped.Unmap() != v4 {
+		t.Errorf("%v.Unmap() = %v; want %v", mapped, mapped.Unmap(), v4)
+	}
+	if mapped.As4() != v4.As4() || mapped.As16() != v4.As16() {
+		t.Errorf("%v and %v have different byte forms", v4, mapped)
+	}
+	if got := v4.AsSlice(); !reflect.DeepEqual(got, []byte{1, 2, 3, 4}) {
+		t.Errorf("AsSlice() = %v", got)
+	}
+	if got := (Addr{}).AsSlice(); got != nil {
+		t.Errorf("zero Addr AsSlice() = %v; want nil", got)
+	}
+	if got, ok := AddrFromSlice([]byte{1, 2, 3, 4}); !ok || got != v4 {
+		t.Errorf("AddrFromSlice(4 bytes) = %v, %v", got, ok)
+	}
+	if got, ok := AddrFromSlice(mapped.AsSlice()); !ok || got != mapped {
+		t.Errorf("AddrFromSlice(16 bytes) = %v, %v", got, ok)
+	}
+	if _, ok := AddrFromSlice([]byte{1, 2, 3}); ok {
+		t.Error("AddrFromSlice(3 bytes) succeeded")
+	}
+	if got := v4.WithZone("eth0"); got != v4 {
+		t.Errorf("IPv4 WithZone = %v; want no zone", got)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("As4 of an IPv6 address did not panic")
+			}
+		}()
+		MustParseAddr("::1").As4()
+	}()
+}
+
+func TestAddrNextPrev(t *testing.T) {
+	tests := []struct {
+		ip, next string
+	}{
+		{"0.0.0.0", "0.0.0.1"},
+		{"1.2.3.255", "1.2.4.0"},
+		{"::", "::1"},
+		{"::ffff", "::1:0"},
+		{"1::ffff:ffff:ffff:ffff", "1:0:0:1::"},
+	}
+	for _, tt := range tests {
+		ip, next := MustParseAddr(tt.ip), MustParseAddr(tt.next)
+		if got := ip.Next(); got != next {
+			t.Errorf("%v.Next() = %v; want %v", ip, got, next)
+		}
+		if got := next.Prev(); got != ip {
+			t.Errorf("%v.Prev() = %v; want %v", next, got, ip)
+		}
+	}
+	for _, ip := range []Addr{MustParseAddr("255.255.255.255"), MustParseAddr("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"), {}} {
+		if got := ip.Next(); got.IsValid() {
+			t.Errorf("%v.Next() = %v; want zero Addr", ip, got)
+		}
+	}
+	for _, ip := range []Addr{IPv4Unspecified(), IPv6Unspecified(), {}} {
+		if got := ip.Prev(); got.IsValid() {
+			t.Errorf("%v.Prev() = %v; want zero Addr", ip, got)
+		}
+	}
+}
+
+func TestAddrCompare(t *testing.T) {
+	values := []Addr{
+		{},
+		MustParseAddr("1.2.3.4"),
+		MustParseAddr("1.2.3.5"),
+		MustParseAddr("::1"),
+		MustParseAddr("::1%eth0"),
+		MustParseAddr("::ffff:1.2.3.4"),
+		MustParseAddr("fe80::1"),
+	}
+	for i, a := range values {
+		for j, b := range values {
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if got := a.Compare(b); got != want {
+				t.Errorf("Compare(%v, %v) = %d; want %d", a, b, got, want)
+			}
+			if got := a.Less(b); got != (want < 0) {
+				t.Errorf("Less(%v, %v) = %v", a, b, got)
+			}
+		}
+	}
+}
+
+func TestAddrMapKey(t *testing.T) {
+	m := map[Addr]int{}
+	for _, s := range []string{"1.2.3.4", "::ffff:1.2.3.4", "1.2.3.4", "::1", "::1%eth0", "::1"} {
+		m[MustParseAddr(s)]++
+	}
+	want := map[Addr]int{
+		MustParseAddr("1.2.3.4"):        2,
+		MustParseAddr("::ffff:1.2.3.4"): 1,
+		MustParseAddr("::1"):            2,
+		MustParseAddr("::1%eth0"):       1,
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("map = %v; want %v", m, want)
+	}
+}
+
+func TestParseAddrPort(t *testing.T) {
+	tests := []struct {
+		in   string
+		want AddrPort
+		ok   bool
+	}{
+		{"1.2.3.4:80", AddrPortFrom(MustParseAddr("1.2.3.4"), 80), true},
+		{"[::1]:53", AddrPortFrom(MustParseAddr("::1"), 53), true},
+		{"[fe80::1%eth0]:0", AddrPortFrom(MustParseAddr("fe80::1%eth0"), 0), true},
+		{"[::ffff:1.2.3.4]:65535", AddrPortFrom(MustParseAddr("::ffff:1.2.3.4"), 65535), true},
+		{"1.2.3.4", AddrPort{}, false},
+		{"1.2.3.4:", AddrPort{}, false},
+		{":80", AddrPort{}, false},
+		{"1.2.3.4:65536", AddrPort{}, false},
+		{"1.2.3.4:http", AddrPort{}, false},
+		{"::1:80", AddrPort{}, false},
+		{"[1.2.3.4]:80", AddrPort{}, false},
+		{"[::1:80", AddrPort{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseAddrPort(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseAddrPort(%q) = %v, %v; want %v, ok=%v", tt.in, got, err, tt.want, tt.ok)
+			continue
+		}
+		if tt.ok && got.String() != tt.in {
+			t.Errorf("ParseAddrPort(%q).String() = %q", tt.in, got.String())
+		}
+	}
+	if got := (AddrPort{}).String(); got != "invalid AddrPort" {
+		t.Errorf("zero AddrPort String() = %q", got)
+	}
+}
+
+func TestParsePrefix(t *testing.T) {
+	tests := []struct {
+		in     string
+		ip     Addr
+		bits   int
+		masked string
+		ok     bool
+	}{
+		{"192.168.1.0/24", MustParseAddr("192.168.1.0"), 24, "192.168.1.0/24", true},
+		{"192.168.1.77/24", MustParseAddr("192.168.1.77"), 24, "192.168.1.0/24", true},
+		{"0.0.0.0/0", IPv4Unspecified(), 0, "0.0.0.0/0", true},
+		{"1.2.3.4/32", MustParseAddr("1.2.3.4"), 32, "1.2.3.4/32", true},
+		{"2001:db8::1/32", MustParseAddr("2001:db8::1"), 32, "2001:db8::/32", true},
+		{"::ffff:10.0.0.1/104", MustParseAddr("::ffff:10.0.0.1"), 104, "::ffff:10.0.0.0/104", true},
+		{"1.2.3.4/33", Addr{}, 0, "", false},
+		{"::1/129", Addr{}, 0, "", false},
+		{"1.2.3.4/-1", Addr{}, 0, "", false},
+		{"1.2.3.4/024", Addr{}, 0, "", false},
+		{"1.2.3.4", Addr{}, 0, "", false},
+		{"fe80::1%eth0/64", Addr{}, 0, "", false},
+	}
+	for _, tt := range tests {
+		p, err := ParsePrefix(tt.in)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParsePrefix(%q) error = %v; want ok=%v", tt.in, err, tt.ok)
+			continue
+		}
+		if !tt.ok {
+			continue
+		}
+		if p.Addr() != tt.ip || p.Bits() != tt.bits {
+			t.Errorf("ParsePrefix(%q) = %v, %d; want %v, %d", tt.in, p.Addr(), p.Bits(), tt.ip, tt.bits)
+		}
+		if p.String() != tt.in {
+			t.Errorf("ParsePrefix(%q).String() = %q", tt.in, p.String())
+		}
+		if got := p.Masked().String(); got != tt.masked {
+			t.Errorf("ParsePrefix(%q).Masked() = %q; want %q", tt.in, got, tt.masked)
+		}
+	}
+}
+
+func TestPrefixContains(t *testing.T) {
+	tests := []struct {
+		prefix string
+		ip     string
+		want   bool
+	}{
+		{"192.168.0.0/16", "192.168.4.5", true},
+		{"192.168.0.0/16", "192.169.0.1", false},
+		{"192.168.0.0/16", "::ffff:192.168.4.5", false},
+		{"0.0.0.0/0", "8.8.8.8", true},
+		{"1.2.3.4/32", "1.2.3.4", true},
+		{"1.2.3.4/32", "1.2.3.5", false},
+		{"2001:db8::/32", "2001:db8:1::1", true},
+		{"2001:db8::/32", "2001:db9::1", false},
+		{"2001:db8::/32", "2001:db8::1%eth0", false},
+		{"::/0", "1.2.3.4", false},
+		{"::ffff:0:0/96", "::ffff:1.2.3.4", true},
+		{"fe80::/10", "febf::1", true},
+		{"fe80::/10", "fec0::1", false},
+	}
+	for _, tt := range tests {
+		p := MustParsePrefix(tt.prefix)
+		if got := p.Contains(MustParseAddr(tt.ip)); got != tt.want {
+			t.Errorf("%v.Contains(%v) = %v; want %v", p, tt.ip, got, tt.want)
+		}
+	}
+	if (Prefix{}).Contains(IPv4Unspecified()) {
+		t.Error("zero Prefix contains 0.0.0.0")
+	}
+	if MustParsePrefix("0.0.0.0/0").Contains(Addr{}) {
+		t.Error("0.0.0.0/0 contains the zero Addr")
+	}
+}
+
+func TestPrefixOverlaps(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"1.2.0.0/16", "1.2.3.0/24", true},
+		{"1.2.3.0/24", "1.2.4.0/24", false},
+		{"0.0.0.0/0", "1.2.3.4/32", true},
+		{"1.2.3.0/24", "::ffff:1.2.3.0/120", false},
+		{"2001:db8::/32", "2001:db8:1::/48", true},
+		{"2001:db8::/48", "2001:db8:1::/48", false},
+	}
+	for _, tt := range tests {
+		a, b := MustParsePrefix(tt.a), MustParsePrefix(tt.b)
+		if got := a.Overlaps(b); got != tt.want {
+			t.Errorf("%v.Overlaps(%v) = %v; want %v", a, b, got, tt.want)
+		}
+		if got := b.Overlaps(a); got != tt.want {
+			t.Errorf("%v.Overlaps(%v) = %v; want %v", b, a, got, tt.want)
+		}
+	}
+}
+
+func TestPrefixFrom(t *testing.T) {
+	ip := MustParseAddr("fe80::1%eth0")
+	p := PrefixFrom(ip, 64)
+	if p.Addr() != ip.WithZone("") || p.Bits() != 64 || !p.IsValid() {
+		t.Errorf("PrefixFrom(%v, 64) = %v", ip, p)
+	}
+	for _, bits := range []int{-1, 129} {
+		if p := PrefixFrom(ip, bits); p.IsValid() || p.Bits() != -1 {
+			t.Errorf("PrefixFrom(%v, %d) = %v; want invalid", ip, bits, p)
+		}
+	}
+	if !PrefixFrom(MustParseAddr("1.2.3.4"), 32).IsSingleIP() {
+		t.Error("1.2.3.4/32 is not a single IP")
+	}
+	if got, err := MustParseAddr("10.1.2.3").Prefix(8); err != nil || got != MustParsePrefix("10.0.0.0/8") {
+		t.Errorf("Prefix(8) = %v, %v", got, err)
+	}
+	if _, err := MustParseAddr("10.1.2.3").Prefix(33); err == nil {
+		t.Error("Prefix(33) of an IPv4 address succeeded")
+	}
+}
+
+func TestMarshalText(t *testing.T) {
+	type record struct {
+		Addr     Addr
+		AddrPort AddrPort
+		Prefix   Prefix
+	}
+	in := []record{
+		{MustParseAddr("1.2.3.4"), MustParseAddrPort("[::1%lo]:53"), MustParsePrefix("10.0.0.0/8")},
+		{},
+	}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `[{"Addr":"1.2.3.4","AddrPort":"[::1%lo]:53","Prefix":"10.0.0.0/8"},{"Addr":"","AddrPort":"","Prefix":""}]`
+	if string(b) != want {
+		t.Errorf("json.Marshal = %s; want %s", b, want)
+	}
+	var out []record
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("json.Unmarshal = %v; want %v", out, in)
+	}
+	var ip Addr
+	if err := ip.UnmarshalText([]byte("1.2.3")); err == nil {
+		t.Error("UnmarshalText of a bad address succeeded")
+	}
+}
+
+func TestSortAddrs(t *testing.T) {
+	ips := []Addr{MustParseAddr("::1"), MustParseAddr("10.0.0.2"), MustParseAddr("10.0.0.1"), {}}
+	sort.Slice(ips, func(i, j int) bool { return ips[i].Less(ips[j]) })
+	want := []Addr{{}, MustParseAddr("10.0.0.1"), MustParseAddr("10.0.0.2"), MustParseAddr("::1")}
+	if !reflect.DeepEqual(ips, want) {
+		t.Errorf("sorted = %v; want %v", ips, want)
+	}
+}
+
+func TestNoAllocs(t *testing.T) {
+	ip4, ip6 := MustParseAddr("192.168.1.1"), MustParseAddr("2001:db8::1")
+	p := MustParsePrefix("192.168.0.0/16")
+	buf := make([]byte, 0, 64)
+	tests := []struct {
+		name string
+		f    func()
+	}{
+		{"ParseAddr/4", func() { ParseAddr("192.168.1.1") }},
+		{"ParseAddr/6", func() { ParseAddr("2001:db8::1") }},
+		{"ParseAddrPort", func() { ParseAddrPort("[2001:db8::1]:80") }},
+		{"ParsePrefix", func() { ParsePrefix("192.168.0.0/16") }},
+		{"AppendTo", func() { ip6.AppendTo(buf[:0]) }},
+		{"Contains", func() { p.Contains(ip4) }},
+		{"Masked", func() { p.Masked() }},
+		{"As16", func() { ip4.As16() }},
+		{"Unmap", func() { ip6.Unmap() }},
+	}
+	for _, tt := range tests {
+		if n := testing.AllocsPerRun(100, tt.f); n != 0 {
+			t.Errorf("%s: got %v allocs; want 0", tt.name, n)
+		}
+	}
+}
@@ -8,6 +8,8 @@ package net
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"syscall"
 	"testing"
 )
@@ -142,3 +144,140 @@ func TestRawConnListener(t *testing.T) {
 		t.Fatal("Control after Close should fail")
 	}
 }
+
+func TestListenConfigControl(t *testing.T) {
+	tests := []struct {
+		network, address string
+		ctrlNetwork      string
+	}{
+		{"tcp", "127.0.0.1:0", "tcp4"},
+		{"tcp4", "127.0.0.1:0", "tcp4"},
+		{"tcp6", "[::1]:0", "tcp6"},
+		{"udp", "127.0.0.1:0", "udp4"},
+		{"udp6", "[::1]:0", "udp6"},
+		{"unix", testUnixAddr(), "unix"},
+		{"unixgram", testUnixAddr(), "unixgram"},
+	}
+	for _, tt := range tests {
+		if !testableNetwork(tt.network) {
+			continue
+		}
+		var gotNetwork, gotAddress string
+		var operr error
+		lc := ListenConfig{Control: func(network, address string, c syscall.RawConn) error {
+			gotNetwork, gotAddress = network, address
+			// SO_REUSEADDR is not set by default on datagram
+			// sockets, so this shows that Control ran.
+			err := c.Control(func(s uintptr) {
+				operr = syscall.SetsockoptInt(int(s), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+			})
+			if err != nil {
+				return err
+			}
+			return operr
+		}}
+		var sc syscall.Conn
+		var closer interface{ Close() error }
+		switch tt.network {
+		case "udp", "udp6", "unixgram":
+			c, err := lc.ListenPacket(context.Background(), tt.network, tt.address)
+			if err != nil {
+				t.Errorf("%s: %v", tt.network, err)
+				continue
+			}
+			sc, closer = c.(syscall.Conn), c
+		default:
+			ln, err := lc.Listen(context.Background(), tt.network, tt.address)
+			if err != nil {
+				t.Errorf("%s: %v", tt.network, err)
+				continue
+			}
+			sc, closer = ln.(syscall.Conn), ln
+		}
+		if gotNetwork != tt.ctrlNetwork || gotAddress != tt.address {
+			t.Errorf("%s: Control called with %q, %q; want %q, %q", tt.network, gotNetwork, gotAddress, tt.ctrlNetwork, tt.address)
+		}
+		if tt.network == "udp" || tt.network == "udp6" {
+			rc, err := sc.SyscallConn()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var v int
+			rc.Control(func(s uintptr) {
+				v, operr = syscall.GetsockoptInt(int(s), syscall.SOL_SOCKET, syscall.SO_REUSEADDR)
+			})
+			if operr != nil || v == 0 {
+				t.Errorf("%s: SO_REUSEADDR = %d, %v; want set by Control", tt.network, v, operr)
+			}
+		}
+		closer.Close()
+		if tt.network == "unixgram" {
+			syscall.Unlink(tt.address)
+		}
+	}
+}
+
+func TestListenConfigControlError(t *testing.T) {
+	errCtrl := errors.New("control failed")
+	lc := ListenConfig{Control: func(network, address string, c syscall.RawConn) error {
+		return errCtrl
+	}}
+	if ln, err := lc.Listen(context.Background(), "tcp", "127.0.0.1:0"); err == nil {
+		ln.Close()
+		t.Error("Listen succeeded despite the Control error")
+	} else if oe, ok := err.(*OpError); !ok || oe.Err != errCtrl {
+		t.Errorf("Listen error = %v; want an OpError wrapping %v", err, errCtrl)
+	}
+	if c, err := lc.ListenPacket(context.Background(), "udp", "127.0.0.1:0"); err == nil {
+		c.Close()
+		t.Error("ListenPacket succeeded despite the Control error")
+	}
+}
+
+func TestDialerControl(t *testing.T) {
+	for _, network := range []string{"tcp", "udp"} {
+		var ln Listener
+		var pc PacketConn
+		var raddr string
+		var err error
+		if network == "tcp" {
+			ln, err = newLocalListener(network)
+			if err == nil {
+				defer ln.Close()
+				raddr = ln.Addr().String()
+			}
+		} else {
+			pc, err = newLocalPacketListener(network)
+			if err == nil {
+				defer pc.Close()
+				raddr = pc.LocalAddr().String()
+			}
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var gotNetwork, gotAddress string
+		d := Dialer{Control: func(network, address string, c syscall.RawConn) error {
+			gotNetwork, gotAddress = network, address
+			return nil
+		}}
+		c, err := d.Dial(network, raddr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+		if gotNetwork != network+"4" || gotAddress != raddr {
+			t.Errorf("%s: Control called with %q, %q; want %q, %q", network, gotNetwork, gotAddress, network+"4", raddr)
+		}
+
+		errCtrl := errors.New("control failed")
+		d.Control = func(string, string, syscall.RawConn) error { return errCtrl }
+		if c, err := d.Dial(network, raddr); err == nil {
+			c.Close()
+			t.Errorf("%s: Dial succeeded despite the Control error", network)
+		} else if oe, ok := err.(*OpError); !ok || oe.Err != errCtrl {
+			t.Errorf("%s: Dial error = %v; want an OpError wrapping %v", network, err, errCtrl)
+		}
+	}
+}
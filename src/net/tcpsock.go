@@ -7,6 +7,7 @@ package net
 import (
 	"context"
 	"io"
+	"net/netip"
 	"os"
 	"syscall"
 	"time"
@@ -50,6 +51,33 @@ func (a *TCPAddr) opAddr() Addr {
 	return a
 }
 
+// AddrPort returns the TCPAddr a as a netip.AddrPort.
+//
+// If a.Port does not fit in a uint16, it's silently truncated.
+// As with UDPAddr.AddrPort, a 16-byte IPv4 address converts to an
+// IPv4-mapped IPv6 address.
+//
+// If a is nil, a zero value is returned.
+func (a *TCPAddr) AddrPort() netip.AddrPort {
+	if a == nil {
+		return netip.AddrPort{}
+	}
+	na, _ := netip.AddrFromSlice(a.IP)
+	na = na.WithZone(a.Zone)
+	return netip.AddrPortFrom(na, uint16(a.Port))
+}
+
+// TCPAddrFromAddrPort returns addr as a TCPAddr. If addr.IsValid() is
+// false, then the returned TCPAddr will contain a nil IP field,
+// indicating an address family-agnostic unspecified address.
+func TCPAddrFromAddrPort(addr netip.AddrPort) *TCPAddr {
+	return &TCPAddr{
+		IP:   addr.Addr().AsSlice(),
+		Zone: addr.Addr().Zone(),
+		Port: int(addr.Port()),
+	}
+}
+
 // ResolveTCPAddr returns an address of TCP end point.
 //
 // The network must be a TCP network name.
@@ -176,6 +204,82 @@ func (c *TCPConn) SetKeepAlivePeriod(d time.Duration) error {
 	return nil
 }
 
+// KeepAliveConfig contains TCP keep-alive options.
+//
+// A zero Idle, Interval, Count or UserTimeout leaves the operating
+// system's setting of that option unchanged. Not every system
+// supports every option.
+type KeepAliveConfig struct {
+	// If Enable is true, keep-alive probes are enabled.
+	// Otherwise Idle, Interval and Count are ignored.
+	Enable bool
+
+	// Idle is how long the connection must be idle before the
+	// first keep-alive probe is sent.
+	Idle time.Duration
+
+	// Interval is the time between keep-alive probes.
+	Interval time.Duration
+
+	// Count is the number of keep-alive probes that may go
+	// unanswered before the connection is dropped.
+	Count int
+
+	// UserTimeout is how long transmitted data, keep-alive
+	// probes included, may remain unacknowledged before the
+	// connection is dropped (TCP_USER_TIMEOUT, RFC 5482).
+	// It is only supported on Linux and Android.
+	UserTimeout time.Duration
+}
+
+// SetKeepAliveConfig configures the keep-alive behavior of the
+// connection. Unlike SetKeepAlivePeriod, it sets the idle time and
+// the interval between probes separately. Each option is set even
+// if setting another fails, and the first error is returned.
+func (c *TCPConn) SetKeepAliveConfig(config KeepAliveConfig) error {
+	if !c.ok() {
+		return syscall.EINVAL
+	}
+	if err := setKeepAliveConfig(c.fd, config); err != nil {
+		return &OpError{Op: "set", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return nil
+}
+
+// setKeepAliveConfig sets each option of config independently, so
+// that one the system does not support doesn't stop the others from
+// being set, and returns the first error.
+func setKeepAliveConfig(fd *netFD, config KeepAliveConfig) error {
+	var firstErr error
+	check := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	check(setKeepAlive(fd, config.Enable))
+	if config.Enable {
+		if config.Idle > 0 {
+			check(setKeepAliveIdle(fd, config.Idle))
+		}
+		if config.Interval > 0 {
+			check(setKeepAliveInterval(fd, config.Interval))
+		}
+		if config.Count > 0 {
+			check(setKeepAliveCount(fd, config.Count))
+		}
+	}
+	if config.UserTimeout > 0 {
+		check(setTCPUserTimeout(fd, config.UserTimeout))
+	}
+	return firstErr
+}
+
+// roundDurationUp rounds d up to the next multiple of unit, the
+// granularity in which the kernel takes a socket option.
+func roundDurationUp(d, unit time.Duration) int {
+	return int((d + unit - 1) / unit)
+}
+
 // SetNoDelay controls whether the operating system should delay
 // packet transmission in hopes of sending fewer packets (Nagle's
 // algorithm).  The default is true (no delay), meaning that data is
@@ -212,7 +316,8 @@ func DialTCP(network string, laddr, raddr *TCPAddr) (*TCPConn, error) {
 	if raddr == nil {
 		return nil, &OpError{Op: "dial", Net: network, Source: laddr.opAddr(), Addr: nil, Err: errMissingAddress}
 	}
-	c, err := dialTCP(context.Background(), network, laddr, raddr)
+	dp := &dialParam{network: network, address: raddr.String()}
+	c, err := dp.dialTCP(context.Background(), laddr, raddr)
 	if err != nil {
 		return nil, &OpError{Op: "dial", Net: network, Source: laddr.opAddr(), Addr: raddr.opAddr(), Err: err}
 	}
@@ -223,6 +328,7 @@ func DialTCP(network string, laddr, raddr *TCPAddr) (*TCPConn, error) {
 // use variables of type Listener instead of assuming TCP.
 type TCPListener struct {
 	fd *netFD
+	lc ListenConfig
 }
 
 // SyscallConn returns a raw network connection.
@@ -328,7 +434,8 @@ func ListenTCP(network string, laddr *TCPAddr) (*TCPListener, error) {
 	if laddr == nil {
 		laddr = &TCPAddr{}
 	}
-	ln, err := listenTCP(context.Background(), network, laddr)
+	lp := &listenParam{network: network, address: laddr.String()}
+	ln, err := lp.listenTCP(context.Background(), laddr)
 	if err != nil {
 		return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: laddr.opAddr(), Err: err}
 	}
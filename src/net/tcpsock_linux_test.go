@@ -0,0 +1,96 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+)
+
+var testKeepAliveConfig = KeepAliveConfig{
+	Enable:      true,
+	Idle:        5 * time.Second,
+	Interval:    2500 * time.Millisecond, // rounded up to 3s
+	Count:       4,
+	UserTimeout: 20 * time.Second,
+}
+
+// checkKeepAliveConfig checks that the socket options of c are those
+// of testKeepAliveConfig.
+func checkKeepAliveConfig(t *testing.T, who string, c *TCPConn) {
+	t.Helper()
+	rc, err := c.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := []struct {
+		name       string
+		level, opt int
+		want       int
+	}{
+		{"SO_KEEPALIVE", syscall.SOL_SOCKET, syscall.SO_KEEPALIVE, 1},
+		{"TCP_KEEPIDLE", syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE, 5},
+		{"TCP_KEEPINTVL", syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL, 3},
+		{"TCP_KEEPCNT", syscall.IPPROTO_TCP, syscall.TCP_KEEPCNT, 4},
+		{"TCP_USER_TIMEOUT", syscall.IPPROTO_TCP, sysTCP_USER_TIMEOUT, 20000},
+	}
+	for _, o := range opts {
+		var got int
+		var serr error
+		if err := rc.Control(func(s uintptr) {
+			got, serr = syscall.GetsockoptInt(int(s), o.level, o.opt)
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if serr != nil {
+			t.Fatalf("%s: getsockopt %s: %v", who, o.name, serr)
+		}
+		if got != o.want {
+			t.Errorf("%s: %s = %d; want %d", who, o.name, got, o.want)
+		}
+	}
+}
+
+func TestTCPKeepAliveConfig(t *testing.T) {
+	lc := ListenConfig{KeepAliveConfig: testKeepAliveConfig}
+	ln, err := lc.Listen(context.Background(), "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	d := Dialer{KeepAlive: time.Hour, KeepAliveConfig: testKeepAliveConfig}
+	c, err := d.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	checkKeepAliveConfig(t, "Dialer", c.(*TCPConn))
+
+	sc, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+	checkKeepAliveConfig(t, "ListenConfig", sc.(*TCPConn))
+
+	c2, err := Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+	if err := c2.(*TCPConn).SetKeepAliveConfig(testKeepAliveConfig); err != nil {
+		t.Fatal(err)
+	}
+	checkKeepAliveConfig(t, "SetKeepAliveConfig", c2.(*TCPConn))
+
+	// Zero fields leave the settings alone.
+	if err := c2.(*TCPConn).SetKeepAliveConfig(KeepAliveConfig{Enable: true}); err != nil {
+		t.Fatal(err)
+	}
+	checkKeepAliveConfig(t, "SetKeepAliveConfig with zero fields", c2.(*TCPConn))
+}
@@ -0,0 +1,16 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build freebsd netbsd
+
+package net
+
+import (
+	"syscall"
+	"time"
+)
+
+func setTCPUserTimeout(fd *netFD, d time.Duration) error {
+	return syscall.ENOPROTOOPT
+}
@@ -10,7 +10,10 @@ import (
 	"time"
 )
 
-const sysTCP_KEEPINTVL = 0x101
+const (
+	sysTCP_KEEPINTVL = 0x101
+	sysTCP_KEEPCNT   = 0x102
+)
 
 func setKeepAlivePeriod(fd *netFD, d time.Duration) error {
 	// The kernel expects seconds so round to next highest second.
@@ -25,3 +28,25 @@ func setKeepAlivePeriod(fd *netFD, d time.Duration) error {
 	runtime.KeepAlive(fd)
 	return wrapSyscallError("setsockopt", err)
 }
+
+func setKeepAliveIdle(fd *netFD, d time.Duration) error {
+	err := fd.pfd.SetsockoptInt(syscall.IPPROTO_TCP, syscall.TCP_KEEPALIVE, roundDurationUp(d, time.Second))
+	runtime.KeepAlive(fd)
+	return wrapSyscallError("setsockopt", err)
+}
+
+func setKeepAliveInterval(fd *netFD, d time.Duration) error {
+	err := fd.pfd.SetsockoptInt(syscall.IPPROTO_TCP, sysTCP_KEEPINTVL, roundDurationUp(d, time.Second))
+	runtime.KeepAlive(fd)
+	return wrapSyscallError("setsockopt", err)
+}
+
+func setKeepAliveCount(fd *netFD, n int) error {
+	err := fd.pfd.SetsockoptInt(syscall.IPPROTO_TCP, sysTCP_KEEPCNT, n)
+	runtime.KeepAlive(fd)
+	return wrapSyscallError("setsockopt", err)
+}
+
+func setTCPUserTimeout(fd *netFD, d time.Duration) error {
+	return syscall.ENOPROTOOPT
+}
@@ -22,3 +22,25 @@ func setKeepAlivePeriod(fd *netFD, d time.Duration) error {
 	runtime.KeepAlive(fd)
 	return wrapSyscallError("setsockopt", err)
 }
+
+func setKeepAliveIdle(fd *netFD, d time.Duration) error {
+	err := fd.pfd.SetsockoptInt(syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE, roundDurationUp(d, time.Millisecond))
+	runtime.KeepAlive(fd)
+	return wrapSyscallError("setsockopt", err)
+}
+
+func setKeepAliveInterval(fd *netFD, d time.Duration) error {
+	err := fd.pfd.SetsockoptInt(syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL, roundDurationUp(d, time.Millisecond))
+	runtime.KeepAlive(fd)
+	return wrapSyscallError("setsockopt", err)
+}
+
+func setKeepAliveCount(fd *netFD, n int) error {
+	err := fd.pfd.SetsockoptInt(syscall.IPPROTO_TCP, syscall.TCP_KEEPCNT, n)
+	runtime.KeepAlive(fd)
+	return wrapSyscallError("setsockopt", err)
+}
+
+func setTCPUserTimeout(fd *netFD, d time.Duration) error {
+	return syscall.ENOPROTOOPT
+}
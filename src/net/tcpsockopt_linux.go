@@ -0,0 +1,22 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// sysTCP_USER_TIMEOUT is TCP_USER_TIMEOUT, which package syscall
+// does not define on every architecture.
+const sysTCP_USER_TIMEOUT = 0x12
+
+func setTCPUserTimeout(fd *netFD, d time.Duration) error {
+	// The kernel expects milliseconds.
+	err := fd.pfd.SetsockoptInt(syscall.IPPROTO_TCP, sysTCP_USER_TIMEOUT, roundDurationUp(d, time.Millisecond))
+	runtime.KeepAlive(fd)
+	return wrapSyscallError("setsockopt", err)
+}
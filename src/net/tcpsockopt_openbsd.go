@@ -14,3 +14,19 @@ func setKeepAlivePeriod(fd *netFD, d time.Duration) error {
 	// options.
 	return syscall.ENOPROTOOPT
 }
+
+func setKeepAliveIdle(fd *netFD, d time.Duration) error {
+	return syscall.ENOPROTOOPT
+}
+
+func setKeepAliveInterval(fd *netFD, d time.Duration) error {
+	return syscall.ENOPROTOOPT
+}
+
+func setKeepAliveCount(fd *netFD, n int) error {
+	return syscall.ENOPROTOOPT
+}
+
+func setTCPUserTimeout(fd *netFD, d time.Duration) error {
+	return syscall.ENOPROTOOPT
+}
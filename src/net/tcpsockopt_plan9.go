@@ -21,3 +21,19 @@ func setKeepAlivePeriod(fd *netFD, d time.Duration) error {
 	_, e := fd.ctl.WriteAt([]byte(cmd), 0)
 	return e
 }
+
+func setKeepAliveIdle(fd *netFD, d time.Duration) error {
+	return setKeepAlivePeriod(fd, d)
+}
+
+func setKeepAliveInterval(fd *netFD, d time.Duration) error {
+	return syscall.EPLAN9
+}
+
+func setKeepAliveCount(fd *netFD, n int) error {
+	return syscall.EPLAN9
+}
+
+func setTCPUserTimeout(fd *netFD, d time.Duration) error {
+	return syscall.EPLAN9
+}
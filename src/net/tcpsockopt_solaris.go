@@ -31,3 +31,20 @@ func setKeepAlivePeriod(fd *netFD, d time.Duration) error {
 	runtime.KeepAlive(fd)
 	return wrapSyscallError("setsockopt", err)
 }
+
+func setKeepAliveIdle(fd *netFD, d time.Duration) error {
+	return setKeepAlivePeriod(fd, d)
+}
+
+func setKeepAliveInterval(fd *netFD, d time.Duration) error {
+	// See setKeepAlivePeriod for why TCP_KEEPINTVL is not used.
+	return syscall.ENOPROTOOPT
+}
+
+func setKeepAliveCount(fd *netFD, n int) error {
+	return syscall.ENOPROTOOPT
+}
+
+func setTCPUserTimeout(fd *netFD, d time.Duration) error {
+	return syscall.ENOPROTOOPT
+}
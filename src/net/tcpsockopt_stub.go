@@ -18,3 +18,19 @@ func setNoDelay(fd *netFD, noDelay bool) error {
 func setKeepAlivePeriod(fd *netFD, d time.Duration) error {
 	return syscall.ENOPROTOOPT
 }
+
+func setKeepAliveIdle(fd *netFD, d time.Duration) error {
+	return syscall.ENOPROTOOPT
+}
+
+func setKeepAliveInterval(fd *netFD, d time.Duration) error {
+	return syscall.ENOPROTOOPT
+}
+
+func setKeepAliveCount(fd *netFD, n int) error {
+	return syscall.ENOPROTOOPT
+}
+
+func setTCPUserTimeout(fd *netFD, d time.Duration) error {
+	return syscall.ENOPROTOOPT
+}
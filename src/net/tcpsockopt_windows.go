@@ -28,3 +28,33 @@ func setKeepAlivePeriod(fd *netFD, d time.Duration) error {
 	runtime.KeepAlive(fd)
 	return os.NewSyscallError("wsaioctl", err)
 }
+
+// Per-socket keep-alive options, available since Windows 10
+// version 1709. Earlier versions fail to set them.
+const (
+	sysTCP_KEEPIDLE  = 3
+	sysTCP_KEEPCNT   = 16
+	sysTCP_KEEPINTVL = 17
+)
+
+func setKeepAliveIdle(fd *netFD, d time.Duration) error {
+	err := fd.pfd.SetsockoptInt(syscall.IPPROTO_TCP, sysTCP_KEEPIDLE, roundDurationUp(d, time.Second))
+	runtime.KeepAlive(fd)
+	return wrapSyscallError("setsockopt", err)
+}
+
+func setKeepAliveInterval(fd *netFD, d time.Duration) error {
+	err := fd.pfd.SetsockoptInt(syscall.IPPROTO_TCP, sysTCP_KEEPINTVL, roundDurationUp(d, time.Second))
+	runtime.KeepAlive(fd)
+	return wrapSyscallError("setsockopt", err)
+}
+
+func setKeepAliveCount(fd *netFD, n int) error {
+	err := fd.pfd.SetsockoptInt(syscall.IPPROTO_TCP, sysTCP_KEEPCNT, n)
+	runtime.KeepAlive(fd)
+	return wrapSyscallError("setsockopt", err)
+}
+
+func setTCPUserTimeout(fd *netFD, d time.Duration) error {
+	return syscall.ENOPROTOOPT
+}
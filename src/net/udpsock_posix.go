@@ -8,6 +8,7 @@ package net
 
 import (
 	"context"
+	"net/netip"
 	"syscall"
 )
 
@@ -54,6 +55,27 @@ func (c *UDPConn) readFrom(b []byte) (int, *UDPAddr, error) {
 	return n, addr, err
 }
 
+func (c *UDPConn) readFromAddrPort(b []byte) (n int, addr netip.AddrPort, err error) {
+	switch c.fd.family {
+	case syscall.AF_INET:
+		var from syscall.SockaddrInet4
+		n, err = c.fd.readFromInet4(b, &from)
+		if err == nil {
+			addr = netip.AddrPortFrom(netip.AddrFrom4(from.Addr), uint16(from.Port))
+		}
+	case syscall.AF_INET6:
+		var from syscall.SockaddrInet6
+		n, err = c.fd.readFromInet6(b, &from)
+		if err == nil {
+			ip := netip.AddrFrom16(from.Addr).WithZone(zoneCache.name(int(from.ZoneId)))
+			addr = netip.AddrPortFrom(ip, uint16(from.Port))
+		}
+	default:
+		err = syscall.EAFNOSUPPORT
+	}
+	return n, addr, err
+}
+
 func (c *UDPConn) readMsg(b, oob []byte) (n, oobn, flags int, addr *UDPAddr, err error) {
 	var sa syscall.Sockaddr
 	n, oobn, flags, sa, err = c.fd.readMsg(b, oob)
@@ -80,6 +102,34 @@ func (c *UDPConn) writeTo(b []byte, addr *UDPAddr) (int, error) {
 	return c.fd.writeTo(b, sa)
 }
 
+func (c *UDPConn) writeToAddrPort(b []byte, addr netip.AddrPort) (int, error) {
+	if c.fd.isConnected {
+		return 0, ErrWriteToConnected
+	}
+	if !addr.IsValid() {
+		return 0, errMissingAddress
+	}
+	ip := addr.Addr()
+	switch c.fd.family {
+	case syscall.AF_INET:
+		ip = ip.Unmap()
+		if !ip.Is4() {
+			return 0, &AddrError{Err: "non-IPv4 address", Addr: ip.String()}
+		}
+		sa := syscall.SockaddrInet4{Addr: ip.As4(), Port: int(addr.Port())}
+		return c.fd.writeToInet4(b, &sa)
+	case syscall.AF_INET6:
+		// As in ipToSockaddr, 0.0.0.0 stands for the IPv6
+		// unspecified address.
+		if ip == netip.IPv4Unspecified() {
+			ip = netip.IPv6Unspecified()
+		}
+		sa := syscall.SockaddrInet6{Addr: ip.As16(), Port: int(addr.Port()), ZoneId: uint32(zoneCache.index(ip.Zone()))}
+		return c.fd.writeToInet6(b, &sa)
+	}
+	return 0, &AddrError{Err: "invalid address family", Addr: ip.String()}
+}
+
 func (c *UDPConn) writeMsg(b, oob []byte, addr *UDPAddr) (n, oobn int, err error) {
 	if c.fd.isConnected && addr != nil {
 		return 0, 0, ErrWriteToConnected
@@ -94,24 +144,24 @@ func (c *UDPConn) writeMsg(b, oob []byte, addr *UDPAddr) (n, oobn int, err error
 	return c.fd.writeMsg(b, oob, sa)
 }
 
-func dialUDP(ctx context.Context, net string, laddr, raddr *UDPAddr) (*UDPConn, error) {
-	fd, err := internetSocket(ctx, net, laddr, raddr, syscall.SOCK_DGRAM, 0, "dial")
+func (dp *dialParam) dialUDP(ctx context.Context, laddr, raddr *UDPAddr) (*UDPConn, error) {
+	fd, err := internetSocket(ctx, dp.network, laddr, raddr, syscall.SOCK_DGRAM, 0, "dial", dp.Control)
 	if err != nil {
 		return nil, err
 	}
 	return newUDPConn(fd), nil
 }
 
-func listenUDP(ctx context.Context, network string, laddr *UDPAddr) (*UDPConn, error) {
-	fd, err := internetSocket(ctx, network, laddr, nil, syscall.SOCK_DGRAM, 0, "listen")
+func (lp *listenParam) listenUDP(ctx context.Context, laddr *UDPAddr) (*UDPConn, error) {
+	fd, err := internetSocket(ctx, lp.network, laddr, nil, syscall.SOCK_DGRAM, 0, "listen", lp.Control)
 	if err != nil {
 		return nil, err
 	}
 	return newUDPConn(fd), nil
 }
 
-func listenMulticastUDP(ctx context.Context, network string, ifi *Interface, gaddr *UDPAddr) (*UDPConn, error) {
-	fd, err := internetSocket(ctx, network, gaddr, nil, syscall.SOCK_DGRAM, 0, "listen")
+func (lp *listenParam) listenMulticastUDP(ctx context.Context, ifi *Interface, gaddr *UDPAddr) (*UDPConn, error) {
+	fd, err := internetSocket(ctx, lp.network, gaddr, nil, syscall.SOCK_DGRAM, 0, "listen", lp.Control)
 	if err != nil {
 		return nil, err
 	}
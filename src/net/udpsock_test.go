@@ -6,6 +6,7 @@ package net
 
 import (
 	"internal/testenv"
+	"net/netip"
 	"reflect"
 	"runtime"
 	"testing"
@@ -213,6 +214,98 @@ func testWriteToPacketConn(t *testing.T, raddr string) {
 	}
 }
 
+func TestUDPReadWriteAddrPort(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9":
+		t.Skipf("not supported on %s", runtime.GOOS)
+	}
+
+	for _, network := range []string{"udp4", "udp6"} {
+		if network == "udp6" && !supportsIPv6() {
+			continue
+		}
+		c1, err := newLocalPacketListener(network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c1.Close()
+		c2, err := newLocalPacketListener(network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c2.Close()
+		uc1, uc2 := c1.(*UDPConn), c2.(*UDPConn)
+
+		to := uc1.LocalAddr().(*UDPAddr).AddrPort()
+		from := uc2.LocalAddr().(*UDPAddr).AddrPort()
+		if network == "udp4" {
+			from = netip.AddrPortFrom(from.Addr().Unmap(), from.Port())
+		}
+		wb := []byte("UDP ADDRPORT TEST")
+		rb := make([]byte, 128)
+		uc1.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if _, err := uc2.WriteToUDPAddrPort(wb, to); err != nil {
+			t.Fatal(err)
+		}
+		n, addr, err := uc1.ReadFromUDPAddrPort(rb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(rb[:n]) != string(wb) {
+			t.Errorf("%s: got %q; want %q", network, rb[:n], wb)
+		}
+		if addr != from {
+			t.Errorf("%s: got source address %v; want %v", network, addr, from)
+		}
+
+		if _, err := uc2.WriteToUDPAddrPort(wb, netip.AddrPort{}); err == nil || err.(*OpError).Err != errMissingAddress {
+			t.Errorf("%s: WriteToUDPAddrPort with the zero AddrPort: %v; want errMissingAddress", network, err)
+		}
+
+		allocs := testing.AllocsPerRun(100, func() {
+			if _, err := uc2.WriteToUDPAddrPort(wb, to); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := uc1.ReadFromUDPAddrPort(rb); err != nil {
+				t.Fatal(err)
+			}
+		})
+		if allocs > 0 {
+			t.Errorf("%s: got %v allocs; want 0", network, allocs)
+		}
+	}
+}
+
+func TestUDPAddrAddrPort(t *testing.T) {
+	tests := []struct {
+		addr *UDPAddr
+		want netip.AddrPort
+	}{
+		{nil, netip.AddrPort{}},
+		{&UDPAddr{IP: IP{192, 0, 2, 1}, Port: 53}, netip.MustParseAddrPort("192.0.2.1:53")},
+		{&UDPAddr{IP: IPv4(192, 0, 2, 1), Port: 53}, netip.MustParseAddrPort("[::ffff:192.0.2.1]:53")},
+		{&UDPAddr{IP: ParseIP("fe80::1"), Port: 80, Zone: "eth0"}, netip.MustParseAddrPort("[fe80::1%eth0]:80")},
+	}
+	for _, tt := range tests {
+		if got := tt.addr.AddrPort(); got != tt.want {
+			t.Errorf("%v.AddrPort() = %v; want %v", tt.addr, got, tt.want)
+		}
+		if tt.addr == nil {
+			continue
+		}
+		if got := UDPAddrFromAddrPort(tt.want); !reflect.DeepEqual(got, tt.addr) {
+			t.Errorf("UDPAddrFromAddrPort(%v) = %v; want %v", tt.want, got, tt.addr)
+		}
+		tcp := &TCPAddr{IP: tt.addr.IP, Port: tt.addr.Port, Zone: tt.addr.Zone}
+		if got := tcp.AddrPort(); got != tt.want {
+			t.Errorf("%v.AddrPort() = %v; want %v", tcp, got, tt.want)
+		}
+		if got := TCPAddrFromAddrPort(tt.want); !reflect.DeepEqual(got, tcp) {
+			t.Errorf("TCPAddrFromAddrPort(%v) = %v; want %v", tt.want, got, tcp)
+		}
+	}
+}
+
 var udpConnLocalNameTests = []struct {
 	net   string
 	laddr *UDPAddr
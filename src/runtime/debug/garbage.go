@@ -92,6 +92,32 @@ func SetGCPercent(percent int) int {
 	return int(setGCPercent(int32(percent)))
 }
 
+// SetMemoryLimit sets a soft limit, in bytes, on the total memory
+// mapped by the Go runtime and not yet returned to the operating
+// system. This includes the heap, goroutine stacks, and the runtime's
+// own data structures, but not the program binary or memory allocated
+// outside of Go, such as by C code.
+//
+// To stay under the limit, the garbage collector starts collections
+// earlier than GOGC alone would, and the runtime returns free memory to
+// the operating system as the heap grows. The limit applies even when
+// garbage collection is otherwise disabled with SetGCPercent(-1), in
+// which case it alone determines when collections run.
+//
+// The limit is soft: if the live heap does not fit under it, the
+// garbage collector runs nearly continuously. To keep the program making
+// progress, once the collector is using more than half of the available
+// CPU it lets the heap grow past the limit, as it would with GOGC=100.
+//
+// SetMemoryLimit returns the previous setting. A negative limit leaves
+// the setting unchanged, so SetMemoryLimit(-1) reports the current limit.
+// The initial setting is math.MaxInt64, meaning no limit, unless the
+// GOMEMLIMIT environment variable is set; see the runtime package
+// documentation for its syntax.
+func SetMemoryLimit(limit int64) int64 {
+	return setMemoryLimit(limit)
+}
+
 // FreeOSMemory forces a garbage collection followed by an
 // attempt to return as much memory to the operating system
 // as possible. (Even if this is not called, the runtime gradually
@@ -172,6 +172,45 @@ func TestSetGCPercent(t *testing.T) {
 	}
 }
 
+func TestSetMemoryLimit(t *testing.T) {
+	// Test that the limit is being set and returned correctly.
+	old := SetMemoryLimit(123 << 20)
+	defer SetMemoryLimit(old)
+	if got := SetMemoryLimit(-1); got != 123<<20 {
+		t.Errorf("SetMemoryLimit(123<<20); SetMemoryLimit(-1) = %d, want %d", got, 123<<20)
+	}
+
+	// Test that the limit drives collection even with GOGC=off.
+	defer SetGCPercent(SetGCPercent(-1))
+	defer func() {
+		setGCPercentSink = nil
+	}()
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	limit := int64(ms.Sys-ms.HeapReleased) + 64<<20
+	SetMemoryLimit(limit)
+	runtime.ReadMemStats(&ms)
+	ngc1, nlimited1 := ms.NumGC, ms.NumLimitedGC
+	if int64(ms.NextGC) > limit {
+		t.Errorf("NextGC = %d MB, want at most the %d MB limit", ms.NextGC>>20, limit>>20)
+	}
+	// Allocate 256 MB of garbage, well past the limit.
+	for i := 0; i < 256; i++ {
+		setGCPercentSink = make([]byte, 1<<20)
+	}
+	runtime.ReadMemStats(&ms)
+	if ms.NumGC == ngc1 {
+		t.Errorf("expected GC to run but it did not")
+	}
+	if ms.NumLimitedGC == nlimited1 {
+		t.Errorf("expected GC paced by the memory limit but NumLimitedGC did not change")
+	}
+	if mapped := int64(ms.Sys - ms.HeapReleased); mapped > limit+16<<20 {
+		t.Errorf("runtime has %d MB mapped, want about the %d MB limit", mapped>>20, limit>>20)
+	}
+}
+
 func abs64(a int64) int64 {
 	if a < 0 {
 		return -a
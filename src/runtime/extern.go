@@ -21,6 +21,14 @@ is GOGC=100. Setting GOGC=off disables the garbage collector entirely.
 The runtime/debug package's SetGCPercent function allows changing this
 percentage at run time. See https://golang.org/pkg/runtime/debug/#SetGCPercent.
 
+The GOMEMLIMIT variable sets a soft limit on the total memory used by the Go
+runtime, as a number of bytes with an optional unit suffix: B, KiB, MiB, GiB,
+or TiB. The garbage collector runs more often, and the runtime returns more
+memory to the operating system, as needed to stay under the limit. The default
+is GOMEMLIMIT=off, meaning no limit. The runtime/debug package's SetMemoryLimit
+function allows changing this limit at run time.
+See https://golang.org/pkg/runtime/debug/#SetMemoryLimit.
+
 The GODEBUG variable controls debugging variables within the runtime.
 It is a comma-separated list of name=val pairs setting these named variables:
 
@@ -61,7 +61,7 @@ func TestMemStats(t *testing.T) {
 		"BuckHashSys": {nz, le(1e10)}, "GCSys": {nz, le(1e10)}, "OtherSys": {nz, le(1e10)},
 		"NextGC": {nz, le(1e10)}, "LastGC": {nz},
 		"PauseTotalNs": {le(1e11)}, "PauseNs": nil, "PauseEnd": nil,
-		"NumGC": {nz, le(1e9)}, "NumForcedGC": {nz, le(1e9)},
+		"NumGC": {nz, le(1e9)}, "NumForcedGC": {nz, le(1e9)}, "NumLimitedGC": {le(1e9)},
 		"GCCPUFraction": {le(0.99)}, "EnableGC": {eq(true)}, "DebugGC": {eq(false)},
 		"BySize": nil,
 	}
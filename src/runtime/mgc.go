@@ -151,6 +151,11 @@ const (
 	// (in bytes) reserved for concurrent sweeping between GC
 	// cycles. This will be scaled by gcpercent/100.
 	sweepMinHeapDistance = 1024 * 1024
+
+	// memoryLimitTriggerRatio is the fraction of the way from
+	// heap_marked to a heap goal set by the memory limit at which
+	// to trigger GC.
+	memoryLimitTriggerRatio = 0.7
 )
 
 // heapminimum is the minimum heap size at which to trigger GC.
@@ -173,6 +178,11 @@ const defaultHeapMinimum = 4 << 20
 // Initialized from $GOGC.  GOGC=off means no GC.
 var gcpercent int32
 
+// memoryLimit is the soft limit on the total memory mapped by the
+// runtime, in bytes. Initialized from $GOMEMLIMIT; maxInt64 means no
+// limit. Protected by mheap_.lock.
+var memoryLimit int64 = maxInt64
+
 func gcinit() {
 	if unsafe.Sizeof(workbuf{}) != _WorkbufSize {
 		throw("size of Workbuf is suboptimal")
@@ -189,9 +199,13 @@ func gcinit() {
 	// This will go into computing the initial GC goal.
 	memstats.heap_marked = uint64(float64(heapminimum) / (1 + memstats.triggerRatio))
 
-	// Set gcpercent from the environment. This will also compute
-	// and set the GC trigger and goal.
+	// Set the memory limit and gcpercent from the environment.
+	// The latter will also compute and set the GC trigger and goal.
+	memoryLimit = readGOMEMLIMIT()
 	_ = setGCPercent(readgogc())
+	lock(&mheap_.lock)
+	mheap_.scavengeToLimitLocked()
+	unlock(&mheap_.lock)
 
 	work.startSema = 1
 	work.markDoneSema = 1
@@ -208,6 +222,19 @@ func readgogc() int32 {
 	return 100
 }
 
+func readGOMEMLIMIT() int64 {
+	p := gogetenv("GOMEMLIMIT")
+	if p == "" || p == "off" {
+		return maxInt64
+	}
+	n, ok := parseByteCount(p)
+	if !ok {
+		print("GOMEMLIMIT=", p, "\n")
+		throw("malformed GOMEMLIMIT; see `go doc runtime/debug.SetMemoryLimit`")
+	}
+	return n
+}
+
 // gcenable is called after the bulk of the runtime initialization,
 // just before we're about to start letting user code run.
 // It kicks off the background sweeper goroutine and enables GC.
@@ -251,6 +278,52 @@ func setGCPercent(in int32) (out int32) {
 	return out
 }
 
+//go:linkname setMemoryLimit runtime/debug.setMemoryLimit
+func setMemoryLimit(in int64) (out int64) {
+	// Run on the system stack since we may scavenge with the
+	// heap locked.
+	systemstack(func() {
+		lock(&mheap_.lock)
+		out = memoryLimit
+		if in >= 0 {
+			memoryLimit = in
+			// Update pacing in response to the new limit, and
+			// give memory back to the OS if we're already over it.
+			gcSetTriggerRatio(memstats.triggerRatio)
+			mheap_.scavengeToLimitLocked()
+		}
+		unlock(&mheap_.lock)
+	})
+	return out
+}
+
+// memoryLimitHeapGoal returns the largest heap goal that keeps the
+// total memory mapped by the runtime under memoryLimit, or ^0 if
+// there is no limit. It reserves room for the runtime's non-heap
+// memory and a little headroom for fragmentation.
+//
+// mheap_.lock must be held or the world must be stopped.
+func memoryLimitHeapGoal() uint64 {
+	if memoryLimit == maxInt64 {
+		return ^uint64(0)
+	}
+	limit := uint64(memoryLimit)
+	overhead := mappedReady() - (memstats.heap_sys - memstats.heap_released)
+	overhead += limit / 32
+	goal := uint64(0)
+	if overhead < limit {
+		goal = limit - overhead
+	}
+	if gcCPULimiter.limiting() {
+		// Rather than collect continuously, let the heap
+		// grow as it would with GOGC=100. See gcCPULimiter.
+		if floor := memstats.heap_marked * 2; goal < floor {
+			goal = floor
+		}
+	}
+	return goal
+}
+
 // Garbage collector phase.
 // Indicates to write barrier and synchronization task to perform.
 var gcphase uint32
@@ -454,6 +527,14 @@ func (c *gcControllerState) startCycle() {
 	if gcpercent < 0 {
 		memstats.next_gc = ^uint64(0)
 	}
+	limitGoal := memoryLimitHeapGoal()
+	work.heapGoalLimited = limitGoal < memstats.next_gc
+	if work.heapGoalLimited {
+		memstats.next_gc = limitGoal
+		if trace.enabled {
+			traceMemoryLimitGC()
+		}
+	}
 
 	// Ensure that the heap goal is at least a little larger than
 	// the current live heap size. This may not be the case if GC
@@ -519,7 +600,19 @@ func (c *gcControllerState) startCycle() {
 // available).
 func (c *gcControllerState) revise() {
 	gcpercent := gcpercent
-	if gcpercent < 0 {
+	if work.heapGoalLimited {
+		// The memory limit set the heap goal, so use the
+		// GOGC it effectively implies for the below
+		// calculations.
+		gcpercent = 0
+		if m := memstats.heap_marked; m > 0 && memstats.next_gc > m {
+			if p := (memstats.next_gc - m) * 100 / m; p < 100000 {
+				gcpercent = int32(p)
+			} else {
+				gcpercent = 100000
+			}
+		}
+	} else if gcpercent < 0 {
 		// If GC is disabled but we're running a forced GC,
 		// act like GOGC is huge for the below calculations.
 		gcpercent = 100000
@@ -788,7 +881,7 @@ func pollFractionalWorkerExit() bool {
 // This can be called any time. If GC is the in the middle of a
 // concurrent phase, it will adjust the pacing of that phase.
 //
-// This depends on gcpercent, memstats.heap_marked, and
+// This depends on gcpercent, memoryLimit, memstats.heap_marked, and
 // memstats.heap_live. These must be up to date.
 //
 // mheap_.lock must be held or the world must be stopped.
@@ -851,6 +944,24 @@ func gcSetTriggerRatio(triggerRatio float64) {
 			goal = trigger
 		}
 	}
+
+	// If the memory limit leaves the heap less room than GOGC
+	// does, pace against the limit instead. This applies even
+	// when GOGC=off.
+	if limitGoal := memoryLimitHeapGoal(); limitGoal < goal {
+		goal = limitGoal
+		// Start the cycle early enough to finish marking
+		// before the heap reaches the goal. If the live heap
+		// alone is over the goal, collect right away.
+		limitTrigger := memstats.heap_marked
+		if goal > limitTrigger {
+			limitTrigger += uint64(float64(goal-limitTrigger) * memoryLimitTriggerRatio)
+		}
+		if limitTrigger < trigger {
+			trigger = limitTrigger
+			memstats.gc_trigger = trigger
+		}
+	}
 	memstats.next_gc = goal
 	if trace.enabled {
 		traceNextGC()
@@ -1022,6 +1133,10 @@ var work struct {
 	// explicit user call.
 	userForced bool
 
+	// heapGoalLimited indicates the heap goal of the current GC
+	// cycle was set by the memory limit rather than GOGC.
+	heapGoalLimited bool
+
 	// totaltime is the CPU nanoseconds spent in GC since the
 	// program started if debug.gctrace > 0.
 	totaltime int64
@@ -1631,6 +1746,14 @@ func gcMarkTermination(nextTriggerRatio float64) {
 	if work.userForced {
 		memstats.numforcedgc++
 	}
+	if work.heapGoalLimited {
+		memstats.numlimitedgc++
+	}
+	if gcCPULimiter.update(cycleCpu, now, work.heapGoalLimited) {
+		// The limiter changed the heap goal the memory limit
+		// implies, so update pacing for the next cycle again.
+		gcSetTriggerRatio(memstats.triggerRatio)
+	}
 
 	// Bump GC cycle count and wake goroutines waiting on sweep.
 	lock(&work.sweepWaiters.lock)
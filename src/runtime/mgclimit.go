@@ -0,0 +1,69 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import "runtime/internal/atomic"
+
+// gcCPULimit is the fraction of total CPU time above which the GC CPU
+// limiter engages.
+const gcCPULimit = 0.5
+
+// gcCPULimiter keeps the memory limit from driving the garbage
+// collector into a death spiral.
+//
+// When the live heap approaches or exceeds the memory limit, the
+// pacer runs GC cycles back to back with ever smaller heap goals, and
+// mark assists take over more and more of the CPU, so the program
+// stops making progress long before it would otherwise run out of
+// memory. The limiter measures the CPU time spent in GC between the
+// ends of successive cycles. If a cycle paced by the memory limit
+// used more than gcCPULimit of the CPU, the next cycle's heap goal is
+// allowed to grow to twice the marked heap, as with GOGC=100, even if
+// that exceeds the limit. The heap may then overshoot the limit, which
+// is preferable to making no progress.
+var gcCPULimiter gcCPULimiterState
+
+type gcCPULimiterState struct {
+	// lastUpdate is the nanotime of the last update.
+	lastUpdate int64
+
+	// enabled is 1 while the limiter is engaged. Accessed
+	// atomically.
+	enabled uint32
+}
+
+// update records that a GC cycle ending at now used gcTime
+// nanoseconds of CPU time, and engages or disengages the limiter for
+// the next cycle. limited indicates the cycle's heap goal was set by
+// the memory limit. It reports whether the limiter changed state.
+//
+// The world must be stopped.
+func (l *gcCPULimiterState) update(gcTime, now int64, limited bool) bool {
+	var enable uint32
+	if limited && l.lastUpdate != 0 {
+		total := (now - l.lastUpdate) * int64(gomaxprocs)
+		if total > 0 && float64(gcTime) > gcCPULimit*float64(total) {
+			enable = 1
+		}
+	}
+	l.lastUpdate = now
+	if enable == atomic.Load(&l.enabled) {
+		return false
+	}
+	if debug.gctrace > 0 {
+		if enable != 0 {
+			print("gc: memory limit CPU limiter engaged\n")
+		} else {
+			print("gc: memory limit CPU limiter disengaged\n")
+		}
+	}
+	atomic.Store(&l.enabled, enable)
+	return true
+}
+
+// limiting reports whether the limiter is engaged.
+func (l *gcCPULimiterState) limiting() bool {
+	return atomic.Load(&l.enabled) != 0
+}
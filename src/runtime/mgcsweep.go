@@ -120,11 +120,16 @@ func sweepone() uintptr {
 	}
 
 	// Decrement the number of active sweepers and if this is the
-	// last one print trace information.
+	// last one print trace information and, since sweeping has now
+	// freed all it can, return enough free memory to the OS to get
+	// back under the memory limit.
 	if atomic.Xadd(&mheap_.sweepers, -1) == 0 && atomic.Load(&mheap_.sweepdone) != 0 {
 		if debug.gcpacertrace > 0 {
 			print("pacer: sweep done at heap size ", memstats.heap_live>>20, "MB; allocated ", (memstats.heap_live-mheap_.sweepHeapLiveBasis)>>20, "MB during sweep; swept ", mheap_.pagesSwept, " pages at ", sweepRatio, " pages/byte\n")
 		}
+		lock(&mheap_.lock)
+		mheap_.scavengeToLimitLocked()
+		unlock(&mheap_.lock)
 	}
 	_g_.m.locks--
 	return npages
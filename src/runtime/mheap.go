@@ -786,6 +786,9 @@ func (h *mheap) setSpans(base, npage uintptr, s *mspan) {
 func (h *mheap) allocSpanLocked(npage uintptr, stat *uint64) *mspan {
 	var list *mSpanList
 	var s *mspan
+	// scavenge indicates that we mapped memory, so we may be over
+	// the memory limit.
+	scavenge := false
 
 	// Try in fixed-size lists up to max.
 	for i := int(npage); i < len(h.free); i++ {
@@ -802,6 +805,7 @@ func (h *mheap) allocSpanLocked(npage uintptr, stat *uint64) *mspan {
 		if !h.grow(npage) {
 			return nil
 		}
+		scavenge = true
 		s = h.allocLarge(npage)
 		if s == nil {
 			return nil
@@ -816,16 +820,18 @@ HaveSpan:
 	if s.npages < npage {
 		throw("MHeap_AllocLocked - bad npages")
 	}
-	if s.npreleased > 0 {
-		sysUsed(unsafe.Pointer(s.base()), s.npages<<_PageShift)
-		memstats.heap_released -= uint64(s.npreleased << _PageShift)
-		s.npreleased = 0
-	}
-
 	if s.npages > npage {
 		// Trim extra and put it back in the heap.
 		t := (*mspan)(h.spanalloc.alloc())
 		t.init(s.base()+npage<<_PageShift, s.npages-npage)
+		// If all of s was released, so is all of t, and only
+		// the pages we're about to use need to come back.
+		// Otherwise we don't know which pages were released.
+		var treleased uintptr
+		if s.npreleased == s.npages {
+			treleased = t.npages
+			s.npreleased = npage
+		}
 		s.npages = npage
 		h.setSpan(t.base()-1, s)
 		h.setSpan(t.base(), t)
@@ -834,8 +840,15 @@ HaveSpan:
 		s.state = _MSpanManual // prevent coalescing with s
 		t.state = _MSpanManual
 		h.freeSpanLocked(t, false, false, s.unusedsince)
+		t.npreleased += treleased
 		s.state = _MSpanFree
 	}
+	if s.npreleased > 0 {
+		sysUsed(unsafe.Pointer(s.base()), s.npages<<_PageShift)
+		memstats.heap_released -= uint64(s.npreleased << _PageShift)
+		s.npreleased = 0
+		scavenge = true
+	}
 	s.unusedsince = 0
 
 	h.setSpans(s.base(), npage, s)
@@ -843,6 +856,10 @@ HaveSpan:
 	*stat += uint64(npage << _PageShift)
 	memstats.heap_idle -= uint64(npage << _PageShift)
 
+	if scavenge {
+		h.scavengeToLimitLocked()
+	}
+
 	//println("spanalloc", hex(s.start<<_PageShift))
 	if s.inList() {
 		throw("still in list")
@@ -1026,37 +1043,43 @@ func (h *mheap) busyList(npages uintptr) *mSpanList {
 	return &h.busylarge
 }
 
+// scavenge returns the free pages of s that have not already been
+// released to the OS and reports how many bytes it released.
+// h must be locked.
+func (s *mspan) scavenge() uintptr {
+	start := s.base()
+	end := start + s.npages<<_PageShift
+	if physPageSize > _PageSize {
+		// We can only release pages in
+		// physPageSize blocks, so round start
+		// and end in. (Otherwise, madvise
+		// will round them *out* and release
+		// more memory than we want.)
+		start = (start + physPageSize - 1) &^ (physPageSize - 1)
+		end &^= physPageSize - 1
+		if end <= start {
+			// start and end don't span a
+			// whole physical page.
+			return 0
+		}
+	}
+	len := end - start
+	released := len - (s.npreleased << _PageShift)
+	if physPageSize > _PageSize && released == 0 {
+		return 0
+	}
+	memstats.heap_released += uint64(released)
+	s.npreleased = len >> _PageShift
+	sysUnused(unsafe.Pointer(start), len)
+	return released
+}
+
 func scavengeTreapNode(t *treapNode, now, limit uint64) uintptr {
 	s := t.spanKey
-	var sumreleased uintptr
 	if (now-uint64(s.unusedsince)) > limit && s.npreleased != s.npages {
-		start := s.base()
-		end := start + s.npages<<_PageShift
-		if physPageSize > _PageSize {
-			// We can only release pages in
-			// physPageSize blocks, so round start
-			// and end in. (Otherwise, madvise
-			// will round them *out* and release
-			// more memory than we want.)
-			start = (start + physPageSize - 1) &^ (physPageSize - 1)
-			end &^= physPageSize - 1
-			if end <= start {
-				// start and end don't span a
-				// whole physical page.
-				return sumreleased
-			}
-		}
-		len := end - start
-		released := len - (s.npreleased << _PageShift)
-		if physPageSize > _PageSize && released == 0 {
-			return sumreleased
-		}
-		memstats.heap_released += uint64(released)
-		sumreleased += released
-		s.npreleased = len >> _PageShift
-		sysUnused(unsafe.Pointer(start), len)
+		return s.scavenge()
 	}
-	return sumreleased
+	return 0
 }
 
 func scavengelist(list *mSpanList, now, limit uint64) uintptr {
@@ -1069,34 +1092,53 @@ func scavengelist(list *mSpanList, now, limit uint64) uintptr {
 		if (now-uint64(s.unusedsince)) <= limit || s.npreleased == s.npages {
 			continue
 		}
-		start := s.base()
-		end := start + s.npages<<_PageShift
-		if physPageSize > _PageSize {
-			// We can only release pages in
-			// physPageSize blocks, so round start
-			// and end in. (Otherwise, madvise
-			// will round them *out* and release
-			// more memory than we want.)
-			start = (start + physPageSize - 1) &^ (physPageSize - 1)
-			end &^= physPageSize - 1
-			if end <= start {
-				// start and end don't span a
-				// whole physical page.
-				continue
+		sumreleased += s.scavenge()
+	}
+	return sumreleased
+}
+
+// scavengeLargest releases at least nbytes of free memory to the OS,
+// if there is that much, starting with the largest free spans.
+// It returns the number of bytes released. h must be locked.
+func (h *mheap) scavengeLargest(nbytes uintptr) uintptr {
+	released := scavengeTreapLargest(h.freelarge.treap, nbytes)
+	for i := len(h.free) - 1; i > 0 && released < nbytes; i-- {
+		for s := h.free[i].first; s != nil && released < nbytes; s = s.next {
+			if s.npreleased != s.npages {
+				released += s.scavenge()
 			}
 		}
-		len := end - start
+	}
+	return released
+}
 
-		released := len - (s.npreleased << _PageShift)
-		if physPageSize > _PageSize && released == 0 {
-			continue
-		}
-		memstats.heap_released += uint64(released)
-		sumreleased += released
-		s.npreleased = len >> _PageShift
-		sysUnused(unsafe.Pointer(start), len)
+func scavengeTreapLargest(t *treapNode, nbytes uintptr) uintptr {
+	if t == nil {
+		return 0
+	}
+	// Larger spans are to the right.
+	released := scavengeTreapLargest(t.right, nbytes)
+	if s := t.spanKey; released < nbytes && s.npreleased != s.npages {
+		released += s.scavenge()
+	}
+	if released < nbytes {
+		released += scavengeTreapLargest(t.left, nbytes-released)
+	}
+	return released
+}
+
+// scavengeToLimitLocked releases free memory to the OS until the
+// memory mapped by the runtime is back under memoryLimit, or there is
+// no more free memory to release. h must be locked.
+func (h *mheap) scavengeToLimitLocked() {
+	mapped := mappedReady()
+	if memoryLimit == maxInt64 || mapped <= uint64(memoryLimit) {
+		return
+	}
+	released := h.scavengeLargest(uintptr(mapped - uint64(memoryLimit)))
+	if debug.gctrace > 0 && released > 0 {
+		print("scvg: ", released>>20, " MB released to stay under the memory limit\n")
 	}
-	return sumreleased
 }
 
 func (h *mheap) scavenge(k int32, now, limit uint64) {
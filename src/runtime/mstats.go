@@ -77,6 +77,7 @@ type mstats struct {
 	pause_end       [256]uint64 // circular buffer of recent gc end times (nanoseconds since 1970)
 	numgc           uint32
 	numforcedgc     uint32  // number of user-forced GCs
+	numlimitedgc    uint32  // number of GCs paced by the memory limit
 	gc_cpu_fraction float64 // fraction of CPU time used by GC
 	enablegc        bool
 	debuggc         bool
@@ -383,6 +384,11 @@ type MemStats struct {
 	// the application calling the GC function.
 	NumForcedGC uint32
 
+	// NumLimitedGC is the number of GC cycles whose heap goal was
+	// set by the memory limit rather than by GOGC. See
+	// runtime/debug.SetMemoryLimit.
+	NumLimitedGC uint32
+
 	// GCCPUFraction is the fraction of this program's available
 	// CPU time used by the GC since the program started.
 	//
@@ -517,6 +523,17 @@ func readGCStats_m(pauses *[]uint64) {
 	*pauses = p[:n+n+3]
 }
 
+// mappedReady returns the memory mapped by the runtime that has not
+// been released back to the OS. This is the quantity the memory limit
+// applies to. Like updatememstats, it counts stacks_inuse as sys memory.
+//
+// mheap_.lock must be held or the world must be stopped.
+func mappedReady() uint64 {
+	return memstats.heap_sys - memstats.heap_released + memstats.stacks_inuse +
+		memstats.stacks_sys + memstats.mspan_sys + memstats.mcache_sys +
+		memstats.buckhash_sys + memstats.gc_sys + memstats.other_sys
+}
+
 //go:nowritebarrier
 func updatememstats() {
 	memstats.mcache_inuse = uint64(mheap_.cachealloc.inuse)
@@ -338,6 +338,8 @@ func hasprefix(s, t string) bool {
 const (
 	maxUint = ^uint(0)
 	maxInt  = int(maxUint >> 1)
+
+	maxInt64 = 1<<63 - 1
 )
 
 // atoi parses an int from a string s.
@@ -397,6 +399,55 @@ func atoi32(s string) (int32, bool) {
 	return 0, false
 }
 
+// parseByteCount parses a string that represents a count of bytes:
+// a non-negative decimal integer with an optional unit suffix, one
+// of B, KiB, MiB, GiB, or TiB.
+func parseByteCount(s string) (int64, bool) {
+	// Strip the unit suffix, if any, recording its multiplier.
+	m := uint64(1)
+	if len(s) > 0 && s[len(s)-1] == 'B' {
+		s = s[:len(s)-1]
+		if len(s) > 0 && s[len(s)-1] == 'i' {
+			if len(s) < 2 {
+				return 0, false
+			}
+			switch s[len(s)-2] {
+			case 'K':
+				m = 1 << 10
+			case 'M':
+				m = 1 << 20
+			case 'G':
+				m = 1 << 30
+			case 'T':
+				m = 1 << 40
+			default:
+				return 0, false
+			}
+			s = s[:len(s)-2]
+		}
+	}
+	if s == "" {
+		return 0, false
+	}
+	n := uint64(0)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		if n > (maxInt64-uint64(c-'0'))/10 {
+			// overflow
+			return 0, false
+		}
+		n = n*10 + uint64(c-'0')
+	}
+	if n > maxInt64/m {
+		// overflow
+		return 0, false
+	}
+	return int64(n * m), true
+}
+
 //go:nosplit
 func findnull(s *byte) int {
 	if s == nil {
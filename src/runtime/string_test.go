@@ -388,3 +388,63 @@ func TestAtoi32(t *testing.T) {
 		}
 	}
 }
+
+var parseByteCountTests = []struct {
+	in  string
+	out int64
+	ok  bool
+}{
+	// Good numeric inputs.
+	{"1", 1, true},
+	{"12345", 12345, true},
+	{"012345", 12345, true},
+	{"98765432100", 98765432100, true},
+	{"9223372036854775807", 1<<63 - 1, true},
+
+	// Good trivial suffix inputs.
+	{"1B", 1, true},
+	{"12345B", 12345, true},
+	{"9223372036854775807B", 1<<63 - 1, true},
+
+	// Good binary suffix inputs.
+	{"1KiB", 1 << 10, true},
+	{"05KiB", 5 << 10, true},
+	{"1MiB", 1 << 20, true},
+	{"10MiB", 10 << 20, true},
+	{"1GiB", 1 << 30, true},
+	{"100GiB", 100 << 30, true},
+	{"1TiB", 1 << 40, true},
+	{"99TiB", 99 << 40, true},
+	{"8388607TiB", 8388607 << 40, true},
+
+	// Bad inputs.
+	{"", 0, false},
+	{"B", 0, false},
+	{"iB", 0, false},
+	{"KiB", 0, false},
+	{"-1", 0, false},
+	{"-1KiB", 0, false},
+	{"1.5KiB", 0, false},
+	{"1KB", 0, false},
+	{"1kiB", 0, false},
+	{"1PiB", 0, false},
+	{"1 KiB", 0, false},
+	{"1KiBB", 0, false},
+	{"off", 0, false},
+
+	// Overflow.
+	{"9223372036854775808", 0, false},
+	{"9223372036854775808B", 0, false},
+	{"8388608TiB", 0, false},
+	{"20496382327982653440KiB", 0, false},
+}
+
+func TestParseByteCount(t *testing.T) {
+	for _, test := range parseByteCountTests {
+		out, ok := runtime.ParseByteCount(test.in)
+		if test.out != out || test.ok != ok {
+			t.Errorf("parseByteCount(%q) = (%v, %v) want (%v, %v)",
+				test.in, out, ok, test.out, test.ok)
+		}
+	}
+}
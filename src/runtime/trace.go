@@ -68,7 +68,8 @@ const (
 	traceEvUserTaskEnd       = 46 // end of a task [timestamp, internal task id, stack]
 	traceEvUserSpan          = 47 // trace.WithSpan [timestamp, internal task id, mode(0:start, 1:end), stack, name string]
 	traceEvUserLog           = 48 // trace.Log [timestamp, internal task id, key string id, stack, value string]
-	traceEvCount             = 49
+	traceEvMemoryLimitGC     = 49 // GC cycle paced by the memory limit [timestamp, memory limit]
+	traceEvCount             = 50
 	// Byte is used but only 6 bits are available for event type.
 	// The remaining 2 bits are used to specify the number of arguments.
 	// That means, the max event type value is 63.
@@ -1145,6 +1146,10 @@ func traceNextGC() {
 	}
 }
 
+func traceMemoryLimitGC() {
+	traceEvent(traceEvMemoryLimitGC, -1, uint64(memoryLimit))
+}
+
 // To access runtime functions from runtime/trace.
 // See runtime/trace/annotation.go
 
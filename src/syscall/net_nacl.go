@@ -845,6 +845,30 @@ func Sendto(fd int, p []byte, flags int, to Sockaddr) error {
 	return f.sendto(p, flags, to)
 }
 
+func RecvfromInet4(fd int, p []byte, flags int, from *SockaddrInet4) (n int, err error) {
+	n, sa, err := Recvfrom(fd, p, flags)
+	if sa, ok := sa.(*SockaddrInet4); ok {
+		*from = *sa
+	}
+	return n, err
+}
+
+func RecvfromInet6(fd int, p []byte, flags int, from *SockaddrInet6) (n int, err error) {
+	n, sa, err := Recvfrom(fd, p, flags)
+	if sa, ok := sa.(*SockaddrInet6); ok {
+		*from = *sa
+	}
+	return n, err
+}
+
+func SendtoInet4(fd int, p []byte, flags int, to *SockaddrInet4) error {
+	return Sendto(fd, p, flags, to)
+}
+
+func SendtoInet6(fd int, p []byte, flags int, to *SockaddrInet6) error {
+	return Sendto(fd, p, flags, to)
+}
+
 func Recvmsg(fd int, p, oob []byte, flags int) (n, oobn, recvflags int, from Sockaddr, err error) {
 	f, err := fdToNetFile(fd)
 	if err != nil {
@@ -0,0 +1,241 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Reading and writing of fuzz corpus files.
+
+package testing
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A corpus file holds one input to a fuzz function. The first line is
+// corpusHeader; each following line holds one argument, written as a Go
+// conversion of a literal to the argument's type:
+//
+//	go test fuzz v1
+//	[]byte("\x00hello")
+//	int(-7)
+//	rune('☺')
+const corpusHeader = "go test fuzz v1"
+
+// corpusDir returns the directory holding the corpus files
+// of the fuzz target name below dir.
+func corpusDir(dir, name string) string {
+	return dir + string(os.PathSeparator) + name
+}
+
+// corpusFileName returns the name of the corpus file holding data:
+// a hash of the content, so that saving the same input twice
+// yields a single file.
+func corpusFileName(data []byte) string {
+	// 64-bit FNV-1a.
+	h := uint64(14695981039346656037)
+	for _, c := range data {
+		h ^= uint64(c)
+		h *= 1099511628211
+	}
+	return fmt.Sprintf("%016x", h)
+}
+
+// readCorpus reads the corpus files in dir, which need not exist,
+// and checks that their values match the fuzz function's argument types.
+func readCorpus(dir string, types []reflect.Type) ([]corpusEntry, error) {
+	d, err := os.Open(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	names, err := d.Readdirnames(-1)
+	d.Close()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	var entries []corpusEntry
+	for _, name := range names {
+		path := dir + string(os.PathSeparator) + name
+		data, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		values, err := unmarshalCorpusFile(data)
+		if err == nil {
+			err = checkCorpusTypes(values, types)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		entries = append(entries, corpusEntry{name: name, values: values})
+	}
+	return entries, nil
+}
+
+func readFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(f)
+	return buf.Bytes(), err
+}
+
+// writeCorpusFile writes data to the file name in dir,
+// creating dir if necessary, and returns the file's path.
+func writeCorpusFile(dir, name string, data []byte) (string, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return "", err
+	}
+	path := dir + string(os.PathSeparator) + name
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(data)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	return path, err
+}
+
+// marshalCorpusFile encodes values in the corpus file format.
+func marshalCorpusFile(values []interface{}) []byte {
+	var b bytes.Buffer
+	b.WriteString(corpusHeader + "\n")
+	for _, v := range values {
+		switch v := v.(type) {
+		case []byte:
+			fmt.Fprintf(&b, "[]byte(%q)\n", v)
+		case string:
+			fmt.Fprintf(&b, "string(%q)\n", v)
+		case byte:
+			// %q would quote a byte as a rune,
+			// so quote only the ASCII ones.
+			if v < utf8.RuneSelf {
+				fmt.Fprintf(&b, "byte(%q)\n", v)
+			} else {
+				fmt.Fprintf(&b, "byte(%#x)\n", v)
+			}
+		case rune:
+			if utf8.ValidRune(v) {
+				fmt.Fprintf(&b, "rune(%q)\n", v)
+			} else {
+				fmt.Fprintf(&b, "int32(%d)\n", v)
+			}
+		case float32:
+			fmt.Fprintf(&b, "float32(%s)\n", strconv.FormatFloat(float64(v), 'g', -1, 32))
+		case float64:
+			fmt.Fprintf(&b, "float64(%s)\n", strconv.FormatFloat(v, 'g', -1, 64))
+		case bool, int, int8, int16, int64, uint, uint16, uint32, uint64:
+			fmt.Fprintf(&b, "%T(%v)\n", v, v)
+		default:
+			panic(fmt.Sprintf("testing: unsupported corpus value type %T", v))
+		}
+	}
+	return b.Bytes()
+}
+
+// unmarshalCorpusFile decodes the values in a corpus file.
+func unmarshalCorpusFile(data []byte) ([]interface{}, error) {
+	lines := strings.Split(string(data), "\n")
+	if strings.TrimSpace(lines[0]) != corpusHeader {
+		return nil, fmt.Errorf("corpus file must begin with %q", corpusHeader)
+	}
+	var values []interface{}
+	for i, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		v, err := parseCorpusValue(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+2, err)
+		}
+		values = append(values, v)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("corpus file has no values")
+	}
+	return values, nil
+}
+
+// parseCorpusValue parses a single line of a corpus file,
+// as written by marshalCorpusFile.
+func parseCorpusValue(line string) (interface{}, error) {
+	i := strings.Index(line, "(")
+	if i < 0 || !strings.HasSuffix(line, ")") {
+		return nil, fmt.Errorf("malformed value %q", line)
+	}
+	typ, lit := line[:i], line[i+1:len(line)-1]
+	switch typ {
+	case "[]byte":
+		s, err := strconv.Unquote(lit)
+		return []byte(s), err
+	case "string":
+		return strconv.Unquote(lit)
+	case "bool":
+		return strconv.ParseBool(lit)
+	case "byte", "uint8":
+		if strings.HasPrefix(lit, "'") {
+			s, err := strconv.Unquote(lit)
+			if err != nil || len(s) != 1 {
+				return nil, fmt.Errorf("malformed byte literal %s", lit)
+			}
+			return s[0], nil
+		}
+		n, err := strconv.ParseUint(lit, 0, 8)
+		return uint8(n), err
+	case "rune", "int32":
+		if strings.HasPrefix(lit, "'") {
+			s, err := strconv.Unquote(lit)
+			r, size := utf8.DecodeRuneInString(s)
+			if err != nil || size == 0 || size != len(s) {
+				return nil, fmt.Errorf("malformed rune literal %s", lit)
+			}
+			return r, nil
+		}
+		n, err := strconv.ParseInt(lit, 0, 32)
+		return int32(n), err
+	case "int":
+		n, err := strconv.ParseInt(lit, 0, strconv.IntSize)
+		return int(n), err
+	case "int8":
+		n, err := strconv.ParseInt(lit, 0, 8)
+		return int8(n), err
+	case "int16":
+		n, err := strconv.ParseInt(lit, 0, 16)
+		return int16(n), err
+	case "int64":
+		return strconv.ParseInt(lit, 0, 64)
+	case "uint":
+		n, err := strconv.ParseUint(lit, 0, strconv.IntSize)
+		return uint(n), err
+	case "uint16":
+		n, err := strconv.ParseUint(lit, 0, 16)
+		return uint16(n), err
+	case "uint32":
+		n, err := strconv.ParseUint(lit, 0, 32)
+		return uint32(n), err
+	case "uint64":
+		return strconv.ParseUint(lit, 0, 64)
+	case "float32":
+		x, err := strconv.ParseFloat(lit, 32)
+		return float32(x), err
+	case "float64":
+		return strconv.ParseFloat(lit, 64)
+	}
+	return nil, fmt.Errorf("unsupported type %s", typ)
+}
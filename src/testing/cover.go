@@ -28,6 +28,9 @@ var cover Cover
 // Cover records information about test coverage checking.
 // NOTE: This struct is internal to the testing infrastructure and may change.
 // It is not covered (yet) by the Go 1 compatibility guidelines.
+//
+// When the counters are registered only to guide fuzzing,
+// Mode is empty and no coverage is reported.
 type Cover struct {
 	Mode            string
 	Counters        map[string][]uint32
@@ -43,6 +46,10 @@ type Cover struct {
 // It is not a replacement for the reports generated by 'go test -cover' and
 // 'go tool cover'.
 func Coverage() float64 {
+	if cover.Mode == "" {
+		// The counters, if any, only guide fuzzing.
+		return 0
+	}
 	var n, d int64
 	for _, counters := range cover.Counters {
 		for i := range counters {
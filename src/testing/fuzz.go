@@ -0,0 +1,671 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testing
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	matchFuzz        = flag.String("test.fuzz", "", "run the fuzz target matching `regexp`")
+	fuzzDuration     = flag.Duration("test.fuzztime", 0, "spend at most `d` fuzzing (default 0, run until a failure is found)")
+	minimizeDuration = flag.Duration("test.fuzzminimizetime", 60*time.Second, "spend at most `d` minimizing a failing input")
+	fuzzCacheDir     = flag.String("test.fuzzcachedir", "", "store interesting inputs found while fuzzing in `dir` (for use only by cmd/go)")
+)
+
+// An internal type but exported because it is cross-package; part of the implementation
+// of the "go test" command.
+type InternalFuzzTarget struct {
+	Name string
+	Fn   func(f *F)
+}
+
+// F is a type passed to fuzz targets.
+//
+// A fuzz target seeds a corpus of inputs by calling F.Add and then
+// calls F.Fuzz with a fuzz function to be run on each input. When go
+// test runs without the -fuzz flag, the fuzz function is run once, as a
+// subtest, for each input in the seed corpus and for each file in the
+// package's testdata/fuzz/FuzzXxx directory. With -fuzz, the inputs are
+// mutated at random, guided by coverage, until the fuzz function fails.
+// The failing input is then minimized and written to testdata/fuzz/FuzzXxx,
+// so that later runs of go test replay it as a regular test.
+//
+// The reporting methods, such as Log, Error, and Skip, report on the fuzz
+// target itself and must not be called from within the fuzz function;
+// use the *T passed to the fuzz function instead.
+type F struct {
+	common
+	context    *testContext
+	fuzzing    bool          // fuzz inputs instead of just running the corpus
+	corpus     []corpusEntry // seed corpus, then inputs from testdata and the cache
+	fuzzCalled bool
+}
+
+var _ TB = (*F)(nil)
+
+// A corpusEntry is one input to a fuzz function.
+type corpusEntry struct {
+	name   string        // name of the subtest that runs this entry
+	values []interface{} // arguments to the fuzz function, after the *T
+}
+
+// supportedTypes lists the argument types that a fuzz function may take
+// after its *T, and that F.Add accepts.
+var supportedTypes = map[reflect.Type]bool{
+	reflect.TypeOf([]byte(nil)): true,
+	reflect.TypeOf(""):          true,
+	reflect.TypeOf(false):       true,
+	reflect.TypeOf(float32(0)):  true,
+	reflect.TypeOf(float64(0)):  true,
+	reflect.TypeOf(int(0)):      true,
+	reflect.TypeOf(int8(0)):     true,
+	reflect.TypeOf(int16(0)):    true,
+	reflect.TypeOf(int32(0)):    true,
+	reflect.TypeOf(int64(0)):    true,
+	reflect.TypeOf(uint(0)):     true,
+	reflect.TypeOf(uint8(0)):    true,
+	reflect.TypeOf(uint16(0)):   true,
+	reflect.TypeOf(uint32(0)):   true,
+	reflect.TypeOf(uint64(0)):   true,
+}
+
+// Add adds the arguments to the seed corpus of the fuzz target.
+// The arguments must match, in number and type, the arguments
+// the fuzz function takes after its *T.
+// Add must be called before Fuzz.
+func (f *F) Add(args ...interface{}) {
+	if f.fuzzCalled {
+		panic("testing: F.Add called after F.Fuzz")
+	}
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		if !supportedTypes[reflect.TypeOf(arg)] {
+			panic(fmt.Sprintf("testing: unsupported type %T passed to F.Add", arg))
+		}
+		if b, ok := arg.([]byte); ok {
+			arg = append([]byte(nil), b...)
+		}
+		values[i] = arg
+	}
+	f.corpus = append(f.corpus, corpusEntry{
+		name:   fmt.Sprintf("seed#%d", len(f.corpus)),
+		values: values,
+	})
+}
+
+// Fuzz runs the fuzz function ff on the inputs of the fuzz target.
+// ff must be a function with no return value whose first argument
+// is a *T and whose remaining arguments are of type []byte, string,
+// bool, a sized or unsized integer type, or float32 or float64.
+// Fuzz may be called at most once.
+//
+// A failure in ff, whether reported by a call to a *T method or by a
+// panic, marks the fuzz target as failed. When fuzzing, ff must not
+// call t.Parallel and must not retain its arguments after returning.
+func (f *F) Fuzz(ff interface{}) {
+	if f.fuzzCalled {
+		panic("testing: F.Fuzz called more than once")
+	}
+	f.fuzzCalled = true
+	f.Helper()
+
+	fn := reflect.ValueOf(ff)
+	types, err := fuzzArgTypes(fn)
+	if err != nil {
+		panic("testing: F.Fuzz: " + err.Error())
+	}
+	for _, e := range f.corpus {
+		if err := checkCorpusTypes(e.values, types); err != nil {
+			f.Fatalf("%s: %v", e.name, err)
+		}
+	}
+	entries, err := readCorpus(corpusDir("testdata"+string(os.PathSeparator)+"fuzz", f.name), types)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.corpus = append(f.corpus, entries...)
+
+	if f.fuzzing {
+		f.fuzz(fn, types)
+		return
+	}
+	for _, e := range f.corpus {
+		e := e
+		f.run(e.name, func(t *T) { callFuzzFn(fn, t, e.values) })
+	}
+}
+
+// fuzzArgTypes checks that fn is a valid fuzz function and
+// returns the types of its arguments after the *T.
+func fuzzArgTypes(fn reflect.Value) ([]reflect.Type, error) {
+	if fn.Kind() != reflect.Func {
+		return nil, fmt.Errorf("fuzz function must be a func, not %v", fn.Kind())
+	}
+	ft := fn.Type()
+	if ft.NumIn() < 2 || ft.In(0) != reflect.TypeOf((*T)(nil)) {
+		return nil, errors.New("fuzz function must take a *T followed by at least one argument")
+	}
+	if ft.NumOut() != 0 || ft.IsVariadic() {
+		return nil, errors.New("fuzz function must not be variadic or return values")
+	}
+	var types []reflect.Type
+	for i := 1; i < ft.NumIn(); i++ {
+		typ := ft.In(i)
+		if !supportedTypes[typ] {
+			return nil, fmt.Errorf("fuzz function takes unsupported argument type %v", typ)
+		}
+		types = append(types, typ)
+	}
+	return types, nil
+}
+
+// checkCorpusTypes reports whether the values match the fuzz function's argument types.
+func checkCorpusTypes(values []interface{}, types []reflect.Type) error {
+	if len(values) != len(types) {
+		return fmt.Errorf("have %d values, fuzz function takes %d", len(values), len(types))
+	}
+	for i, v := range values {
+		if reflect.TypeOf(v) != types[i] {
+			return fmt.Errorf("value %d has type %T, fuzz function takes %v", i, v, types[i])
+		}
+	}
+	return nil
+}
+
+func callFuzzFn(fn reflect.Value, t *T, values []interface{}) {
+	args := make([]reflect.Value, 1+len(values))
+	args[0] = reflect.ValueOf(t)
+	for i, v := range values {
+		args[1+i] = reflect.ValueOf(v)
+	}
+	fn.Call(args)
+}
+
+// run runs fn as a subtest of the fuzz target, like T.Run.
+func (f *F) run(name string, fn func(t *T)) bool {
+	atomic.StoreInt32(&f.hasSub, 1)
+	testName, ok, _ := f.context.match.fullName(&f.common, name)
+	if !ok || shouldFailFast() {
+		return true
+	}
+	t := &T{
+		common: common{
+			barrier: make(chan bool),
+			signal:  make(chan bool),
+			name:    testName,
+			parent:  &f.common,
+			level:   f.level + 1,
+			chatty:  f.chatty,
+		},
+		context: f.context,
+	}
+	t.w = indenter{&t.common}
+	if t.chatty {
+		root := f.root()
+		root.mu.Lock()
+		fmt.Fprintf(root.w, "=== RUN   %s\n", t.name)
+		root.mu.Unlock()
+	}
+	go tRunner(t, fn)
+	<-t.signal
+	return !t.failed
+}
+
+// root returns the root of the test tree containing f.
+func (f *F) root() *common {
+	root := f.parent
+	for ; root.parent != nil; root = root.parent {
+	}
+	return root
+}
+
+// fRunner runs the fuzz target fn, like tRunner does for tests.
+func fRunner(f *F, fn func(f *F)) {
+	f.runner = callerName(0)
+
+	defer func() {
+		f.duration += time.Since(f.start)
+		err := recover()
+		if !f.finished && err == nil {
+			err = fmt.Errorf("fuzz target executed panic(nil) or runtime.Goexit")
+		}
+
+		// As in tRunner, signal completion from a deferred call so that
+		// a cleanup function calling f.FailNow does not hang the caller.
+		didPanic := false
+		defer func() {
+			if !didPanic {
+				f.signal <- true
+			}
+		}()
+
+		doPanic := func(err interface{}) {
+			f.Fail()
+			if r := f.runCleanup(recoverAndReturnPanic); r != nil {
+				f.Logf("cleanup panicked with %v", r)
+			}
+			f.report()
+			didPanic = true
+			panic(err)
+		}
+		if err != nil {
+			doPanic(err)
+		}
+
+		if len(f.sub) > 0 {
+			// Run the parallel subtests started by the corpus entries.
+			f.context.release()
+			close(f.barrier)
+			for _, sub := range f.sub {
+				<-sub.signal
+			}
+			if err := f.runCleanup(recoverAndReturnPanic); err != nil {
+				doPanic(err)
+			}
+			f.context.waitParallel()
+		}
+		f.report()
+		f.done = true
+		if atomic.LoadInt32(&f.hasSub) == 0 {
+			f.setRan()
+		}
+	}()
+	defer func() {
+		if len(f.sub) == 0 {
+			f.runCleanup(normalPanic)
+		}
+	}()
+
+	f.start = time.Now()
+	fn(f)
+	if !f.fuzzCalled && !f.failed && !f.skipped {
+		f.Errorf("fuzz target did not call F.Fuzz")
+	}
+	if f.failed {
+		atomic.AddUint32(&numFailed, 1)
+	}
+	f.finished = true
+}
+
+// runFuzzTargets runs the corpus of each fuzz target matching -test.run.
+func runFuzzTargets(matchString func(pat, str string) (bool, error), fuzzTargets []InternalFuzzTarget) (ran, ok bool) {
+	ok = true
+	if len(fuzzTargets) == 0 {
+		return ran, ok
+	}
+	for i := uint(0); i < *count; i++ {
+		if shouldFailFast() {
+			break
+		}
+		ctx := newTestContext(*parallel, newMatcher(matchString, *match, "-test.run"))
+		root := common{w: os.Stdout, chatty: *chatty}
+		for _, ft := range fuzzTargets {
+			if shouldFailFast() {
+				break
+			}
+			name, matched, _ := ctx.match.fullName(nil, ft.Name)
+			if !matched {
+				continue
+			}
+			f := newF(&root, ctx, name)
+			if f.chatty {
+				fmt.Fprintf(root.w, "=== RUN   %s\n", f.name)
+			}
+			go fRunner(f, ft.Fn)
+			<-f.signal
+			ok = ok && !f.Failed()
+		}
+		ran = ran || root.ran
+	}
+	return ran, ok
+}
+
+// runFuzzing fuzzes the fuzz target matching -test.fuzz, if any.
+func runFuzzing(matchString func(pat, str string) (bool, error), fuzzTargets []InternalFuzzTarget) (ran, ok bool) {
+	m := newMatcher(matchString, *matchFuzz, "-test.fuzz")
+	var target *InternalFuzzTarget
+	var name string
+	for i := range fuzzTargets {
+		n, matched, _ := m.fullName(nil, fuzzTargets[i].Name)
+		if !matched {
+			continue
+		}
+		if target != nil {
+			fmt.Fprintf(os.Stderr, "testing: -test.fuzz matches more than one fuzz target: %s and %s\n", target.Name, fuzzTargets[i].Name)
+			return false, false
+		}
+		target, name = &fuzzTargets[i], n
+	}
+	if target == nil {
+		return false, true
+	}
+
+	root := common{w: os.Stdout, chatty: *chatty}
+	f := newF(&root, newTestContext(1, m), name)
+	f.fuzzing = true
+	if f.chatty {
+		fmt.Fprintf(root.w, "=== FUZZ  %s\n", f.name)
+	}
+	go fRunner(f, target.Fn)
+	<-f.signal
+	return true, !f.Failed()
+}
+
+func newF(root *common, ctx *testContext, name string) *F {
+	f := &F{
+		common: common{
+			signal:  make(chan bool),
+			barrier: make(chan bool),
+			name:    name,
+			parent:  root,
+			level:   root.level + 1,
+			chatty:  root.chatty,
+		},
+		context: ctx,
+	}
+	f.w = indenter{&f.common}
+	return f
+}
+
+// fuzz runs the fuzzing loop: it mutates inputs from the corpus at random
+// and runs the fuzz function on each one, keeping the inputs that reach
+// new coverage, until an input fails or -test.fuzztime elapses.
+func (f *F) fuzz(fn reflect.Value, types []reflect.Type) {
+	var cacheDir string
+	if *fuzzCacheDir != "" {
+		cacheDir = corpusDir(*fuzzCacheDir, f.name)
+		cached, err := readCorpus(cacheDir, types)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.corpus = append(f.corpus, cached...)
+	}
+	if len(f.corpus) == 0 {
+		values := make([]interface{}, len(types))
+		for i, typ := range types {
+			values[i] = reflect.Zero(typ).Interface()
+		}
+		f.corpus = append(f.corpus, corpusEntry{name: "zero", values: values})
+	}
+
+	cov := newFuzzCoverage()
+	if !cov.enabled() {
+		f.fuzzLog("warning: test binary built without coverage instrumentation; mutating inputs blindly")
+	}
+	defer cov.restore()
+
+	start := time.Now()
+	// Run the corpus first, to establish the coverage
+	// against which new inputs are judged.
+	for _, e := range f.corpus {
+		t := f.runInput(fn, e.values)
+		if t.Failed() {
+			f.reportFailure(t, e.values, e.name)
+			return
+		}
+		cov.update()
+	}
+	baseline := len(f.corpus)
+
+	mut := newMutator(rand.New(rand.NewSource(time.Now().UnixNano())))
+	var execs int64
+	lastLog := start
+	for {
+		now := time.Now()
+		if *fuzzDuration > 0 && now.Sub(start) >= *fuzzDuration {
+			break
+		}
+		if now.Sub(lastLog) >= 3*time.Second {
+			f.logStats(now.Sub(start), execs, len(f.corpus)-baseline)
+			lastLog = now
+		}
+
+		values := mut.mutate(f.corpus[mut.rand.Intn(len(f.corpus))].values)
+		t := f.runInput(fn, values)
+		execs++
+		if t.Failed() {
+			values, t = f.minimize(fn, values, t)
+			f.reportFailure(t, values, "")
+			return
+		}
+		if cov.update() {
+			data := marshalCorpusFile(values)
+			name := corpusFileName(data)
+			f.corpus = append(f.corpus, corpusEntry{name: name, values: values})
+			if cacheDir != "" {
+				// The cache only saves work; failing to write it is not fatal.
+				writeCorpusFile(cacheDir, name, data)
+			}
+		}
+	}
+	f.logStats(time.Since(start), execs, len(f.corpus)-baseline)
+}
+
+// runInput runs the fuzz function on values in a new T. Unlike in a
+// subtest, a panic in the fuzz function is recovered and recorded as a
+// failure of the returned T, so that the failing input can be minimized
+// and saved.
+func (f *F) runInput(fn reflect.Value, values []interface{}) *T {
+	t := &T{
+		common: common{
+			signal:  make(chan bool),
+			barrier: make(chan bool),
+			name:    f.name,
+			parent:  &f.common,
+			level:   f.level + 1,
+		},
+		context: f.context,
+	}
+	t.w = indenter{&t.common}
+	go func() {
+		t.runner = callerName(0)
+		defer func() {
+			t.duration = time.Since(t.start)
+			err := recover()
+			if !t.finished && err == nil {
+				err = fmt.Errorf("fuzz function executed panic(nil) or runtime.Goexit")
+			}
+			if err != nil {
+				t.mu.Lock()
+				t.failed = true
+				stack := strings.TrimSuffix(string(debug.Stack()), "\n")
+				t.output = append(t.output, fmt.Sprintf("\tpanic: %v\n\t\t%s\n", err, strings.Replace(stack, "\n", "\n\t\t", -1))...)
+				t.mu.Unlock()
+			}
+			t.signal <- true
+		}()
+		defer t.runCleanup(normalPanic)
+		t.start = time.Now()
+		callFuzzFn(fn, t, values)
+		t.finished = true
+	}()
+	<-t.signal
+	return t
+}
+
+// minimize looks for a smaller input that still makes the fuzz function
+// fail, spending at most -test.fuzzminimizetime doing so. It returns the
+// smallest failing input it found, along with the T that ran it.
+func (f *F) minimize(fn reflect.Value, values []interface{}, t *T) ([]interface{}, *T) {
+	deadline := time.Now().Add(*minimizeDuration)
+	f.fuzzLog("minimizing failing input")
+	values = append([]interface{}(nil), values...)
+	for i := range values {
+		fails := func(v interface{}) bool {
+			if time.Now().After(deadline) {
+				return false
+			}
+			try := append([]interface{}(nil), values...)
+			try[i] = v
+			if t1 := f.runInput(fn, try); t1.Failed() {
+				t = t1
+				return true
+			}
+			return false
+		}
+		switch v := values[i].(type) {
+		case []byte:
+			values[i] = minimizeBytes(v, func(b []byte) bool { return fails(b) })
+		case string:
+			values[i] = string(minimizeBytes([]byte(v), func(b []byte) bool { return fails(string(b)) }))
+		default:
+			zero := reflect.Zero(reflect.TypeOf(v)).Interface()
+			if v != zero && fails(zero) {
+				values[i] = zero
+			}
+		}
+	}
+	return values, t
+}
+
+// minimizeBytes removes ever smaller chunks of b
+// for as long as fails reports that the result still fails.
+func minimizeBytes(b []byte, fails func([]byte) bool) []byte {
+	for chunk := len(b); chunk > 0; chunk /= 2 {
+		for i := 0; i+chunk <= len(b); {
+			try := make([]byte, 0, len(b)-chunk)
+			try = append(try, b[:i]...)
+			try = append(try, b[i+chunk:]...)
+			if fails(try) {
+				b = try
+			} else {
+				i += chunk
+			}
+		}
+	}
+	return b
+}
+
+// reportFailure reports the failing run t of the fuzz function on values.
+// If the input is new, it is written to testdata so that go test replays it.
+func (f *F) reportFailure(t *T, values []interface{}, name string) {
+	path := ""
+	if name == "" {
+		data := marshalCorpusFile(values)
+		name = corpusFileName(data)
+		dir := corpusDir("testdata"+string(os.PathSeparator)+"fuzz", f.name)
+		var err error
+		if path, err = writeCorpusFile(dir, name, data); err != nil {
+			f.Errorf("failed to write failing input: %v", err)
+		}
+	}
+	t.name = f.name + "/" + name
+	t.flushToParent("--- FAIL: %s (%s)\n", t.name, fmtDuration(t.duration))
+	f.Fail()
+	if path != "" {
+		f.mu.Lock()
+		fmt.Fprintf(f.w, "\nFailing input written to %s\nTo re-run:\ngo test -run=%s\n", path, t.name)
+		f.mu.Unlock()
+	}
+}
+
+func (f *F) logStats(elapsed time.Duration, execs int64, interesting int) {
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(execs) / elapsed.Seconds()
+	}
+	f.fuzzLog("elapsed: %v, execs: %d (%.0f/sec), new interesting: %d (total: %d)",
+		elapsed/time.Second*time.Second, execs, rate, interesting, len(f.corpus))
+}
+
+// fuzzLog prints progress while fuzzing directly to the root's io.Writer,
+// so that it appears without delay.
+func (f *F) fuzzLog(format string, args ...interface{}) {
+	root := f.root()
+	root.mu.Lock()
+	fmt.Fprintf(root.w, "fuzz: "+format+"\n", args...)
+	root.mu.Unlock()
+}
+
+// fuzzCoverage tracks the coverage counters hit while fuzzing.
+// Counters are grouped into buckets by hit count, so that an input
+// running a loop a different number of times also counts as new
+// coverage. Between inputs the counters are reset to zero; their
+// totals are kept separately and restored when fuzzing ends, so that
+// a coverage profile still reflects everything the test ran.
+type fuzzCoverage struct {
+	seen  map[string][]uint8  // buckets seen, by file and counter
+	total map[string][]uint32 // counts accumulated while fuzzing
+}
+
+func newFuzzCoverage() *fuzzCoverage {
+	c := &fuzzCoverage{
+		seen:  make(map[string][]uint8),
+		total: make(map[string][]uint32),
+	}
+	for name, counters := range cover.Counters {
+		c.seen[name] = make([]uint8, len(counters))
+		c.total[name] = make([]uint32, len(counters))
+	}
+	c.update()
+	return c
+}
+
+func (c *fuzzCoverage) enabled() bool {
+	return len(c.total) > 0
+}
+
+// update moves the current counters into the totals and
+// reports whether they hit any bucket not seen before.
+func (c *fuzzCoverage) update() bool {
+	found := false
+	for name, counters := range cover.Counters {
+		seen, total := c.seen[name], c.total[name]
+		for i := range counters {
+			n := atomic.LoadUint32(&counters[i])
+			if n == 0 {
+				continue
+			}
+			atomic.StoreUint32(&counters[i], 0)
+			total[i] += n
+			if b := countBucket(n); seen[i]&b == 0 {
+				seen[i] |= b
+				found = true
+			}
+		}
+	}
+	return found
+}
+
+// restore puts the accumulated totals back into the counters.
+func (c *fuzzCoverage) restore() {
+	for name, counters := range cover.Counters {
+		total := c.total[name]
+		for i := range counters {
+			atomic.AddUint32(&counters[i], total[i])
+		}
+	}
+}
+
+// countBucket returns the bucket, as a bit, for a nonzero hit count n.
+func countBucket(n uint32) uint8 {
+	switch {
+	case n == 1:
+		return 1 << 0
+	case n == 2:
+		return 1 << 1
+	case n == 3:
+		return 1 << 2
+	case n < 8:
+		return 1 << 3
+	case n < 16:
+		return 1 << 4
+	case n < 32:
+		return 1 << 5
+	case n < 128:
+		return 1 << 6
+	}
+	return 1 << 7
+}
@@ -0,0 +1,96 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testing
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+)
+
+func TestCorpusFileRoundTrip(t *T) {
+	tests := [][]interface{}{
+		{[]byte("hello\x00\xff"), "wörld\n", true},
+		{int(-1), int8(math.MinInt8), int16(math.MaxInt16), int32(-5), int64(math.MinInt64)},
+		{uint(7), uint8('a'), uint8(0x80), uint16(math.MaxUint16), uint32(1), uint64(math.MaxUint64)},
+		{rune('☺'), int32(0xd800), float32(1.5), float64(-2.25e-300)},
+		{math.Inf(1), float32(math.Inf(-1)), []byte{}, ""},
+	}
+	for _, values := range tests {
+		data := marshalCorpusFile(values)
+		got, err := unmarshalCorpusFile(data)
+		if err != nil {
+			t.Errorf("unmarshalCorpusFile(%q): %v", data, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, values) {
+			t.Errorf("round trip of %#v through\n%s\nproduced %#v", values, data, got)
+		}
+	}
+
+	// NaN does not equal itself, so check it separately.
+	got, err := unmarshalCorpusFile(marshalCorpusFile([]interface{}{math.NaN()}))
+	if err != nil || len(got) != 1 || !math.IsNaN(got[0].(float64)) {
+		t.Errorf("round trip of NaN produced %v, %v", got, err)
+	}
+}
+
+func TestUnmarshalCorpusFileErrors(t *T) {
+	tests := []struct {
+		data, err string
+	}{
+		{"", "must begin with"},
+		{"go test fuzz v1\n", "no values"},
+		{"go test fuzz v1\nint(1", "malformed value"},
+		{"go test fuzz v1\ncomplex128(1)", "unsupported type"},
+		{"go test fuzz v1\nint8(300)", "out of range"},
+		{"go test fuzz v1\nbyte('☺')", "malformed byte literal"},
+		{"go test fuzz v1\nstring(\"unterminated)", "invalid syntax"},
+	}
+	for _, tt := range tests {
+		_, err := unmarshalCorpusFile([]byte(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("unmarshalCorpusFile(%q) = %v, want error containing %q", tt.data, err, tt.err)
+		}
+	}
+}
+
+func TestMutatorPreservesTypes(t *T) {
+	m := newMutator(rand.New(rand.NewSource(1)))
+	values := []interface{}{[]byte("abc"), "xyz", true, int8(1), uint32(2), float64(3), rune('r')}
+	for i := 0; i < 10000; i++ {
+		mutated := m.mutate(values)
+		for j, v := range mutated {
+			if reflect.TypeOf(v) != reflect.TypeOf(values[j]) {
+				t.Fatalf("mutate changed value %d from %T to %T", j, values[j], v)
+			}
+		}
+		values = mutated
+	}
+}
+
+func TestMinimizeBytes(t *T) {
+	in := []byte("xxxxxBUGxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx")
+	got := minimizeBytes(in, func(b []byte) bool { return bytes.Contains(b, []byte("BUG")) })
+	if string(got) != "BUG" {
+		t.Errorf("minimizeBytes = %q, want %q", got, "BUG")
+	}
+}
+
+func TestCountBucket(t *T) {
+	seen := uint8(0)
+	for _, n := range []uint32{1, 2, 3, 4, 8, 16, 32, 128} {
+		b := countBucket(n)
+		if seen&b != 0 {
+			t.Errorf("countBucket(%d) = %#x, shared with a smaller count", n, b)
+		}
+		seen |= b
+	}
+	if countBucket(5) != countBucket(7) || countBucket(200) != countBucket(math.MaxUint32) {
+		t.Errorf("counts within a bucket map to different buckets")
+	}
+}
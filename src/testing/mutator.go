@@ -0,0 +1,175 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Random mutation of fuzz inputs.
+
+package testing
+
+import (
+	"math"
+	"math/rand"
+)
+
+// maxFuzzInputLen bounds the length of the []byte and string
+// inputs generated by the mutator.
+const maxFuzzInputLen = 1 << 20
+
+type mutator struct {
+	rand *rand.Rand
+}
+
+func newMutator(r *rand.Rand) *mutator {
+	return &mutator{rand: r}
+}
+
+// mutate returns a copy of values with one value changed at random.
+func (m *mutator) mutate(values []interface{}) []interface{} {
+	values = append([]interface{}(nil), values...)
+	i := m.rand.Intn(len(values))
+	switch v := values[i].(type) {
+	case []byte:
+		values[i] = m.mutateBytes(append([]byte(nil), v...))
+	case string:
+		values[i] = string(m.mutateBytes([]byte(v)))
+	case bool:
+		values[i] = !v
+	case int:
+		values[i] = int(m.mutateInt(int64(v), 64))
+	case int8:
+		values[i] = int8(m.mutateInt(int64(v), 8))
+	case int16:
+		values[i] = int16(m.mutateInt(int64(v), 16))
+	case int32:
+		values[i] = int32(m.mutateInt(int64(v), 32))
+	case int64:
+		values[i] = m.mutateInt(v, 64)
+	case uint:
+		values[i] = uint(m.mutateInt(int64(v), 64))
+	case uint8:
+		values[i] = uint8(m.mutateInt(int64(v), 8))
+	case uint16:
+		values[i] = uint16(m.mutateInt(int64(v), 16))
+	case uint32:
+		values[i] = uint32(m.mutateInt(int64(v), 32))
+	case uint64:
+		values[i] = uint64(m.mutateInt(int64(v), 64))
+	case float32:
+		values[i] = float32(m.mutateFloat(float64(v)))
+	case float64:
+		values[i] = m.mutateFloat(v)
+	default:
+		panic("testing: unsupported fuzz value type")
+	}
+	return values
+}
+
+var interestingInts = []int64{
+	0, 1, -1, 16, 32, 64, 100, 127, -128, 255, 256,
+	math.MaxInt16, math.MinInt16, math.MaxUint16,
+	math.MaxInt32, math.MinInt32, math.MaxUint32,
+	math.MaxInt64, math.MinInt64,
+}
+
+// mutateInt mutates v, an integer of the given size in bits.
+// The caller converts the result back to the integer's type,
+// truncating it as needed.
+func (m *mutator) mutateInt(v int64, bits uint) int64 {
+	switch m.rand.Intn(5) {
+	case 0:
+		return v + int64(1+m.rand.Intn(35))
+	case 1:
+		return v - int64(1+m.rand.Intn(35))
+	case 2:
+		return v ^ 1<<uint(m.rand.Intn(int(bits)))
+	case 3:
+		return interestingInts[m.rand.Intn(len(interestingInts))]
+	}
+	return int64(m.rand.Uint64())
+}
+
+var interestingFloats = []float64{
+	0, 1, -1, 0.5, 1e-300, 1e300,
+	math.SmallestNonzeroFloat64, math.MaxFloat64,
+	math.Inf(1), math.Inf(-1), math.NaN(),
+}
+
+func (m *mutator) mutateFloat(v float64) float64 {
+	switch m.rand.Intn(5) {
+	case 0:
+		return v + float64(1+m.rand.Intn(35))
+	case 1:
+		return v * float64(2+m.rand.Intn(10))
+	case 2:
+		return -v
+	case 3:
+		return interestingFloats[m.rand.Intn(len(interestingFloats))]
+	}
+	return math.Float64frombits(math.Float64bits(v) ^ 1<<uint(m.rand.Intn(64)))
+}
+
+var interestingBytes = []byte{0, 1, '0', 'a', ' ', '\n', 0x7f, 0x80, 0xff}
+
+// mutateBytes applies between one and four random edits to b,
+// which it may modify in place, and returns the result.
+func (m *mutator) mutateBytes(b []byte) []byte {
+	for n := 1 + m.rand.Intn(4); n > 0; n-- {
+		switch m.rand.Intn(8) {
+		case 0: // Flip a bit.
+			if len(b) > 0 {
+				b[m.rand.Intn(len(b))] ^= 1 << uint(m.rand.Intn(8))
+			}
+		case 1: // Set a byte to a random value.
+			if len(b) > 0 {
+				b[m.rand.Intn(len(b))] = byte(m.rand.Intn(256))
+			}
+		case 2: // Set a byte to an interesting value.
+			if len(b) > 0 {
+				b[m.rand.Intn(len(b))] = interestingBytes[m.rand.Intn(len(interestingBytes))]
+			}
+		case 3: // Add to or subtract from a byte.
+			if len(b) > 0 {
+				b[m.rand.Intn(len(b))] += byte(m.rand.Intn(71) - 35)
+			}
+		case 4: // Swap two bytes.
+			if len(b) > 1 {
+				i, j := m.rand.Intn(len(b)), m.rand.Intn(len(b))
+				b[i], b[j] = b[j], b[i]
+			}
+		case 5: // Delete a range.
+			if len(b) > 0 {
+				i := m.rand.Intn(len(b))
+				j := i + 1 + m.rand.Intn(len(b)-i)
+				b = append(b[:i], b[j:]...)
+			}
+		case 6: // Insert random bytes.
+			k := 1 + m.rand.Intn(8)
+			if len(b)+k > maxFuzzInputLen {
+				break
+			}
+			ins := make([]byte, k)
+			m.rand.Read(ins)
+			b = insertBytes(b, m.rand.Intn(len(b)+1), ins)
+		case 7: // Duplicate a range.
+			if len(b) == 0 {
+				break
+			}
+			i := m.rand.Intn(len(b))
+			j := i + 1 + m.rand.Intn(len(b)-i)
+			if len(b)+j-i > maxFuzzInputLen {
+				break
+			}
+			dup := append([]byte(nil), b[i:j]...)
+			b = insertBytes(b, m.rand.Intn(len(b)+1), dup)
+		}
+	}
+	return b
+}
+
+// insertBytes inserts ins into b at index i.
+func insertBytes(b []byte, i int, ins []byte) []byte {
+	b = append(b, ins...)
+	copy(b[i+len(ins):], b[i:])
+	copy(b[i:], ins)
+	return b
+}